@@ -2,19 +2,42 @@ package dto
 
 import (
 	"aur-cache-service/internal/cache/config"
+	"context"
 	"go.uber.org/zap"
+	"strconv"
 )
 
 type ResolverMapper struct {
 	cacheConfigService config.CacheService
+	generations        Generations
 }
 
 const StorageKeySeparator = ":"
 
+// Generations — поколения кэшей с config.Cache.Generations: номер поколения входит
+// в ключ хранилища, и его увеличение делает недоступными все записи кэша.
+type Generations interface {
+	// Generation возвращает текущее поколение кэша; enabled = false, если поколения для кэша выключены.
+	Generation(cacheName string) (gen int64, enabled bool)
+	// Bump увеличивает поколение кэша и возвращает новое.
+	Bump(ctx context.Context, cacheName string) (gen int64, err error)
+}
+
 func NewResolverMapper(cacheConfigService config.CacheService) *ResolverMapper {
 	return &ResolverMapper{cacheConfigService: cacheConfigService}
 }
 
+// WithGenerations подключает поколения кэшей; nil — поколения выключены для всех кэшей.
+func (s *ResolverMapper) WithGenerations(generations Generations) *ResolverMapper {
+	s.generations = generations
+	return s
+}
+
+// Generations возвращает подключённые поколения кэшей или nil.
+func (s *ResolverMapper) Generations() Generations {
+	return s.generations
+}
+
 func (s *ResolverMapper) MapAllResolvedCacheEntry(cacheEntries []*CacheEntry) []*ResolvedCacheEntry {
 	resolvedList := make([]*ResolvedCacheEntry, 0, len(cacheEntries))
 	for _, cacheEntry := range cacheEntries {
@@ -36,20 +59,35 @@ func (s *ResolverMapper) mapResolvedCacheEntry(cacheEntry *CacheEntry) (*Resolve
 	return &ResolvedCacheEntry{
 		ResolvedCacheId: resolvedCacheId,
 		Value:           cacheEntry.Value,
+		TTL:             cacheEntry.TTL,
+		TTLs:            cacheEntry.TTLs,
+		IfAbsent:        cacheEntry.IfAbsent,
+		IfVersion:       cacheEntry.IfVersion,
+		Tags:            cacheEntry.Tags,
 	}, nil
 }
 
 func (s *ResolverMapper) MapAllResolvedCacheId(cacheIds []*CacheId) []*ResolvedCacheId {
+	resolvedList, _ := s.MapAllResolvedCacheIdWithErrors(cacheIds)
+	return resolvedList
+}
+
+// MapAllResolvedCacheIdWithErrors — как MapAllResolvedCacheId, но дополнительно возвращает
+// ключи, которые не удалось сопоставить с кэшем, вместе с причиной.
+// Порядок resolved совпадает с порядком cacheIds.
+func (s *ResolverMapper) MapAllResolvedCacheIdWithErrors(cacheIds []*CacheId) ([]*ResolvedCacheId, []*UnresolvedCacheId) {
 	resolvedList := make([]*ResolvedCacheId, 0, len(cacheIds))
+	var unresolved []*UnresolvedCacheId
 	for _, cacheId := range cacheIds {
 		resolved, err := s.mapResolvedCacheId(cacheId)
 		if err != nil {
 			zap.S().Errorw("while resolve cacheId", "cacheId", cacheId, "error", err)
+			unresolved = append(unresolved, &UnresolvedCacheId{CacheId: cacheId, Err: err})
 		} else {
 			resolvedList = append(resolvedList, resolved)
 		}
 	}
-	return resolvedList
+	return resolvedList, unresolved
 }
 
 func (s *ResolverMapper) mapResolvedCacheId(cacheId *CacheId) (*ResolvedCacheId, error) {
@@ -68,9 +106,48 @@ func (s *ResolverMapper) toStorageKey(cacheId CacheIdRef) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if s.generations != nil {
+		if gen, ok := s.generations.Generation(cacheId.GetCacheName()); ok {
+			return prefix + StorageKeySeparator + strconv.FormatInt(gen, 10) + StorageKeySeparator + cacheId.GetKey(), nil
+		}
+	}
 	return prefix + StorageKeySeparator + cacheId.GetKey(), nil
 }
 
+// StoragePrefix возвращает общий префикс ключей хранилища кэша cacheName («prefix:»),
+// с поколениями — префикс записей всех поколений.
+func (s *ResolverMapper) StoragePrefix(cacheName string) (string, error) {
+	prefix, err := s.cacheConfigService.GetPrefix(&CacheId{CacheName: cacheName})
+	if err != nil {
+		return "", err
+	}
+	return prefix + StorageKeySeparator, nil
+}
+
+// CurrentPrefix возвращает префикс ключей хранилища кэша cacheName в текущем поколении
+// («prefix:gen:»; без поколений — как StoragePrefix).
+func (s *ResolverMapper) CurrentPrefix(cacheName string) (string, error) {
+	return s.toStorageKey(&CacheId{CacheName: cacheName})
+}
+
+// KeyPrefix возвращает префикс ключей хранилища для ключей кэша, начинающихся с keyPrefix:
+// с поколениями — только в текущем поколении. Пустой keyPrefix — как StoragePrefix.
+func (s *ResolverMapper) KeyPrefix(cacheName, keyPrefix string) (string, error) {
+	if keyPrefix == "" {
+		return s.StoragePrefix(cacheName)
+	}
+	return s.toStorageKey(&CacheId{CacheName: cacheName, Key: keyPrefix})
+}
+
+// GenerationPrefix возвращает префикс ключей хранилища поколения gen кэша cacheName («prefix:gen:»).
+func (s *ResolverMapper) GenerationPrefix(cacheName string, gen int64) (string, error) {
+	prefix, err := s.StoragePrefix(cacheName)
+	if err != nil {
+		return "", err
+	}
+	return prefix + strconv.FormatInt(gen, 10) + StorageKeySeparator, nil
+}
+
 func (s *ResolverMapper) MapAllCacheEntryHit(resolvedHits []*ResolvedCacheHit) []*CacheEntryHit {
 	hits := make([]*CacheEntryHit, 0, len(resolvedHits))
 	for _, rh := range resolvedHits {
@@ -80,11 +157,28 @@ func (s *ResolverMapper) MapAllCacheEntryHit(resolvedHits []*ResolvedCacheHit) [
 }
 
 func (s *ResolverMapper) mapCacheEntryHit(resolved *ResolvedCacheHit) *CacheEntryHit {
+	status := HitStatusNotFound
+	if resolved.Found {
+		status = HitStatusFound
+	}
+	layer := resolved.Layer
+	var meta *HitMeta
+	if resolved.Meta != nil {
+		copied := *resolved.Meta
+		copied.Layer = layer
+		if resolved.Found && resolved.ResolvedCacheEntry.Value != nil {
+			copied.Version = ValueVersion(*resolved.ResolvedCacheEntry.Value)
+		}
+		meta = &copied
+	}
 	return &CacheEntryHit{
 		CacheEntry: &CacheEntry{
 			CacheId: resolved.ResolvedCacheEntry.ResolvedCacheId.CacheId,
 			Value:   resolved.ResolvedCacheEntry.Value,
 		},
-		Found: resolved.Found,
+		Found:  resolved.Found,
+		Status: status,
+		Layer:  &layer,
+		Meta:   meta,
 	}
 }
@@ -2,6 +2,7 @@ package dto
 
 import (
 	"aur-cache-service/internal/cache/config"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
@@ -47,6 +48,58 @@ func TestMapAllResolvedCacheId(t *testing.T) {
 	result := mapper.MapAllResolvedCacheId(ids)
 	assert.Len(t, result, 1)
 	assert.Equal(t, "pfx:key1", result[0].StorageKey)
+
+	resolved, unresolved := mapper.MapAllResolvedCacheIdWithErrors(ids)
+	assert.Len(t, resolved, 1)
+	assert.Len(t, unresolved, 1)
+	assert.Same(t, ids[1], unresolved[0].CacheId)
+	assert.Error(t, unresolved[0].Err)
+}
+
+// mockGenerations — поколения в памяти; кэши без записи в gens работают без поколений.
+type mockGenerations struct {
+	gens map[string]int64
+}
+
+func (m *mockGenerations) Generation(cacheName string) (int64, bool) {
+	gen, ok := m.gens[cacheName]
+	return gen, ok
+}
+
+func (m *mockGenerations) Bump(_ context.Context, cacheName string) (int64, error) {
+	m.gens[cacheName]++
+	return m.gens[cacheName], nil
+}
+
+func TestMapAllResolvedCacheId_Generations(t *testing.T) {
+	gens := &mockGenerations{gens: map[string]int64{"gen": 3}}
+	mapper := NewResolverMapper(&mockCacheService{
+		prefixMap: map[string]string{"gen": "g", "plain": "p"},
+	}).WithGenerations(gens)
+
+	result := mapper.MapAllResolvedCacheId([]*CacheId{
+		{CacheName: "gen", Key: "key1"},
+		{CacheName: "plain", Key: "key1"},
+	})
+	assert.Equal(t, "g:3:key1", result[0].StorageKey)
+	assert.Equal(t, "p:key1", result[1].StorageKey)
+
+	_, _ = gens.Bump(context.Background(), "gen")
+	result = mapper.MapAllResolvedCacheId([]*CacheId{{CacheName: "gen", Key: "key1"}})
+	assert.Equal(t, "g:4:key1", result[0].StorageKey)
+
+	prefix, _ := mapper.StoragePrefix("gen")
+	assert.Equal(t, "g:", prefix)
+	prefix, _ = mapper.KeyPrefix("gen", "ab")
+	assert.Equal(t, "g:4:ab", prefix)
+	prefix, _ = mapper.KeyPrefix("gen", "")
+	assert.Equal(t, "g:", prefix)
+	prefix, _ = mapper.GenerationPrefix("gen", 3)
+	assert.Equal(t, "g:3:", prefix)
+	prefix, _ = mapper.CurrentPrefix("gen")
+	assert.Equal(t, "g:4:", prefix)
+	prefix, _ = mapper.CurrentPrefix("plain")
+	assert.Equal(t, "p:", prefix)
 }
 
 func TestMapAllResolvedCacheEntry_FilterFailing(t *testing.T) {
@@ -69,6 +122,44 @@ func TestMapAllResolvedCacheEntry_FilterFailing(t *testing.T) {
 	assert.Equal(t, &raw, result[0].Value)
 }
 
+func TestMapAllResolvedCacheEntry_TTL(t *testing.T) {
+	mapper := NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"ok": "x"}})
+
+	raw := json.RawMessage(`"value"`)
+	ttl, l1 := int64(60), int64(600)
+	entries := []*CacheEntry{
+		{CacheId: &CacheId{CacheName: "ok", Key: "1"}, Value: &raw, TTL: &ttl, TTLs: []*int64{nil, &l1}},
+		{CacheId: &CacheId{CacheName: "ok", Key: "2"}, Value: &raw},
+	}
+
+	result := mapper.MapAllResolvedCacheEntry(entries)
+	assert.Len(t, result, 2)
+
+	got, ok := result[0].TTLFor(0)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, got)
+	got, ok = result[0].TTLFor(1)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Minute, got)
+	got, ok = result[0].TTLFor(2)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, got)
+
+	_, ok = result[1].TTLFor(0)
+	assert.False(t, ok)
+}
+
+func TestCacheEntry_Validate(t *testing.T) {
+	zero, positive := int64(0), int64(5)
+	assert.NoError(t, (&CacheEntry{}).Validate())
+	assert.NoError(t, (&CacheEntry{TTL: &positive, TTLs: []*int64{nil, &positive}}).Validate())
+	assert.Error(t, (&CacheEntry{TTL: &zero}).Validate())
+	assert.Error(t, (&CacheEntry{TTLs: []*int64{&positive, &zero}}).Validate())
+	assert.NoError(t, (&CacheEntry{Tags: []string{"tenant:42"}}).Validate())
+	assert.Error(t, (&CacheEntry{Tags: []string{""}}).Validate())
+	assert.Error(t, (&CacheEntry{Tags: []string{"a\x00b"}}).Validate())
+}
+
 func TestMapAllCacheEntryHit_Mixed(t *testing.T) {
 	v := json.RawMessage(`"v"`)
 
@@ -82,6 +173,8 @@ func TestMapAllCacheEntryHit_Mixed(t *testing.T) {
 				Value: &v,
 			},
 			Found: true,
+			Layer: 1,
+			Meta:  &HitMeta{Provider: "redis"},
 		},
 		{
 			ResolvedCacheEntry: &ResolvedCacheEntry{
@@ -102,4 +195,15 @@ func TestMapAllCacheEntryHit_Mixed(t *testing.T) {
 	assert.Equal(t, false, result[1].Found)
 	assert.Equal(t, "ok", result[0].CacheEntry.CacheId.CacheName)
 	assert.Equal(t, "fail", result[1].CacheEntry.CacheId.CacheName)
+	assert.Equal(t, HitStatusFound, result[0].Status)
+	assert.Equal(t, 1, *result[0].Layer)
+	assert.Equal(t, HitStatusNotFound, result[1].Status)
+	assert.Equal(t, &HitMeta{Layer: 1, Provider: "redis", Version: ValueVersion(v)}, result[0].Meta)
+	assert.Nil(t, result[1].Meta)
+}
+
+func TestValueVersion(t *testing.T) {
+	// версия не зависит от форматирования JSON
+	assert.Equal(t, ValueVersion(json.RawMessage(`{"a":1}`)), ValueVersion(json.RawMessage(`{ "a": 1 }`)))
+	assert.NotEqual(t, ValueVersion(json.RawMessage(`{"a":1}`)), ValueVersion(json.RawMessage(`{"a":2}`)))
 }
@@ -1,7 +1,13 @@
 package dto
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // /////////////////////
@@ -18,12 +24,226 @@ type CacheId struct {
 type CacheEntry struct {
 	*CacheId
 	Value *json.RawMessage `json:"v"`
+	// TTL — время жизни записи в секундах во всех слоях вместо TTL слоёв из конфигурации.
+	// Не больше maxTtl кэша (или TTL слоя, если maxTtl не задан).
+	TTL *int64 `json:"ttl,omitempty"`
+	// TTLs — время жизни по слоям (индекс — номер слоя); null или отсутствующий элемент — TTL.
+	TTLs []*int64 `json:"ttls,omitempty"`
+	// IfAbsent — записать, только если ключа нет в слое кэша (put-if-absent).
+	IfAbsent bool `json:"ifAbsent,omitempty"`
+	// IfVersion — записать, только если версия текущего значения равна заданной (compare-and-set).
+	// Версию возвращает get_all?meta=true.
+	IfVersion string `json:"ifVersion,omitempty"`
+	// Tags — теги записи (например, tenant:42): evict_by_tag удаляет все записи с тегом.
+	Tags []string `json:"tags,omitempty"`
 }
 
+// IsConditional сообщает, что запись выполняется только при выполнении условия (IfAbsent, IfVersion).
+func (e *CacheEntry) IsConditional() bool { return e.IfAbsent || e.IfVersion != "" }
+
+// Validate проверяет, что заданные TTL положительны, а условия записи не противоречат друг другу.
+func (e *CacheEntry) Validate() error {
+	if e.IfAbsent && e.IfVersion != "" {
+		return errors.New("ifAbsent and ifVersion cannot be combined")
+	}
+	if e.TTL != nil && *e.TTL <= 0 {
+		return errors.New("ttl must be > 0")
+	}
+	for _, ttl := range e.TTLs {
+		if ttl != nil && *ttl <= 0 {
+			return errors.New("ttls must be > 0")
+		}
+	}
+	for _, tag := range e.Tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateTag проверяет, что тег не пустой и не содержит нулевой байт (разделитель в индексе тегов).
+func ValidateTag(tag string) error {
+	if tag == "" {
+		return errors.New("tag must not be empty")
+	}
+	if strings.ContainsRune(tag, 0) {
+		return errors.New("tag must not contain NUL")
+	}
+	return nil
+}
+
+// Статусы результата GET по ключу
+const (
+	HitStatusFound         = "found"          // значение найдено в слое кэша или во внешнем API
+	HitStatusNotFound      = "not_found"      // ключа заведомо нет
+	HitStatusUpstreamError = "upstream_error" // ключа нет в слоях, а внешний API не ответил: наличие неизвестно
+	HitStatusUnknownCache  = "unknown_cache"  // кэш не найден в конфигурации
+)
+
 // Внешний API содержит минимальные данные для идентифиации, значение и др информацию это результат GET
 type CacheEntryHit struct {
 	*CacheEntry
-	Found bool `json:"f"`
+	Found  bool   `json:"f"`
+	Status string `json:"status,omitempty"`
+	// Layer — слой, отдавший значение (или tombstone); число слоёв означает внешний API
+	Layer *int     `json:"layer,omitempty"`
+	Error string   `json:"error,omitempty"` // причина статуса upstream_error / unknown_cache
+	Meta  *HitMeta `json:"meta,omitempty"`  // только для get_all с meta=true
+}
+
+// Внешний API: откуда и когда получено значение (get_all с meta=true)
+type HitMeta struct {
+	Layer    int    `json:"layer"`              // слой, отдавший значение; число слоёв — внешний API
+	Provider string `json:"provider,omitempty"` // имя провайдера слоя из конфигурации
+	Upstream bool   `json:"upstream"`           // значение получено из внешнего API
+	// WrittenAt — момент записи в слой; известен, только если у значения есть срок жизни
+	WrittenAt *time.Time `json:"writtenAt,omitempty"`
+	// TtlMs — оставшееся время жизни в слое, мс; не задано, если срока жизни нет
+	TtlMs *int64 `json:"ttlMs,omitempty"`
+	// Version — версия значения для условной записи (put_all с ifVersion)
+	Version string `json:"version,omitempty"`
+}
+
+// ValueVersion — версия значения: FNV-64a от компактного JSON. Одинаковые значения
+// имеют одинаковую версию независимо от слоя и форматирования.
+func ValueVersion(value json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err != nil {
+		buf.Reset()
+		buf.Write(value)
+	}
+	h := fnv.New64a()
+	h.Write(buf.Bytes())
+	return strconv.FormatUint(h.Sum64(), 10)
+}
+
+// Параметры чтения get_all
+type GetOptions struct {
+	// Meta — заполнить CacheEntryHit.Meta для найденных ключей
+	Meta bool
+	// BypassCache — не читать слои: запросить ключи во внешнем API и перезаписать ими все слои
+	BypassCache bool
+	// NoUpstream — читать только слои кэша, внешний API (в том числе фоновое обновление) не вызывается
+	NoUpstream bool
+	// MinLayer, MaxLayer — диапазон слоёв, которые читаются (включительно); MaxLayer = nil — до последнего
+	MinLayer int
+	MaxLayer *int
+	// NoBackfill — не дозаписывать найденные значения в верхние слои
+	NoBackfill bool
+}
+
+// ReadsLayer сообщает, читается ли слой level при этих параметрах.
+func (o GetOptions) ReadsLayer(level int) bool {
+	if o.BypassCache || level < o.MinLayer {
+		return false
+	}
+	return o.MaxLayer == nil || level <= *o.MaxLayer
+}
+
+// Внешний API: ошибка записи ключа во внешний источник (system of record)
+type UpstreamError struct {
+	*CacheId
+	Error string `json:"error"`
+}
+
+// Статусы записи ключа в слой кэша
+const (
+	LayerStatusOk      = "ok"      // слой записан
+	LayerStatusError   = "error"   // слой вернул ошибку
+	LayerStatusSkipped = "skipped" // слой отключён для кэша ключа
+	LayerStatusMissing = "missing" // ключа нет в слое (touch_all)
+)
+
+// Внешний API: результат записи ключа в один слой кэша
+type LayerWriteStatus struct {
+	Layer  int    `json:"layer"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Внешний API: результат синхронной записи (удаления) ключа по слоям кэша
+type WriteItemResult struct {
+	*CacheId
+	Layers []*LayerWriteStatus `json:"layers"`
+}
+
+// Внешний API: результат touch_all по ключу.
+// Touched — TTL продлён хотя бы в одном слое; false — ключа нет ни в одном слое кэша.
+type TouchItemResult struct {
+	*CacheId
+	Touched bool                `json:"touched"`
+	Layers  []*LayerWriteStatus `json:"layers"`
+}
+
+// Внешний API: ответ синхронного put_all/evict_all.
+// Errors — ключи, которые не удалось записать во внешний API; слои для них не менялись.
+// Conflicts — ключи условной записи (ifAbsent, ifVersion), условие которых не выполнилось.
+type WriteReport struct {
+	Results   []*WriteItemResult `json:"results"`
+	Errors    []*UpstreamError   `json:"errors,omitempty"`
+	Conflicts []*CacheId         `json:"conflicts,omitempty"`
+}
+
+// Внешний API: результат evict_by_tag в одном слое кэша.
+// Evicted — число ключей тега в индексе слоя (в том числе уже истёкших).
+type TagLayerStatus struct {
+	Layer   int    `json:"layer"`
+	Status  string `json:"status"`
+	Evicted int    `json:"evicted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Внешний API: ответ evict_by_tag
+type TagEvictReport struct {
+	Tag    string            `json:"tag"`
+	Layers []*TagLayerStatus `json:"layers"`
+}
+
+// Статусы фоновой очистки кэша (flush) и её слоёв
+const (
+	FlushStatusPending   = "pending"   // слой ещё не очищался
+	FlushStatusRunning   = "running"   // очистка выполняется
+	FlushStatusDone      = "done"      // все слои очищены
+	FlushStatusFailed    = "failed"    // хотя бы один слой вернул ошибку
+	FlushStatusCancelled = "cancelled" // очистка отменена
+)
+
+// Внешний API: состояние фоновой очистки кэша.
+// Prefix — префикс удаляемых ключей хранилища; Status — running | done | failed | cancelled.
+type FlushJob struct {
+	Cache      string              `json:"cache"`
+	Prefix     string              `json:"prefix"`
+	Status     string              `json:"status"`
+	StartedAt  time.Time           `json:"startedAt"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty"`
+	Layers     []*FlushLayerStatus `json:"layers"`
+}
+
+// Внешний API: очистка одного слоя. Status — pending | running | ok | error | cancelled;
+// Deleted — сколько ключей удалено (для Ristretto не считается).
+type FlushLayerStatus struct {
+	Layer   int    `json:"layer"`
+	Status  string `json:"status"`
+	Deleted int64  `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Внешний API: поколение кэша. Sweep — очистка записей предыдущего поколения,
+// если она была запрошена и запущена.
+type CacheGeneration struct {
+	Cache      string    `json:"cache"`
+	Generation int64     `json:"generation"`
+	Sweep      *FlushJob `json:"sweep,omitempty"`
+}
+
+// Внешний API: страница ключей кэша на одном слое. Keys — ключи кэша (без префикса хранилища
+// и поколения); Cursor — курсор следующей страницы, пустой — ключи закончились.
+type KeyPage struct {
+	Cache  string   `json:"cache"`
+	Layer  int      `json:"layer"`
+	Keys   []string `json:"keys"`
+	Cursor string   `json:"cursor,omitempty"`
 }
 
 // /////////////////////
@@ -64,16 +284,57 @@ func (r *ResolvedCacheId) GetStorageKey() string { return r.StorageKey }
 type ResolvedCacheEntry struct {
 	ResolvedCacheId *ResolvedCacheId
 	Value           *json.RawMessage
+	// Tombstone — запись является маркером отсутствия ключа во внешнем API (negative caching).
+	// Value при этом не используется.
+	Tombstone bool
+	// TTL и TTLs — время жизни, заданное клиентом (секунды), см. CacheEntry
+	TTL  *int64
+	TTLs []*int64
+	// IfAbsent и IfVersion — условие записи, см. CacheEntry
+	IfAbsent  bool
+	IfVersion string
+	// Tags — теги записи, см. CacheEntry
+	Tags []string
+}
+
+// maxTTLSeconds — наибольший TTL в секундах, представимый time.Duration
+const maxTTLSeconds = int64(1<<63-1) / int64(time.Second)
+
+// TTLFor возвращает заданное клиентом время жизни записи в слое level;
+// false — используется TTL слоя из конфигурации.
+func (r *ResolvedCacheEntry) TTLFor(level int) (time.Duration, bool) {
+	ttl := r.TTL
+	if level >= 0 && level < len(r.TTLs) && r.TTLs[level] != nil {
+		ttl = r.TTLs[level]
+	}
+	if ttl == nil || *ttl <= 0 {
+		return 0, false
+	}
+	if *ttl > maxTTLSeconds {
+		return time.Duration(maxTTLSeconds) * time.Second, true
+	}
+	return time.Duration(*ttl) * time.Second, true
 }
 
 func (r *ResolvedCacheEntry) GetStorageKey() string { return r.ResolvedCacheId.GetStorageKey() }
 func (r *ResolvedCacheEntry) GetCacheName() string  { return r.ResolvedCacheId.GetCacheName() }
 func (r *ResolvedCacheEntry) GetKey() string        { return r.ResolvedCacheId.GetKey() }
 
-// содержит все координаты кэша его значение и результат выполнения операции
+// содержит все координаты кэша его значение и результат выполнения операции.
+// Found = false при найденном в слое tombstone: ключ заведомо отсутствует во внешнем API.
 type ResolvedCacheHit struct {
 	ResolvedCacheEntry *ResolvedCacheEntry
 	Found              bool
+	// Layer — номер слоя, отдавшего значение; для внешнего API — число слоёв кэша
+	Layer int
+	// Stale — значение устарело (истёк мягкий TTL), но ещё может быть отдано клиенту,
+	// пока оно обновляется в фоне (stale-while-revalidate).
+	Stale bool
+	// RefreshDue — значение прочитано незадолго до истечения TTL на самом «горячем» слое
+	// и должно быть заранее обновлено в фоне (refresh-ahead).
+	RefreshDue bool
+	// Meta — провайдер, время записи и оставшийся TTL; заполняется только при GetOptions.Meta
+	Meta *HitMeta
 }
 
 func (r *ResolvedCacheHit) GetStorageKey() string { return r.ResolvedCacheEntry.GetStorageKey() }
@@ -81,14 +342,112 @@ func (r *ResolvedCacheHit) GetStorageKey() string { return r.ResolvedCacheEntry.
 func (r *ResolvedCacheHit) GetCacheName() string { return r.ResolvedCacheEntry.GetCacheName() }
 func (r *ResolvedCacheHit) GetKey() string       { return r.ResolvedCacheEntry.GetKey() }
 
+// результат GET в одном слое или во внешнем API
+//   - Skipped — ключи, которые не проверялись (слой отключён для кэша или вернул ошибку);
+//   - Errors  — причины, по которым ключи из Skipped проверить не удалось (если это ошибка).
 type GetResult struct {
 	Hits    []*ResolvedCacheHit
 	Misses  []*ResolvedCacheId
 	Skipped []*ResolvedCacheId
+	Errors  []*ResolvedCacheError
 }
 
 func (r *GetResult) Merge(other *GetResult) {
 	r.Hits = append(r.Hits, other.Hits...)
 	r.Misses = append(r.Misses, other.Misses...)
 	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Errors = append(r.Errors, other.Errors...)
+}
+
+// ключ, который не удалось сопоставить с кэшем (например, кэш не найден в конфигурации)
+type UnresolvedCacheId struct {
+	CacheId *CacheId
+	Err     error
+}
+
+// ошибка операции с конкретным ключом
+type ResolvedCacheError struct {
+	ResolvedCacheId *ResolvedCacheId
+	Err             error
+}
+
+func (r *ResolvedCacheError) GetStorageKey() string { return r.ResolvedCacheId.GetStorageKey() }
+func (r *ResolvedCacheError) GetCacheName() string  { return r.ResolvedCacheId.GetCacheName() }
+func (r *ResolvedCacheError) GetKey() string        { return r.ResolvedCacheId.GetKey() }
+
+// результат записи (удаления) пачки во внешнем API
+//   - Written — операция выполнена во внешнем API;
+//   - Skipped — для кэша эндпоинт записи не настроен, внешний API не вызывался;
+//   - Failed  — внешний API вернул ошибку.
+type WriteResult struct {
+	Written []*ResolvedCacheId
+	Skipped []*ResolvedCacheId
+	Failed  []*ResolvedCacheError
+}
+
+func (r *WriteResult) Merge(other *WriteResult) {
+	r.Written = append(r.Written, other.Written...)
+	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Failed = append(r.Failed, other.Failed...)
+}
+
+// результат записи put_all во внешний API и план обновления слоёв кэша
+//   - Put    — значения, которые нужно сохранить в слоях кэша;
+//   - Evict  — ключи, которые нужно удалить из слоёв кэша (write-around);
+//   - Failed — ключи, которые не удалось записать во внешний API; слои для них не меняются.
+type UpstreamWriteResult struct {
+	Put    []*CacheEntry
+	Evict  []*CacheId
+	Failed []*UpstreamError
+}
+
+// результат записи (удаления) пачки в один слой кэша
+//   - Skipped — ключи, для которых слой отключён;
+//   - Err     — ошибка слоя, относится ко всем остальным ключам пачки.
+type LayerResult struct {
+	Skipped []*ResolvedCacheId
+	Err     error
+}
+
+// результат удаления ключей по тегу в одном слое кэша
+//   - Keys — удалённые ключи хранилища (по индексу тегов слоя);
+//   - Err  — ошибка слоя.
+type TagLayerResult struct {
+	Keys []string
+	Err  error
+}
+
+// результат продления TTL пачки в одном слое кэша
+//   - Missing — ключа нет в слое (или в нём tombstone), TTL не менялся;
+//   - Skipped — ключи, для которых слой отключён;
+//   - Err     — ошибка слоя, относится ко всем остальным ключам пачки.
+type TouchLayerResult struct {
+	Missing []*ResolvedCacheId
+	Skipped []*ResolvedCacheId
+	Err     error
+}
+
+// результат условной записи пачки в один слой кэша
+//   - Applied   — условие выполнилось, значение записано;
+//   - Conflicts — условие не выполнилось, значение не менялось;
+//   - Skipped   — ключи, для которых слой отключён;
+//   - Errors    — ключи, для которых не удалось прочитать настройки слоя; значение не менялось.
+type CondLayerResult struct {
+	Applied   []*ResolvedCacheId
+	Conflicts []*ResolvedCacheId
+	Skipped   []*ResolvedCacheId
+	Errors    []*ResolvedCacheError
+}
+
+// результат условной записи в слои кэша
+//   - Applied   — условие выполнилось в нижнем включённом слое кэша, значение записано в него и в слои выше;
+//     Layers — статусы записи этих ключей по слоям (по StorageKey);
+//   - Conflicts — условие не выполнилось, слои не менялись;
+//   - Errors    — условие не удалось проверить (слой недоступен, нет настроек слоя
+//     или для кэша нет включённых слоёв).
+type CondWriteResult struct {
+	Applied   []*ResolvedCacheId
+	Layers    map[string][]*LayerWriteStatus
+	Conflicts []*ResolvedCacheId
+	Errors    []*ResolvedCacheError
 }
@@ -0,0 +1,1383 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: cache.proto
+
+package cachepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CacheId struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cache         string                 `protobuf:"bytes,1,opt,name=cache,proto3" json:"cache,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheId) Reset() {
+	*x = CacheId{}
+	mi := &file_cache_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheId) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheId) ProtoMessage() {}
+
+func (x *CacheId) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheId.ProtoReflect.Descriptor instead.
+func (*CacheId) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CacheId) GetCache() string {
+	if x != nil {
+		return x.Cache
+	}
+	return ""
+}
+
+func (x *CacheId) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type CacheEntry struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cache string                 `protobuf:"bytes,1,opt,name=cache,proto3" json:"cache,omitempty"`
+	Key   string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// value — значение в формате JSON
+	Value []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// ttl — время жизни в секундах во всех слоях вместо TTL из конфигурации (не больше maxTtl кэша)
+	Ttl *int64 `protobuf:"varint,4,opt,name=ttl,proto3,oneof" json:"ttl,omitempty"`
+	// ttls — время жизни в секундах по слоям; 0 — ttl (или TTL слоя из конфигурации)
+	Ttls []int64 `protobuf:"varint,5,rep,packed,name=ttls,proto3" json:"ttls,omitempty"`
+	// if_absent — записать, только если ключа нет в нижнем (авторитетном) слое
+	IfAbsent bool `protobuf:"varint,6,opt,name=if_absent,json=ifAbsent,proto3" json:"if_absent,omitempty"`
+	// if_version — записать, только если версия текущего значения совпадает (HitMeta.version)
+	IfVersion string `protobuf:"bytes,7,opt,name=if_version,json=ifVersion,proto3" json:"if_version,omitempty"`
+	// tags — теги записи для удаления по тегу (EvictByTag)
+	Tags          []string `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheEntry) Reset() {
+	*x = CacheEntry{}
+	mi := &file_cache_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheEntry) ProtoMessage() {}
+
+func (x *CacheEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheEntry.ProtoReflect.Descriptor instead.
+func (*CacheEntry) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CacheEntry) GetCache() string {
+	if x != nil {
+		return x.Cache
+	}
+	return ""
+}
+
+func (x *CacheEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CacheEntry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *CacheEntry) GetTtl() int64 {
+	if x != nil && x.Ttl != nil {
+		return *x.Ttl
+	}
+	return 0
+}
+
+func (x *CacheEntry) GetTtls() []int64 {
+	if x != nil {
+		return x.Ttls
+	}
+	return nil
+}
+
+func (x *CacheEntry) GetIfAbsent() bool {
+	if x != nil {
+		return x.IfAbsent
+	}
+	return false
+}
+
+func (x *CacheEntry) GetIfVersion() string {
+	if x != nil {
+		return x.IfVersion
+	}
+	return ""
+}
+
+func (x *CacheEntry) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type CacheEntryHit struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cache string                 `protobuf:"bytes,1,opt,name=cache,proto3" json:"cache,omitempty"`
+	Key   string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// value — значение в формате JSON, пусто если found = false
+	Value []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Found bool   `protobuf:"varint,4,opt,name=found,proto3" json:"found,omitempty"`
+	// status — found, not_found, upstream_error или unknown_cache
+	Status string `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	// layer — слой, из которого получено значение (число слоёв — внешний API)
+	Layer *int32 `protobuf:"varint,6,opt,name=layer,proto3,oneof" json:"layer,omitempty"`
+	// error — причина для upstream_error и unknown_cache
+	Error string `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	// meta — только при GetAllRequest.meta = true
+	Meta          *HitMeta `protobuf:"bytes,8,opt,name=meta,proto3" json:"meta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheEntryHit) Reset() {
+	*x = CacheEntryHit{}
+	mi := &file_cache_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheEntryHit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheEntryHit) ProtoMessage() {}
+
+func (x *CacheEntryHit) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheEntryHit.ProtoReflect.Descriptor instead.
+func (*CacheEntryHit) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CacheEntryHit) GetCache() string {
+	if x != nil {
+		return x.Cache
+	}
+	return ""
+}
+
+func (x *CacheEntryHit) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CacheEntryHit) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *CacheEntryHit) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *CacheEntryHit) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CacheEntryHit) GetLayer() int32 {
+	if x != nil && x.Layer != nil {
+		return *x.Layer
+	}
+	return 0
+}
+
+func (x *CacheEntryHit) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CacheEntryHit) GetMeta() *HitMeta {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+// HitMeta — откуда и когда получено значение (аналог ?meta=true в REST).
+type HitMeta struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Layer int32                  `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	// provider — имя провайдера слоя из конфигурации
+	Provider string `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	Upstream bool   `protobuf:"varint,3,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	// written_at_ms — момент записи в слой (Unix, мс), если известен
+	WrittenAtMs *int64 `protobuf:"varint,4,opt,name=written_at_ms,json=writtenAtMs,proto3,oneof" json:"written_at_ms,omitempty"`
+	// ttl_ms — оставшееся время жизни в слое, мс; не задано, если срока жизни нет
+	TtlMs *int64 `protobuf:"varint,5,opt,name=ttl_ms,json=ttlMs,proto3,oneof" json:"ttl_ms,omitempty"`
+	// version — версия значения для условной записи (CacheEntry.if_version)
+	Version       string `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HitMeta) Reset() {
+	*x = HitMeta{}
+	mi := &file_cache_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HitMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HitMeta) ProtoMessage() {}
+
+func (x *HitMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HitMeta.ProtoReflect.Descriptor instead.
+func (*HitMeta) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HitMeta) GetLayer() int32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *HitMeta) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *HitMeta) GetUpstream() bool {
+	if x != nil {
+		return x.Upstream
+	}
+	return false
+}
+
+func (x *HitMeta) GetWrittenAtMs() int64 {
+	if x != nil && x.WrittenAtMs != nil {
+		return *x.WrittenAtMs
+	}
+	return 0
+}
+
+func (x *HitMeta) GetTtlMs() int64 {
+	if x != nil && x.TtlMs != nil {
+		return *x.TtlMs
+	}
+	return 0
+}
+
+func (x *HitMeta) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type GetAllRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Requests []*CacheId             `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	// meta — вернуть метаданные найденных значений (аналог ?meta=true)
+	Meta bool `protobuf:"varint,2,opt,name=meta,proto3" json:"meta,omitempty"`
+	// параметры чтения — аналоги ?bypassCache, ?noUpstream, ?minLayer, ?maxLayer, ?noBackfill
+	BypassCache   bool   `protobuf:"varint,3,opt,name=bypass_cache,json=bypassCache,proto3" json:"bypass_cache,omitempty"`
+	NoUpstream    bool   `protobuf:"varint,4,opt,name=no_upstream,json=noUpstream,proto3" json:"no_upstream,omitempty"`
+	MinLayer      int32  `protobuf:"varint,5,opt,name=min_layer,json=minLayer,proto3" json:"min_layer,omitempty"`
+	MaxLayer      *int32 `protobuf:"varint,6,opt,name=max_layer,json=maxLayer,proto3,oneof" json:"max_layer,omitempty"`
+	NoBackfill    bool   `protobuf:"varint,7,opt,name=no_backfill,json=noBackfill,proto3" json:"no_backfill,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAllRequest) Reset() {
+	*x = GetAllRequest{}
+	mi := &file_cache_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAllRequest) ProtoMessage() {}
+
+func (x *GetAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAllRequest.ProtoReflect.Descriptor instead.
+func (*GetAllRequest) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetAllRequest) GetRequests() []*CacheId {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+func (x *GetAllRequest) GetMeta() bool {
+	if x != nil {
+		return x.Meta
+	}
+	return false
+}
+
+func (x *GetAllRequest) GetBypassCache() bool {
+	if x != nil {
+		return x.BypassCache
+	}
+	return false
+}
+
+func (x *GetAllRequest) GetNoUpstream() bool {
+	if x != nil {
+		return x.NoUpstream
+	}
+	return false
+}
+
+func (x *GetAllRequest) GetMinLayer() int32 {
+	if x != nil {
+		return x.MinLayer
+	}
+	return 0
+}
+
+func (x *GetAllRequest) GetMaxLayer() int32 {
+	if x != nil && x.MaxLayer != nil {
+		return *x.MaxLayer
+	}
+	return 0
+}
+
+func (x *GetAllRequest) GetNoBackfill() bool {
+	if x != nil {
+		return x.NoBackfill
+	}
+	return false
+}
+
+type GetAllResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*CacheEntryHit       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAllResponse) Reset() {
+	*x = GetAllResponse{}
+	mi := &file_cache_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAllResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAllResponse) ProtoMessage() {}
+
+func (x *GetAllResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAllResponse.ProtoReflect.Descriptor instead.
+func (*GetAllResponse) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetAllResponse) GetResults() []*CacheEntryHit {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type PutAllRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Requests []*CacheEntry          `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	// sync — записать слои кэша до ответа и вернуть результат по слоям (аналог ?sync=true)
+	Sync          bool `protobuf:"varint,2,opt,name=sync,proto3" json:"sync,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutAllRequest) Reset() {
+	*x = PutAllRequest{}
+	mi := &file_cache_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutAllRequest) ProtoMessage() {}
+
+func (x *PutAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutAllRequest.ProtoReflect.Descriptor instead.
+func (*PutAllRequest) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PutAllRequest) GetRequests() []*CacheEntry {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+func (x *PutAllRequest) GetSync() bool {
+	if x != nil {
+		return x.Sync
+	}
+	return false
+}
+
+type EvictAllRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Requests []*CacheId             `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	// sync — удалить из слоёв кэша до ответа и вернуть результат по слоям (аналог ?sync=true)
+	Sync          bool `protobuf:"varint,2,opt,name=sync,proto3" json:"sync,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvictAllRequest) Reset() {
+	*x = EvictAllRequest{}
+	mi := &file_cache_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvictAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvictAllRequest) ProtoMessage() {}
+
+func (x *EvictAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvictAllRequest.ProtoReflect.Descriptor instead.
+func (*EvictAllRequest) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *EvictAllRequest) GetRequests() []*CacheId {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+func (x *EvictAllRequest) GetSync() bool {
+	if x != nil {
+		return x.Sync
+	}
+	return false
+}
+
+type UpstreamError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cache         string                 `protobuf:"bytes,1,opt,name=cache,proto3" json:"cache,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpstreamError) Reset() {
+	*x = UpstreamError{}
+	mi := &file_cache_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpstreamError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpstreamError) ProtoMessage() {}
+
+func (x *UpstreamError) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpstreamError.ProtoReflect.Descriptor instead.
+func (*UpstreamError) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpstreamError) GetCache() string {
+	if x != nil {
+		return x.Cache
+	}
+	return ""
+}
+
+func (x *UpstreamError) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *UpstreamError) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type LayerWriteStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Layer int32                  `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	// status — ok | error | skipped (для TouchAll также missing)
+	Status        string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LayerWriteStatus) Reset() {
+	*x = LayerWriteStatus{}
+	mi := &file_cache_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LayerWriteStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LayerWriteStatus) ProtoMessage() {}
+
+func (x *LayerWriteStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LayerWriteStatus.ProtoReflect.Descriptor instead.
+func (*LayerWriteStatus) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *LayerWriteStatus) GetLayer() int32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *LayerWriteStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *LayerWriteStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type WriteItemResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cache         string                 `protobuf:"bytes,1,opt,name=cache,proto3" json:"cache,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Layers        []*LayerWriteStatus    `protobuf:"bytes,3,rep,name=layers,proto3" json:"layers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteItemResult) Reset() {
+	*x = WriteItemResult{}
+	mi := &file_cache_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteItemResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteItemResult) ProtoMessage() {}
+
+func (x *WriteItemResult) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteItemResult.ProtoReflect.Descriptor instead.
+func (*WriteItemResult) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WriteItemResult) GetCache() string {
+	if x != nil {
+		return x.Cache
+	}
+	return ""
+}
+
+func (x *WriteItemResult) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *WriteItemResult) GetLayers() []*LayerWriteStatus {
+	if x != nil {
+		return x.Layers
+	}
+	return nil
+}
+
+type WriteResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// results — результат по слоям, заполняется только при sync = true
+	Results []*WriteItemResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Errors  []*UpstreamError   `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"`
+	// conflicts — ключи, для которых не выполнено условие записи (if_absent, if_version)
+	Conflicts     []*CacheId `protobuf:"bytes,3,rep,name=conflicts,proto3" json:"conflicts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteResponse) Reset() {
+	*x = WriteResponse{}
+	mi := &file_cache_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteResponse) ProtoMessage() {}
+
+func (x *WriteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteResponse.ProtoReflect.Descriptor instead.
+func (*WriteResponse) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WriteResponse) GetResults() []*WriteItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *WriteResponse) GetErrors() []*UpstreamError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *WriteResponse) GetConflicts() []*CacheId {
+	if x != nil {
+		return x.Conflicts
+	}
+	return nil
+}
+
+type TouchAllRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// requests — ключи и ttl / ttls (как в PutAllRequest); value не используется
+	Requests      []*CacheEntry `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TouchAllRequest) Reset() {
+	*x = TouchAllRequest{}
+	mi := &file_cache_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TouchAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TouchAllRequest) ProtoMessage() {}
+
+func (x *TouchAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TouchAllRequest.ProtoReflect.Descriptor instead.
+func (*TouchAllRequest) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TouchAllRequest) GetRequests() []*CacheEntry {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+type TouchItemResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cache string                 `protobuf:"bytes,1,opt,name=cache,proto3" json:"cache,omitempty"`
+	Key   string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// touched — TTL продлён хотя бы в одном слое
+	Touched bool `protobuf:"varint,3,opt,name=touched,proto3" json:"touched,omitempty"`
+	// layers — status: ok | missing | error | skipped
+	Layers        []*LayerWriteStatus `protobuf:"bytes,4,rep,name=layers,proto3" json:"layers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TouchItemResult) Reset() {
+	*x = TouchItemResult{}
+	mi := &file_cache_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TouchItemResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TouchItemResult) ProtoMessage() {}
+
+func (x *TouchItemResult) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TouchItemResult.ProtoReflect.Descriptor instead.
+func (*TouchItemResult) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TouchItemResult) GetCache() string {
+	if x != nil {
+		return x.Cache
+	}
+	return ""
+}
+
+func (x *TouchItemResult) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *TouchItemResult) GetTouched() bool {
+	if x != nil {
+		return x.Touched
+	}
+	return false
+}
+
+func (x *TouchItemResult) GetLayers() []*LayerWriteStatus {
+	if x != nil {
+		return x.Layers
+	}
+	return nil
+}
+
+type TouchAllResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*TouchItemResult     `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TouchAllResponse) Reset() {
+	*x = TouchAllResponse{}
+	mi := &file_cache_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TouchAllResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TouchAllResponse) ProtoMessage() {}
+
+func (x *TouchAllResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TouchAllResponse.ProtoReflect.Descriptor instead.
+func (*TouchAllResponse) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TouchAllResponse) GetResults() []*TouchItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type EvictByTagRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvictByTagRequest) Reset() {
+	*x = EvictByTagRequest{}
+	mi := &file_cache_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvictByTagRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvictByTagRequest) ProtoMessage() {}
+
+func (x *EvictByTagRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvictByTagRequest.ProtoReflect.Descriptor instead.
+func (*EvictByTagRequest) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *EvictByTagRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type TagLayerStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Layer int32                  `protobuf:"varint,1,opt,name=layer,proto3" json:"layer,omitempty"`
+	// status — ok | error
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	// evicted — сколько ключей тега найдено в индексе слоя
+	Evicted       int32  `protobuf:"varint,3,opt,name=evicted,proto3" json:"evicted,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TagLayerStatus) Reset() {
+	*x = TagLayerStatus{}
+	mi := &file_cache_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TagLayerStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TagLayerStatus) ProtoMessage() {}
+
+func (x *TagLayerStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TagLayerStatus.ProtoReflect.Descriptor instead.
+func (*TagLayerStatus) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *TagLayerStatus) GetLayer() int32 {
+	if x != nil {
+		return x.Layer
+	}
+	return 0
+}
+
+func (x *TagLayerStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *TagLayerStatus) GetEvicted() int32 {
+	if x != nil {
+		return x.Evicted
+	}
+	return 0
+}
+
+func (x *TagLayerStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type EvictByTagResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Layers        []*TagLayerStatus      `protobuf:"bytes,2,rep,name=layers,proto3" json:"layers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvictByTagResponse) Reset() {
+	*x = EvictByTagResponse{}
+	mi := &file_cache_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvictByTagResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvictByTagResponse) ProtoMessage() {}
+
+func (x *EvictByTagResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvictByTagResponse.ProtoReflect.Descriptor instead.
+func (*EvictByTagResponse) Descriptor() ([]byte, []int) {
+	return file_cache_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *EvictByTagResponse) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *EvictByTagResponse) GetLayers() []*TagLayerStatus {
+	if x != nil {
+		return x.Layers
+	}
+	return nil
+}
+
+var File_cache_proto protoreflect.FileDescriptor
+
+const file_cache_proto_rawDesc = "" +
+	"\n" +
+	"\vcache.proto\x12\vaurcache.v1\"1\n" +
+	"\aCacheId\x12\x14\n" +
+	"\x05cache\x18\x01 \x01(\tR\x05cache\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"\xcd\x01\n" +
+	"\n" +
+	"CacheEntry\x12\x14\n" +
+	"\x05cache\x18\x01 \x01(\tR\x05cache\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x15\n" +
+	"\x03ttl\x18\x04 \x01(\x03H\x00R\x03ttl\x88\x01\x01\x12\x12\n" +
+	"\x04ttls\x18\x05 \x03(\x03R\x04ttls\x12\x1b\n" +
+	"\tif_absent\x18\x06 \x01(\bR\bifAbsent\x12\x1d\n" +
+	"\n" +
+	"if_version\x18\a \x01(\tR\tifVersion\x12\x12\n" +
+	"\x04tags\x18\b \x03(\tR\x04tagsB\x06\n" +
+	"\x04_ttl\"\xe0\x01\n" +
+	"\rCacheEntryHit\x12\x14\n" +
+	"\x05cache\x18\x01 \x01(\tR\x05cache\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x14\n" +
+	"\x05found\x18\x04 \x01(\bR\x05found\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x19\n" +
+	"\x05layer\x18\x06 \x01(\x05H\x00R\x05layer\x88\x01\x01\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12(\n" +
+	"\x04meta\x18\b \x01(\v2\x14.aurcache.v1.HitMetaR\x04metaB\b\n" +
+	"\x06_layer\"\xd3\x01\n" +
+	"\aHitMeta\x12\x14\n" +
+	"\x05layer\x18\x01 \x01(\x05R\x05layer\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x1a\n" +
+	"\bupstream\x18\x03 \x01(\bR\bupstream\x12'\n" +
+	"\rwritten_at_ms\x18\x04 \x01(\x03H\x00R\vwrittenAtMs\x88\x01\x01\x12\x1a\n" +
+	"\x06ttl_ms\x18\x05 \x01(\x03H\x01R\x05ttlMs\x88\x01\x01\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\tR\aversionB\x10\n" +
+	"\x0e_written_at_msB\t\n" +
+	"\a_ttl_ms\"\x87\x02\n" +
+	"\rGetAllRequest\x120\n" +
+	"\brequests\x18\x01 \x03(\v2\x14.aurcache.v1.CacheIdR\brequests\x12\x12\n" +
+	"\x04meta\x18\x02 \x01(\bR\x04meta\x12!\n" +
+	"\fbypass_cache\x18\x03 \x01(\bR\vbypassCache\x12\x1f\n" +
+	"\vno_upstream\x18\x04 \x01(\bR\n" +
+	"noUpstream\x12\x1b\n" +
+	"\tmin_layer\x18\x05 \x01(\x05R\bminLayer\x12 \n" +
+	"\tmax_layer\x18\x06 \x01(\x05H\x00R\bmaxLayer\x88\x01\x01\x12\x1f\n" +
+	"\vno_backfill\x18\a \x01(\bR\n" +
+	"noBackfillB\f\n" +
+	"\n" +
+	"_max_layer\"F\n" +
+	"\x0eGetAllResponse\x124\n" +
+	"\aresults\x18\x01 \x03(\v2\x1a.aurcache.v1.CacheEntryHitR\aresults\"X\n" +
+	"\rPutAllRequest\x123\n" +
+	"\brequests\x18\x01 \x03(\v2\x17.aurcache.v1.CacheEntryR\brequests\x12\x12\n" +
+	"\x04sync\x18\x02 \x01(\bR\x04sync\"W\n" +
+	"\x0fEvictAllRequest\x120\n" +
+	"\brequests\x18\x01 \x03(\v2\x14.aurcache.v1.CacheIdR\brequests\x12\x12\n" +
+	"\x04sync\x18\x02 \x01(\bR\x04sync\"M\n" +
+	"\rUpstreamError\x12\x14\n" +
+	"\x05cache\x18\x01 \x01(\tR\x05cache\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"V\n" +
+	"\x10LayerWriteStatus\x12\x14\n" +
+	"\x05layer\x18\x01 \x01(\x05R\x05layer\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"p\n" +
+	"\x0fWriteItemResult\x12\x14\n" +
+	"\x05cache\x18\x01 \x01(\tR\x05cache\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x125\n" +
+	"\x06layers\x18\x03 \x03(\v2\x1d.aurcache.v1.LayerWriteStatusR\x06layers\"\xaf\x01\n" +
+	"\rWriteResponse\x126\n" +
+	"\aresults\x18\x01 \x03(\v2\x1c.aurcache.v1.WriteItemResultR\aresults\x122\n" +
+	"\x06errors\x18\x02 \x03(\v2\x1a.aurcache.v1.UpstreamErrorR\x06errors\x122\n" +
+	"\tconflicts\x18\x03 \x03(\v2\x14.aurcache.v1.CacheIdR\tconflicts\"F\n" +
+	"\x0fTouchAllRequest\x123\n" +
+	"\brequests\x18\x01 \x03(\v2\x17.aurcache.v1.CacheEntryR\brequests\"\x8a\x01\n" +
+	"\x0fTouchItemResult\x12\x14\n" +
+	"\x05cache\x18\x01 \x01(\tR\x05cache\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x18\n" +
+	"\atouched\x18\x03 \x01(\bR\atouched\x125\n" +
+	"\x06layers\x18\x04 \x03(\v2\x1d.aurcache.v1.LayerWriteStatusR\x06layers\"J\n" +
+	"\x10TouchAllResponse\x126\n" +
+	"\aresults\x18\x01 \x03(\v2\x1c.aurcache.v1.TouchItemResultR\aresults\"%\n" +
+	"\x11EvictByTagRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\"n\n" +
+	"\x0eTagLayerStatus\x12\x14\n" +
+	"\x05layer\x18\x01 \x01(\x05R\x05layer\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x18\n" +
+	"\aevicted\x18\x03 \x01(\x05R\aevicted\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"[\n" +
+	"\x12EvictByTagResponse\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x123\n" +
+	"\x06layers\x18\x02 \x03(\v2\x1b.aurcache.v1.TagLayerStatusR\x06layers2\xbb\x03\n" +
+	"\fCacheService\x12A\n" +
+	"\x06GetAll\x12\x1a.aurcache.v1.GetAllRequest\x1a\x1b.aurcache.v1.GetAllResponse\x12H\n" +
+	"\fStreamGetAll\x12\x1a.aurcache.v1.GetAllRequest\x1a\x1a.aurcache.v1.CacheEntryHit0\x01\x12@\n" +
+	"\x06PutAll\x12\x1a.aurcache.v1.PutAllRequest\x1a\x1a.aurcache.v1.WriteResponse\x12D\n" +
+	"\bEvictAll\x12\x1c.aurcache.v1.EvictAllRequest\x1a\x1a.aurcache.v1.WriteResponse\x12G\n" +
+	"\bTouchAll\x12\x1c.aurcache.v1.TouchAllRequest\x1a\x1d.aurcache.v1.TouchAllResponse\x12M\n" +
+	"\n" +
+	"EvictByTag\x12\x1e.aurcache.v1.EvictByTagRequest\x1a\x1f.aurcache.v1.EvictByTagResponseB$Z\"aur-cache-service/api/grpc;cachepbb\x06proto3"
+
+var (
+	file_cache_proto_rawDescOnce sync.Once
+	file_cache_proto_rawDescData []byte
+)
+
+func file_cache_proto_rawDescGZIP() []byte {
+	file_cache_proto_rawDescOnce.Do(func() {
+		file_cache_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_cache_proto_rawDesc), len(file_cache_proto_rawDesc)))
+	})
+	return file_cache_proto_rawDescData
+}
+
+var file_cache_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_cache_proto_goTypes = []any{
+	(*CacheId)(nil),            // 0: aurcache.v1.CacheId
+	(*CacheEntry)(nil),         // 1: aurcache.v1.CacheEntry
+	(*CacheEntryHit)(nil),      // 2: aurcache.v1.CacheEntryHit
+	(*HitMeta)(nil),            // 3: aurcache.v1.HitMeta
+	(*GetAllRequest)(nil),      // 4: aurcache.v1.GetAllRequest
+	(*GetAllResponse)(nil),     // 5: aurcache.v1.GetAllResponse
+	(*PutAllRequest)(nil),      // 6: aurcache.v1.PutAllRequest
+	(*EvictAllRequest)(nil),    // 7: aurcache.v1.EvictAllRequest
+	(*UpstreamError)(nil),      // 8: aurcache.v1.UpstreamError
+	(*LayerWriteStatus)(nil),   // 9: aurcache.v1.LayerWriteStatus
+	(*WriteItemResult)(nil),    // 10: aurcache.v1.WriteItemResult
+	(*WriteResponse)(nil),      // 11: aurcache.v1.WriteResponse
+	(*TouchAllRequest)(nil),    // 12: aurcache.v1.TouchAllRequest
+	(*TouchItemResult)(nil),    // 13: aurcache.v1.TouchItemResult
+	(*TouchAllResponse)(nil),   // 14: aurcache.v1.TouchAllResponse
+	(*EvictByTagRequest)(nil),  // 15: aurcache.v1.EvictByTagRequest
+	(*TagLayerStatus)(nil),     // 16: aurcache.v1.TagLayerStatus
+	(*EvictByTagResponse)(nil), // 17: aurcache.v1.EvictByTagResponse
+}
+var file_cache_proto_depIdxs = []int32{
+	3,  // 0: aurcache.v1.CacheEntryHit.meta:type_name -> aurcache.v1.HitMeta
+	0,  // 1: aurcache.v1.GetAllRequest.requests:type_name -> aurcache.v1.CacheId
+	2,  // 2: aurcache.v1.GetAllResponse.results:type_name -> aurcache.v1.CacheEntryHit
+	1,  // 3: aurcache.v1.PutAllRequest.requests:type_name -> aurcache.v1.CacheEntry
+	0,  // 4: aurcache.v1.EvictAllRequest.requests:type_name -> aurcache.v1.CacheId
+	9,  // 5: aurcache.v1.WriteItemResult.layers:type_name -> aurcache.v1.LayerWriteStatus
+	10, // 6: aurcache.v1.WriteResponse.results:type_name -> aurcache.v1.WriteItemResult
+	8,  // 7: aurcache.v1.WriteResponse.errors:type_name -> aurcache.v1.UpstreamError
+	0,  // 8: aurcache.v1.WriteResponse.conflicts:type_name -> aurcache.v1.CacheId
+	1,  // 9: aurcache.v1.TouchAllRequest.requests:type_name -> aurcache.v1.CacheEntry
+	9,  // 10: aurcache.v1.TouchItemResult.layers:type_name -> aurcache.v1.LayerWriteStatus
+	13, // 11: aurcache.v1.TouchAllResponse.results:type_name -> aurcache.v1.TouchItemResult
+	16, // 12: aurcache.v1.EvictByTagResponse.layers:type_name -> aurcache.v1.TagLayerStatus
+	4,  // 13: aurcache.v1.CacheService.GetAll:input_type -> aurcache.v1.GetAllRequest
+	4,  // 14: aurcache.v1.CacheService.StreamGetAll:input_type -> aurcache.v1.GetAllRequest
+	6,  // 15: aurcache.v1.CacheService.PutAll:input_type -> aurcache.v1.PutAllRequest
+	7,  // 16: aurcache.v1.CacheService.EvictAll:input_type -> aurcache.v1.EvictAllRequest
+	12, // 17: aurcache.v1.CacheService.TouchAll:input_type -> aurcache.v1.TouchAllRequest
+	15, // 18: aurcache.v1.CacheService.EvictByTag:input_type -> aurcache.v1.EvictByTagRequest
+	5,  // 19: aurcache.v1.CacheService.GetAll:output_type -> aurcache.v1.GetAllResponse
+	2,  // 20: aurcache.v1.CacheService.StreamGetAll:output_type -> aurcache.v1.CacheEntryHit
+	11, // 21: aurcache.v1.CacheService.PutAll:output_type -> aurcache.v1.WriteResponse
+	11, // 22: aurcache.v1.CacheService.EvictAll:output_type -> aurcache.v1.WriteResponse
+	14, // 23: aurcache.v1.CacheService.TouchAll:output_type -> aurcache.v1.TouchAllResponse
+	17, // 24: aurcache.v1.CacheService.EvictByTag:output_type -> aurcache.v1.EvictByTagResponse
+	19, // [19:25] is the sub-list for method output_type
+	13, // [13:19] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_cache_proto_init() }
+func file_cache_proto_init() {
+	if File_cache_proto != nil {
+		return
+	}
+	file_cache_proto_msgTypes[1].OneofWrappers = []any{}
+	file_cache_proto_msgTypes[2].OneofWrappers = []any{}
+	file_cache_proto_msgTypes[3].OneofWrappers = []any{}
+	file_cache_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cache_proto_rawDesc), len(file_cache_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_cache_proto_goTypes,
+		DependencyIndexes: file_cache_proto_depIdxs,
+		MessageInfos:      file_cache_proto_msgTypes,
+	}.Build()
+	File_cache_proto = out.File
+	file_cache_proto_goTypes = nil
+	file_cache_proto_depIdxs = nil
+}
@@ -0,0 +1,335 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: cache.proto
+
+package cachepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CacheService_GetAll_FullMethodName       = "/aurcache.v1.CacheService/GetAll"
+	CacheService_StreamGetAll_FullMethodName = "/aurcache.v1.CacheService/StreamGetAll"
+	CacheService_PutAll_FullMethodName       = "/aurcache.v1.CacheService/PutAll"
+	CacheService_EvictAll_FullMethodName     = "/aurcache.v1.CacheService/EvictAll"
+	CacheService_TouchAll_FullMethodName     = "/aurcache.v1.CacheService/TouchAll"
+	CacheService_EvictByTag_FullMethodName   = "/aurcache.v1.CacheService/EvictByTag"
+)
+
+// CacheServiceClient is the client API for CacheService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CacheService — gRPC-доступ к многослойному кэшу. Семантика совпадает с REST API
+// (/api/v1/cache/get_all, put_all, evict_all, touch_all, evict_by_tag).
+type CacheServiceClient interface {
+	// GetAll получает значения по ключам с fallback на внешний API.
+	GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (*GetAllResponse, error)
+	// StreamGetAll — то же, что GetAll, но результаты отдаются потоком частями
+	// по мере обработки. Предназначен для очень больших наборов ключей.
+	StreamGetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CacheEntryHit], error)
+	// PutAll сохраняет значения. Ключи, не принятые внешним API (putBatch), возвращаются в errors.
+	PutAll(ctx context.Context, in *PutAllRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// EvictAll удаляет ключи. Ключи, не удалённые во внешнем API (deleteBatch), возвращаются в errors.
+	EvictAll(ctx context.Context, in *EvictAllRequest, opts ...grpc.CallOption) (*WriteResponse, error)
+	// TouchAll продлевает TTL ключей в слоях кэша, не перезаписывая значения.
+	TouchAll(ctx context.Context, in *TouchAllRequest, opts ...grpc.CallOption) (*TouchAllResponse, error)
+	// EvictByTag удаляет из слоёв кэша все ключи с тегом; внешний API не вызывается.
+	EvictByTag(ctx context.Context, in *EvictByTagRequest, opts ...grpc.CallOption) (*EvictByTagResponse, error)
+}
+
+type cacheServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCacheServiceClient(cc grpc.ClientConnInterface) CacheServiceClient {
+	return &cacheServiceClient{cc}
+}
+
+func (c *cacheServiceClient) GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (*GetAllResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAllResponse)
+	err := c.cc.Invoke(ctx, CacheService_GetAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) StreamGetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CacheEntryHit], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CacheService_ServiceDesc.Streams[0], CacheService_StreamGetAll_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetAllRequest, CacheEntryHit]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CacheService_StreamGetAllClient = grpc.ServerStreamingClient[CacheEntryHit]
+
+func (c *cacheServiceClient) PutAll(ctx context.Context, in *PutAllRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, CacheService_PutAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) EvictAll(ctx context.Context, in *EvictAllRequest, opts ...grpc.CallOption) (*WriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteResponse)
+	err := c.cc.Invoke(ctx, CacheService_EvictAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) TouchAll(ctx context.Context, in *TouchAllRequest, opts ...grpc.CallOption) (*TouchAllResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TouchAllResponse)
+	err := c.cc.Invoke(ctx, CacheService_TouchAll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) EvictByTag(ctx context.Context, in *EvictByTagRequest, opts ...grpc.CallOption) (*EvictByTagResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EvictByTagResponse)
+	err := c.cc.Invoke(ctx, CacheService_EvictByTag_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CacheServiceServer is the server API for CacheService service.
+// All implementations must embed UnimplementedCacheServiceServer
+// for forward compatibility.
+//
+// CacheService — gRPC-доступ к многослойному кэшу. Семантика совпадает с REST API
+// (/api/v1/cache/get_all, put_all, evict_all, touch_all, evict_by_tag).
+type CacheServiceServer interface {
+	// GetAll получает значения по ключам с fallback на внешний API.
+	GetAll(context.Context, *GetAllRequest) (*GetAllResponse, error)
+	// StreamGetAll — то же, что GetAll, но результаты отдаются потоком частями
+	// по мере обработки. Предназначен для очень больших наборов ключей.
+	StreamGetAll(*GetAllRequest, grpc.ServerStreamingServer[CacheEntryHit]) error
+	// PutAll сохраняет значения. Ключи, не принятые внешним API (putBatch), возвращаются в errors.
+	PutAll(context.Context, *PutAllRequest) (*WriteResponse, error)
+	// EvictAll удаляет ключи. Ключи, не удалённые во внешнем API (deleteBatch), возвращаются в errors.
+	EvictAll(context.Context, *EvictAllRequest) (*WriteResponse, error)
+	// TouchAll продлевает TTL ключей в слоях кэша, не перезаписывая значения.
+	TouchAll(context.Context, *TouchAllRequest) (*TouchAllResponse, error)
+	// EvictByTag удаляет из слоёв кэша все ключи с тегом; внешний API не вызывается.
+	EvictByTag(context.Context, *EvictByTagRequest) (*EvictByTagResponse, error)
+	mustEmbedUnimplementedCacheServiceServer()
+}
+
+// UnimplementedCacheServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCacheServiceServer struct{}
+
+func (UnimplementedCacheServiceServer) GetAll(context.Context, *GetAllRequest) (*GetAllResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAll not implemented")
+}
+func (UnimplementedCacheServiceServer) StreamGetAll(*GetAllRequest, grpc.ServerStreamingServer[CacheEntryHit]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamGetAll not implemented")
+}
+func (UnimplementedCacheServiceServer) PutAll(context.Context, *PutAllRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutAll not implemented")
+}
+func (UnimplementedCacheServiceServer) EvictAll(context.Context, *EvictAllRequest) (*WriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvictAll not implemented")
+}
+func (UnimplementedCacheServiceServer) TouchAll(context.Context, *TouchAllRequest) (*TouchAllResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TouchAll not implemented")
+}
+func (UnimplementedCacheServiceServer) EvictByTag(context.Context, *EvictByTagRequest) (*EvictByTagResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EvictByTag not implemented")
+}
+func (UnimplementedCacheServiceServer) mustEmbedUnimplementedCacheServiceServer() {}
+func (UnimplementedCacheServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeCacheServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CacheServiceServer will
+// result in compilation errors.
+type UnsafeCacheServiceServer interface {
+	mustEmbedUnimplementedCacheServiceServer()
+}
+
+func RegisterCacheServiceServer(s grpc.ServiceRegistrar, srv CacheServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCacheServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CacheService_ServiceDesc, srv)
+}
+
+func _CacheService_GetAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).GetAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_GetAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).GetAll(ctx, req.(*GetAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_StreamGetAll_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetAllRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CacheServiceServer).StreamGetAll(m, &grpc.GenericServerStream[GetAllRequest, CacheEntryHit]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CacheService_StreamGetAllServer = grpc.ServerStreamingServer[CacheEntryHit]
+
+func _CacheService_PutAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).PutAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_PutAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).PutAll(ctx, req.(*PutAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_EvictAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvictAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).EvictAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_EvictAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).EvictAll(ctx, req.(*EvictAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_TouchAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TouchAllRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).TouchAll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_TouchAll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).TouchAll(ctx, req.(*TouchAllRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_EvictByTag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvictByTagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).EvictByTag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_EvictByTag_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).EvictByTag(ctx, req.(*EvictByTagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CacheService_ServiceDesc is the grpc.ServiceDesc for CacheService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CacheService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aurcache.v1.CacheService",
+	HandlerType: (*CacheServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAll",
+			Handler:    _CacheService_GetAll_Handler,
+		},
+		{
+			MethodName: "PutAll",
+			Handler:    _CacheService_PutAll_Handler,
+		},
+		{
+			MethodName: "EvictAll",
+			Handler:    _CacheService_EvictAll_Handler,
+		},
+		{
+			MethodName: "TouchAll",
+			Handler:    _CacheService_TouchAll_Handler,
+		},
+		{
+			MethodName: "EvictByTag",
+			Handler:    _CacheService_EvictByTag_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamGetAll",
+			Handler:       _CacheService_StreamGetAll_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cache.proto",
+}
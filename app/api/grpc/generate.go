@@ -0,0 +1,4 @@
+// Package cachepb содержит gRPC API сервиса, сгенерированный из cache.proto.
+package cachepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cache.proto
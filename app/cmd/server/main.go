@@ -3,18 +3,27 @@ package main
 import (
 	"aur-cache-service/api/dto"
 	"aur-cache-service/internal/cache"
+	"aur-cache-service/internal/cache/config"
+	"aur-cache-service/internal/grpcserver"
 	"aur-cache-service/internal/httpserver"
 	"aur-cache-service/internal/integration"
 	"aur-cache-service/internal/logger"
 	"aur-cache-service/internal/manager"
+	"aur-cache-service/internal/memcached"
 	"aur-cache-service/internal/metrics"
+	"aur-cache-service/internal/resp"
+	"context"
 	"fmt"
 	"go.uber.org/zap"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
+	"google.golang.org/grpc"
 	"telegram-alerts-go/alert"
 )
 
@@ -22,6 +31,7 @@ const (
 	configFilePath  = "/configs/config.yml"
 	putAllTimeout   = 10 * time.Second
 	evictAllTimeout = 10 * time.Second
+	shutdownTimeout = 30 * time.Second
 	portApi         = 8080
 	portMetrics     = 9080
 )
@@ -35,52 +45,139 @@ func main() {
 
 	metrics.Register()
 
+	appConfig, err := config.LoadAppConfig(configFilePath)
+	if err != nil {
+		zap.S().Fatalw(alert.Prefix("error reading config file"), "error", err)
+	}
+
 	configCacheService := cache.CreateCacheService(configFilePath)
 
 	layersCacheController := cache.CreateLayersCacheController(configFilePath, configCacheService)
 
 	httpCacheController := integration.CreateHttpCacheController(configCacheService)
 
-	mapper := dto.NewResolverMapper(configCacheService)
+	mapper := dto.NewResolverMapper(configCacheService).WithGenerations(cache.CreateGenerations(configFilePath))
 
-	mainAdapter := manager.CreateAsyncManagerAdapter(mapper, layersCacheController, httpCacheController, putAllTimeout, evictAllTimeout)
+	mainAdapter := manager.CreateAsyncManagerAdapter(mapper, configCacheService, layersCacheController, httpCacheController, putAllTimeout, evictAllTimeout, appConfig.WriteBehind)
 
-	routerApi := httpserver.NewRouter(&mainAdapter)
+	routerApi := httpserver.NewRouter(mainAdapter, mainAdapter)
 	routerMetrics := httpserver.NewMetricRouter()
+	grpcApi := grpcserver.NewServer(mainAdapter)
 
-	// запуск двух HTTP-серверов параллельно (в отдельных горутинах),
+	apiServer := newHttpServer(routerApi, portApi)
+	metricsServer := newHttpServer(routerMetrics, portMetrics)
+
+	// запуск HTTP- и gRPC-серверов параллельно (в отдельных горутинах),
 	// и ожидание их завершения через sync.WaitGroup
 	//
 	// Это нужно, чтобы:
-	// - main-функция не завершилась раньше времени
-	// - оба сервера работали одновременно
-	//
-	// Без этого программа бы завершилась сразу после запуска горутин.
+	// - все серверы работали одновременно
+	// - при остановке main дождалась, пока каждый сервер завершит обработку запросов
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(3)
 
 	go func() {
 		defer wg.Done()
-		listenServer("api", routerApi, portApi)
+		listenServer("api", apiServer)
 	}()
 
 	go func() {
 		defer wg.Done()
-		listenServer("metrics", routerMetrics, portMetrics)
+		listenServer("metrics", metricsServer)
 	}()
 
+	go func() {
+		defer wg.Done()
+		grpcserver.Listen(grpcApi, appConfig.Server.GetGrpcPort())
+	}()
+
+	var respServer *resp.Server
+	if port := appConfig.Server.RespPort; port > 0 {
+		respServer = resp.NewServer(mainAdapter)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			respServer.Listen(port)
+		}()
+	}
+
+	var memcachedServer *memcached.Server
+	if mc := appConfig.Server.Memcached; mc.Port > 0 {
+		memcachedServer = memcached.NewServer(mainAdapter, mc)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			memcachedServer.Listen(mc.Port)
+		}()
+	}
+
+	// остановка по SIGINT / SIGTERM: сначала серверы перестают принимать запросы
+	// и дожидаются уже начатых, затем очередь write-behind применяет принятые записи
+	// и закрывает журнал
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stop
+	zap.S().Infow("shutting down", "signal", sig.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	shutdownHttpServer(ctx, "api", apiServer)
+	stopGrpcServer(ctx, grpcApi)
+	if respServer != nil {
+		if err := respServer.Close(); err != nil {
+			zap.S().Warnw("error stopping server", "name", "resp", "error", err)
+		}
+	}
+	if memcachedServer != nil {
+		if err := memcachedServer.Close(); err != nil {
+			zap.S().Warnw("error stopping server", "name", "memcached", "error", err)
+		}
+	}
+
+	if err := mainAdapter.Close(); err != nil {
+		zap.S().Errorw(alert.Prefix("error closing write-behind queue"), "error", err)
+	}
+
+	// метрики отдаются до конца применения очереди write-behind
+	shutdownHttpServer(ctx, "metrics", metricsServer)
 	wg.Wait()
-	//// end
+	zap.S().Info("stopped")
 }
 
-func listenServer(serverName string, router http.Handler, port int) {
-	srv := &http.Server{
+func newHttpServer(router http.Handler, port int) *http.Server {
+	return &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
 		Handler: router,
 	}
+}
 
+func listenServer(serverName string, srv *http.Server) {
 	zap.S().Infow("starting server", "name", serverName, "addr", srv.Addr)
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		zap.S().Fatalw(alert.Prefix("server error"), "error", err)
 	}
 }
+
+// shutdownHttpServer перестаёт принимать запросы и дожидается начатых до истечения ctx.
+func shutdownHttpServer(ctx context.Context, serverName string, srv *http.Server) {
+	if err := srv.Shutdown(ctx); err != nil {
+		zap.S().Warnw("error stopping server", "name", serverName, "error", err)
+		_ = srv.Close()
+	}
+}
+
+// stopGrpcServer дожидается начатых запросов до истечения ctx, затем обрывает оставшиеся.
+func stopGrpcServer(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		zap.S().Warnw("error stopping server", "name", "grpc", "error", ctx.Err())
+		srv.Stop()
+	}
+}
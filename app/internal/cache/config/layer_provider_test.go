@@ -24,9 +24,9 @@ func TestNewLayerProviderService_Success(t *testing.T) {
 	}
 
 	svc := NewLayerProviderService(cfg)
-	assert.Len(t, svc.layerProviders, 1)
-	assert.Equal(t, LayerModeEnabled, svc.layerProviders[0].Mode)
-	assert.Equal(t, "mem", svc.layerProviders[0].Provider.GetName())
+	assert.Len(t, svc.LayerProviders, 1)
+	assert.Equal(t, LayerModeEnabled, svc.LayerProviders[0].Mode)
+	assert.Equal(t, "mem", svc.LayerProviders[0].Provider.GetName())
 }
 
 func TestNewLayerProviderService_Success_WithExpected(t *testing.T) {
@@ -60,7 +60,7 @@ func TestNewLayerProviderService_Success_WithExpected(t *testing.T) {
 		},
 	}
 
-	assert.Equal(t, expected, svc.layerProviders)
+	assert.Equal(t, expected, svc.LayerProviders)
 }
 
 func TestNewLayerProviderService_PanicWhenProviderMissing(t *testing.T) {
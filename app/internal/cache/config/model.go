@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/dustin/go-humanize"
 	"gopkg.in/yaml.v3"
+	"net/http"
 	"net/url"
 	"os"
 	"strings"
@@ -11,9 +12,14 @@ import (
 )
 
 type AppConfigIntermediary struct {
-	Providers Providers `yaml:"providers"`
-	Layers    []Layer   `yaml:"layers"`
-	Caches    []Cache   `yaml:"caches"`
+	Providers   Providers         `yaml:"providers"`
+	Layers      []Layer           `yaml:"layers"`
+	Caches      []Cache           `yaml:"caches"`
+	WriteBehind WriteBehindConfig `yaml:"writeBehind"`
+	Server      ServerConfig      `yaml:"server"`
+
+	Invalidation InvalidationConfig `yaml:"invalidation"`
+	Generations  GenerationsConfig  `yaml:"generations"`
 }
 
 func (c *AppConfigIntermediary) Validate() error {
@@ -28,6 +34,22 @@ func (c *AppConfigIntermediary) Validate() error {
 	if err := c.validateCaches(); err != nil {
 		return err
 	}
+
+	if err := c.validateWriteBehind(); err != nil {
+		return err
+	}
+
+	if err := c.validateServer(); err != nil {
+		return err
+	}
+
+	if err := c.validateInvalidation(); err != nil {
+		return err
+	}
+
+	if err := c.validateGenerations(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -214,6 +236,22 @@ func (c *AppConfigIntermediary) validateCaches() error {
 			}
 		}
 
+		if cache.StaleWhileRevalidate < 0 {
+			return fmt.Errorf("cache[%d]: staleWhileRevalidate must be >= 0", i)
+		}
+
+		if cache.NegativeTTL < 0 {
+			return fmt.Errorf("cache[%d]: negativeTTL must be >= 0", i)
+		}
+
+		if cache.MaxTTL < 0 {
+			return fmt.Errorf("cache[%d]: maxTtl must be >= 0", i)
+		}
+
+		if cache.RefreshAhead.Percent < 0 || cache.RefreshAhead.Percent >= 100 {
+			return fmt.Errorf("cache[%d]: refreshAhead.percent must be in range 0..99", i)
+		}
+
 		if err := c.validateIntegrationApi(i, cache.Api); err != nil {
 			return err
 		}
@@ -226,33 +264,175 @@ func (c *AppConfigIntermediary) validateIntegrationApi(i int, apiConfig ApiConfi
 		return nil
 	}
 
-	if apiConfig.GetBatch.Prop == "" {
-		return fmt.Errorf("cache[%d]: api.getBatch.prop is required", i)
+	if err := c.validateApiBatch(i, "getBatch", &apiConfig.GetBatch); err != nil {
+		return err
+	}
+
+	if apiConfig.GetWriteMode() != WriteModeThrough && apiConfig.GetWriteMode() != WriteModeAround {
+		return fmt.Errorf("cache[%d]: invalid api.writeMode '%s'", i, apiConfig.WriteMode)
+	}
+
+	switch apiConfig.GetWriteOrder() {
+	case WriteOrderBefore:
+	case WriteOrderAfter:
+		if apiConfig.GetWriteMode() != WriteModeThrough {
+			return fmt.Errorf("cache[%d]: api.writeOrder 'after' requires api.writeMode '%s'", i, WriteModeThrough)
+		}
+	default:
+		return fmt.Errorf("cache[%d]: invalid api.writeOrder '%s'", i, apiConfig.WriteOrder)
+	}
+
+	if apiConfig.PutBatch != nil {
+		if err := c.validatePutBatch(i, apiConfig.PutBatch); err != nil {
+			return err
+		}
+	}
+
+	if apiConfig.DeleteBatch != nil {
+		if err := c.validateApiBatch(i, "deleteBatch", apiConfig.DeleteBatch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateApiBatch проверяет эндпоинт с контрактом getBatch: POST {prop: [keys]}.
+func (c *AppConfigIntermediary) validateApiBatch(i int, name string, batch *ApiBatchConfig) error {
+	if batch.Prop == "" {
+		return fmt.Errorf("cache[%d]: api.%s.prop is required", i, name)
+	}
+	if batch.KeyType != KeyTypeString && batch.KeyType != KeyTypeNumber {
+		return fmt.Errorf("cache[%d]: invalid keyType '%s' in api.%s", i, batch.KeyType, name)
+	}
+	if batch.URL == "" {
+		return fmt.Errorf("cache[%d]: api.%s.url is required", i, name)
+	}
+
+	u, err := url.Parse(batch.URL)
+	if err != nil {
+		return fmt.Errorf("cache[%d]: invalid api.%s.url '%s': %v", i, name, batch.URL, err)
 	}
-	if apiConfig.GetBatch.KeyType != KeyTypeString && apiConfig.GetBatch.KeyType != KeyTypeNumber {
-		return fmt.Errorf("cache[%d]: invalid keyType '%s'", i, apiConfig.GetBatch.KeyType)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("cache[%d]: unsupported scheme '%s' in api.%s.url", i, u.Scheme, name)
 	}
-	if apiConfig.GetBatch.URL == "" {
-		return fmt.Errorf("cache[%d]: api.getBatch.url is required", i)
+	if u.Host == "" {
+		return fmt.Errorf("cache[%d]: missing host in api.%s.url '%s'", i, name, batch.URL)
 	}
 
-	u, err := url.Parse(apiConfig.GetBatch.URL)
+	if batch.Timeout <= 0 {
+		return fmt.Errorf("cache[%d]: api.%s.timeout must be > 0", i, name)
+	}
+	return nil
+}
+
+func (c *AppConfigIntermediary) validatePutBatch(i int, putBatch *ApiPutBatchConfig) error {
+	if putBatch.URL == "" {
+		return fmt.Errorf("cache[%d]: api.putBatch.url is required", i)
+	}
+	u, err := url.Parse(putBatch.URL)
 	if err != nil {
-		return fmt.Errorf("cache[%d]: invalid api.getBatch.url '%s': %v", i, apiConfig.GetBatch.URL, err)
+		return fmt.Errorf("cache[%d]: invalid api.putBatch.url '%s': %v", i, putBatch.URL, err)
 	}
 	if u.Scheme != "http" && u.Scheme != "https" {
-		return fmt.Errorf("cache[%d]: unsupported scheme '%s' in api.getBatch.url", i, u.Scheme)
+		return fmt.Errorf("cache[%d]: unsupported scheme '%s' in api.putBatch.url", i, u.Scheme)
 	}
 	if u.Host == "" {
-		return fmt.Errorf("cache[%d]: missing host in api.getBatch.url '%s'", i, apiConfig.GetBatch.URL)
+		return fmt.Errorf("cache[%d]: missing host in api.putBatch.url '%s'", i, putBatch.URL)
+	}
+
+	switch putBatch.GetMethod() {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("cache[%d]: unsupported api.putBatch.method '%s'", i, putBatch.Method)
+	}
+
+	if putBatch.Timeout <= 0 {
+		return fmt.Errorf("cache[%d]: api.putBatch.timeout must be > 0", i)
+	}
+
+	switch putBatch.GetShape() {
+	case BodyShapeMap:
+	case BodyShapeList:
+		if putBatch.Prop == "" || putBatch.ValueProp == "" {
+			return fmt.Errorf("cache[%d]: api.putBatch.prop and api.putBatch.valueProp are required for shape 'list'", i)
+		}
+		if putBatch.KeyType != KeyTypeString && putBatch.KeyType != KeyTypeNumber {
+			return fmt.Errorf("cache[%d]: invalid api.putBatch.keyType '%s'", i, putBatch.KeyType)
+		}
+	default:
+		return fmt.Errorf("cache[%d]: invalid api.putBatch.shape '%s'", i, putBatch.Shape)
+	}
+	return nil
+}
+
+func (c *AppConfigIntermediary) validateWriteBehind() error {
+	if c.WriteBehind.QueueSize < 0 {
+		return fmt.Errorf("writeBehind: queueSize must be >= 0")
+	}
+	if c.WriteBehind.Workers < 0 {
+		return fmt.Errorf("writeBehind: workers must be >= 0")
 	}
+	return nil
+}
 
-	if apiConfig.GetBatch.Timeout <= 0 {
-		return fmt.Errorf("cache[%d]: api.getBatch.timeout must be > 0", i)
+func (c *AppConfigIntermediary) validateServer() error {
+	if c.Server.GrpcPort < 0 || c.Server.GrpcPort > 65535 {
+		return fmt.Errorf("server: invalid grpcPort %d", c.Server.GrpcPort)
+	}
+	if c.Server.RespPort < 0 || c.Server.RespPort > 65535 {
+		return fmt.Errorf("server: invalid respPort %d", c.Server.RespPort)
+	}
+
+	mc := c.Server.Memcached
+	if mc.Port < 0 || mc.Port > 65535 {
+		return fmt.Errorf("server.memcached: invalid port %d", mc.Port)
+	}
+	if mc.Port > 0 && len(mc.Prefixes) == 0 {
+		return fmt.Errorf("server.memcached: prefixes are required")
+	}
+	cacheNames := make(map[string]bool, len(c.Caches))
+	for _, cache := range c.Caches {
+		cacheNames[cache.Name] = true
+	}
+	for prefix, cacheName := range mc.Prefixes {
+		if prefix == "" {
+			return fmt.Errorf("server.memcached: empty prefix")
+		}
+		if !cacheNames[cacheName] {
+			return fmt.Errorf("server.memcached: prefix '%s' refers to unknown cache '%s'", prefix, cacheName)
+		}
 	}
 	return nil
 }
 
+func (c *AppConfigIntermediary) validateInvalidation() error {
+	return c.validateRedisRef("invalidation", c.Invalidation.Provider)
+}
+
+func (c *AppConfigIntermediary) validateGenerations() error {
+	if c.Generations.RefreshInterval < 0 {
+		return fmt.Errorf("generations: refreshInterval must be >= 0")
+	}
+	return c.validateRedisRef("generations", c.Generations.Provider)
+}
+
+// validateRedisRef проверяет, что name ("" — не задано) ссылается на Redis-провайдер.
+func (c *AppConfigIntermediary) validateRedisRef(section, name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, p := range c.Providers {
+		if p.GetName() != name {
+			continue
+		}
+		if p.GetType() != ProviderTypeRedis {
+			return fmt.Errorf("%s: provider '%s' must be of type redis", section, name)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: unknown provider '%s'", section, name)
+}
+
 ///////////////////////////////////////////////////////////
 /// Providers structs
 ///////////////////////////////////////////////////////////
@@ -441,9 +621,110 @@ type ApiBatchConfig struct {
 	Timeout time.Duration     `yaml:"timeout"`
 }
 
+// BodyShape — форма тела запроса putBatch.
+type BodyShape string
+
+const (
+	// BodyShapeMap — объект «ключ → значение», как в ответе getBatch: {"1": {...}, "2": {...}}.
+	BodyShapeMap BodyShape = "map"
+	// BodyShapeList — массив объектов: [{"<prop>": 1, "<valueProp>": {...}}, ...].
+	BodyShapeList BodyShape = "list"
+)
+
+// WriteMode — как put_all обновляет слои кэша, если для кэша настроен putBatch.
+type WriteMode string
+
+const (
+	// WriteModeThrough — значение пишется во внешний API, затем во все слои кэша.
+	WriteModeThrough WriteMode = "write-through"
+	// WriteModeAround — значение пишется только во внешний API, а из слоёв кэша удаляется;
+	// новое значение будет загружено через getBatch при следующем чтении.
+	WriteModeAround WriteMode = "write-around"
+)
+
+// WriteOrder — когда put_all пишет значения во внешний API относительно слоёв кэша,
+// если для кэша настроен putBatch.
+type WriteOrder string
+
+const (
+	// WriteOrderBefore — сначала внешний API, затем слои: в слои попадает только то,
+	// что принял внешний API.
+	WriteOrderBefore WriteOrder = "before"
+	// WriteOrderAfter — сначала слои, затем внешний API: значение сразу видно из кэша,
+	// а ключи, которые внешний API не принял, удаляются из слоёв. Только для write-through.
+	WriteOrderAfter WriteOrder = "after"
+)
+
+// ApiPutBatchConfig — эндпоинт внешнего API для записи значений (system of record).
+type ApiPutBatchConfig struct {
+	URL       string            `yaml:"url"`
+	Method    string            `yaml:"method"` // POST | PUT | PATCH, по умолчанию POST
+	Headers   map[string]string `yaml:"headers"`
+	Timeout   time.Duration     `yaml:"timeout"`
+	Shape     BodyShape         `yaml:"shape"`     // map | list, по умолчанию map
+	Prop      string            `yaml:"prop"`      // имя поля ключа (только для shape: list)
+	ValueProp string            `yaml:"valueProp"` // имя поля значения (только для shape: list)
+	KeyType   KeyType           `yaml:"keyType"`   // тип ключа (только для shape: list)
+}
+
+// GetMethod возвращает HTTP-метод запроса putBatch.
+func (c *ApiPutBatchConfig) GetMethod() string {
+	if c.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(c.Method)
+}
+
+// GetShape возвращает форму тела запроса putBatch.
+func (c *ApiPutBatchConfig) GetShape() BodyShape {
+	if c.Shape == "" {
+		return BodyShapeMap
+	}
+	return c.Shape
+}
+
 type ApiConfig struct {
 	Enabled  bool           `yaml:"enabled"`
 	GetBatch ApiBatchConfig `yaml:"getBatch"`
+
+	// PutBatch — необязательный эндпоинт записи. nil = put_all меняет только слои кэша.
+	PutBatch *ApiPutBatchConfig `yaml:"putBatch"`
+
+	// WriteMode — режим записи при настроенном PutBatch, по умолчанию write-through.
+	WriteMode WriteMode `yaml:"writeMode"`
+
+	// WriteOrder — порядок записи во внешний API и в слои, по умолчанию before.
+	WriteOrder WriteOrder `yaml:"writeOrder"`
+
+	// DeleteBatch — необязательный эндпоинт удаления с контрактом getBatch.
+	// nil = evict_all удаляет значения только из слоёв кэша.
+	DeleteBatch *ApiBatchConfig `yaml:"deleteBatch"`
+}
+
+// GetWriteMode возвращает режим записи кэша.
+func (c *ApiConfig) GetWriteMode() WriteMode {
+	if c.WriteMode == "" {
+		return WriteModeThrough
+	}
+	return c.WriteMode
+}
+
+// GetWriteOrder возвращает порядок записи кэша.
+func (c *ApiConfig) GetWriteOrder() WriteOrder {
+	if c.WriteOrder == "" {
+		return WriteOrderBefore
+	}
+	return c.WriteOrder
+}
+
+// IsPutBatchEnabled сообщает, нужно ли при put_all писать значения во внешний API.
+func (c *ApiConfig) IsPutBatchEnabled() bool {
+	return c.Enabled && c.PutBatch != nil
+}
+
+// IsDeleteBatchEnabled сообщает, нужно ли при evict_all удалять значения во внешнем API.
+func (c *ApiConfig) IsDeleteBatchEnabled() bool {
+	return c.Enabled && c.DeleteBatch != nil
 }
 
 type Cache struct {
@@ -451,6 +732,151 @@ type Cache struct {
 	Prefix string             `yaml:"prefix"`
 	Layers []CacheLayerConfig `yaml:"layers"`
 	Api    ApiConfig          `yaml:"api"`
+
+	// StaleWhileRevalidate — окно, в течение которого запись после истечения TTL слоя
+	// ещё отдаётся клиенту, а её обновление из внешнего API выполняется в фоне.
+	// 0 = режим выключен, запись удаляется сразу по истечении TTL.
+	StaleWhileRevalidate time.Duration `yaml:"staleWhileRevalidate"`
+
+	// NegativeTTL — сколько помнить, что внешний API не вернул ключ.
+	// На это время во включённые слои записывается маркер отсутствия (tombstone),
+	// и get_all отвечает f:false без обращения к внешнему API.
+	// 0 = отрицательное кэширование выключено.
+	NegativeTTL time.Duration `yaml:"negativeTTL"`
+
+	// MaxTTL — верхняя граница TTL, который клиент может задать записи в put_all (ttl / ttls).
+	// Больший TTL уменьшается до MaxTTL. 0 = не больше TTL слоя из конфигурации
+	// (для слоёв без TTL — без ограничения).
+	MaxTTL time.Duration `yaml:"maxTtl"`
+
+	RefreshAhead RefreshAheadConfig `yaml:"refreshAhead"`
+
+	// Generations — хранить ключи с номером поколения кэша («prefix:gen:key»).
+	// Увеличение поколения через административный API мгновенно делает все записи кэша
+	// недоступными на всех слоях; старые записи истекают по TTL или удаляются очисткой.
+	// Включение и выключение меняют формат ключей: записи в старом формате становятся недоступны.
+	Generations bool `yaml:"generations"`
+}
+
+// RefreshAheadConfig — заблаговременное обновление «горячих» ключей.
+//
+// Если значение прочитано с самого быстрого включённого для кэша слоя, когда до истечения
+// его TTL осталось не больше Percent процентов, оно перезапрашивается из внешнего API в фоне
+// и перезаписывается во всех слоях. Клиент при этом получает текущее значение без задержки.
+type RefreshAheadConfig struct {
+	Percent int `yaml:"percent"` // 0 = выключено
+}
+
+///////////////////////////////////////////////////////////
+/// Write-behind structs
+///////////////////////////////////////////////////////////
+
+const (
+	DefaultWriteBehindQueueSize = 10_000
+	DefaultWriteBehindWorkers   = 8
+)
+
+// WriteBehindConfig — очередь записи put_all / evict_all в слои кэша.
+//
+// Принятые операции сначала дописываются в журнал на диске, затем применяются
+// к слоям фоновыми воркерами. После перезапуска неподтверждённые операции
+// из журнала применяются повторно.
+type WriteBehindConfig struct {
+	JournalDir string `yaml:"journalDir"` // "" = очередь только в памяти, без журнала
+	QueueSize  int    `yaml:"queueSize"`  // максимум операций в очереди, 0 = DefaultWriteBehindQueueSize
+	Workers    int    `yaml:"workers"`    // число воркеров, 0 = DefaultWriteBehindWorkers
+
+	// AllowMemoryOnly — если журнал не открылся, работать с очередью только в памяти
+	// вместо остановки сервиса. Принятые, но не применённые записи при перезапуске теряются.
+	AllowMemoryOnly bool `yaml:"allowMemoryOnly"`
+}
+
+func (c WriteBehindConfig) GetQueueSize() int {
+	if c.QueueSize == 0 {
+		return DefaultWriteBehindQueueSize
+	}
+	return c.QueueSize
+}
+
+func (c WriteBehindConfig) GetWorkers() int {
+	if c.Workers == 0 {
+		return DefaultWriteBehindWorkers
+	}
+	return c.Workers
+}
+
+const DefaultGrpcPort = 9090
+
+// ServerConfig — дополнительные сетевые интерфейсы сервиса (помимо REST API и метрик).
+type ServerConfig struct {
+	GrpcPort int `yaml:"grpcPort"` // порт gRPC API, 0 = DefaultGrpcPort
+	RespPort int `yaml:"respPort"` // порт Redis-протокола (RESP), 0 = выключен
+
+	Memcached MemcachedConfig `yaml:"memcached"`
+}
+
+// MemcachedConfig — текстовый протокол memcached.
+//
+// Ключ memcached сопоставляется с кэшем по самому длинному совпавшему префиксу:
+// при prefixes {"u:": user} ключ "u:42" — это ключ "42" кэша user.
+type MemcachedConfig struct {
+	Port     int               `yaml:"port"`     // 0 = выключен
+	Prefixes map[string]string `yaml:"prefixes"` // префикс ключа -> имя кэша
+}
+
+func (c ServerConfig) GetGrpcPort() int {
+	if c.GrpcPort == 0 {
+		return DefaultGrpcPort
+	}
+	return c.GrpcPort
+}
+
+const DefaultInvalidationChannel = "aur-cache:invalidate"
+
+// InvalidationConfig — рассылка инвалидаций между экземплярами сервиса через Redis pub/sub.
+//
+// У каждого экземпляра свои слои в памяти процесса (Ristretto): после put_all или evict_all
+// на одном экземпляре остальные удаляют изменённые ключи из своих таких слоёв.
+type InvalidationConfig struct {
+	Provider string `yaml:"provider"` // имя Redis-провайдера из providers, "" = выключено
+	Channel  string `yaml:"channel"`  // канал pub/sub, "" = DefaultInvalidationChannel
+}
+
+func (c InvalidationConfig) GetChannel() string {
+	if c.Channel == "" {
+		return DefaultInvalidationChannel
+	}
+	return c.Channel
+}
+
+const (
+	DefaultGenerationsKey             = "aur-cache:generations"
+	DefaultGenerationsRefreshInterval = time.Second
+)
+
+// GenerationsConfig — хранилище поколений кэшей с Cache.Generations.
+//
+// Поколения хранятся в HASH Redis, общем для всех экземпляров сервиса; каждый экземпляр
+// перечитывает его раз в RefreshInterval. Без провайдера поколения живут только в памяти
+// экземпляра и после перезапуска начинаются с 0.
+type GenerationsConfig struct {
+	Provider        string        `yaml:"provider"`        // имя Redis-провайдера из providers, "" = только в памяти
+	Key             string        `yaml:"key"`             // ключ HASH, "" = DefaultGenerationsKey
+	RefreshInterval time.Duration `yaml:"refreshInterval"` // 0 = DefaultGenerationsRefreshInterval
+}
+
+func (c GenerationsConfig) GetKey() string {
+	if c.Key == "" {
+		return DefaultGenerationsKey
+	}
+	return c.Key
+}
+
+func (c GenerationsConfig) GetRefreshInterval() time.Duration {
+	if c.RefreshInterval == 0 {
+		return DefaultGenerationsRefreshInterval
+	}
+	return c.RefreshInterval
 }
 
 ///////////////////////////////////////////////////////////
@@ -353,3 +353,163 @@ func TestValidate_CacheFailures(t *testing.T) {
 	err := appCfg.Validate()
 	assert.ErrorContains(t, err, "name is required")
 }
+
+func TestValidate_NegativeCacheDurations(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache func(c *Cache)
+		err   string
+	}{
+		{"staleWhileRevalidate", func(c *Cache) { c.StaleWhileRevalidate = -time.Second }, "staleWhileRevalidate must be >= 0"},
+		{"negativeTTL", func(c *Cache) { c.NegativeTTL = -time.Second }, "negativeTTL must be >= 0"},
+		{"maxTtl", func(c *Cache) { c.MaxTTL = -time.Second }, "maxTtl must be >= 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := Cache{Name: "c", Prefix: "p", Layers: []CacheLayerConfig{{Enabled: true, TTL: time.Second}}}
+			tt.cache(&cache)
+			appCfg := AppConfigIntermediary{
+				Providers: Providers{
+					&Ristretto{ProviderMeta: ProviderMeta{Name: "mem", Type: ProviderTypeRistretto}, NumCounters: 10, BufferItems: 10, MaxCost: "1MB"},
+				},
+				Layers: []Layer{
+					{Name: "mem", Mode: LayerModeEnabled},
+				},
+				Caches: []Cache{cache},
+			}
+
+			err := appCfg.Validate()
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestValidate_InvalidGrpcPort(t *testing.T) {
+	appCfg := AppConfigIntermediary{Server: ServerConfig{GrpcPort: 70000}}
+
+	err := appCfg.Validate()
+	assert.ErrorContains(t, err, "invalid grpcPort")
+}
+
+func TestValidate_MemcachedFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		memcached MemcachedConfig
+		expected  string
+	}{
+		{"no prefixes", MemcachedConfig{Port: 11211}, "prefixes are required"},
+		{"empty prefix", MemcachedConfig{Port: 11211, Prefixes: map[string]string{"": "c"}}, "empty prefix"},
+		{"unknown cache", MemcachedConfig{Port: 11211, Prefixes: map[string]string{"x:": "missing"}}, "unknown cache 'missing'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appCfg := AppConfigIntermediary{
+				Providers: Providers{
+					&Ristretto{ProviderMeta: ProviderMeta{Name: "mem", Type: ProviderTypeRistretto}, NumCounters: 10, BufferItems: 10, MaxCost: "1MB"},
+				},
+				Layers: []Layer{{Name: "mem", Mode: LayerModeEnabled}},
+				Caches: []Cache{{Name: "c", Prefix: "p", Layers: []CacheLayerConfig{{Enabled: true, TTL: time.Second}}}},
+				Server: ServerConfig{Memcached: tt.memcached},
+			}
+
+			err := appCfg.Validate()
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestValidate_WriteEndpointsFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		api      ApiConfig
+		expected string
+	}{
+		{"no url", ApiConfig{PutBatch: &ApiPutBatchConfig{Timeout: time.Second}}, "api.putBatch.url is required"},
+		{"bad method", ApiConfig{PutBatch: &ApiPutBatchConfig{URL: "http://x", Method: "GET", Timeout: time.Second}}, "unsupported api.putBatch.method"},
+		{"no timeout", ApiConfig{PutBatch: &ApiPutBatchConfig{URL: "http://x"}}, "api.putBatch.timeout must be > 0"},
+		{"list without prop", ApiConfig{PutBatch: &ApiPutBatchConfig{URL: "http://x", Timeout: time.Second, Shape: BodyShapeList}}, "valueProp are required"},
+		{"bad shape", ApiConfig{PutBatch: &ApiPutBatchConfig{URL: "http://x", Timeout: time.Second, Shape: "tree"}}, "invalid api.putBatch.shape"},
+		{"bad write mode", ApiConfig{WriteMode: "write-back"}, "invalid api.writeMode"},
+		{"bad write order", ApiConfig{WriteOrder: "during"}, "invalid api.writeOrder"},
+		{"write order after with write-around", ApiConfig{WriteMode: WriteModeAround, WriteOrder: WriteOrderAfter}, "api.writeOrder 'after' requires api.writeMode 'write-through'"},
+		{"delete without prop", ApiConfig{DeleteBatch: &ApiBatchConfig{URL: "http://x", Timeout: time.Second}}, "api.deleteBatch.prop is required"},
+		{"delete bad url", ApiConfig{DeleteBatch: &ApiBatchConfig{URL: "ftp://x", Prop: "id", KeyType: KeyTypeString, Timeout: time.Second}}, "unsupported scheme 'ftp' in api.deleteBatch.url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := tt.api
+			api.Enabled = true
+			api.GetBatch = ApiBatchConfig{URL: "http://x", Prop: "id", KeyType: KeyTypeString, Timeout: time.Second}
+			appCfg := AppConfigIntermediary{
+				Providers: Providers{
+					&Ristretto{ProviderMeta: ProviderMeta{Name: "mem", Type: ProviderTypeRistretto}, NumCounters: 10, BufferItems: 10, MaxCost: "1MB"},
+				},
+				Layers: []Layer{
+					{Name: "mem", Mode: LayerModeEnabled},
+				},
+				Caches: []Cache{
+					{Name: "c", Prefix: "p", Layers: []CacheLayerConfig{{Enabled: true, TTL: time.Second}}, Api: api},
+				},
+			}
+			assert.ErrorContains(t, appCfg.Validate(), tt.expected)
+		})
+	}
+}
+
+func TestValidate_InvalidationFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		expected string
+	}{
+		{"unknown provider", "missing", "invalidation: unknown provider 'missing'"},
+		{"not redis", "mem", "invalidation: provider 'mem' must be of type redis"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appCfg := AppConfigIntermediary{
+				Providers: Providers{
+					&Ristretto{ProviderMeta: ProviderMeta{Name: "mem", Type: ProviderTypeRistretto}, NumCounters: 10, BufferItems: 10, MaxCost: "1MB"},
+				},
+				Layers:       []Layer{{Name: "mem", Mode: LayerModeEnabled}},
+				Caches:       []Cache{{Name: "c", Prefix: "p", Layers: []CacheLayerConfig{{Enabled: true, TTL: time.Second}}}},
+				Invalidation: InvalidationConfig{Provider: tt.provider},
+			}
+
+			err := appCfg.Validate()
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestValidate_GenerationsFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      GenerationsConfig
+		expected string
+	}{
+		{"unknown provider", GenerationsConfig{Provider: "missing"}, "generations: unknown provider 'missing'"},
+		{"not redis", GenerationsConfig{Provider: "mem"}, "generations: provider 'mem' must be of type redis"},
+		{"negative interval", GenerationsConfig{RefreshInterval: -time.Second}, "generations: refreshInterval must be >= 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appCfg := AppConfigIntermediary{
+				Providers: Providers{
+					&Ristretto{ProviderMeta: ProviderMeta{Name: "mem", Type: ProviderTypeRistretto}, NumCounters: 10, BufferItems: 10, MaxCost: "1MB"},
+				},
+				Layers:      []Layer{{Name: "mem", Mode: LayerModeEnabled}},
+				Caches:      []Cache{{Name: "c", Prefix: "p", Layers: []CacheLayerConfig{{Enabled: true, TTL: time.Second}}, Generations: true}},
+				Generations: tt.cfg,
+			}
+
+			err := appCfg.Validate()
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
@@ -7,9 +7,14 @@ import (
 )
 
 type AppConfig struct {
-	Provider []Provider
-	Layers   []Layer
-	Caches   []Cache
+	Provider    []Provider
+	Layers      []Layer
+	Caches      []Cache
+	WriteBehind WriteBehindConfig
+	Server      ServerConfig
+
+	Invalidation InvalidationConfig
+	Generations  GenerationsConfig
 }
 
 func LoadAppConfig(path string) (*AppConfig, error) {
@@ -31,5 +36,11 @@ func LoadAppConfig(path string) (*AppConfig, error) {
 		Provider: interm.Providers,
 		Layers:   interm.Layers,
 		Caches:   interm.Caches,
+
+		WriteBehind: interm.WriteBehind,
+		Server:      interm.Server,
+
+		Invalidation: interm.Invalidation,
+		Generations:  interm.Generations,
 	}, nil
 }
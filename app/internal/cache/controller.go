@@ -5,6 +5,8 @@ import (
 	"aur-cache-service/internal/cache/providers"
 	"aur-cache-service/internal/metrics"
 	"context"
+	"errors"
+	"fmt"
 
 	"go.uber.org/zap"
 )
@@ -20,13 +22,41 @@ import (
 //   - GetAll:
 //     Итерирует по уровням сверху вниз, извлекая значения и собирая статистику по каждому уровню.
 //     Возвращает срез результатов (hits/misses/skipped) для каждого слоя.
+//     При opts.Meta hits дополнительно содержат провайдера и оставшийся TTL (Service.GetAllWithMeta).
+//     Слои вне диапазона opts.MinLayer..opts.MaxLayer (или все слои при opts.BypassCache) не читаются:
+//     все ключи, дошедшие до такого слоя, попадают в его Skipped.
 //
 //   - PutAll:
 //     Сохраняет значения во все уровни до заданного уровня включительно.
 //     Это используется, чтобы "прокинуть" значения вниз (например, при кэшировании результата запроса).
+//     Возвращает результат записи для каждого затронутого слоя.
 //
-//   - EvictAll:
-//     Удаляет значения со всех уровней.
+//   - PutAllIf:
+//     Условная запись (ifAbsent / ifVersion). Условие проверяется в самом нижнем включённом
+//     для кэша слое — он считается авторитетным; при выполнении условия значение записывается
+//     в него и затем во все слои выше.
+//
+//   - TouchAll:
+//     Продлевает TTL значений на всех уровнях, не перезаписывая их.
+//     Возвращает результат для каждого слоя (ключи, которых в слое нет, — в Missing).
+//
+//   - DeleteAll:
+//     Удаляет значения со всех уровней. Возвращает результат удаления для каждого слоя.
+//
+//   - EvictByTag:
+//     Удаляет со всех уровней ключи с тегом (по индексу тегов каждого слоя).
+//     Возвращает удалённые ключи для каждого слоя.
+//
+//   - FlushPrefix:
+//     Удаляет со всех уровней ключи с префиксом, начиная с нижнего. Возвращает ошибку каждого слоя.
+//
+//   - ScanKeys:
+//     Возвращает страницу ключей хранилища с префиксом на одном уровне.
+//
+// Если включена шина инвалидаций (EnableInvalidation), ключи, изменённые через PutAllToAllLevels,
+// PutAllIf, DeleteAll и EvictByTag, и префиксы FlushPrefix рассылаются другим экземплярам сервиса,
+// и те удаляют их из своих слоёв в памяти процесса. Дозапись верхних слоёв после чтения (PutAll) не рассылается: значение в ней
+// то же, что и в нижних слоях.
 //
 // Пример сценария:
 //   1. Клиент запрашивает значения → GetAll обходит уровни и возвращает найденные значения.
@@ -51,14 +81,22 @@ import (
 //	└──────────────┘
 
 type Controller interface {
-	GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (results []*dto.GetResult)
-	PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry, boundLevel int)
-	PutAllToAllLevels(ctx context.Context, entries []*dto.ResolvedCacheEntry)
-	DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId)
+	GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId, opts dto.GetOptions) (results []*dto.GetResult)
+	PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry, boundLevel int) (results []*dto.LayerResult)
+	PutAllToAllLevels(ctx context.Context, entries []*dto.ResolvedCacheEntry) (results []*dto.LayerResult)
+	PutAllIf(ctx context.Context, entries []*dto.ResolvedCacheEntry) *dto.CondWriteResult
+	TouchAll(ctx context.Context, entries []*dto.ResolvedCacheEntry) (results []*dto.TouchLayerResult)
+	DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (results []*dto.LayerResult)
+	EvictByTag(ctx context.Context, tag string) (results []*dto.TagLayerResult)
+	FlushPrefix(ctx context.Context, prefix string, progress func(level int, deleted int64)) (results []error)
+	ScanKeys(ctx context.Context, level int, prefix, match, cursor string, count int) (keys []string, next string, err error)
 }
 
 type ControllerImpl struct {
 	services []providers.Service
+
+	bus         *InvalidationBus // nil — инвалидации не рассылаются
+	localLevels []int            // слои в памяти процесса, очищаемые по инвалидациям
 }
 
 func CreateControllerImpl(services []providers.Service) Controller {
@@ -66,21 +104,40 @@ func CreateControllerImpl(services []providers.Service) Controller {
 }
 
 // GetAll обходит все уровни кэша сверху вниз, собирая значения и возвращая срез GetResult для каждого слоя.
-func (c *ControllerImpl) GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (results []*dto.GetResult) {
+// Hits получают номер слоя; если слой вернул ошибку, все ключи попадают в его Skipped, а причина — в Errors.
+// Слои, которые opts не разрешает читать, пропускаются без ошибки.
+func (c *ControllerImpl) GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId, opts dto.GetOptions) (results []*dto.GetResult) {
 
 	results = make([]*dto.GetResult, len(c.services))
 	for i, service := range c.services {
-		r, err := service.GetAll(ctx, reqs)
+		if !opts.ReadsLayer(i) {
+			results[i] = &dto.GetResult{Hits: []*dto.ResolvedCacheHit{}, Misses: []*dto.ResolvedCacheId{}, Skipped: reqs}
+			continue
+		}
+		getAll := service.GetAll
+		if opts.Meta {
+			getAll = service.GetAllWithMeta
+		}
+		r, err := getAll(ctx, reqs)
 		if err != nil {
 			zap.S().Warnw("layer unavailable", "layer", i, "error", err)
+			layerErr := fmt.Errorf("layer %d: %w", i, err)
+			errs := make([]*dto.ResolvedCacheError, len(reqs))
+			for j, req := range reqs {
+				errs[j] = &dto.ResolvedCacheError{ResolvedCacheId: req, Err: layerErr}
+			}
 			results[i] = &dto.GetResult{
 				Hits:    []*dto.ResolvedCacheHit{},
 				Misses:  []*dto.ResolvedCacheId{},
 				Skipped: reqs,
+				Errors:  errs,
 			}
 			metrics.RecordCacheLayer(i, 0, len(reqs))
 			continue
 		}
+		for _, hit := range r.Hits {
+			hit.Layer = i
+		}
 		results[i] = r
 		metrics.RecordCacheLayer(i, len(r.Hits), len(r.Misses))
 		nextReqs := make([]*dto.ResolvedCacheId, 0, len(r.Misses)+len(r.Skipped))
@@ -91,30 +148,271 @@ func (c *ControllerImpl) GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId
 	return
 }
 
-// PutAll вставляет значения во все уровни до boundLevel включительно
-func (c *ControllerImpl) PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry, boundLevel int) {
+// PutAll вставляет значения во все уровни до boundLevel включительно.
+// results[i] — результат записи в слой i; ошибка одного слоя не прерывает запись в остальные.
+func (c *ControllerImpl) PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry, boundLevel int) (results []*dto.LayerResult) {
+	results = make([]*dto.LayerResult, 0, len(c.services))
 	for i, service := range c.services {
 		if i > boundLevel {
 			break
 		}
-		err := service.PutAll(ctx, entries)
+		skipped, err := service.PutAll(ctx, entries)
+		if err != nil {
+			zap.S().Warnw("layer unavailable", "layer", i, "error", err)
+		}
+		results = append(results, &dto.LayerResult{Skipped: skipped, Err: err})
+	}
+	return
+}
+
+func (c *ControllerImpl) PutAllToAllLevels(ctx context.Context, entries []*dto.ResolvedCacheEntry) (results []*dto.LayerResult) {
+	results = c.PutAll(ctx, entries, len(c.services)-1)
+	c.publishEntries(ctx, entries)
+	return
+}
+
+// errNoEnabledLayer — для кэша ключа нет включённых слоёв, условие записи проверить негде.
+var errNoEnabledLayer = errors.New("no cache layer is enabled for the key")
+
+// PutAllIf обходит слои снизу вверх: ключи, для которых слой отключён, передаются выше,
+// остальные проверяются и записываются в этом слое (Service.PutAllIf), а записанные —
+// копируются в слои выше. Ошибка слоя относится ко всем ключам, проверявшимся в нём.
+func (c *ControllerImpl) PutAllIf(ctx context.Context, entries []*dto.ResolvedCacheEntry) *dto.CondWriteResult {
+	result := &dto.CondWriteResult{
+		Applied:   []*dto.ResolvedCacheId{},
+		Layers:    make(map[string][]*dto.LayerWriteStatus),
+		Conflicts: []*dto.ResolvedCacheId{},
+	}
+
+	reqs := entries
+	for level := len(c.services) - 1; level >= 0 && len(reqs) > 0; level-- {
+		r, err := c.services[level].PutAllIf(ctx, reqs)
+		if err != nil && r == nil {
+			zap.S().Warnw("layer unavailable", "layer", level, "error", err)
+			layerErr := fmt.Errorf("layer %d: %w", level, err)
+			for _, req := range reqs {
+				result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: req.ResolvedCacheId, Err: layerErr})
+			}
+			return result
+		}
+		if err != nil {
+			// значения записаны, но слой вернул ошибку (индекс тегов) — как у PutAll, статус слоя error
+			zap.S().Warnw("conditional write applied with layer error", "layer", level, "error", err)
+		}
+		result.Conflicts = append(result.Conflicts, r.Conflicts...)
+		for _, e := range r.Errors {
+			result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: e.ResolvedCacheId, Err: fmt.Errorf("layer %d: %w", level, e.Err)})
+		}
+
+		byKey := make(map[string]*dto.ResolvedCacheEntry, len(reqs))
+		for _, req := range reqs {
+			byKey[req.GetStorageKey()] = req
+		}
+		applied := make([]*dto.ResolvedCacheEntry, 0, len(r.Applied))
+		for _, id := range r.Applied {
+			applied = append(applied, byKey[id.GetStorageKey()])
+		}
+		c.recordCondApplied(ctx, result, applied, level, err)
+
+		next := make([]*dto.ResolvedCacheEntry, 0, len(r.Skipped))
+		for _, id := range r.Skipped {
+			next = append(next, byKey[id.GetStorageKey()])
+		}
+		reqs = next
+	}
+	for _, req := range reqs {
+		result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: req.ResolvedCacheId, Err: errNoEnabledLayer})
+	}
+	c.publish(ctx, result.Applied)
+	return result
+}
+
+// recordCondApplied копирует значения, записанные условно в слой level, в слои выше
+// и сохраняет статусы записи по слоям: слои ниже level отключены для этих ключей.
+// levelErr — ошибка слоя level после записи значений (индекс тегов).
+func (c *ControllerImpl) recordCondApplied(ctx context.Context, result *dto.CondWriteResult, applied []*dto.ResolvedCacheEntry, level int, levelErr error) {
+	if len(applied) == 0 {
+		return
+	}
+	upper := c.PutAll(ctx, applied, level-1)
+	skipped := make([]map[string]bool, len(upper))
+	for i, r := range upper {
+		skipped[i] = make(map[string]bool, len(r.Skipped))
+		for _, id := range r.Skipped {
+			skipped[i][id.GetStorageKey()] = true
+		}
+	}
+	for _, e := range applied {
+		statuses := make([]*dto.LayerWriteStatus, len(c.services))
+		for i := range c.services {
+			statuses[i] = &dto.LayerWriteStatus{Layer: i, Status: dto.LayerStatusOk}
+			switch {
+			case i > level:
+				statuses[i].Status = dto.LayerStatusSkipped
+			case i == level && levelErr != nil:
+				statuses[i].Status = dto.LayerStatusError
+				statuses[i].Error = levelErr.Error()
+			case i < level && upper[i].Err != nil:
+				statuses[i].Status = dto.LayerStatusError
+				statuses[i].Error = upper[i].Err.Error()
+			case i < level && skipped[i][e.GetStorageKey()]:
+				statuses[i].Status = dto.LayerStatusSkipped
+			}
+		}
+		result.Applied = append(result.Applied, e.ResolvedCacheId)
+		result.Layers[e.GetStorageKey()] = statuses
+	}
+}
+
+// TouchAll продлевает TTL значений на всех уровнях.
+// results[i] — результат слоя i; ошибка одного слоя не прерывает продление в остальных.
+func (c *ControllerImpl) TouchAll(ctx context.Context, entries []*dto.ResolvedCacheEntry) (results []*dto.TouchLayerResult) {
+
+	results = make([]*dto.TouchLayerResult, 0, len(c.services))
+	for i, service := range c.services {
+		missing, skipped, err := service.TouchAll(ctx, entries)
 		if err != nil {
 			zap.S().Warnw("layer unavailable", "layer", i, "error", err)
 		}
+		results = append(results, &dto.TouchLayerResult{Missing: missing, Skipped: skipped, Err: err})
 	}
+	return
 }
 
-func (c *ControllerImpl) PutAllToAllLevels(ctx context.Context, entries []*dto.ResolvedCacheEntry) {
-	c.PutAll(ctx, entries, len(c.services)-1)
+// DeleteAll удаляет значения со всех уровней.
+// results[i] — результат удаления из слоя i; ошибка одного слоя не прерывает удаление из остальных.
+func (c *ControllerImpl) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (results []*dto.LayerResult) {
+
+	results = make([]*dto.LayerResult, 0, len(c.services))
+	for i, service := range c.services {
+		skipped, err := service.DeleteAll(ctx, reqs)
+		if err != nil {
+			zap.S().Warnw("layer unavailable", "layer", i, "error", err)
+		}
+		results = append(results, &dto.LayerResult{Skipped: skipped, Err: err})
+	}
+	c.publish(ctx, reqs)
+	return
 }
 
-// DeleteAll удаляет значения со всех уровней
-func (c *ControllerImpl) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) {
+// EvictByTag удаляет ключи с тегом со всех уровней.
+// results[i] — результат слоя i; ошибка одного слоя не прерывает удаление из остальных.
+func (c *ControllerImpl) EvictByTag(ctx context.Context, tag string) (results []*dto.TagLayerResult) {
 
+	results = make([]*dto.TagLayerResult, 0, len(c.services))
+	evicted := make(map[string]struct{})
 	for i, service := range c.services {
-		err := service.DeleteAll(ctx, reqs)
+		keys, err := service.EvictTag(ctx, tag)
 		if err != nil {
 			zap.S().Warnw("layer unavailable", "layer", i, "error", err)
 		}
+		for _, key := range keys {
+			evicted[key] = struct{}{}
+		}
+		results = append(results, &dto.TagLayerResult{Keys: keys, Err: err})
+	}
+	keys := make([]string, 0, len(evicted))
+	for key := range evicted {
+		keys = append(keys, key)
+	}
+	c.publishKeys(ctx, keys)
+	return
+}
+
+// FlushPrefix удаляет ключи хранилища с префиксом со всех уровней, начиная с нижнего: верхний уровень,
+// очищенный раньше нижнего, успел бы дозаписаться из него старыми значениями при чтении.
+// progress(level, deleted) сообщает о ходе очистки уровня; deleted = 0 — очистка уровня начата.
+// results[i] — ошибка уровня i (nil — уровень очищен). После отмены ctx оставшиеся уровни
+// не очищаются и получают ошибку ctx.
+func (c *ControllerImpl) FlushPrefix(ctx context.Context, prefix string, progress func(level int, deleted int64)) (results []error) {
+
+	results = make([]error, len(c.services))
+	for i := len(c.services) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			results[i] = err
+			continue
+		}
+		progress(i, 0)
+		_, err := c.services[i].DeletePrefix(ctx, prefix, func(deleted int64) { progress(i, deleted) })
+		if err != nil {
+			zap.S().Warnw("cannot flush layer", "layer", i, "prefix", prefix, "error", err)
+		}
+		results[i] = err
+	}
+	if c.bus != nil {
+		// слои в памяти других экземпляров очищаются, даже если здесь очистка прервана
+		if err := c.bus.PublishPrefix(context.WithoutCancel(ctx), prefix); err != nil {
+			zap.S().Warnw("cannot publish invalidation", "prefix", prefix, "error", err)
+		}
+	}
+	return
+}
+
+// ScanKeys возвращает страницу ключей хранилища с префиксом на уровне level (см. providers.Scanner).
+func (c *ControllerImpl) ScanKeys(ctx context.Context, level int, prefix, match, cursor string, count int) ([]string, string, error) {
+	if level < 0 || level >= len(c.services) {
+		return nil, "", fmt.Errorf("unknown layer %d", level)
+	}
+	return c.services[level].ScanKeys(ctx, prefix, match, cursor, count)
+}
+
+// EnableInvalidation включает рассылку изменённых ключей через bus и запускает приём
+// инвалидаций от других экземпляров: полученные ключи и префиксы удаляются из слоёв localLevels.
+// Приём работает, пока не отменён ctx.
+func (c *ControllerImpl) EnableInvalidation(ctx context.Context, bus *InvalidationBus, localLevels []int) {
+	c.bus = bus
+	c.localLevels = localLevels
+	go bus.Run(ctx, c.invalidateLocal, c.flushLocal)
+}
+
+// invalidateLocal удаляет ключи из слоёв в памяти процесса, не рассылая их дальше.
+func (c *ControllerImpl) invalidateLocal(ctx context.Context, keys []string) {
+	for _, level := range c.localLevels {
+		if err := c.services[level].EvictKeys(ctx, keys); err != nil {
+			zap.S().Warnw("cannot apply invalidation", "layer", level, "error", err)
+		}
+	}
+}
+
+// flushLocal удаляет ключи с префиксом из слоёв в памяти процесса, не рассылая префикс дальше.
+func (c *ControllerImpl) flushLocal(ctx context.Context, prefix string) {
+	for _, level := range c.localLevels {
+		if _, err := c.services[level].DeletePrefix(ctx, prefix, func(int64) {}); err != nil {
+			zap.S().Warnw("cannot apply invalidation", "layer", level, "prefix", prefix, "error", err)
+		}
+	}
+}
+
+func (c *ControllerImpl) publishEntries(ctx context.Context, entries []*dto.ResolvedCacheEntry) {
+	if c.bus == nil {
+		return
+	}
+	ids := make([]*dto.ResolvedCacheId, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ResolvedCacheId)
+	}
+	c.publish(ctx, ids)
+}
+
+// publish рассылает ключи хранилища ids другим экземплярам.
+func (c *ControllerImpl) publish(ctx context.Context, ids []*dto.ResolvedCacheId) {
+	if c.bus == nil {
+		return
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, id.GetStorageKey())
+	}
+	c.publishKeys(ctx, keys)
+}
+
+// publishKeys рассылает ключи хранилища другим экземплярам. Ошибка рассылки не влияет
+// на результат записи: она только логируется.
+func (c *ControllerImpl) publishKeys(ctx context.Context, keys []string) {
+	if c.bus == nil || len(keys) == 0 {
+		return
+	}
+	if err := c.bus.Publish(ctx, keys); err != nil {
+		zap.S().Warnw("cannot publish invalidation", "keys", len(keys), "error", err)
 	}
 }
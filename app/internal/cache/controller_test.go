@@ -11,10 +11,26 @@ import (
 
 type mockService struct {
 	getAllCalled    int
+	withMetaCalled  int
 	putAllCalled    int
 	deleteAllCalled int
+	putIfCalled     int
+	touchCalled     int
 	fail            bool
 	layer           int
+	// disabled — слой отключён для всех ключей (PutAllIf возвращает их в Skipped)
+	disabled bool
+	// condErr — PutAllIf записывает значения, но возвращает ошибку вместе с результатом;
+	// condFailed — ключи, которые PutAllIf возвращает в Errors
+	condErr    error
+	condFailed map[string]bool
+	// evicted — ключи, полученные через EvictKeys
+	evicted chan []string
+	// tagKeys — ключи, которые возвращает EvictTag
+	tagKeys []string
+	// flushed — префиксы, полученные через DeletePrefix; flushOrder — слои в порядке очистки
+	flushed    chan string
+	flushOrder *[]int
 }
 
 func (m *mockService) GetAll(_ context.Context, reqs []*dto.ResolvedCacheId) (*dto.GetResult, error) {
@@ -29,22 +45,100 @@ func (m *mockService) GetAll(_ context.Context, reqs []*dto.ResolvedCacheId) (*d
 	}, nil
 }
 
-func (m *mockService) PutAll(_ context.Context, _ []*dto.ResolvedCacheEntry) error {
+func (m *mockService) GetAllWithMeta(ctx context.Context, reqs []*dto.ResolvedCacheId) (*dto.GetResult, error) {
+	m.withMetaCalled++
+	return m.GetAll(ctx, reqs)
+}
+
+func (m *mockService) PutAll(_ context.Context, _ []*dto.ResolvedCacheEntry) ([]*dto.ResolvedCacheId, error) {
 	m.putAllCalled++
 	if m.fail {
-		return errors.New("put failed")
+		return nil, errors.New("put failed")
 	}
-	return nil
+	return nil, nil
 }
 
-func (m *mockService) DeleteAll(_ context.Context, _ []*dto.ResolvedCacheId) error {
+func (m *mockService) PutAllIf(_ context.Context, reqs []*dto.ResolvedCacheEntry) (*dto.CondLayerResult, error) {
+	m.putIfCalled++
+	if m.fail {
+		return nil, errors.New("put failed")
+	}
+	ids := make([]*dto.ResolvedCacheId, len(reqs))
+	for i, req := range reqs {
+		ids[i] = req.ResolvedCacheId
+	}
+	if m.disabled {
+		return &dto.CondLayerResult{Skipped: ids}, nil
+	}
+	result := &dto.CondLayerResult{}
+	for _, id := range ids {
+		if m.condFailed[id.GetStorageKey()] {
+			result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: id, Err: errors.New("no layer config")})
+		} else {
+			result.Applied = append(result.Applied, id)
+		}
+	}
+	return result, m.condErr
+}
+
+func (m *mockService) TouchAll(_ context.Context, reqs []*dto.ResolvedCacheEntry) ([]*dto.ResolvedCacheId, []*dto.ResolvedCacheId, error) {
+	m.touchCalled++
+	if m.fail {
+		return nil, nil, errors.New("touch failed")
+	}
+	ids := make([]*dto.ResolvedCacheId, 0, len(reqs))
+	for _, r := range reqs {
+		ids = append(ids, r.ResolvedCacheId)
+	}
+	if m.disabled {
+		return []*dto.ResolvedCacheId{}, ids, nil
+	}
+	return []*dto.ResolvedCacheId{}, []*dto.ResolvedCacheId{}, nil
+}
+
+func (m *mockService) DeleteAll(_ context.Context, _ []*dto.ResolvedCacheId) ([]*dto.ResolvedCacheId, error) {
 	m.deleteAllCalled++
 	if m.fail {
-		return errors.New("delete failed")
+		return nil, errors.New("delete failed")
+	}
+	return nil, nil
+}
+
+func (m *mockService) EvictTag(_ context.Context, _ string) ([]string, error) {
+	if m.fail {
+		return nil, errors.New("evict tag failed")
+	}
+	return m.tagKeys, nil
+}
+
+func (m *mockService) EvictKeys(_ context.Context, keys []string) error {
+	if m.evicted != nil {
+		m.evicted <- keys
 	}
 	return nil
 }
 
+func (m *mockService) DeletePrefix(_ context.Context, prefix string, progress func(int64)) (int64, error) {
+	if m.flushOrder != nil {
+		*m.flushOrder = append(*m.flushOrder, m.layer)
+	}
+	if m.flushed != nil {
+		m.flushed <- prefix
+	}
+	if m.fail {
+		return 0, errors.New("flush failed")
+	}
+	progress(3)
+	return 3, nil
+}
+
+func (m *mockService) ScanKeys(_ context.Context, prefix, _, cursor string, _ int) ([]string, string, error) {
+	if m.fail {
+		return nil, "", errors.New("scan failed")
+	}
+	return []string{prefix + cursor + "k"}, "", nil
+}
+
 func (m *mockService) Close() error {
 	return nil
 }
@@ -57,13 +151,65 @@ func TestController_GetAll(t *testing.T) {
 		{CacheId: &dto.CacheId{CacheName: "test", Key: "1"}, StorageKey: "test:1"},
 	}
 
-	results := controller.GetAll(context.Background(), reqs)
+	results := controller.GetAll(context.Background(), reqs, dto.GetOptions{})
 	assert.Len(t, results, 1)
 	assert.Equal(t, 1, service.getAllCalled)
 	assert.Len(t, results[0].Hits, 1)
 	assert.Equal(t, "test:1", results[0].Hits[0].ResolvedCacheEntry.ResolvedCacheId.StorageKey)
 }
 
+func TestController_GetAllWithMeta(t *testing.T) {
+	service := &mockService{}
+	controller := CreateControllerImpl([]providers.Service{service})
+
+	reqs := []*dto.ResolvedCacheId{
+		{CacheId: &dto.CacheId{CacheName: "test", Key: "1"}, StorageKey: "test:1"},
+	}
+
+	controller.GetAll(context.Background(), reqs, dto.GetOptions{Meta: true})
+	assert.Equal(t, 1, service.withMetaCalled)
+}
+
+func TestController_GetAllLayerRange(t *testing.T) {
+	s1, s2, s3 := &mockService{}, &mockService{}, &mockService{}
+	controller := CreateControllerImpl([]providers.Service{s1, s2, s3})
+
+	reqs := []*dto.ResolvedCacheId{
+		{CacheId: &dto.CacheId{CacheName: "test", Key: "1"}, StorageKey: "test:1"},
+	}
+
+	maxLayer := 1
+	results := controller.GetAll(context.Background(), reqs, dto.GetOptions{MinLayer: 1, MaxLayer: &maxLayer})
+	assert.Len(t, results, 3)
+	assert.Equal(t, 0, s1.getAllCalled)
+	assert.Equal(t, 1, s2.getAllCalled)
+	assert.Equal(t, 0, s3.getAllCalled)
+	assert.Equal(t, reqs, results[0].Skipped)
+	assert.Equal(t, 1, results[1].Hits[0].Layer)
+	assert.Empty(t, results[2].Errors)
+
+	results = controller.GetAll(context.Background(), reqs, dto.GetOptions{BypassCache: true})
+	assert.Equal(t, 1, s2.getAllCalled)
+	assert.Equal(t, reqs, results[2].Skipped)
+}
+
+func TestController_GetAllReportsLayerErrors(t *testing.T) {
+	s1 := &mockService{fail: true}
+	s2 := &mockService{}
+	controller := CreateControllerImpl([]providers.Service{s1, s2})
+
+	reqs := []*dto.ResolvedCacheId{
+		{CacheId: &dto.CacheId{CacheName: "test", Key: "1"}, StorageKey: "test:1"},
+	}
+
+	results := controller.GetAll(context.Background(), reqs, dto.GetOptions{})
+	assert.Len(t, results, 2)
+	assert.Equal(t, reqs, results[0].Skipped)
+	assert.Len(t, results[0].Errors, 1)
+	assert.ErrorContains(t, results[0].Errors[0].Err, "layer 0")
+	assert.Equal(t, 1, results[1].Hits[0].Layer)
+}
+
 func TestController_PutAll(t *testing.T) {
 	s1 := &mockService{layer: 0}
 	s2 := &mockService{layer: 1}
@@ -111,3 +257,171 @@ func TestController_DeleteAll(t *testing.T) {
 	assert.Equal(t, 1, s1.deleteAllCalled)
 	assert.Equal(t, 1, s2.deleteAllCalled)
 }
+
+func TestController_PutAllReportsLayerErrors(t *testing.T) {
+	s1 := &mockService{fail: true}
+	s2 := &mockService{}
+	controller := CreateControllerImpl([]providers.Service{s1, s2})
+
+	entry := &dto.ResolvedCacheEntry{
+		ResolvedCacheId: &dto.ResolvedCacheId{
+			CacheId:    &dto.CacheId{CacheName: "test", Key: "1"},
+			StorageKey: "test:1",
+		},
+	}
+	results := controller.PutAllToAllLevels(context.Background(), []*dto.ResolvedCacheEntry{entry})
+
+	assert.Len(t, results, 2)
+	assert.Error(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, 1, s2.putAllCalled)
+}
+
+func TestController_PutAllIf(t *testing.T) {
+	s1 := &mockService{}
+	s2 := &mockService{disabled: true}
+	controller := CreateControllerImpl([]providers.Service{s1, s2})
+
+	entry := &dto.ResolvedCacheEntry{
+		ResolvedCacheId: &dto.ResolvedCacheId{
+			CacheId:    &dto.CacheId{CacheName: "test", Key: "1"},
+			StorageKey: "test:1",
+		},
+		IfAbsent: true,
+	}
+	result := controller.PutAllIf(context.Background(), []*dto.ResolvedCacheEntry{entry})
+
+	// нижний слой отключён для ключа — условие проверяется в слое 0
+	assert.Equal(t, 1, s2.putIfCalled)
+	assert.Equal(t, 1, s1.putIfCalled)
+	assert.Equal(t, 0, s2.putAllCalled)
+	assert.Equal(t, []*dto.ResolvedCacheId{entry.ResolvedCacheId}, result.Applied)
+	assert.Equal(t, []*dto.LayerWriteStatus{
+		{Layer: 0, Status: dto.LayerStatusOk},
+		{Layer: 1, Status: dto.LayerStatusSkipped},
+	}, result.Layers["test:1"])
+	assert.Empty(t, result.Errors)
+}
+
+func TestController_PutAllIfLayerError(t *testing.T) {
+	s1 := &mockService{}
+	s2 := &mockService{fail: true}
+	controller := CreateControllerImpl([]providers.Service{s1, s2})
+
+	entry := &dto.ResolvedCacheEntry{
+		ResolvedCacheId: &dto.ResolvedCacheId{
+			CacheId:    &dto.CacheId{CacheName: "test", Key: "1"},
+			StorageKey: "test:1",
+		},
+		IfVersion: "1",
+	}
+	result := controller.PutAllIf(context.Background(), []*dto.ResolvedCacheEntry{entry})
+
+	// слой с ошибкой не пропускается: условие в слоях выше не авторитетно
+	assert.Equal(t, 0, s1.putIfCalled)
+	assert.Empty(t, result.Applied)
+	assert.Len(t, result.Errors, 1)
+	assert.ErrorContains(t, result.Errors[0].Err, "layer 1")
+}
+
+func TestController_PutAllIfPartialErrors(t *testing.T) {
+	s1 := &mockService{condErr: errors.New("tags down"), condFailed: map[string]bool{"test:2": true}}
+	controller := CreateControllerImpl([]providers.Service{s1})
+
+	entry := func(key string) *dto.ResolvedCacheEntry {
+		return &dto.ResolvedCacheEntry{
+			ResolvedCacheId: &dto.ResolvedCacheId{
+				CacheId:    &dto.CacheId{CacheName: "test", Key: key},
+				StorageKey: "test:" + key,
+			},
+			IfAbsent: true,
+		}
+	}
+	applied, failed := entry("1"), entry("2")
+	result := controller.PutAllIf(context.Background(), []*dto.ResolvedCacheEntry{applied, failed})
+
+	// значение записано, ошибка слоя после записи попадает в статус слоя
+	assert.Equal(t, []*dto.ResolvedCacheId{applied.ResolvedCacheId}, result.Applied)
+	assert.Equal(t, []*dto.LayerWriteStatus{
+		{Layer: 0, Status: dto.LayerStatusError, Error: "tags down"},
+	}, result.Layers["test:1"])
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, failed.ResolvedCacheId, result.Errors[0].ResolvedCacheId)
+	assert.ErrorContains(t, result.Errors[0].Err, "layer 0: no layer config")
+}
+
+func TestController_TouchAll(t *testing.T) {
+	s1 := &mockService{fail: true}
+	s2 := &mockService{disabled: true}
+	controller := CreateControllerImpl([]providers.Service{s1, s2})
+
+	entry := &dto.ResolvedCacheEntry{
+		ResolvedCacheId: &dto.ResolvedCacheId{
+			CacheId:    &dto.CacheId{CacheName: "test", Key: "1"},
+			StorageKey: "test:1",
+		},
+	}
+	results := controller.TouchAll(context.Background(), []*dto.ResolvedCacheEntry{entry})
+
+	assert.Len(t, results, 2)
+	assert.Error(t, results[0].Err)
+	assert.Equal(t, []*dto.ResolvedCacheId{entry.ResolvedCacheId}, results[1].Skipped)
+	assert.Equal(t, 1, s2.touchCalled)
+}
+
+func TestController_EvictByTag(t *testing.T) {
+	s1 := &mockService{tagKeys: []string{"test:1", "test:2"}}
+	s2 := &mockService{fail: true}
+	controller := CreateControllerImpl([]providers.Service{s1, s2})
+
+	results := controller.EvictByTag(context.Background(), "tenant:42")
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, []string{"test:1", "test:2"}, results[0].Keys)
+	assert.NoError(t, results[0].Err)
+	assert.Error(t, results[1].Err)
+}
+
+func TestController_FlushPrefix(t *testing.T) {
+	var order []int
+	s0 := &mockService{layer: 0, flushOrder: &order}
+	s1 := &mockService{layer: 1, flushOrder: &order, fail: true}
+	s2 := &mockService{layer: 2, flushOrder: &order}
+	controller := CreateControllerImpl([]providers.Service{s0, s1, s2})
+
+	progress := map[int]int64{}
+	results := controller.FlushPrefix(context.Background(), "test:", func(level int, deleted int64) {
+		progress[level] = deleted
+	})
+
+	// нижние слои очищаются первыми; ошибка слоя не прерывает очистку остальных
+	assert.Equal(t, []int{2, 1, 0}, order)
+	assert.NoError(t, results[0])
+	assert.Error(t, results[1])
+	assert.NoError(t, results[2])
+	assert.Equal(t, map[int]int64{0: 3, 1: 0, 2: 3}, progress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	order = nil
+	results = controller.FlushPrefix(ctx, "test:", func(int, int64) {})
+	assert.Empty(t, order)
+	for _, err := range results {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
+func TestController_ScanKeys(t *testing.T) {
+	controller := CreateControllerImpl([]providers.Service{&mockService{}, &mockService{fail: true}})
+
+	keys, next, err := controller.ScanKeys(context.Background(), 0, "p:", "", "c", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"p:ck"}, keys)
+	assert.Empty(t, next)
+
+	_, _, err = controller.ScanKeys(context.Background(), 1, "p:", "", "", 10)
+	assert.Error(t, err)
+
+	_, _, err = controller.ScanKeys(context.Background(), 2, "p:", "", "", 10)
+	assert.ErrorContains(t, err, "unknown layer 2")
+}
@@ -1,8 +1,10 @@
 package cache
 
 import (
+	"aur-cache-service/api/dto"
 	"aur-cache-service/internal/cache/config"
 	"aur-cache-service/internal/cache/providers"
+	"context"
 
 	"go.uber.org/zap"
 	"telegram-alerts-go/alert"
@@ -30,5 +32,78 @@ func CreateLayersCacheController(configFilePath string, configCacheService confi
 		zap.S().Errorw(alert.Prefix("error creating service list"), "error", err)
 	}
 
-	return CreateControllerImpl(clientServices)
+	controller := &ControllerImpl{services: clientServices}
+	if appConfig.Invalidation.Provider != "" {
+		enableInvalidation(controller, appConfig, providerService.LayerProviders)
+	}
+	return controller
+}
+
+// enableInvalidation подключает шину инвалидаций к Redis-провайдеру из конфигурации.
+// Очищаются только слои Ristretto: Redis и RocksDB общие для экземпляров или
+// не требуют инвалидаций между ними.
+func enableInvalidation(controller *ControllerImpl, appConfig *config.AppConfig, layerProviders []*config.LayerProvider) {
+	redisCfg := findRedis(appConfig, appConfig.Invalidation.Provider)
+	if redisCfg == nil {
+		zap.S().Errorw(alert.Prefix("invalidation provider not found"), "provider", appConfig.Invalidation.Provider)
+		return
+	}
+
+	localLevels := make([]int, 0, len(layerProviders))
+	for i, lp := range layerProviders {
+		if lp.Mode != config.LayerModeDisabled && lp.Provider.GetType() == config.ProviderTypeRistretto {
+			localLevels = append(localLevels, i)
+		}
+	}
+
+	bus := NewInvalidationBus(providers.NewRedisClient(*redisCfg), appConfig.Invalidation.GetChannel())
+	controller.EnableInvalidation(context.Background(), bus, localLevels)
+}
+
+// CreateGenerations создаёт хранилище поколений для кэшей с config.Cache.Generations
+// и запускает его обновление из Redis. nil — ни у одного кэша поколения не включены.
+func CreateGenerations(configFilePath string) dto.Generations {
+	appConfig, err := config.LoadAppConfig(configFilePath)
+	if err != nil {
+		zap.S().Errorw(alert.Prefix("error reading config file"), "error", err)
+		return nil
+	}
+
+	var cacheNames []string
+	for _, c := range appConfig.Caches {
+		if c.Generations {
+			cacheNames = append(cacheNames, c.Name)
+		}
+	}
+	if len(cacheNames) == 0 {
+		return nil
+	}
+
+	cfg := appConfig.Generations
+	if cfg.Provider == "" {
+		zap.S().Warnw("generations are kept in memory only, they are not shared between instances", "caches", cacheNames)
+		return NewGenerationStore(nil, "", cacheNames)
+	}
+	redisCfg := findRedis(appConfig, cfg.Provider)
+	if redisCfg == nil {
+		zap.S().Errorw(alert.Prefix("generations provider not found"), "provider", cfg.Provider)
+		return NewGenerationStore(nil, "", cacheNames)
+	}
+
+	store := NewGenerationStore(providers.NewRedisClient(*redisCfg), cfg.GetKey(), cacheNames)
+	// до первого запроса: иначе экземпляр писал бы и читал записи устаревшего поколения
+	if err := store.Load(context.Background()); err != nil {
+		zap.S().Errorw(alert.Prefix("error loading generations"), "error", err)
+	}
+	go store.Run(context.Background(), cfg.GetRefreshInterval())
+	return store
+}
+
+func findRedis(appConfig *config.AppConfig, name string) *config.Redis {
+	for _, p := range appConfig.Provider {
+		if r, ok := p.(*config.Redis); ok && r.GetName() == name {
+			return r
+		}
+	}
+	return nil
 }
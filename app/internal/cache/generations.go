@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// bumpGenerationScript увеличивает поколение кэша в HASH, но не ниже ARGV[2] + 1:
+// если HASH потерян (FLUSHALL, новый Redis), поколение не возвращается к уже использованным.
+//
+// KEYS[1] — HASH поколений; ARGV[1] — имя кэша; ARGV[2] — текущее поколение экземпляра.
+var bumpGenerationScript = redis.NewScript(`
+local cur = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+local known = tonumber(ARGV[2])
+if cur < known then cur = known end
+cur = cur + 1
+redis.call('HSET', KEYS[1], ARGV[1], cur)
+return cur
+`)
+
+// GenerationStore хранит поколения кэшей с config.Cache.Generations (см. dto.Generations).
+//
+// С Redis поколения общие для всех экземпляров: Bump увеличивает поле HASH, а Run
+// периодически перечитывает HASH, так что увеличение на одном экземпляре видно остальным
+// не позже чем через интервал обновления. Поколение на экземпляре никогда не уменьшается.
+// Без Redis поколения живут только в памяти экземпляра.
+type GenerationStore struct {
+	rdb     *redis.Client // nil — только в памяти
+	key     string
+	enabled map[string]bool
+
+	mu   sync.RWMutex
+	gens map[string]int64
+}
+
+func NewGenerationStore(rdb *redis.Client, key string, cacheNames []string) *GenerationStore {
+	enabled := make(map[string]bool, len(cacheNames))
+	for _, name := range cacheNames {
+		enabled[name] = true
+	}
+	return &GenerationStore{rdb: rdb, key: key, enabled: enabled, gens: make(map[string]int64)}
+}
+
+func (s *GenerationStore) Generation(cacheName string) (int64, bool) {
+	if !s.enabled[cacheName] {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.gens[cacheName], true
+}
+
+func (s *GenerationStore) Bump(ctx context.Context, cacheName string) (int64, error) {
+	if !s.enabled[cacheName] {
+		return 0, fmt.Errorf("generations are disabled for cache %q", cacheName)
+	}
+	if s.rdb == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.gens[cacheName]++
+		return s.gens[cacheName], nil
+	}
+
+	known, _ := s.Generation(cacheName)
+	gen, err := bumpGenerationScript.Run(ctx, s.rdb, []string{s.key}, cacheName, known).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("bump generation: %w", err)
+	}
+	s.advance(cacheName, gen)
+	return gen, nil
+}
+
+// advance поднимает поколение кэша до gen, если оно не меньше текущего.
+func (s *GenerationStore) advance(cacheName string, gen int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gen > s.gens[cacheName] {
+		s.gens[cacheName] = gen
+	}
+}
+
+// Load перечитывает поколения из Redis. Поля HASH для кэшей без поколений пропускаются.
+func (s *GenerationStore) Load(ctx context.Context) error {
+	if s.rdb == nil {
+		return nil
+	}
+	fields, err := s.rdb.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return fmt.Errorf("load generations: %w", err)
+	}
+	for name, value := range fields {
+		if !s.enabled[name] {
+			continue
+		}
+		gen, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			zap.S().Warnw("invalid cache generation", "cache", name, "value", value)
+			continue
+		}
+		s.advance(name, gen)
+	}
+	return nil
+}
+
+// Run перечитывает поколения раз в interval, пока не отменён ctx.
+func (s *GenerationStore) Run(ctx context.Context, interval time.Duration) {
+	if s.rdb == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Load(ctx); err != nil {
+			if !failed && ctx.Err() == nil {
+				zap.S().Warnw("generations refresh failed, bumps from other instances are not visible", "error", err)
+			}
+			failed = true
+			continue
+		}
+		if failed {
+			zap.S().Info("generations refresh recovered")
+			failed = false
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedisGenerationStore(t *testing.T, srv *miniredis.Miniredis) *GenerationStore {
+	rdb := redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return NewGenerationStore(rdb, "gens", []string{"user"})
+}
+
+func TestGenerationStore_Local(t *testing.T) {
+	ctx := context.Background()
+	store := NewGenerationStore(nil, "gens", []string{"user"})
+
+	gen, ok := store.Generation("user")
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), gen)
+
+	_, ok = store.Generation("other")
+	assert.False(t, ok)
+
+	gen, err := store.Bump(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), gen)
+
+	_, err = store.Bump(ctx, "other")
+	assert.Error(t, err)
+}
+
+func TestGenerationStore_SharedThroughRedis(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx := context.Background()
+
+	a := newRedisGenerationStore(t, srv)
+	b := newRedisGenerationStore(t, srv)
+
+	gen, err := a.Bump(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), gen)
+	assert.Equal(t, "1", srv.HGet("gens", "user"))
+
+	// b видит увеличение после перечитывания
+	gen, _ = b.Generation("user")
+	assert.Equal(t, int64(0), gen)
+	assert.NoError(t, b.Load(ctx))
+	gen, _ = b.Generation("user")
+	assert.Equal(t, int64(1), gen)
+
+	gen, err = b.Bump(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), gen)
+
+	// поля кэшей без поколений игнорируются
+	srv.HSet("gens", "other", "7")
+	assert.NoError(t, a.Load(ctx))
+	_, ok := a.Generation("other")
+	assert.False(t, ok)
+}
+
+func TestGenerationStore_NeverGoesBack(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx := context.Background()
+
+	store := newRedisGenerationStore(t, srv)
+	_, _ = store.Bump(ctx, "user")
+	_, _ = store.Bump(ctx, "user")
+
+	// HASH потерян: перечитывание не уменьшает поколение, а следующее увеличение продолжает счёт
+	srv.FlushAll()
+	assert.NoError(t, store.Load(ctx))
+	gen, _ := store.Generation("user")
+	assert.Equal(t, int64(2), gen)
+
+	gen, err := store.Bump(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), gen)
+}
+
+func TestGenerationStore_Run(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newRedisGenerationStore(t, srv)
+	b := newRedisGenerationStore(t, srv)
+	go b.Run(ctx, 10*time.Millisecond)
+
+	_, err := a.Bump(ctx, "user")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		gen, _ := b.Generation("user")
+		return gen == 1
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	invalidationRetryMin = 100 * time.Millisecond
+	invalidationRetryMax = 5 * time.Second
+)
+
+// InvalidationBus рассылает ключи хранилища, изменённые на этом экземпляре сервиса,
+// и префиксы очищенных кэшей через Redis pub/sub и принимает такие же рассылки
+// от других экземпляров.
+//
+// Каждое сообщение помечено идентификатором экземпляра-отправителя: собственные
+// сообщения, которые Redis доставляет и самому отправителю, пропускаются.
+//
+// Pub/sub не хранит сообщения: пока подписка разорвана, инвалидации теряются,
+// и слои в памяти процесса могут отдавать устаревшие значения до истечения их TTL.
+type InvalidationBus struct {
+	rdb        *redis.Client
+	channel    string
+	instanceID string
+}
+
+// invalidationMessage — формат сообщения в канале: ключи или префикс очищенных ключей.
+type invalidationMessage struct {
+	Source string   `json:"src"`
+	Keys   []string `json:"keys,omitempty"`
+	Prefix string   `json:"prefix,omitempty"`
+}
+
+func NewInvalidationBus(rdb *redis.Client, channel string) *InvalidationBus {
+	return &InvalidationBus{rdb: rdb, channel: channel, instanceID: newInstanceID()}
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Publish рассылает ключи хранилища другим экземплярам.
+func (b *InvalidationBus) Publish(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return b.publish(ctx, invalidationMessage{Source: b.instanceID, Keys: keys})
+}
+
+// PublishPrefix рассылает префикс ключей хранилища, очищенных на этом экземпляре.
+func (b *InvalidationBus) PublishPrefix(ctx context.Context, prefix string) error {
+	return b.publish(ctx, invalidationMessage{Source: b.instanceID, Prefix: prefix})
+}
+
+func (b *InvalidationBus) publish(ctx context.Context, msg invalidationMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := b.rdb.Publish(ctx, b.channel, payload).Err(); err != nil {
+		return fmt.Errorf("publish invalidation: %w", err)
+	}
+	return nil
+}
+
+// Run подписывается на канал и вызывает handle для ключей (handlePrefix — для префиксов)
+// из сообщений других экземпляров, пока не отменён ctx. При разрыве соединения клиент Redis переподключается и
+// восстанавливает подписку; между попытками Run ждёт с растущей паузой.
+func (b *InvalidationBus) Run(ctx context.Context, handle func(ctx context.Context, keys []string), handlePrefix func(ctx context.Context, prefix string)) {
+	ps := b.rdb.Subscribe(ctx, b.channel)
+	defer ps.Close()
+
+	zap.S().Infow("invalidation bus started", "channel", b.channel, "instance", b.instanceID)
+	defer zap.S().Info("invalidation bus stopped")
+
+	retry := invalidationRetryMin
+	subscribed := false
+	for {
+		msg, err := ps.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if subscribed {
+				zap.S().Warnw("invalidation bus disconnected, invalidations may be lost", "channel", b.channel, "error", err)
+				subscribed = false
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			retry = min(retry*2, invalidationRetryMax)
+			continue
+		}
+		retry = invalidationRetryMin
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				subscribed = true
+				zap.S().Infow("invalidation bus subscribed", "channel", m.Channel)
+			}
+		case *redis.Message:
+			var im invalidationMessage
+			if err := json.Unmarshal([]byte(m.Payload), &im); err != nil {
+				zap.S().Warnw("invalid invalidation message", "error", err)
+				continue
+			}
+			if im.Source == b.instanceID {
+				continue
+			}
+			if len(im.Keys) > 0 {
+				handle(ctx, im.Keys)
+			}
+			if im.Prefix != "" {
+				handlePrefix(ctx, im.Prefix)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/providers"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBusController создаёт контроллер с одним слоем в памяти процесса и подключённой шиной.
+func newBusController(t *testing.T, ctx context.Context, srv *miniredis.Miniredis) (*ControllerImpl, *mockService) {
+	rdb := redis.NewClient(&redis.Options{Addr: srv.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	service := &mockService{evicted: make(chan []string, 10), flushed: make(chan string, 10)}
+	controller := CreateControllerImpl([]providers.Service{service, &mockService{}}).(*ControllerImpl)
+	controller.EnableInvalidation(ctx, NewInvalidationBus(rdb, "inv"), []int{0})
+	return controller, service
+}
+
+// waitSubscribers ждёт, пока все шины подпишутся на канал.
+func waitSubscribers(t *testing.T, srv *miniredis.Miniredis, n int) {
+	assert.Eventually(t, func() bool {
+		return srv.PubSubNumSub("inv")["inv"] == n
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestInvalidationBus_PropagatesToOtherInstances(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, aLocal := newBusController(t, ctx, srv)
+	_, bLocal := newBusController(t, ctx, srv)
+	waitSubscribers(t, srv, 2)
+
+	ids := []*dto.ResolvedCacheId{{CacheId: &dto.CacheId{CacheName: "test", Key: "1"}, StorageKey: "test:1"}}
+	a.DeleteAll(ctx, ids)
+
+	select {
+	case keys := <-bLocal.evicted:
+		assert.Equal(t, []string{"test:1"}, keys)
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalidation was not received")
+	}
+
+	a.PutAllToAllLevels(ctx, []*dto.ResolvedCacheEntry{{ResolvedCacheId: ids[0]}})
+	select {
+	case keys := <-bLocal.evicted:
+		assert.Equal(t, []string{"test:1"}, keys)
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalidation was not received")
+	}
+
+	// собственные сообщения отправитель пропускает
+	assert.Empty(t, aLocal.evicted)
+}
+
+func TestInvalidationBus_PutAllDoesNotPublish(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, _ := newBusController(t, ctx, srv)
+	_, bLocal := newBusController(t, ctx, srv)
+	waitSubscribers(t, srv, 2)
+
+	ids := []*dto.ResolvedCacheId{{CacheId: &dto.CacheId{CacheName: "test", Key: "1"}, StorageKey: "test:1"}}
+	a.PutAll(ctx, []*dto.ResolvedCacheEntry{{ResolvedCacheId: ids[0]}}, 0)
+
+	select {
+	case keys := <-bLocal.evicted:
+		t.Fatalf("unexpected invalidation: %v", keys)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInvalidationBus_Resubscribes(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, _ := newBusController(t, ctx, srv)
+	_, bLocal := newBusController(t, ctx, srv)
+	waitSubscribers(t, srv, 2)
+
+	srv.Close()
+	assert.NoError(t, srv.Restart())
+	waitSubscribers(t, srv, 2)
+
+	ids := []*dto.ResolvedCacheId{{CacheId: &dto.CacheId{CacheName: "test", Key: "2"}, StorageKey: "test:2"}}
+	a.DeleteAll(ctx, ids)
+
+	select {
+	case keys := <-bLocal.evicted:
+		assert.Equal(t, []string{"test:2"}, keys)
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalidation was not received after reconnect")
+	}
+}
+
+func TestInvalidationBus_EvictByTag(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, aLocal := newBusController(t, ctx, srv)
+	aLocal.tagKeys = []string{"test:3"}
+	_, bLocal := newBusController(t, ctx, srv)
+	waitSubscribers(t, srv, 2)
+
+	a.EvictByTag(ctx, "tenant:42")
+
+	select {
+	case keys := <-bLocal.evicted:
+		assert.Equal(t, []string{"test:3"}, keys)
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalidation was not received")
+	}
+}
+
+func TestInvalidationBus_FlushPrefix(t *testing.T) {
+	srv := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, aLocal := newBusController(t, ctx, srv)
+	_, bLocal := newBusController(t, ctx, srv)
+	waitSubscribers(t, srv, 2)
+
+	a.FlushPrefix(ctx, "test:", func(int, int64) {})
+	assert.Equal(t, "test:", <-aLocal.flushed)
+
+	select {
+	case prefix := <-bLocal.flushed:
+		assert.Equal(t, "test:", prefix)
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalidation was not received")
+	}
+	assert.Empty(t, bLocal.evicted)
+}
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -11,16 +12,79 @@ type CacheProvider interface {
 	// BatchGet возвращает значения по ключам. Не найденые ключи игнорируются
 	BatchGet(ctx context.Context, keys []string) (map[string]string, error)
 
+	// BatchGetWithTTL как BatchGet, но вместе с оставшимся временем жизни каждого значения.
+	BatchGetWithTTL(ctx context.Context, keys []string) (map[string]TTLValue, error)
+
 	// BatchPut сохраняет ключи со значениями и соответствующими TTL.
 	BatchPut(ctx context.Context, items map[string]string, ttls map[string]time.Duration) error
 
+	// BatchPutIf для каждого ключа атомарно сравнивает текущее значение с CondValue.Expected
+	// и записывает новое, только если они совпадают. Возвращает ключи, которые были записаны.
+	BatchPutIf(ctx context.Context, items map[string]CondValue) (applied map[string]bool, err error)
+
+	// BatchTouch задаёт новое время жизни существующим ключам, не перезаписывая значения
+	// (0 — без срока жизни). Возвращает ключи, которые были найдены.
+	BatchTouch(ctx context.Context, ttls map[string]time.Duration) (touched map[string]bool, err error)
+
 	// BatchDelete удаляет указанные ключи.
 	BatchDelete(ctx context.Context, keys []string) error
 
+	// BatchTag добавляет ключи в индекс тег → ключи (tags: ключ → его теги). ttls — время жизни
+	// ключей: запись индекса живёт не меньше самого долгоживущего ключа тега (0 — без срока жизни).
+	BatchTag(ctx context.Context, tags map[string][]string, ttls map[string]time.Duration) error
+
+	// EvictTag удаляет все ключи с тегом вместе с записью индекса и возвращает ключи из индекса.
+	// Индекс может содержать ключи, которых уже нет в хранилище.
+	EvictTag(ctx context.Context, tag string) (keys []string, err error)
+
+	// DeletePrefix удаляет все ключи, начинающиеся с prefix, и возвращает их число (0, если провайдер
+	// не может их посчитать). progress вызывается по ходу удаления с числом уже удалённых ключей.
+	// При отмене ctx удаление прерывается: удалённые к этому моменту ключи не восстанавливаются.
+	DeletePrefix(ctx context.Context, prefix string, progress func(deleted int64)) (deleted int64, err error)
+
 	// Close освобождает ресурсы.
 	Close() error
 }
 
+// Scanner — необязательная возможность CacheProvider: постраничный перебор ключей хранилища.
+// Провайдеры, которые не могут перечислить ключи (Ristretto), его не реализуют.
+type Scanner interface {
+
+	// Scan возвращает около count ключей, начинающихся с prefix, у которых остаток после prefix
+	// подходит под glob-шаблон match ("" — любой), и курсор следующей страницы ("" — ключи закончились).
+	// cursor — курсор из предыдущего вызова ("" — с начала). Страница может быть пустой при непустом
+	// курсоре; ключи, изменённые во время перебора, могут быть пропущены или повторены.
+	Scan(ctx context.Context, prefix, match, cursor string, count int) (keys []string, next string, err error)
+}
+
+// ErrInvalidCursor — курсор Scan не выдавался этим провайдером для этого префикса.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TTLValue — значение и оставшееся время жизни в хранилище (0 — срок жизни не задан).
+type TTLValue struct {
+	Value string
+	TTL   time.Duration
+}
+
+// CondValue — условная запись: значение с TTL (0 — без срока жизни) и ожидаемое текущее
+// значение ключа (nil — ключа не должно быть).
+type CondValue struct {
+	Value    string
+	TTL      time.Duration
+	Expected *string
+}
+
+// invertTags переводит ключ → теги в тег → ключи.
+func invertTags(tags map[string][]string) map[string][]string {
+	byTag := make(map[string][]string)
+	for key, keyTags := range tags {
+		for _, tag := range keyTags {
+			byTag[tag] = append(byTag[tag], key)
+		}
+	}
+	return byTag
+}
+
 // calcChunkSize вычисляет оптимальный размер chunk'а для равномерного распределения элементов.
 //
 // Параметры:
@@ -124,3 +188,82 @@ func splitKeysToChunks(keys []string, minSize int, maxSize int) [][]string {
 
 	return chunks
 }
+
+// matchGlob сообщает, подходит ли s под glob-шаблон в синтаксисе MATCH Redis:
+// * — любая последовательность, ? — один символ, [abc], [^a], [a-z] — класс, \ — экранирование.
+func matchGlob(pattern, s string) bool {
+	p, str := []rune(pattern), []rune(s)
+	// позиция последней * и символ строки, с которого она сопоставляется, — для отката
+	star, mark := -1, 0
+	pi, si := 0, 0
+	for si < len(str) {
+		if pi < len(p) {
+			switch p[pi] {
+			case '*':
+				star, mark = pi, si
+				pi++
+				continue
+			case '?':
+				pi++
+				si++
+				continue
+			case '[':
+				if next, ok := matchClass(p, pi, str[si]); ok {
+					pi, si = next, si+1
+					continue
+				}
+			case '\\':
+				if pi+1 < len(p) && p[pi+1] == str[si] {
+					pi, si = pi+2, si+1
+					continue
+				}
+			default:
+				if p[pi] == str[si] {
+					pi++
+					si++
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		mark++
+		pi, si = star+1, mark
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// matchClass сопоставляет символ r с классом [...], начинающимся в p[start], и возвращает
+// позицию после класса. Незакрытый класс сопоставляется до конца шаблона, как в Redis.
+func matchClass(p []rune, start int, r rune) (next int, ok bool) {
+	i := start + 1
+	negate := i < len(p) && p[i] == '^'
+	if negate {
+		i++
+	}
+	matched := false
+	for ; i < len(p) && p[i] != ']'; i++ {
+		switch {
+		case p[i] == '\\' && i+1 < len(p):
+			i++
+			matched = matched || p[i] == r
+		case i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']':
+			lo, hi := p[i], p[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (r >= lo && r <= hi)
+			i += 2
+		default:
+			matched = matched || p[i] == r
+		}
+	}
+	if i < len(p) {
+		i++ // ]
+	}
+	return i, matched != negate
+}
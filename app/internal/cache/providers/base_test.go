@@ -1,7 +1,10 @@
 package providers
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -67,3 +70,180 @@ func TestEmptyInputs(t *testing.T) {
 	assert.Empty(t, splitKeyValueToChunks(nil, 1, 5))
 	assert.Empty(t, splitKeyValueToChunks(map[string]string{}, 1, 5))
 }
+
+// assertBatchPutIf проверяет условную запись провайдера: запись при отсутствии ключа
+// и сравнение с текущим значением.
+func assertBatchPutIf(t *testing.T, p CacheProvider) {
+	ctx := context.Background()
+
+	applied, err := p.BatchPutIf(ctx, map[string]CondValue{"lock": {Value: "a", TTL: time.Minute}})
+	assert.NoError(t, err)
+	assert.True(t, applied["lock"])
+
+	wrong, right := "b", "a"
+	applied, err = p.BatchPutIf(ctx, map[string]CondValue{
+		"lock":  {Value: "c"},                   // ключ уже есть
+		"other": {Value: "x", Expected: &wrong}, // ключа нет
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	applied, err = p.BatchPutIf(ctx, map[string]CondValue{"lock": {Value: "b", Expected: &wrong}})
+	assert.NoError(t, err)
+	assert.False(t, applied["lock"])
+
+	applied, err = p.BatchPutIf(ctx, map[string]CondValue{"lock": {Value: "b", Expected: &right}})
+	assert.NoError(t, err)
+	assert.True(t, applied["lock"])
+
+	result, err := p.BatchGet(ctx, []string{"lock", "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lock": "b"}, result)
+}
+
+// assertBatchTouch проверяет продление TTL провайдера: значение не меняется,
+// отсутствующие ключи не создаются.
+func assertBatchTouch(t *testing.T, p CacheProvider) {
+	ctx := context.Background()
+
+	assert.NoError(t, p.BatchPut(ctx, map[string]string{"session": "s"}, map[string]time.Duration{"session": time.Second}))
+
+	touched, err := p.BatchTouch(ctx, map[string]time.Duration{"session": time.Hour, "absent": time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"session": true}, filterTrue(touched))
+
+	result, err := p.BatchGetWithTTL(ctx, []string{"session", "absent"})
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "s", result["session"].Value)
+	assert.Greater(t, result["session"].TTL, time.Minute)
+}
+
+// assertTags проверяет индекс тегов провайдера: EvictTag удаляет ключи тега,
+// повторный EvictTag ничего не находит.
+func assertTags(t *testing.T, p CacheProvider) {
+	ctx := context.Background()
+
+	items := map[string]string{"a": "1", "b": "2", "c": "3"}
+	ttls := map[string]time.Duration{"a": time.Minute, "b": time.Minute, "c": time.Minute}
+	assert.NoError(t, p.BatchPut(ctx, items, ttls))
+	assert.NoError(t, p.BatchTag(ctx, map[string][]string{"a": {"t1"}, "b": {"t1", "t2"}, "c": {"t2"}}, ttls))
+
+	keys, err := p.EvictTag(ctx, "t1")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, keys)
+
+	result, err := p.BatchGet(ctx, []string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+
+	keys, err = p.EvictTag(ctx, "t1")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// ключ с двумя тегами остаётся в индексе второго тега
+	keys, err = p.EvictTag(ctx, "t2")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"b", "c"}, keys)
+}
+
+// assertDeletePrefix проверяет удаление по префиксу: ключи с префиксом удалены,
+// остальные на месте, значения, записанные после удаления, видны.
+func assertDeletePrefix(t *testing.T, p CacheProvider) {
+	ctx := context.Background()
+
+	items := map[string]string{"u:1": "1", "u:2": "2", "u2:1": "3", "v:1": "4"}
+	assert.NoError(t, p.BatchPut(ctx, items, nil))
+
+	var progress []int64
+	_, err := p.DeletePrefix(ctx, "u:", func(deleted int64) { progress = append(progress, deleted) })
+	assert.NoError(t, err)
+	assert.NotEmpty(t, progress)
+
+	result, err := p.BatchGet(ctx, []string{"u:1", "u:2", "u2:1", "v:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"u2:1": "3", "v:1": "4"}, result)
+
+	assert.NoError(t, p.BatchPut(ctx, map[string]string{"u:1": "5"}, nil))
+	result, err = p.BatchGet(ctx, []string{"u:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"u:1": "5"}, result)
+}
+
+// assertScan проверяет постраничный перебор: все ключи с префиксом перечислены ровно по разу,
+// match применяется к остатку ключа после префикса, чужой курсор — ошибка.
+func assertScan(t *testing.T, p interface {
+	CacheProvider
+	Scanner
+}) {
+	ctx := context.Background()
+
+	items := map[string]string{"v:1": "x"}
+	var want []string
+	for i := 0; i < 25; i++ {
+		key := fmt.Sprintf("u:%02d", i)
+		items[key] = "x"
+		want = append(want, key)
+	}
+	items["u:a1"] = "x"
+	want = append(want, "u:a1")
+	assert.NoError(t, p.BatchPut(ctx, items, nil))
+
+	scanAll := func(match string) []string {
+		var keys []string
+		cursor := ""
+		for i := 0; i < 100; i++ {
+			page, next, err := p.Scan(ctx, "u:", match, cursor, 10)
+			assert.NoError(t, err)
+			keys = append(keys, page...)
+			if next == "" {
+				return keys
+			}
+			cursor = next
+		}
+		t.Fatal("scan did not finish")
+		return nil
+	}
+	assert.ElementsMatch(t, want, scanAll(""))
+	assert.ElementsMatch(t, []string{"u:a1"}, scanAll("a*"))
+	assert.ElementsMatch(t, []string{"u:01", "u:11", "u:21", "u:a1"}, scanAll("?1"))
+
+	_, _, err := p.Scan(ctx, "u:", "", "not a cursor", 10)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"*", "abc", true},
+		{"a*c", "abbbc", true},
+		{"a*c", "abcd", false},
+		{"?b", "ab", true},
+		{"?b", "b", false},
+		{"[ab]1", "b1", true},
+		{"[^ab]1", "b1", false},
+		{"[a-c]", "b", true},
+		{"[a-c]", "d", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{"*:*:x", "u:1:x", true},
+		{"user-*", "user-42", true},
+		{"user-*", "admin-42", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchGlob(tt.pattern, tt.s), "%q ~ %q", tt.pattern, tt.s)
+	}
+}
+
+func filterTrue(m map[string]bool) map[string]bool {
+	res := make(map[string]bool, len(m))
+	for k, v := range m {
+		if v {
+			res[k] = true
+		}
+	}
+	return res
+}
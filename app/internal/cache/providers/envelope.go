@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Формат хранения значения в слое.
+//
+// Обычно значение хранится «как есть» — компактный JSON. Если вместе со значением
+// нужно сохранить служебные данные (например, момент мягкого истечения для
+// stale-while-revalidate), оно упаковывается в конверт:
+//
+//	0x01 | JSON(valueMeta) | 0x00 | значение
+//
+// Валидный JSON не может начинаться с 0x01, а сериализованный JSON(valueMeta)
+// не содержит 0x00, поэтому конверт однозначно отличается от «голого» значения.
+// Записи, сохранённые без конверта, читаются без изменений.
+const (
+	envelopeMarker    = "\x01"
+	envelopeSeparator = "\x00"
+)
+
+// valueMeta — служебные данные, хранящиеся рядом со значением.
+type valueMeta struct {
+	// SoftExpiry — момент (UnixNano), после которого значение считается устаревшим,
+	// но ещё может быть отдано клиенту (stale-while-revalidate). 0 = не задан.
+	SoftExpiry int64 `json:"se,omitempty"`
+
+	// Expiry — момент (UnixNano) истечения TTL слоя. Сохраняется для кэшей с refreshAhead,
+	// чтобы при чтении понять, сколько значению осталось жить. 0 = не задан.
+	Expiry int64 `json:"e,omitempty"`
+
+	// Tombstone — вместо значения хранится маркер отсутствия ключа во внешнем API.
+	Tombstone bool `json:"t,omitempty"`
+
+	// TTL — время жизни (наносекунды), заданное клиентом в put_all вместо TTL слоя.
+	// 0 = использовался TTL слоя из конфигурации.
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+func (m valueMeta) isEmpty() bool {
+	return m == valueMeta{}
+}
+
+// entryTtl возвращает заданный клиентом TTL в секундах (с округлением вверх),
+// чтобы при копировании значения в другие слои он сохранился; nil — TTL не задавался.
+func (m valueMeta) entryTtl() *int64 {
+	if m.TTL <= 0 {
+		return nil
+	}
+	seconds := (m.TTL + int64(time.Second) - 1) / int64(time.Second)
+	return &seconds
+}
+
+// encodeValue упаковывает значение в конверт, если есть служебные данные.
+func encodeValue(payload string, meta valueMeta) string {
+	if meta.isEmpty() {
+		return payload
+	}
+	header, err := json.Marshal(meta)
+	if err != nil {
+		return payload
+	}
+	return envelopeMarker + string(header) + envelopeSeparator + payload
+}
+
+// decodeValue распаковывает конверт. Значения без конверта (или с повреждённым
+// заголовком) возвращаются как есть с пустыми метаданными.
+func decodeValue(stored string) (payload string, meta valueMeta) {
+	if !strings.HasPrefix(stored, envelopeMarker) {
+		return stored, valueMeta{}
+	}
+	header, payload, found := strings.Cut(stored[len(envelopeMarker):], envelopeSeparator)
+	if !found {
+		return stored, valueMeta{}
+	}
+	if err := json.Unmarshal([]byte(header), &meta); err != nil {
+		return stored, valueMeta{}
+	}
+	return payload, meta
+}
@@ -6,6 +6,8 @@ import (
 	"context"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -23,14 +25,7 @@ const (
 
 func NewRedis(ctx context.Context, cfg config.Redis) (*Redis, error) {
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		PoolSize:     cfg.PoolSize,
-		ReadTimeout:  cfg.Timeout,
-		WriteTimeout: cfg.Timeout,
-	})
+	rdb := NewRedisClient(cfg)
 
 	// Connection check
 	if err := rdb.Ping(ctx).Err(); err != nil {
@@ -44,6 +39,19 @@ func NewRedis(ctx context.Context, cfg config.Redis) (*Redis, error) {
 	}, nil
 }
 
+// NewRedisClient создаёт клиента Redis по конфигурации провайдера без проверки соединения.
+// Используется и провайдером, и шиной инвалидаций (cache.InvalidationBus).
+func NewRedisClient(cfg config.Redis) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+	})
+}
+
 // BatchGet получает несколько значений за один запрос, разбивая их на chunk'и
 func (c *Redis) BatchGet(ctx context.Context, keys []string) (result map[string]string, err error) {
 	start := time.Now()
@@ -77,6 +85,42 @@ func (c *Redis) BatchGet(ctx context.Context, keys []string) (result map[string]
 	return result, nil
 }
 
+// BatchGetWithTTL получает значения и их PTTL одним pipeline на chunk
+func (c *Redis) BatchGetWithTTL(ctx context.Context, keys []string) (result map[string]TTLValue, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("redis", "get_ttl", time.Since(start).Seconds())
+		metrics.RecordProviderOp("redis", "get_ttl", err)
+	}()
+
+	result = make(map[string]TTLValue, len(keys))
+	for _, chunk := range splitKeysToChunks(keys, minChunk, maxChunk) {
+		pipe := c.rdb.Pipeline()
+		vals := pipe.MGet(ctx, chunk...)
+		ttls := make([]*redis.DurationCmd, len(chunk))
+		for i, key := range chunk {
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("ошибка пакетного получения TTL из Redis: %w", err)
+		}
+
+		for i, key := range chunk {
+			str, ok := vals.Val()[i].(string)
+			if !ok {
+				continue
+			}
+			// PTTL < 0: срок жизни не задан (-1) или ключ истёк между MGET и PTTL (-2)
+			ttl := ttls[i].Val()
+			if ttl < 0 {
+				ttl = 0
+			}
+			result[key] = TTLValue{Value: str, TTL: ttl}
+		}
+	}
+	return result, nil
+}
+
 // BatchPut сохраняет несколько значений за один запрос, разбивая их на chunk'и
 func (c *Redis) BatchPut(ctx context.Context, items map[string]string, ttls map[string]time.Duration) (err error) {
 	start := time.Now()
@@ -111,6 +155,106 @@ func (c *Redis) BatchPut(ctx context.Context, items map[string]string, ttls map[
 	return nil
 }
 
+// casScript записывает значение, если текущее совпадает с ожидаемым.
+// ARGV: 1 — ожидается значение ("1") или отсутствие ключа ("0"), 2 — ожидаемое значение,
+// 3 — новое значение, 4 — TTL в мс (0 — без срока жизни). Возвращает 1, если значение записано.
+var casScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if ARGV[1] == '1' then
+  if cur ~= ARGV[2] then return 0 end
+elseif cur then
+  return 0
+end
+local ttl = tonumber(ARGV[4])
+if ttl > 0 then
+  redis.call('SET', KEYS[1], ARGV[3], 'PX', ttl)
+else
+  redis.call('SET', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// BatchPutIf выполняет условные записи Lua-скриптом (атомарно по ключу), pipeline на chunk
+func (c *Redis) BatchPutIf(ctx context.Context, items map[string]CondValue) (applied map[string]bool, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("redis", "put_if", time.Since(start).Seconds())
+		metrics.RecordProviderOp("redis", "put_if", err)
+	}()
+
+	applied = make(map[string]bool, len(items))
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	for _, chunk := range splitKeysToChunks(keys, minChunk, maxChunk) {
+		pipe := c.rdb.Pipeline()
+		cmds := make([]*redis.Cmd, len(chunk))
+		for i, key := range chunk {
+			item := items[key]
+			hasExpected, expected := "0", ""
+			if item.Expected != nil {
+				hasExpected, expected = "1", *item.Expected
+			}
+			ttl := item.TTL.Milliseconds()
+			if item.TTL > 0 && ttl == 0 {
+				ttl = 1
+			}
+			cmds[i] = casScript.Eval(ctx, pipe, []string{key}, hasExpected, expected, item.Value, ttl)
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("ошибка условной записи в Redis: %w", err)
+		}
+		for i, key := range chunk {
+			if n, _ := cmds[i].Int(); n == 1 {
+				applied[key] = true
+			}
+		}
+	}
+	return applied, nil
+}
+
+// BatchTouch продлевает TTL командами PEXPIRE (PERSIST для TTL 0), pipeline на chunk
+func (c *Redis) BatchTouch(ctx context.Context, ttls map[string]time.Duration) (touched map[string]bool, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("redis", "touch", time.Since(start).Seconds())
+		metrics.RecordProviderOp("redis", "touch", err)
+	}()
+
+	touched = make(map[string]bool, len(ttls))
+	keys := make([]string, 0, len(ttls))
+	for key := range ttls {
+		keys = append(keys, key)
+	}
+	for _, chunk := range splitKeysToChunks(keys, minChunk, maxChunk) {
+		pipe := c.rdb.Pipeline()
+		cmds := make([]redis.Cmder, len(chunk))
+		for i, key := range chunk {
+			ttl := ttls[key]
+			if ttl <= 0 {
+				// PERSIST возвращает 0 и для ключа без TTL, поэтому наличие проверяется отдельно
+				cmds[i] = pipe.Exists(ctx, key)
+				pipe.Persist(ctx, key)
+				continue
+			}
+			cmds[i] = pipe.PExpire(ctx, key, max(ttl, time.Millisecond))
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("ошибка продления TTL в Redis: %w", err)
+		}
+		for i, key := range chunk {
+			switch cmd := cmds[i].(type) {
+			case *redis.IntCmd:
+				touched[key] = cmd.Val() == 1
+			case *redis.BoolCmd:
+				touched[key] = cmd.Val()
+			}
+		}
+	}
+	return touched, nil
+}
+
 // BatchDelete удаляет несколько значений за один запрос, разбивая их на chunk'и
 func (c *Redis) BatchDelete(ctx context.Context, keys []string) (err error) {
 	start := time.Now()
@@ -137,6 +281,169 @@ func (c *Redis) BatchDelete(ctx context.Context, keys []string) (err error) {
 	return nil
 }
 
+// tagKeyPrefix — префикс множеств индекса тегов: tagKeyPrefix + тег → SET ключей.
+const tagKeyPrefix = "__tag:"
+
+// tagScript добавляет ключи в множество тега и продлевает его срок жизни до самого долгого
+// среди ключей. ARGV: 1 — TTL в мс (0 — без срока жизни), далее ключи.
+var tagScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('SADD', KEYS[1], unpack(ARGV, 2))
+local ttl = tonumber(ARGV[1])
+if ttl <= 0 then
+  redis.call('PERSIST', KEYS[1])
+  return 1
+end
+local cur = redis.call('PTTL', KEYS[1])
+if existed == 0 or (cur >= 0 and cur < ttl) then
+  redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// BatchTag добавляет ключи в множества тегов Lua-скриптом, pipeline на все теги
+func (c *Redis) BatchTag(ctx context.Context, tags map[string][]string, ttls map[string]time.Duration) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("redis", "tag", time.Since(start).Seconds())
+		metrics.RecordProviderOp("redis", "tag", err)
+	}()
+
+	byTag := invertTags(tags)
+	if len(byTag) == 0 {
+		return nil
+	}
+	pipe := c.rdb.Pipeline()
+	for tag, keys := range byTag {
+		// TTL множества — самый долгий TTL среди ключей; ключ без срока жизни делает бессрочным и тег
+		var ttl int64
+		for _, key := range keys {
+			keyTtl := ttls[key].Milliseconds()
+			if ttls[key] > 0 && keyTtl == 0 {
+				keyTtl = 1
+			}
+			if keyTtl <= 0 {
+				ttl = 0
+				break
+			}
+			ttl = max(ttl, keyTtl)
+		}
+		args := make([]interface{}, 0, len(keys)+1)
+		args = append(args, ttl)
+		for _, key := range keys {
+			args = append(args, key)
+		}
+		tagScript.Eval(ctx, pipe, []string{tagKeyPrefix + tag}, args...)
+	}
+	if _, err = pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("ошибка записи индекса тегов в Redis: %w", err)
+	}
+	return nil
+}
+
+// EvictTag атомарно забирает множество тега (SMEMBERS + DEL в транзакции) и удаляет его ключи
+func (c *Redis) EvictTag(ctx context.Context, tag string) (keys []string, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("redis", "evict_tag", time.Since(start).Seconds())
+		metrics.RecordProviderOp("redis", "evict_tag", err)
+	}()
+
+	pipe := c.rdb.TxPipeline()
+	members := pipe.SMembers(ctx, tagKeyPrefix+tag)
+	pipe.Del(ctx, tagKeyPrefix+tag)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка чтения индекса тегов из Redis: %w", err)
+	}
+	keys = members.Val()
+	if err = c.BatchDelete(ctx, keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// scanCount — подсказка COUNT для SCAN при удалении по префиксу
+const scanCount = 500
+
+// globEscaper экранирует спецсимволы шаблона MATCH
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// DeletePrefix проходит ключи командой SCAN MATCH prefix* и удаляет каждую найденную пачку UNLINK.
+// SCAN не блокирует Redis, но ключи, записанные во время прохода, могут быть не удалены.
+func (c *Redis) DeletePrefix(ctx context.Context, prefix string, progress func(deleted int64)) (deleted int64, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("redis", "delete_prefix", time.Since(start).Seconds())
+		metrics.RecordProviderOp("redis", "delete_prefix", err)
+	}()
+
+	match := globEscaper.Replace(prefix) + "*"
+	var cursor uint64
+	for {
+		if err = ctx.Err(); err != nil {
+			return deleted, err
+		}
+		var keys []string
+		keys, cursor, err = c.rdb.Scan(ctx, cursor, match, scanCount).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("ошибка SCAN в Redis: %w", err)
+		}
+		if len(keys) > 0 {
+			var n int64
+			if n, err = c.rdb.Unlink(ctx, keys...).Result(); err != nil {
+				return deleted, fmt.Errorf("ошибка удаления по префиксу из Redis: %w", err)
+			}
+			deleted += n
+			progress(deleted)
+		}
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// Scan перебирает ключи командой SCAN MATCH prefix+match. Курсор — курсор SCAN; за один вызов
+// выполняется столько итераций SCAN, сколько нужно, чтобы набрать count ключей или дойти до конца.
+func (c *Redis) Scan(ctx context.Context, prefix, match, cursor string, count int) (keys []string, next string, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("redis", "scan", time.Since(start).Seconds())
+		metrics.RecordProviderOp("redis", "scan", err)
+	}()
+
+	var cur uint64
+	if cursor != "" {
+		if cur, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+	}
+	if match == "" {
+		match = "*"
+	}
+	pattern := globEscaper.Replace(prefix) + match
+
+	keys = make([]string, 0, count)
+	for {
+		var batch []string
+		batch, cur, err = c.rdb.Scan(ctx, cur, pattern, int64(count)).Result()
+		if err != nil {
+			return nil, "", fmt.Errorf("ошибка SCAN в Redis: %w", err)
+		}
+		keys = append(keys, batch...)
+		if cur == 0 {
+			return keys, "", nil
+		}
+		if len(keys) >= count {
+			return keys, strconv.FormatUint(cur, 10), nil
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, "", err
+		}
+	}
+}
+
 func (c *Redis) Close() error {
 	return c.rdb.Close()
 }
+
+var _ Scanner = (*Redis)(nil)
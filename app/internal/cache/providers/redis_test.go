@@ -62,6 +62,83 @@ func TestRedis_BatchPut_And_BatchGet(t *testing.T) {
 	assert.Equal(t, "Bob", result["user:2"])
 	_, exists := result["user:404"]
 	assert.False(t, exists)
+
+	withTTL, err := r.BatchGetWithTTL(ctx, []string{"user:1", "user:2", "user:404"})
+	assert.NoError(t, err)
+	assert.Len(t, withTTL, 2)
+	assert.Equal(t, TTLValue{Value: "Alice", TTL: 10 * time.Second}, withTTL["user:1"])
+	assert.Equal(t, TTLValue{Value: "Bob"}, withTTL["user:2"])
+}
+
+func TestRedis_BatchPutIf(t *testing.T) {
+	r, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	assertBatchPutIf(t, r)
+
+	withTTL, err := r.BatchGetWithTTL(context.Background(), []string{"lock"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), withTTL["lock"].TTL)
+}
+
+func TestRedis_BatchTouch(t *testing.T) {
+	r, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	assertBatchTouch(t, r)
+
+	// TTL 0 снимает срок жизни
+	touched, err := r.BatchTouch(context.Background(), map[string]time.Duration{"session": 0})
+	assert.NoError(t, err)
+	assert.True(t, touched["session"])
+	withTTL, err := r.BatchGetWithTTL(context.Background(), []string{"session"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), withTTL["session"].TTL)
+}
+
+func TestRedis_Tags(t *testing.T) {
+	r, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	assertTags(t, r)
+
+	// индекс живёт не меньше самого долгоживущего ключа тега
+	ctx := context.Background()
+	assert.NoError(t, r.BatchTag(ctx, map[string][]string{"x": {"t3"}}, map[string]time.Duration{"x": time.Hour}))
+	assert.NoError(t, r.BatchTag(ctx, map[string][]string{"y": {"t3"}}, map[string]time.Duration{"y": time.Minute}))
+	assert.Equal(t, time.Hour, r.rdb.TTL(ctx, tagKeyPrefix+"t3").Val())
+}
+
+func TestRedis_DeletePrefix(t *testing.T) {
+	r, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	assertDeletePrefix(t, r)
+
+	// спецсимволы шаблона MATCH в префиксе экранируются
+	ctx := context.Background()
+	assert.NoError(t, r.BatchPut(ctx, map[string]string{"a*:1": "1", "ab:1": "2"}, nil))
+	deleted, err := r.DeletePrefix(ctx, "a*:", func(int64) {})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	result, err := r.BatchGet(ctx, []string{"a*:1", "ab:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"ab:1": "2"}, result)
+}
+
+func TestRedis_Scan(t *testing.T) {
+	r, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	assertScan(t, r)
+
+	// спецсимволы шаблона MATCH в префиксе экранируются
+	ctx := context.Background()
+	assert.NoError(t, r.BatchPut(ctx, map[string]string{"a*:1": "1", "ab:1": "2"}, nil))
+	keys, next, err := r.Scan(ctx, "a*:", "", "", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a*:1"}, keys)
+	assert.Empty(t, next)
 }
 
 func TestRedis_BatchDelete(t *testing.T) {
@@ -6,29 +6,115 @@ import (
 	"context"
 	"fmt"
 	"github.com/dgraph-io/ristretto"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Client struct {
 	cache *ristretto.Cache
+	casMu sync.Mutex // сериализует условные записи (BatchPutIf)
+
+	// индекс тег → ключи и обратный ключ → теги. Ключ удаляется из индекса при вытеснении,
+	// истечении TTL, отказе политики в приёме значения (OnEvict, OnReject) и при BatchDelete.
+	// Ключи, удалённые вместе с тегом (EvictTag), остаются в индексах своих других тегов
+	// до удаления этих тегов.
+	tagMu   sync.Mutex
+	tags    map[string]map[string]struct{}
+	keyTags map[string]map[string]struct{}
+
+	// Ristretto не умеет перечислять ключи, поэтому DeletePrefix ничего не удаляет, а запоминает
+	// номер последней записи (seq) для префикса: записанные не позже значения с этим префиксом
+	// считаются удалёнными и вытесняются из кэша обычным порядком.
+	seq     atomic.Uint64
+	flushes atomic.Pointer[map[string]uint64] // префикс → seq на момент очистки; заменяется целиком
+	flushMu sync.Mutex                        // сериализует замену flushes
+}
+
+// ristrettoItem — значение в кэше вместе с порядковым номером записи и ключом: в OnEvict
+// Ristretto передаёт только хеш ключа.
+type ristrettoItem struct {
+	key   string
+	value string
+	seq   uint64
 }
 
 const contextCheckInterval = 100
 
 func NewRistretto(cfg config.Ristretto) (*Client, error) {
+	client := &Client{
+		tags:    make(map[string]map[string]struct{}),
+		keyTags: make(map[string]map[string]struct{}),
+	}
 	maxCostBytes, _ := cfg.MaxCostBytes()
 	cache, err := ristretto.NewCache(&ristretto.Config{
 		NumCounters: cfg.NumCounters,
 		MaxCost:     int64(maxCostBytes),
 		BufferItems: cfg.BufferItems,
+		OnEvict:     client.onEvict,
+		OnReject:    client.onEvict,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("не удалось создать Ristretto кэш: %w", err)
 	}
+	client.cache = cache
+	return client, nil
+}
 
-	return &Client{
-		cache: cache,
-	}, nil
+// onEvict удаляет из индекса тегов ключ значения, которое вытеснено, истекло или не принято.
+func (c *Client) onEvict(item *ristretto.Item) {
+	if val, ok := item.Value.(ristrettoItem); ok {
+		c.untag(val.key)
+	}
+}
+
+// untag удаляет ключи из индекса тегов.
+func (c *Client) untag(keys ...string) {
+	c.tagMu.Lock()
+	defer c.tagMu.Unlock()
+	for _, key := range keys {
+		for tag := range c.keyTags[key] {
+			set := c.tags[tag]
+			delete(set, key)
+			if len(set) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+		delete(c.keyTags, key)
+	}
+}
+
+// get возвращает значение ключа, если оно не удалено очисткой по префиксу (DeletePrefix).
+func (c *Client) get(key string) (string, bool) {
+	val, ok := c.cache.Get(key)
+	if !ok {
+		return "", false
+	}
+	item, ok := val.(ristrettoItem)
+	if !ok || c.flushed(key, item.seq) {
+		return "", false
+	}
+	return item.value, true
+}
+
+// set сохраняет значение со следующим порядковым номером записи.
+func (c *Client) set(key, value string, ttl time.Duration) bool {
+	return c.cache.SetWithTTL(key, ristrettoItem{key: key, value: value, seq: c.seq.Add(1)}, int64(len(value)), ttl)
+}
+
+// flushed сообщает, очищен ли префикс ключа после записи с номером seq.
+func (c *Client) flushed(key string, seq uint64) bool {
+	flushes := c.flushes.Load()
+	if flushes == nil {
+		return false
+	}
+	for prefix, flushSeq := range *flushes {
+		if seq <= flushSeq && strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Client) BatchGet(ctx context.Context, keys []string) (result map[string]string, err error) {
@@ -54,12 +140,38 @@ func (c *Client) BatchGet(ctx context.Context, keys []string) (result map[string
 			}
 		}
 
-		val, ok := c.cache.Get(key)
-		if ok {
-			if strVal, castOk := val.(string); castOk {
-				result[key] = strVal
+		if val, ok := c.get(key); ok {
+			result[key] = val
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) BatchGetWithTTL(ctx context.Context, keys []string) (result map[string]TTLValue, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("ristretto", "get_ttl", time.Since(start).Seconds())
+		metrics.RecordProviderOp("ristretto", "get_ttl", err)
+	}()
+
+	result = make(map[string]TTLValue, len(keys))
+	for i, key := range keys {
+
+		// Проверяем контекст каждые 100 итераций
+		if i%contextCheckInterval == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
 			}
 		}
+
+		strVal, ok := c.get(key)
+		if !ok {
+			continue
+		}
+		ttl, _ := c.cache.GetTTL(key)
+		result[key] = TTLValue{Value: strVal, TTL: ttl}
 	}
 	return result, nil
 }
@@ -93,11 +205,69 @@ func (c *Client) BatchPut(ctx context.Context, items map[string]string, ttls map
 		if ttl, ok := ttls[key]; ok && ttl > 0 {
 			expiration = ttl
 		}
-		c.cache.SetWithTTL(key, val, int64(len(val)), expiration)
+		c.set(key, val, expiration)
 	}
 	return nil
 }
 
+// BatchPutIf выполняет условные записи под мьютексом: атомарность гарантируется относительно
+// других условных записей, но не обычных BatchPut. Значение, которое Ristretto не принял
+// (политика вытеснения), считается не записанным.
+func (c *Client) BatchPutIf(ctx context.Context, items map[string]CondValue) (applied map[string]bool, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("ristretto", "put_if", time.Since(start).Seconds())
+		metrics.RecordProviderOp("ristretto", "put_if", err)
+	}()
+
+	c.casMu.Lock()
+	defer c.casMu.Unlock()
+
+	applied = make(map[string]bool, len(items))
+	for key, item := range items {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		cur, ok := c.get(key)
+		if item.Expected == nil && ok || item.Expected != nil && (!ok || cur != *item.Expected) {
+			continue
+		}
+		applied[key] = c.set(key, item.Value, item.TTL)
+	}
+	// запись в Ristretto асинхронна: следующая условная запись должна увидеть результат
+	c.cache.Wait()
+	return applied, nil
+}
+
+// BatchTouch записывает текущее значение заново с новым TTL: Ristretto не умеет менять TTL
+// отдельно от значения. Выполняется под тем же мьютексом, что и BatchPutIf.
+func (c *Client) BatchTouch(ctx context.Context, ttls map[string]time.Duration) (touched map[string]bool, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("ristretto", "touch", time.Since(start).Seconds())
+		metrics.RecordProviderOp("ristretto", "touch", err)
+	}()
+
+	c.casMu.Lock()
+	defer c.casMu.Unlock()
+
+	// запись в Ristretto асинхронна: продлеваются и значения, записанные только что
+	c.cache.Wait()
+	touched = make(map[string]bool, len(ttls))
+	for key, ttl := range ttls {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		cur, ok := c.get(key)
+		if !ok {
+			continue
+		}
+		touched[key] = c.set(key, cur, max(ttl, 0))
+	}
+	c.cache.Wait()
+	return touched, nil
+}
+
 func (c *Client) BatchDelete(ctx context.Context, keys []string) (err error) {
 	start := time.Now()
 	defer func() {
@@ -105,10 +275,15 @@ func (c *Client) BatchDelete(ctx context.Context, keys []string) (err error) {
 		metrics.RecordProviderOp("ristretto", "delete", err)
 	}()
 
-	if len(keys) == 0 {
-		return nil
+	if err = c.del(ctx, keys); err != nil {
+		return err
 	}
+	c.untag(keys...)
+	return nil
+}
 
+// del удаляет ключи из кэша, не трогая индекс тегов.
+func (c *Client) del(ctx context.Context, keys []string) error {
 	for i, key := range keys {
 
 		// Проверяем контекст каждые 100 итераций
@@ -125,6 +300,104 @@ func (c *Client) BatchDelete(ctx context.Context, keys []string) (err error) {
 	return nil
 }
 
+// BatchTag добавляет ключи в индекс тегов в памяти процесса; TTL ключей не учитывается:
+// ключ пропадает из индекса вместе со значением (см. Client.tags). Ключи, которых нет в кэше,
+// в индекс не попадают.
+func (c *Client) BatchTag(ctx context.Context, tags map[string][]string, _ map[string]time.Duration) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("ristretto", "tag", time.Since(start).Seconds())
+		metrics.RecordProviderOp("ristretto", "tag", err)
+	}()
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	// запись в Ristretto асинхронна: вытеснение прежних значений и отказ в приёме новых
+	// должны случиться до добавления ключей в индекс, иначе onEvict удалит их уже после.
+	// Ждать нужно до захвата tagMu: onEvict вызывается из горутины, которую ждёт Wait.
+	c.cache.Wait()
+	c.tagMu.Lock()
+	defer c.tagMu.Unlock()
+	for tag, keys := range invertTags(tags) {
+		for _, key := range keys {
+			// GetTTL, в отличие от Get, не учитывается политикой вытеснения как обращение
+			if _, ok := c.cache.GetTTL(key); !ok {
+				continue
+			}
+			set, ok := c.tags[tag]
+			if !ok {
+				set = make(map[string]struct{}, len(keys))
+				c.tags[tag] = set
+			}
+			set[key] = struct{}{}
+			keyTags, ok := c.keyTags[key]
+			if !ok {
+				keyTags = make(map[string]struct{}, 1)
+				c.keyTags[key] = keyTags
+			}
+			keyTags[tag] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// EvictTag удаляет тег из индекса и ключи тега из кэша. В индексах других тегов ключи
+// остаются, как и множества тегов в Redis.
+func (c *Client) EvictTag(ctx context.Context, tag string) (keys []string, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("ristretto", "evict_tag", time.Since(start).Seconds())
+		metrics.RecordProviderOp("ristretto", "evict_tag", err)
+	}()
+
+	c.tagMu.Lock()
+	set := c.tags[tag]
+	delete(c.tags, tag)
+	keys = make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+		if keyTags := c.keyTags[key]; keyTags != nil {
+			delete(keyTags, tag)
+			if len(keyTags) == 0 {
+				delete(c.keyTags, key)
+			}
+		}
+	}
+	c.tagMu.Unlock()
+
+	if err = c.del(ctx, keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// DeletePrefix отмечает ключи с префиксом как удалённые (см. Client.flushes) и всегда возвращает 0:
+// сколько таких ключей в кэше, неизвестно. Значения, записанные после вызова, видны как обычно.
+func (c *Client) DeletePrefix(ctx context.Context, prefix string, progress func(deleted int64)) (deleted int64, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("ristretto", "delete_prefix", time.Since(start).Seconds())
+		metrics.RecordProviderOp("ristretto", "delete_prefix", err)
+	}()
+
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+	flushes := make(map[string]uint64)
+	if cur := c.flushes.Load(); cur != nil {
+		for p, s := range *cur {
+			flushes[p] = s
+		}
+	}
+	flushes[prefix] = c.seq.Load()
+	c.flushes.Store(&flushes)
+	progress(0)
+	return 0, nil
+}
+
 func (c *Client) Close() error {
 	if c.cache != nil {
 		c.cache.Close()
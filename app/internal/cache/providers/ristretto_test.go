@@ -4,6 +4,7 @@ import (
 	"aur-cache-service/internal/cache/config"
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,6 +44,12 @@ func TestRistretto_BatchPutGetDelete(t *testing.T) {
 	_, ok := result["missing"]
 	assert.False(t, ok)
 
+	withTTL, err := client.BatchGetWithTTL(ctx, []string{"key1", "missing"})
+	assert.NoError(t, err)
+	assert.Len(t, withTTL, 1)
+	assert.Equal(t, "value1", withTTL["key1"].Value)
+	assert.InDelta(t, time.Minute, withTTL["key1"].TTL, float64(time.Second))
+
 	// Delete
 	err = client.BatchDelete(ctx, []string{"key1"})
 	assert.NoError(t, err)
@@ -55,6 +62,115 @@ func TestRistretto_BatchPutGetDelete(t *testing.T) {
 	assert.Equal(t, "value2", result["key2"])
 }
 
+func TestRistretto_BatchPutIf(t *testing.T) {
+	client, err := NewRistretto(config.Ristretto{
+		NumCounters: 1000,
+		BufferItems: 64,
+		MaxCost:     "1MB",
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertBatchPutIf(t, client)
+}
+
+func TestRistretto_BatchTouch(t *testing.T) {
+	client, err := NewRistretto(config.Ristretto{
+		NumCounters: 1000,
+		BufferItems: 64,
+		MaxCost:     "1MB",
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertBatchTouch(t, client)
+}
+
+func TestRistretto_Tags(t *testing.T) {
+	client, err := NewRistretto(config.Ristretto{
+		NumCounters: 1000,
+		BufferItems: 64,
+		MaxCost:     "1MB",
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertTags(t, client)
+}
+
+func TestRistretto_TagIndexFollowsKeys(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewRistretto(config.Ristretto{
+		NumCounters: 1000,
+		BufferItems: 64,
+		MaxCost:     "1KB",
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	indexed := func(tag string) []string {
+		client.tagMu.Lock()
+		defer client.tagMu.Unlock()
+		keys := make([]string, 0)
+		for key := range client.tags[tag] {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+	value := strings.Repeat("v", 600)
+
+	// значение больше кэша не принято политикой и не попадает в индекс
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"big": strings.Repeat("v", 2048)}, nil))
+	assert.NoError(t, client.BatchTag(ctx, map[string][]string{"big": {"t"}}, nil))
+	assert.Empty(t, indexed("t"))
+
+	// удалённый ключ пропадает из индекса
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"a": value}, nil))
+	assert.NoError(t, client.BatchTag(ctx, map[string][]string{"a": {"t"}}, nil))
+	assert.Equal(t, []string{"a"}, indexed("t"))
+	assert.NoError(t, client.BatchDelete(ctx, []string{"a"}))
+	assert.Empty(t, indexed("t"))
+	assert.Empty(t, client.keyTags)
+
+	// вытесненный ключ пропадает из индекса
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"a": value}, nil))
+	assert.NoError(t, client.BatchTag(ctx, map[string][]string{"a": {"t"}}, nil))
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"b": value}, nil))
+	assert.NoError(t, client.BatchTag(ctx, map[string][]string{"b": {"t"}}, nil))
+	assert.Equal(t, []string{"b"}, indexed("t"))
+
+	// перезапись значения не удаляет ключ из индекса
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"b": "2"}, nil))
+	client.cache.Wait()
+	assert.Equal(t, []string{"b"}, indexed("t"))
+}
+
+// syncClient дожидается применения записи: BatchPut в Ristretto асинхронный.
+type syncClient struct{ *Client }
+
+func (c syncClient) BatchPut(ctx context.Context, items map[string]string, ttls map[string]time.Duration) error {
+	err := c.Client.BatchPut(ctx, items, ttls)
+	c.cache.Wait()
+	return err
+}
+
+func TestRistretto_DeletePrefix(t *testing.T) {
+	client, err := NewRistretto(config.Ristretto{
+		NumCounters: 1000,
+		BufferItems: 64,
+		MaxCost:     "1MB",
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertDeletePrefix(t, syncClient{client})
+
+	// условная запись не видит удалённое значение
+	applied, err := client.BatchPutIf(context.Background(), map[string]CondValue{"u:2": {Value: "6"}})
+	assert.NoError(t, err)
+	assert.True(t, applied["u:2"])
+}
+
 func TestRistretto_ContextCancelDuringPut(t *testing.T) {
 	client, _ := NewRistretto(config.Ristretto{
 		NumCounters: 1000,
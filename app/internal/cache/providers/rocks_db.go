@@ -10,7 +10,8 @@
 // Структура CF:
 //
 //	default  — ключ → значение (полезная нагрузка);
-//	ttl_cf   — ключ → время истечения UnixNano (int64 в []byte).
+//	ttl_cf   — ключ → время истечения UnixNano (int64 в []byte);
+//	tag_cf   — тег + "\x00" + ключ → время истечения ключа на момент записи (пусто — без срока жизни).
 //
 // Ключи считаются «устаревшими» (expired), если текущее время превышает
 // сохранённый таймстемп. Удаление просроченных записей происходит двумя
@@ -24,10 +25,13 @@ package providers
 import (
 	"aur-cache-service/internal/cache/config"
 	"aur-cache-service/internal/metrics"
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/binary"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,8 +44,12 @@ import (
 const (
 	defaultCFName = "default"
 	ttlCFName     = "ttl_cf"
+	tagCFName     = "tag_cf"
 )
 
+// tagSeparator отделяет тег от ключа в tag_cf.
+const tagSeparator = "\x00"
+
 // RocksDbCF — реализация CacheProvider c отдельной CF для TTL.
 //
 // Безопасность:
@@ -54,19 +62,22 @@ type RocksDbCF struct {
 	db        *grocksdb.DB
 	defaultCF *grocksdb.ColumnFamilyHandle
 	ttlCF     *grocksdb.ColumnFamilyHandle
+	tagCF     *grocksdb.ColumnFamilyHandle
 
 	readOpts  *grocksdb.ReadOptions
 	writeOpts *grocksdb.WriteOptions
 
 	ttlMu    sync.RWMutex
 	ttlCache map[string]int64 // key -> UnixNano
+
+	casMu sync.Mutex // сериализует условные записи (BatchPutIf)
 }
 
 // -----------------------------------------------------------------------------
 // Создание / закрытие базы
 // -----------------------------------------------------------------------------
 
-// NewRocksDbCF открывает базу с двумя CF (default, ttl_cf) и возвращает провайдер.
+// NewRocksDbCF открывает базу с CF default, ttl_cf и tag_cf и возвращает провайдер.
 
 func NewRocksDbCF(cfg config.RocksDB) (*RocksDbCF, error) {
 	// Shared options
@@ -90,8 +101,8 @@ func NewRocksDbCF(cfg config.RocksDB) (*RocksDbCF, error) {
 	}
 
 	// Column family list & per‑CF opts (reuse dbOpts)
-	cfNames := []string{defaultCFName, ttlCFName}
-	cfOpts := []*grocksdb.Options{dbOpts, dbOpts}
+	cfNames := []string{defaultCFName, ttlCFName, tagCFName}
+	cfOpts := []*grocksdb.Options{dbOpts, dbOpts, dbOpts}
 
 	db, cfHandles, err := grocksdb.OpenDbColumnFamilies(dbOpts, cfg.Path, cfNames, cfOpts)
 	if err != nil {
@@ -102,6 +113,7 @@ func NewRocksDbCF(cfg config.RocksDB) (*RocksDbCF, error) {
 		db:        db,
 		defaultCF: cfHandles[0],
 		ttlCF:     cfHandles[1],
+		tagCF:     cfHandles[2],
 		readOpts:  grocksdb.NewDefaultReadOptions(),
 		writeOpts: grocksdb.NewDefaultWriteOptions(),
 		ttlCache:  make(map[string]int64),
@@ -114,6 +126,7 @@ func (c *RocksDbCF) Close() error {
 	// ColumnFamily handles must be destroyed before db Close.
 	c.defaultCF.Destroy()
 	c.ttlCF.Destroy()
+	c.tagCF.Destroy()
 	c.db.Close()
 	return nil
 }
@@ -233,6 +246,53 @@ func (c *RocksDbCF) BatchGet(ctx context.Context, keys []string) (result map[str
 	return result, nil
 }
 
+// BatchGetWithTTL как BatchGet, оставшийся TTL вычисляется по таймстемпу из ttl_cf.
+func (c *RocksDbCF) BatchGetWithTTL(ctx context.Context, keys []string) (result map[string]TTLValue, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("rocksdb", "get_ttl", time.Since(start).Seconds())
+		metrics.RecordProviderOp("rocksdb", "get_ttl", err)
+	}()
+
+	result = make(map[string]TTLValue, len(keys))
+	now := time.Now()
+
+	expiredKeys := make([]string, 0)
+
+	for i, key := range keys {
+		if i%100 == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+		}
+		ts, hasTTL := c.getTTL(key)
+		if hasTTL && now.UnixNano() > ts {
+			expiredKeys = append(expiredKeys, key)
+			continue
+		}
+		slice, err := c.db.GetCF(c.readOpts, c.defaultCF, []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("rocksdb get: %w", err)
+		}
+		if slice.Exists() {
+			var ttl time.Duration
+			if hasTTL {
+				ttl = time.Duration(ts - now.UnixNano())
+			}
+			result[key] = TTLValue{Value: string(slice.Data()), TTL: ttl}
+		}
+		slice.Free()
+	}
+
+	if len(expiredKeys) > 0 {
+		_ = c.BatchDelete(ctx, expiredKeys) // best‑effort cleanup
+	}
+
+	return result, nil
+}
+
 func (c *RocksDbCF) BatchPut(ctx context.Context, items map[string]string, ttls map[string]time.Duration) (err error) {
 	start := time.Now()
 	defer func() {
@@ -268,6 +328,108 @@ func (c *RocksDbCF) BatchPut(ctx context.Context, items map[string]string, ttls
 	return nil
 }
 
+// BatchPutIf выполняет условные записи: чтение, сравнение и запись одним WriteBatch под мьютексом.
+// Атомарность гарантируется относительно других условных записей, но не обычных BatchPut.
+func (c *RocksDbCF) BatchPutIf(ctx context.Context, items map[string]CondValue) (applied map[string]bool, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("rocksdb", "put_if", time.Since(start).Seconds())
+		metrics.RecordProviderOp("rocksdb", "put_if", err)
+	}()
+
+	c.casMu.Lock()
+	defer c.casMu.Unlock()
+
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	current, err := c.BatchGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := grocksdb.NewWriteBatch()
+	defer batch.Destroy()
+
+	now := time.Now()
+	applied = make(map[string]bool, len(items))
+	for key, item := range items {
+		cur, ok := current[key]
+		if item.Expected == nil && ok || item.Expected != nil && (!ok || cur != *item.Expected) {
+			continue
+		}
+		batch.PutCF(c.defaultCF, []byte(key), []byte(item.Value))
+		if item.TTL > 0 {
+			c.setTTL(batch, key, now.Add(item.TTL))
+		} else {
+			c.deleteTTL(batch, key)
+		}
+		applied[key] = true
+	}
+	if len(applied) == 0 {
+		return applied, nil
+	}
+	if err = c.db.Write(c.writeOpts, batch); err != nil {
+		return nil, fmt.Errorf("rocksdb batch put if: %w", err)
+	}
+	return applied, nil
+}
+
+// BatchTouch переписывает только ttl_cf: значения в default не читаются в память и не меняются.
+func (c *RocksDbCF) BatchTouch(ctx context.Context, ttls map[string]time.Duration) (touched map[string]bool, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("rocksdb", "touch", time.Since(start).Seconds())
+		metrics.RecordProviderOp("rocksdb", "touch", err)
+	}()
+
+	touched = make(map[string]bool, len(ttls))
+	if len(ttls) == 0 {
+		return touched, nil
+	}
+	batch := grocksdb.NewWriteBatch()
+	defer batch.Destroy()
+
+	now := time.Now()
+	count := 0
+	for key, ttl := range ttls {
+		if count%100 == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+		}
+		count++
+		if c.expired(key, now) {
+			continue
+		}
+		slice, err := c.db.GetCF(c.readOpts, c.defaultCF, []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("rocksdb get: %w", err)
+		}
+		exists := slice.Exists()
+		slice.Free()
+		if !exists {
+			continue
+		}
+		if ttl > 0 {
+			c.setTTL(batch, key, now.Add(ttl))
+		} else {
+			c.deleteTTL(batch, key)
+		}
+		touched[key] = true
+	}
+	if len(touched) == 0 {
+		return touched, nil
+	}
+	if err = c.db.Write(c.writeOpts, batch); err != nil {
+		return nil, fmt.Errorf("rocksdb batch touch: %w", err)
+	}
+	return touched, nil
+}
+
 func (c *RocksDbCF) BatchDelete(ctx context.Context, keys []string) (err error) {
 	start := time.Now()
 	defer func() {
@@ -296,6 +458,204 @@ func (c *RocksDbCF) BatchDelete(ctx context.Context, keys []string) (err error)
 	return err
 }
 
+// BatchTag записывает пары тег/ключ в tag_cf одним WriteBatch. Рядом сохраняется время
+// истечения ключа: по нему фоновый коллектор удаляет записи индекса вместе с ключом.
+func (c *RocksDbCF) BatchTag(ctx context.Context, tags map[string][]string, ttls map[string]time.Duration) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("rocksdb", "tag", time.Since(start).Seconds())
+		metrics.RecordProviderOp("rocksdb", "tag", err)
+	}()
+
+	if len(tags) == 0 {
+		return nil
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	batch := grocksdb.NewWriteBatch()
+	defer batch.Destroy()
+
+	now := time.Now()
+	for key, keyTags := range tags {
+		var exp []byte
+		if ttl := ttls[key]; ttl > 0 {
+			exp = encodeInt64(now.Add(ttl).UnixNano())
+		}
+		for _, tag := range keyTags {
+			batch.PutCF(c.tagCF, []byte(tag+tagSeparator+key), exp)
+		}
+	}
+	if err = c.db.Write(c.writeOpts, batch); err != nil {
+		return fmt.Errorf("rocksdb batch tag: %w", err)
+	}
+	return nil
+}
+
+// EvictTag проходит итератором по префиксу тега в tag_cf и удаляет найденные ключи
+// из default и ttl_cf вместе с записями индекса одним WriteBatch.
+func (c *RocksDbCF) EvictTag(ctx context.Context, tag string) (keys []string, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("rocksdb", "evict_tag", time.Since(start).Seconds())
+		metrics.RecordProviderOp("rocksdb", "evict_tag", err)
+	}()
+
+	prefix := []byte(tag + tagSeparator)
+	it := c.db.NewIteratorCF(c.readOpts, c.tagCF)
+	defer it.Close()
+
+	batch := grocksdb.NewWriteBatch()
+	defer batch.Destroy()
+
+	keys = make([]string, 0)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		if len(keys)%100 == 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+		}
+		indexKey := it.Key()
+		key := string(indexKey.Data()[len(prefix):])
+		batch.DeleteCF(c.tagCF, indexKey.Data())
+		indexKey.Free()
+		batch.DeleteCF(c.defaultCF, []byte(key))
+		c.deleteTTL(batch, key)
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return keys, nil
+	}
+	if err = c.db.Write(c.writeOpts, batch); err != nil {
+		return nil, fmt.Errorf("rocksdb evict tag: %w", err)
+	}
+	return keys, nil
+}
+
+// deletePrefixProgressInterval — как часто (в ключах) DeletePrefix сообщает о ходе подсчёта
+const deletePrefixProgressInterval = 1000
+
+// DeletePrefix удаляет диапазон [prefix, prefixEnd(prefix)) из default и ttl_cf через DeleteRange.
+// Перед удалением ключи диапазона подсчитываются итератором — это единственная часть операции,
+// которую можно прервать; само удаление — одна запись WriteBatch. Записи tag_cf не удаляются:
+// индекс тегов допускает ключи, которых уже нет.
+func (c *RocksDbCF) DeletePrefix(ctx context.Context, prefix string, progress func(deleted int64)) (deleted int64, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("rocksdb", "delete_prefix", time.Since(start).Seconds())
+		metrics.RecordProviderOp("rocksdb", "delete_prefix", err)
+	}()
+
+	from, to := []byte(prefix), prefixEnd([]byte(prefix))
+	if to == nil {
+		return 0, fmt.Errorf("rocksdb delete prefix: unsupported prefix %q", prefix)
+	}
+
+	it := c.db.NewIteratorCF(c.readOpts, c.defaultCF)
+	for it.Seek(from); it.ValidForPrefix(from); it.Next() {
+		deleted++
+		if deleted%deletePrefixProgressInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				it.Close()
+				return 0, err
+			}
+			progress(deleted)
+		}
+	}
+	it.Close()
+
+	batch := grocksdb.NewWriteBatch()
+	defer batch.Destroy()
+	batch.DeleteRangeCF(c.defaultCF, from, to)
+	batch.DeleteRangeCF(c.ttlCF, from, to)
+	if err = c.db.Write(c.writeOpts, batch); err != nil {
+		return 0, fmt.Errorf("rocksdb delete prefix: %w", err)
+	}
+
+	c.ttlMu.Lock()
+	for key := range c.ttlCache {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.ttlCache, key)
+		}
+	}
+	c.ttlMu.Unlock()
+
+	progress(deleted)
+	return deleted, nil
+}
+
+// prefixEnd возвращает наименьший ключ, больший всех ключей с префиксом prefix
+// (nil — такого ключа нет: префикс пуст или состоит из байтов 0xFF).
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// scanMaxExamined — сколько ключей Scan просматривает за вызов, прежде чем вернуть неполную страницу
+const scanMaxExamined = 10_000
+
+// Scan перебирает ключи default CF итератором, начиная с prefix или с ключа из курсора, и пропускает
+// истёкшие. Курсор — последний просмотренный ключ в base64url. Если под match подходит мало ключей,
+// за вызов просматривается не больше scanMaxExamined ключей, и страница может вернуться неполной.
+func (c *RocksDbCF) Scan(ctx context.Context, prefix, match, cursor string, count int) (keys []string, next string, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.RecordProviderLatency("rocksdb", "scan", time.Since(start).Seconds())
+		metrics.RecordProviderOp("rocksdb", "scan", err)
+	}()
+
+	from := []byte(prefix)
+	if cursor != "" {
+		after, decodeErr := base64.RawURLEncoding.DecodeString(cursor)
+		if decodeErr != nil || !bytes.HasPrefix(after, from) {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+		}
+		from = after
+	}
+
+	it := c.db.NewIteratorCF(c.readOpts, c.defaultCF)
+	defer it.Close()
+
+	now := time.Now()
+	keys = make([]string, 0, count)
+	examined, last := 0, ""
+	it.Seek(from)
+	for ; it.ValidForPrefix([]byte(prefix)); it.Next() {
+		k := it.Key()
+		key := string(k.Data())
+		k.Free()
+		if cursor != "" && examined == 0 && key == string(from) {
+			continue // ключ из курсора просмотрен предыдущей страницей
+		}
+		if len(keys) >= count || examined >= scanMaxExamined {
+			return keys, base64.RawURLEncoding.EncodeToString([]byte(last)), nil
+		}
+		examined++
+		last = key
+		if examined%deletePrefixProgressInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				return nil, "", err
+			}
+		}
+		if c.expired(key, now) || (match != "" && !matchGlob(match, key[len(prefix):])) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if err = it.Err(); err != nil {
+		return nil, "", fmt.Errorf("rocksdb scan: %w", err)
+	}
+	return keys, "", nil
+}
+
 // ---------------- Background TTL collector ----------------
 
 // StartTTLCollector launches a goroutine that every `interval` scans the ttl_cf
@@ -340,10 +700,28 @@ func (c *RocksDbCF) collectOnce() {
 		it.Value().Free()
 	}
 
+	c.collectTagsOnce(batch, now)
+
 	if batch.Count() > 0 {
 		_ = c.db.Write(c.writeOpts, batch) // ignore error for collector
 	}
 }
 
+// collectTagsOnce scans tag_cf and removes index entries of expired keys.
+func (c *RocksDbCF) collectTagsOnce(batch *grocksdb.WriteBatch, now int64) {
+	it := c.db.NewIteratorCF(c.readOpts, c.tagCF)
+	defer it.Close()
+
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		value := it.Value()
+		if exp := value.Data(); len(exp) > 0 && now > decodeInt64(exp) {
+			batch.DeleteCF(c.tagCF, it.Key().Data())
+			it.Key().Free()
+		}
+		value.Free()
+	}
+}
+
 // ---------------- compile‑time check ----------------
 var _ CacheProvider = (*RocksDbCF)(nil)
+var _ Scanner = (*RocksDbCF)(nil)
@@ -60,6 +60,92 @@ func TestRocksDbCF_BatchPutGetDelete(t *testing.T) {
 	assert.Equal(t, "qux", result["baz"])
 }
 
+func TestRocksDbCF_BatchPutIf(t *testing.T) {
+	client, err := NewRocksDbCF(config.RocksDB{
+		Path:            filepath.Join(t.TempDir(), "db"),
+		CreateIfMissing: true,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertBatchPutIf(t, client)
+
+	// запись без TTL снимает TTL предыдущего значения
+	_, hasTTL := client.getTTL("lock")
+	assert.False(t, hasTTL)
+}
+
+func TestRocksDbCF_BatchTouch(t *testing.T) {
+	client, err := NewRocksDbCF(config.RocksDB{
+		Path:            filepath.Join(t.TempDir(), "db"),
+		CreateIfMissing: true,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertBatchTouch(t, client)
+}
+
+func TestRocksDbCF_Tags(t *testing.T) {
+	client, err := NewRocksDbCF(config.RocksDB{
+		Path:            filepath.Join(t.TempDir(), "db"),
+		CreateIfMissing: true,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertTags(t, client)
+
+	// запись индекса с истёкшим ключом удаляется сборщиком
+	ctx := context.Background()
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"x": "1"}, map[string]time.Duration{"x": time.Millisecond}))
+	assert.NoError(t, client.BatchTag(ctx, map[string][]string{"x": {"t3"}}, map[string]time.Duration{"x": time.Millisecond}))
+	time.Sleep(5 * time.Millisecond)
+	client.collectOnce()
+	keys, err := client.EvictTag(ctx, "t3")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestRocksDbCF_DeletePrefix(t *testing.T) {
+	client, err := NewRocksDbCF(config.RocksDB{
+		Path:            filepath.Join(t.TempDir(), "db"),
+		CreateIfMissing: true,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertDeletePrefix(t, client)
+
+	ctx := context.Background()
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"w:1": "1", "w:2": "2"}, map[string]time.Duration{"w:1": time.Minute}))
+	deleted, err := client.DeletePrefix(ctx, "w:", func(int64) {})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	_, hasTTL := client.getTTL("w:1")
+	assert.False(t, hasTTL)
+}
+
+func TestRocksDbCF_Scan(t *testing.T) {
+	client, err := NewRocksDbCF(config.RocksDB{
+		Path:            filepath.Join(t.TempDir(), "db"),
+		CreateIfMissing: true,
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertScan(t, client)
+
+	// истёкшие ключи не перечисляются
+	ctx := context.Background()
+	assert.NoError(t, client.BatchPut(ctx, map[string]string{"w:1": "1", "w:2": "2"}, map[string]time.Duration{"w:1": time.Millisecond}))
+	time.Sleep(5 * time.Millisecond)
+	keys, next, err := client.Scan(ctx, "w:", "", "", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"w:2"}, keys)
+	assert.Empty(t, next)
+}
+
 func TestRocksDbCF_TTLExpiration(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "db")
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,24 +26,67 @@ import (
 //
 //   - Возвращает три категории ключей:
 //
-//   - hits    — ключи, для которых значение было найдено;
+//   - hits    — ключи, для которых значение было найдено
+//     (Stale = true, если истёк мягкий TTL, см. staleWhileRevalidate;
+//     Found = false, если найден tombstone, см. negativeTTL;
+//     RefreshDue = true, если значение близко к истечению, см. refreshAhead);
 //
 //   - misses  — ключи, у которых слой включён, но значение не найдено;
 //
 //   - skipped — ключи, у которых текущий слой отключён (disabled).
 //
+//   - GetAllWithMeta:
+//
+//   - То же, что GetAll, но hits дополнительно содержат Meta: имя провайдера,
+//     оставшийся TTL в слое и вычисленный по нему момент записи.
+//
 //   - PutAll:
 //
 //   - Сохраняет только те записи, у которых включён текущий слой;
 //
+//   - Tombstone-записи сохраняются только для кэшей с negativeTTL > 0.
+//     Обычная запись по тому же ключу заменяет tombstone;
+//
 //   - Остальные игнорируются (в skipped не возвращаются).
 //
+//   - Теги записей (Tags) добавляются в индекс тегов слоя (BatchTag).
+//
+//   - PutAllIf:
+//
+//   - Условная запись (IfAbsent, IfVersion) с атомарной проверкой условия в слое;
+//     результат — Applied / Conflicts / Skipped (слой отключён) / Errors (нет настроек слоя).
+//
+//   - Ошибка вместе с результатом — значения записаны, но не добавлены в индекс тегов.
+//
+//   - TouchAll:
+//
+//   - Продлевает TTL существующих записей, у которых включён текущий слой, не перезаписывая
+//     значения; возвращает ключи, которых нет в слое (missing), и отключённые (skipped).
+//
 //   - DeleteAll:
 //
 //   - Удаляет только те записи, у которых включён текущий слой;
 //
 //   - Остальные игнорируются.
 //
+//   - EvictTag:
+//
+//   - Удаляет все ключи с тегом по индексу тегов слоя и возвращает их.
+//
+//   - EvictKeys:
+//
+//   - Удаляет ключи хранилища без проверки, включён ли слой для кэша
+//     (используется при получении инвалидаций от других экземпляров сервиса).
+//
+//   - DeletePrefix:
+//
+//   - Удаляет все ключи хранилища с префиксом (очистка кэша целиком) и сообщает о ходе удаления.
+//
+//   - ScanKeys:
+//
+//   - Постранично перебирает ключи хранилища с префиксом, если провайдер реализует Scanner;
+//     иначе возвращает ErrScanUnsupported.
+//
 // Под капотом ServiceImpl использует клиента CacheProvider (BatchGet, BatchPut, BatchDelete).
 // TTL для записи вычисляется на основе конфигурации слоя через configService.
 //
@@ -53,11 +97,21 @@ import (
 // Это позволяет централизованно управлять включением/отключением слоёв без изменения клиентского кода.
 type Service interface {
 	GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (*dto.GetResult, error)
-	PutAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) error
-	DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) error
+	GetAllWithMeta(ctx context.Context, reqs []*dto.ResolvedCacheId) (*dto.GetResult, error)
+	PutAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (skipped []*dto.ResolvedCacheId, err error)
+	PutAllIf(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (*dto.CondLayerResult, error)
+	TouchAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (missing, skipped []*dto.ResolvedCacheId, err error)
+	DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (skipped []*dto.ResolvedCacheId, err error)
+	EvictTag(ctx context.Context, tag string) (keys []string, err error)
+	EvictKeys(ctx context.Context, keys []string) error
+	DeletePrefix(ctx context.Context, prefix string, progress func(deleted int64)) (deleted int64, err error)
+	ScanKeys(ctx context.Context, prefix, match, cursor string, count int) (keys []string, next string, err error)
 	Close() error
 }
 
+// ErrScanUnsupported — провайдер слоя не умеет перечислять ключи (не реализует Scanner).
+var ErrScanUnsupported = errors.New("layer does not support key scan")
+
 func CreateNewServiceList(providerConfigs []*config.LayerProvider, cacheServiceConfig config.CacheService) ([]Service, error) {
 	services := make([]Service, 0, len(providerConfigs))
 
@@ -80,7 +134,7 @@ func createService(providerConfig *config.LayerProvider, cacheServiceConfig conf
 	if err != nil {
 		return nil, err
 	}
-	return &ServiceImpl{client: provider, configService: cacheServiceConfig, level: level}, nil
+	return &ServiceImpl{client: provider, configService: cacheServiceConfig, level: level, name: providerConfig.Provider.GetName()}, nil
 }
 
 func initProvider(p interface{}) (CacheProvider, error) {
@@ -105,32 +159,64 @@ type ServiceImpl struct {
 	client        CacheProvider
 	configService config.CacheService
 	level         int
+	name          string // имя провайдера из конфигурации, для HitMeta
 }
 
 // GetAll получает значения для ключей, у которых включён текущий слой.
 // На выходе — разделение на hits/misses/skipped + возможная ошибка клиента
 func (s *ServiceImpl) GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (*dto.GetResult, error) {
+	return s.getAll(ctx, reqs, false)
+}
+
+// GetAllWithMeta как GetAll, но читает значения вместе с оставшимся TTL (BatchGetWithTTL)
+// и заполняет Meta у hits.
+func (s *ServiceImpl) GetAllWithMeta(ctx context.Context, reqs []*dto.ResolvedCacheId) (*dto.GetResult, error) {
+	return s.getAll(ctx, reqs, true)
+}
+
+func (s *ServiceImpl) getAll(ctx context.Context, reqs []*dto.ResolvedCacheId, withMeta bool) (*dto.GetResult, error) {
 	keyToRequest, enabledKeys, skipped := s.categorizeRequests(reqs)
 	if len(enabledKeys) == 0 {
 		return &dto.GetResult{Hits: []*dto.ResolvedCacheHit{}, Misses: []*dto.ResolvedCacheId{}, Skipped: skipped}, nil
 	}
 
-	values, err := s.client.BatchGet(ctx, enabledKeys)
+	values, ttls, err := s.batchGet(ctx, enabledKeys, withMeta)
 	if err != nil {
-		return nil, fmt.Errorf("BatchGet error: %w", err)
+		return nil, err
 	}
 
+	now := time.Now()
 	hits := make([]*dto.ResolvedCacheHit, 0, len(enabledKeys))
 	misses := make([]*dto.ResolvedCacheId, 0, len(enabledKeys))
 	for _, key := range enabledKeys {
 		if val, ok := values[key]; ok {
-			value := unmarshalRawJSON(val)
+			payload, meta := decodeValue(val)
+			var hitMeta *dto.HitMeta
+			if withMeta {
+				hitMeta = s.hitMeta(keyToRequest[key], ttls[key], meta, now)
+			}
+			if meta.Tombstone {
+				hits = append(hits, &dto.ResolvedCacheHit{
+					ResolvedCacheEntry: &dto.ResolvedCacheEntry{
+						ResolvedCacheId: keyToRequest[key],
+						Tombstone:       true,
+					},
+					Found: false,
+					Meta:  hitMeta,
+				})
+				continue
+			}
+			value := unmarshalRawJSON(payload)
 			hits = append(hits, &dto.ResolvedCacheHit{
 				ResolvedCacheEntry: &dto.ResolvedCacheEntry{
 					ResolvedCacheId: keyToRequest[key],
 					Value:           &value,
+					TTL:             meta.entryTtl(),
 				},
-				Found: true,
+				Found:      true,
+				Stale:      meta.SoftExpiry != 0 && now.UnixNano() > meta.SoftExpiry,
+				RefreshDue: s.isRefreshDue(keyToRequest[key], meta, now.UnixNano()),
+				Meta:       hitMeta,
 			})
 		} else {
 			misses = append(misses, keyToRequest[key])
@@ -143,11 +229,39 @@ func (s *ServiceImpl) GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (
 	}, nil
 }
 
+// batchGet читает значения из провайдера; при withTTL — вместе с оставшимся TTL.
+func (s *ServiceImpl) batchGet(ctx context.Context, keys []string, withTTL bool) (values map[string]string, ttls map[string]time.Duration, err error) {
+	if !withTTL {
+		values, err = s.client.BatchGet(ctx, keys)
+		if err != nil {
+			return nil, nil, fmt.Errorf("BatchGet error: %w", err)
+		}
+		return values, nil, nil
+	}
+
+	items, err := s.client.BatchGetWithTTL(ctx, keys)
+	if err != nil {
+		return nil, nil, fmt.Errorf("BatchGetWithTTL error: %w", err)
+	}
+	values = make(map[string]string, len(items))
+	ttls = make(map[string]time.Duration, len(items))
+	for key, item := range items {
+		values[key] = item.Value
+		ttls[key] = item.TTL
+	}
+	return values, ttls, nil
+}
+
 // PutAll сохраняет все значения в слой, если он включён для соответствующего CacheId.
-// Пропускает записи с отключённым слоем. Возвращает ошибку, если BatchPut не удался.
-func (s *ServiceImpl) PutAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (err error) {
+// Пропускает записи с отключённым слоем и возвращает их в skipped. Возвращает ошибку, если BatchPut не удался.
+//
+// Для кэшей со staleWhileRevalidate TTL слоя становится «мягким»: момент его истечения
+// сохраняется рядом со значением, а сама запись живёт в слое ещё staleWhileRevalidate.
+func (s *ServiceImpl) PutAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (skipped []*dto.ResolvedCacheId, err error) {
+	now := time.Now()
 	entries := make(map[string]string, len(reqs))
 	ttls := make(map[string]time.Duration, len(reqs))
+	skipped = make([]*dto.ResolvedCacheId, 0)
 	for _, req := range reqs {
 		enabled, err := s.isEnabled(req)
 		if err != nil {
@@ -160,30 +274,313 @@ func (s *ServiceImpl) PutAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry
 			continue
 		}
 		if !enabled {
+			skipped = append(skipped, req.ResolvedCacheId)
 			continue
 		}
 
-		key := req.GetStorageKey()
-		entries[key] = marshalRawJSON(req.Value)
-		ttls[key] = ttl
+		if value, storedTtl, ok := s.encodeEntry(req, ttl, now); ok {
+			entries[req.GetStorageKey()] = value
+			ttls[req.GetStorageKey()] = storedTtl
+		}
 	}
 	if len(entries) == 0 {
 		return
 	}
-	return s.client.BatchPut(ctx, entries, ttls)
+	if err = s.client.BatchPut(ctx, entries, ttls); err != nil {
+		return
+	}
+	err = s.tagEntries(ctx, reqs, ttls)
+	return
+}
+
+// tagEntries добавляет в индекс тегов сохранённые в слое записи с тегами (ttls — TTL хранения записей).
+func (s *ServiceImpl) tagEntries(ctx context.Context, reqs []*dto.ResolvedCacheEntry, ttls map[string]time.Duration) error {
+	tags := make(map[string][]string)
+	for _, req := range reqs {
+		if _, stored := ttls[req.GetStorageKey()]; stored && len(req.Tags) > 0 && !req.Tombstone {
+			tags[req.GetStorageKey()] = req.Tags
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if err := s.client.BatchTag(ctx, tags, ttls); err != nil {
+		return fmt.Errorf("BatchTag error: %w", err)
+	}
+	return nil
+}
+
+// encodeEntry готовит запись к сохранению в слое: значение (в конверте, если нужны служебные данные)
+// и TTL хранения. ok = false — запись не сохраняется (tombstone для кэша без negativeTTL).
+func (s *ServiceImpl) encodeEntry(req *dto.ResolvedCacheEntry, ttl time.Duration, now time.Time) (value string, storedTtl time.Duration, ok bool) {
+	cache := s.getCache(req)
+	if req.Tombstone {
+		if cache.NegativeTTL <= 0 {
+			return "", 0, false
+		}
+		return encodeValue("", valueMeta{Tombstone: true}), s.storedTtl(req, ttl, true), true
+	}
+
+	var meta valueMeta
+	if override, ok := req.TTLFor(s.level); ok {
+		ttl = s.clampTtl(req, ttl, override)
+		meta.TTL = int64(ttl)
+	}
+	if ttl > 0 && cache.RefreshAhead.Percent > 0 {
+		meta.Expiry = now.Add(ttl).UnixNano()
+	}
+	if ttl > 0 && cache.StaleWhileRevalidate > 0 {
+		meta.SoftExpiry = now.Add(ttl).UnixNano()
+	}
+	return encodeValue(marshalRawJSON(req.Value), meta), s.storedTtl(req, ttl, false), true
+}
+
+// PutAllIf выполняет условную запись (IfAbsent, IfVersion) для ключей, у которых включён текущий слой.
+// Условие проверяется по значению в слое: tombstone и отсутствие ключа равнозначны, версия
+// сравнивается с dto.ValueVersion текущего значения. Сравнение и запись атомарны (BatchPutIf):
+// если значение изменилось между чтением и записью, ключ попадает в Conflicts.
+// Записи без условия записываются только при отсутствии ключа, как IfAbsent.
+// Ключи, для которых не удалось прочитать настройки слоя, возвращаются в Errors.
+// Если значения записаны, но не попали в индекс тегов, возвращается результат вместе с ошибкой.
+func (s *ServiceImpl) PutAllIf(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (*dto.CondLayerResult, error) {
+	result := &dto.CondLayerResult{
+		Applied:   []*dto.ResolvedCacheId{},
+		Conflicts: []*dto.ResolvedCacheId{},
+		Skipped:   []*dto.ResolvedCacheId{},
+	}
+	byKey := make(map[string]*dto.ResolvedCacheEntry, len(reqs))
+	keys := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		enabled, err := s.isEnabled(req)
+		if err != nil {
+			zap.S().Warnw("cannot check level enabled", "key", req.GetStorageKey(), "error", err)
+			result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: req.ResolvedCacheId, Err: err})
+			continue
+		}
+		if !enabled {
+			result.Skipped = append(result.Skipped, req.ResolvedCacheId)
+			continue
+		}
+		byKey[req.GetStorageKey()] = req
+		keys = append(keys, req.GetStorageKey())
+	}
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	current, err := s.client.BatchGet(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("BatchGet error: %w", err)
+	}
+
+	now := time.Now()
+	items := make(map[string]CondValue, len(keys))
+	for _, key := range keys {
+		req := byKey[key]
+		stored, exists := current[key]
+		payload, meta := decodeValue(stored)
+		present := exists && !meta.Tombstone
+		if present && (req.IfVersion == "" || dto.ValueVersion(unmarshalRawJSON(payload)) != req.IfVersion) ||
+			!present && req.IfVersion != "" {
+			result.Conflicts = append(result.Conflicts, req.ResolvedCacheId)
+			continue
+		}
+
+		ttl, err := s.getTtl(req)
+		if err != nil {
+			zap.S().Warnw("cannot get ttl", "key", key, "error", err)
+			result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: req.ResolvedCacheId, Err: err})
+			continue
+		}
+		value, storedTtl, _ := s.encodeEntry(req, ttl, now)
+		item := CondValue{Value: value, TTL: storedTtl}
+		if exists {
+			item.Expected = &stored
+		}
+		items[key] = item
+	}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	applied, err := s.client.BatchPutIf(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	appliedEntries := make([]*dto.ResolvedCacheEntry, 0, len(applied))
+	ttls := make(map[string]time.Duration, len(applied))
+	for _, key := range keys {
+		if _, ok := items[key]; !ok {
+			continue
+		}
+		if applied[key] {
+			result.Applied = append(result.Applied, byKey[key].ResolvedCacheId)
+			appliedEntries = append(appliedEntries, byKey[key])
+			ttls[key] = items[key].TTL
+		} else {
+			result.Conflicts = append(result.Conflicts, byKey[key].ResolvedCacheId)
+		}
+	}
+	// значения уже записаны: результат нужен вызывающему и при ошибке индекса тегов
+	return result, s.tagEntries(ctx, appliedEntries, ttls)
+}
+
+// TouchAll задаёт записям новый TTL: заданный в запросе (TTL, TTLs; не больше maxTtl) или TTL слоя.
+//
+// Обычно TTL меняется средствами провайдера (BatchTouch), значение не читается и не переписывается.
+// Для кэшей, хранящих моменты истечения рядом со значением (staleWhileRevalidate, refreshAhead)
+// или tombstone (negativeTTL), значение перечитывается и записывается заново с новыми метаданными
+// условной записью: если его успели изменить, у новой записи уже свой TTL.
+// TTL, заданный при записи (ttl в put_all), при продлении средствами провайдера в значении не обновляется.
+func (s *ServiceImpl) TouchAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (missing, skipped []*dto.ResolvedCacheId, err error) {
+	skipped = make([]*dto.ResolvedCacheId, 0)
+	missing = make([]*dto.ResolvedCacheId, 0)
+	byKey := make(map[string]*dto.ResolvedCacheEntry, len(reqs))
+	native := make(map[string]time.Duration, len(reqs))
+	rewrite := make([]string, 0)
+	for _, req := range reqs {
+		enabled, err := s.isEnabled(req)
+		if err != nil {
+			zap.S().Warnw("cannot check level enabled", "key", req.GetStorageKey(), "error", err)
+			continue
+		}
+		ttl, err := s.getTtl(req)
+		if err != nil {
+			zap.S().Warnw("cannot get ttl", "key", req.GetStorageKey(), "error", err)
+			continue
+		}
+		if !enabled {
+			skipped = append(skipped, req.ResolvedCacheId)
+			continue
+		}
+
+		key := req.GetStorageKey()
+		byKey[key] = req
+		if s.keepsExpiry(req) {
+			rewrite = append(rewrite, key)
+			continue
+		}
+		if override, ok := req.TTLFor(s.level); ok {
+			ttl = s.clampTtl(req, ttl, override)
+		}
+		native[key] = s.storedTtl(req, ttl, false)
+	}
+
+	touched := make(map[string]bool, len(byKey))
+	if len(native) > 0 {
+		res, err := s.client.BatchTouch(ctx, native)
+		if err != nil {
+			return nil, nil, fmt.Errorf("BatchTouch error: %w", err)
+		}
+		for key, ok := range res {
+			touched[key] = ok
+		}
+	}
+	if len(rewrite) > 0 {
+		if err := s.touchByRewrite(ctx, rewrite, byKey, touched); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for key, req := range byKey {
+		if !touched[key] {
+			missing = append(missing, req.ResolvedCacheId)
+		}
+	}
+	return missing, skipped, nil
+}
+
+// touchByRewrite перечитывает значения и записывает их заново (BatchPutIf) с TTL из запроса или слоя.
+func (s *ServiceImpl) touchByRewrite(ctx context.Context, keys []string, byKey map[string]*dto.ResolvedCacheEntry, touched map[string]bool) error {
+	current, err := s.client.BatchGet(ctx, keys)
+	if err != nil {
+		return fmt.Errorf("BatchGet error: %w", err)
+	}
+
+	now := time.Now()
+	items := make(map[string]CondValue, len(current))
+	for key, stored := range current {
+		payload, meta := decodeValue(stored)
+		if meta.Tombstone {
+			continue
+		}
+		req := byKey[key]
+		ttl, err := s.getTtl(req)
+		if err != nil {
+			zap.S().Warnw("cannot get ttl", "key", key, "error", err)
+			continue
+		}
+		value := unmarshalRawJSON(payload)
+		entry := &dto.ResolvedCacheEntry{ResolvedCacheId: req.ResolvedCacheId, Value: &value, TTL: req.TTL, TTLs: req.TTLs}
+		encoded, storedTtl, _ := s.encodeEntry(entry, ttl, now)
+		items[key] = CondValue{Value: encoded, TTL: storedTtl, Expected: &stored}
+		// значение есть в слое: даже если его успели изменить, у новой записи свой TTL
+		touched[key] = true
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if _, err := s.client.BatchPutIf(ctx, items); err != nil {
+		return fmt.Errorf("BatchPutIf error: %w", err)
+	}
+	return nil
 }
 
 // DeleteAll удаляет все значения, у которых включён текущий слой.
-// Пропускает отключённые. Возвращает ошибку, если удаление не удалось.
-func (s *ServiceImpl) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (err error) {
-	_, keys, _ := s.categorizeRequests(reqs)
+// Пропускает отключённые и возвращает их в skipped. Возвращает ошибку, если удаление не удалось.
+func (s *ServiceImpl) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) (skipped []*dto.ResolvedCacheId, err error) {
+	_, keys, skipped := s.categorizeRequests(reqs)
 	if len(keys) == 0 {
 		return
 	}
 
+	err = s.client.BatchDelete(ctx, keys)
+	return
+}
+
+// EvictTag удаляет ключи с тегом из слоя. Тег общий для всех кэшей, поэтому проверять,
+// включён ли слой для кэша ключа, не нужно: ключ попал в индекс только при записи в этот слой.
+func (s *ServiceImpl) EvictTag(ctx context.Context, tag string) ([]string, error) {
+	keys, err := s.client.EvictTag(ctx, tag)
+	if err != nil {
+		return nil, fmt.Errorf("EvictTag error: %w", err)
+	}
+	return keys, nil
+}
+
+// EvictKeys удаляет ключи хранилища как есть: удаление отсутствующего ключа ничего не меняет,
+// поэтому проверять, включён ли слой для кэша, не нужно.
+func (s *ServiceImpl) EvictKeys(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
 	return s.client.BatchDelete(ctx, keys)
 }
 
+// DeletePrefix удаляет ключи хранилища с префиксом. Слой очищается, даже если он отключён
+// для кэша: в нём могли остаться записи, сделанные до отключения.
+func (s *ServiceImpl) DeletePrefix(ctx context.Context, prefix string, progress func(deleted int64)) (int64, error) {
+	deleted, err := s.client.DeletePrefix(ctx, prefix, progress)
+	if err != nil {
+		return deleted, fmt.Errorf("DeletePrefix error: %w", err)
+	}
+	return deleted, nil
+}
+
+// ScanKeys перебирает ключи хранилища с префиксом. Как и DeletePrefix, не проверяет,
+// включён ли слой для кэша: показываются и записи, сделанные до отключения.
+func (s *ServiceImpl) ScanKeys(ctx context.Context, prefix, match, cursor string, count int) ([]string, string, error) {
+	scanner, ok := s.client.(Scanner)
+	if !ok {
+		return nil, "", ErrScanUnsupported
+	}
+	keys, next, err := scanner.Scan(ctx, prefix, match, cursor, count)
+	if err != nil {
+		return nil, "", fmt.Errorf("ScanKeys error: %w", err)
+	}
+	return keys, next, nil
+}
+
 func (s *ServiceImpl) Close() error {
 	return s.client.Close()
 }
@@ -221,6 +618,103 @@ func (s *ServiceImpl) isEnabled(cacheId dto.CacheIdRef) (bool, error) {
 	return s.configService.IsLevelEnabled(cacheId, s.level)
 }
 
+// isRefreshDue проверяет, что значение прочитано в последние RefreshAhead.Percent процентов
+// своего TTL на самом «горячем» включённом для кэша слое и его пора обновить заранее.
+// Значения с нижних слоёв не учитываются: они и так будут скопированы наверх со свежим TTL.
+func (s *ServiceImpl) isRefreshDue(cacheId dto.CacheIdRef, meta valueMeta, now int64) bool {
+	if meta.Expiry == 0 || now > meta.Expiry {
+		return false
+	}
+	percent := s.getCache(cacheId).RefreshAhead.Percent
+	if percent <= 0 {
+		return false
+	}
+	ttl, err := s.layerTtl(cacheId, meta)
+	if err != nil || ttl <= 0 {
+		return false
+	}
+	for level := 0; level < s.level; level++ {
+		if enabled, err := s.configService.IsLevelEnabled(cacheId, level); err != nil || enabled {
+			return false
+		}
+	}
+	return meta.Expiry-now <= int64(ttl)*int64(percent)/100
+}
+
+// keepsExpiry сообщает, что значения кэша могут хранить рядом с собой момент истечения
+// или tombstone, и продлить их TTL без перезаписи значения нельзя.
+func (s *ServiceImpl) keepsExpiry(cacheId dto.CacheIdRef) bool {
+	cache := s.getCache(cacheId)
+	return cache.StaleWhileRevalidate > 0 || cache.RefreshAhead.Percent > 0 || cache.NegativeTTL > 0
+}
+
+// clampTtl ограничивает TTL, заданный клиентом, значением maxTtl кэша,
+// а если оно не задано — TTL слоя из конфигурации (0 = без ограничения).
+func (s *ServiceImpl) clampTtl(cacheId dto.CacheIdRef, layerTtl, override time.Duration) time.Duration {
+	limit := s.getCache(cacheId).MaxTTL
+	if limit <= 0 {
+		limit = layerTtl
+	}
+	if limit > 0 && override > limit {
+		return limit
+	}
+	return override
+}
+
+// layerTtl — TTL записи в слое: заданный клиентом при записи или TTL слоя из конфигурации.
+func (s *ServiceImpl) layerTtl(cacheId dto.CacheIdRef, meta valueMeta) (time.Duration, error) {
+	if meta.TTL > 0 {
+		return time.Duration(meta.TTL), nil
+	}
+	return s.getTtl(cacheId)
+}
+
+// storedTtl — TTL, с которым запись хранится в слое: для tombstone — negativeTTL (не больше TTL слоя),
+// для staleWhileRevalidate — TTL слоя плюс окно, в течение которого отдаётся устаревшее значение.
+func (s *ServiceImpl) storedTtl(cacheId dto.CacheIdRef, ttl time.Duration, tombstone bool) time.Duration {
+	cache := s.getCache(cacheId)
+	if tombstone {
+		if ttl > 0 && ttl < cache.NegativeTTL {
+			return ttl
+		}
+		return cache.NegativeTTL
+	}
+	if ttl > 0 && cache.StaleWhileRevalidate > 0 {
+		return ttl + cache.StaleWhileRevalidate
+	}
+	return ttl
+}
+
+// hitMeta формирует метаданные hit'а. Момент записи в слоях не хранится: он вычисляется
+// как «истечение минус TTL записи» и известен только для значений со сроком жизни.
+func (s *ServiceImpl) hitMeta(cacheId dto.CacheIdRef, remaining time.Duration, stored valueMeta, now time.Time) *dto.HitMeta {
+	meta := &dto.HitMeta{Provider: s.name}
+	if remaining <= 0 {
+		return meta
+	}
+	ttlMs := remaining.Milliseconds()
+	meta.TtlMs = &ttlMs
+	ttl, err := s.layerTtl(cacheId, stored)
+	if err != nil {
+		return meta
+	}
+	if full := s.storedTtl(cacheId, ttl, stored.Tombstone); full >= remaining {
+		writtenAt := now.Add(remaining - full)
+		meta.WrittenAt = &writtenAt
+	}
+	return meta
+}
+
+// getCache возвращает конфигурацию кэша; для неизвестного кэша — пустую конфигурацию
+// (все дополнительные режимы выключены).
+func (s *ServiceImpl) getCache(cacheId dto.CacheIdRef) config.Cache {
+	cache, err := s.configService.GetCache(cacheId)
+	if err != nil {
+		return config.Cache{}
+	}
+	return cache
+}
+
 //////////////////////////
 /// DisabledService
 /////////////////////////
@@ -237,13 +731,47 @@ func (s *ServiceDisabled) GetAll(ctx context.Context, reqs []*dto.ResolvedCacheI
 		nil
 }
 
-func (s *ServiceDisabled) PutAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) error {
-	return nil
+func (s *ServiceDisabled) GetAllWithMeta(ctx context.Context, reqs []*dto.ResolvedCacheId) (*dto.GetResult, error) {
+	return s.GetAll(ctx, reqs)
+}
+
+func (s *ServiceDisabled) PutAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) ([]*dto.ResolvedCacheId, error) {
+	skipped := make([]*dto.ResolvedCacheId, 0, len(reqs))
+	for _, req := range reqs {
+		skipped = append(skipped, req.ResolvedCacheId)
+	}
+	return skipped, nil
+}
+func (s *ServiceDisabled) PutAllIf(ctx context.Context, reqs []*dto.ResolvedCacheEntry) (*dto.CondLayerResult, error) {
+	skipped, _ := s.PutAll(ctx, reqs)
+	return &dto.CondLayerResult{Applied: []*dto.ResolvedCacheId{}, Conflicts: []*dto.ResolvedCacheId{}, Skipped: skipped}, nil
+}
+
+func (s *ServiceDisabled) TouchAll(ctx context.Context, reqs []*dto.ResolvedCacheEntry) ([]*dto.ResolvedCacheId, []*dto.ResolvedCacheId, error) {
+	skipped, _ := s.PutAll(ctx, reqs)
+	return []*dto.ResolvedCacheId{}, skipped, nil
 }
-func (s *ServiceDisabled) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) error {
+
+func (s *ServiceDisabled) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) ([]*dto.ResolvedCacheId, error) {
+	return reqs, nil
+}
+
+func (s *ServiceDisabled) EvictTag(ctx context.Context, tag string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (s *ServiceDisabled) EvictKeys(ctx context.Context, keys []string) error {
 	return nil
 }
 
+func (s *ServiceDisabled) DeletePrefix(ctx context.Context, prefix string, progress func(deleted int64)) (int64, error) {
+	return 0, nil
+}
+
+func (s *ServiceDisabled) ScanKeys(ctx context.Context, prefix, match, cursor string, count int) ([]string, string, error) {
+	return []string{}, "", nil
+}
+
 func (s *ServiceDisabled) Close() error {
 	return nil
 }
@@ -0,0 +1,557 @@
+package providers
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryProvider — простой CacheProvider на map для тестов ServiceImpl.
+type memoryProvider struct {
+	items map[string]string
+	ttls  map[string]time.Duration
+	tags  map[string][]string
+	// tagErr — ошибка BatchTag
+	tagErr error
+}
+
+func newMemoryProvider() *memoryProvider {
+	return &memoryProvider{items: map[string]string{}, ttls: map[string]time.Duration{}, tags: map[string][]string{}}
+}
+
+func (p *memoryProvider) BatchGet(_ context.Context, keys []string) (map[string]string, error) {
+	result := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if val, ok := p.items[key]; ok {
+			result[key] = val
+		}
+	}
+	return result, nil
+}
+
+// BatchGetWithTTL отдаёт TTL записи как оставшийся: в тестах время не идёт.
+func (p *memoryProvider) BatchGetWithTTL(_ context.Context, keys []string) (map[string]TTLValue, error) {
+	result := make(map[string]TTLValue, len(keys))
+	for _, key := range keys {
+		if val, ok := p.items[key]; ok {
+			result[key] = TTLValue{Value: val, TTL: p.ttls[key]}
+		}
+	}
+	return result, nil
+}
+
+func (p *memoryProvider) BatchPut(_ context.Context, items map[string]string, ttls map[string]time.Duration) error {
+	for key, val := range items {
+		p.items[key] = val
+		p.ttls[key] = ttls[key]
+	}
+	return nil
+}
+
+func (p *memoryProvider) BatchPutIf(_ context.Context, items map[string]CondValue) (map[string]bool, error) {
+	applied := make(map[string]bool, len(items))
+	for key, item := range items {
+		cur, ok := p.items[key]
+		if item.Expected == nil && ok || item.Expected != nil && (!ok || cur != *item.Expected) {
+			continue
+		}
+		p.items[key] = item.Value
+		p.ttls[key] = item.TTL
+		applied[key] = true
+	}
+	return applied, nil
+}
+
+func (p *memoryProvider) BatchTouch(_ context.Context, ttls map[string]time.Duration) (map[string]bool, error) {
+	touched := make(map[string]bool, len(ttls))
+	for key, ttl := range ttls {
+		if _, ok := p.items[key]; ok {
+			p.ttls[key] = ttl
+			touched[key] = true
+		}
+	}
+	return touched, nil
+}
+
+func (p *memoryProvider) BatchDelete(_ context.Context, keys []string) error {
+	for _, key := range keys {
+		delete(p.items, key)
+		delete(p.ttls, key)
+	}
+	return nil
+}
+
+func (p *memoryProvider) BatchTag(_ context.Context, tags map[string][]string, _ map[string]time.Duration) error {
+	if p.tagErr != nil {
+		return p.tagErr
+	}
+	for tag, keys := range invertTags(tags) {
+		p.tags[tag] = append(p.tags[tag], keys...)
+	}
+	return nil
+}
+
+func (p *memoryProvider) EvictTag(ctx context.Context, tag string) ([]string, error) {
+	keys := p.tags[tag]
+	delete(p.tags, tag)
+	return keys, p.BatchDelete(ctx, keys)
+}
+
+func (p *memoryProvider) DeletePrefix(_ context.Context, prefix string, progress func(int64)) (int64, error) {
+	var deleted int64
+	for key := range p.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(p.items, key)
+			delete(p.ttls, key)
+			deleted++
+		}
+	}
+	progress(deleted)
+	return deleted, nil
+}
+
+func (p *memoryProvider) Close() error { return nil }
+
+func newTestService(provider CacheProvider, cache config.Cache) *ServiceImpl {
+	configService := config.NewCacheService(&config.AppConfig{Caches: []config.Cache{cache}})
+	return &ServiceImpl{client: provider, configService: configService, level: 0}
+}
+
+func resolvedEntry(cacheName, key, value string) *dto.ResolvedCacheEntry {
+	raw := json.RawMessage(value)
+	return &dto.ResolvedCacheEntry{
+		ResolvedCacheId: &dto.ResolvedCacheId{
+			CacheId:    &dto.CacheId{CacheName: cacheName, Key: key},
+			StorageKey: cacheName + ":" + key,
+		},
+		Value: &raw,
+	}
+}
+
+func TestServiceImpl_PutGet_PlainValue(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:   "c",
+		Prefix: "c",
+		Layers: []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+	})
+	ctx := context.Background()
+
+	entry := resolvedEntry("c", "1", `{ "a": 1 }`)
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{entry})
+	assert.NoError(t, err)
+
+	// без staleWhileRevalidate значение хранится без конверта
+	assert.Equal(t, `{"a":1}`, provider.items["c:1"])
+	assert.Equal(t, time.Minute, provider.ttls["c:1"])
+
+	res, err := service.GetAll(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	assert.False(t, res.Hits[0].Stale)
+	assert.JSONEq(t, `{"a":1}`, string(*res.Hits[0].ResolvedCacheEntry.Value))
+}
+
+func TestServiceImpl_GetAllWithMeta(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:                 "c",
+		Prefix:               "c",
+		Layers:               []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+		StaleWhileRevalidate: time.Minute,
+	})
+	service.name = "redis"
+	ctx := context.Background()
+
+	entry := resolvedEntry("c", "1", `"v"`)
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{entry})
+	assert.NoError(t, err)
+	provider.ttls["c:1"] = 90 * time.Second // прошло 30 секунд
+
+	before := time.Now()
+	res, err := service.GetAllWithMeta(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	meta := res.Hits[0].Meta
+	assert.NotNil(t, meta)
+	assert.Equal(t, "redis", meta.Provider)
+	assert.Equal(t, int64(90000), *meta.TtlMs)
+	// TTL записи — минута плюс окно staleWhileRevalidate
+	assert.WithinDuration(t, before.Add(-30*time.Second), *meta.WrittenAt, time.Second)
+
+	// без срока жизни момент записи неизвестен
+	provider.ttls["c:1"] = 0
+	res, err = service.GetAllWithMeta(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Nil(t, res.Hits[0].Meta.TtlMs)
+	assert.Nil(t, res.Hits[0].Meta.WrittenAt)
+
+	res, err = service.GetAll(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Nil(t, res.Hits[0].Meta)
+}
+
+func TestServiceImpl_PutAll_EntryTTL(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:   "c",
+		Prefix: "c",
+		Layers: []config.CacheLayerConfig{{Enabled: true, TTL: time.Hour}},
+		MaxTTL: 2 * time.Hour,
+	})
+	ctx := context.Background()
+
+	short, long, layer := int64(30), int64(86400), int64(60)
+	shortEntry := resolvedEntry("c", "short", `"v"`)
+	shortEntry.TTL = &short
+	longEntry := resolvedEntry("c", "long", `"v"`)
+	longEntry.TTL = &long
+	layerEntry := resolvedEntry("c", "layer", `"v"`)
+	layerEntry.TTL = &long
+	layerEntry.TTLs = []*int64{&layer}
+
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{shortEntry, longEntry, layerEntry})
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, provider.ttls["c:short"])
+	// больше maxTtl — уменьшается до maxTtl
+	assert.Equal(t, 2*time.Hour, provider.ttls["c:long"])
+	// TTL слоя важнее общего
+	assert.Equal(t, time.Minute, provider.ttls["c:layer"])
+
+	// момент записи вычисляется от заданного TTL, а не от TTL слоя
+	provider.ttls["c:short"] = 20 * time.Second
+	before := time.Now()
+	res, err := service.GetAllWithMeta(ctx, []*dto.ResolvedCacheId{shortEntry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	assert.JSONEq(t, `"v"`, string(*res.Hits[0].ResolvedCacheEntry.Value))
+	assert.WithinDuration(t, before.Add(-10*time.Second), *res.Hits[0].Meta.WrittenAt, time.Second)
+	// заданный TTL сохраняется при копировании значения в другие слои
+	assert.Equal(t, short, *res.Hits[0].ResolvedCacheEntry.TTL)
+}
+
+func TestServiceImpl_PutAll_EntryTTLWithoutMaxTTL(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:   "c",
+		Prefix: "c",
+		Layers: []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+	})
+
+	long := int64(3600)
+	entry := resolvedEntry("c", "1", `"v"`)
+	entry.TTL = &long
+	_, err := service.PutAll(context.Background(), []*dto.ResolvedCacheEntry{entry})
+	assert.NoError(t, err)
+	// без maxTtl заданный TTL не больше TTL слоя
+	assert.Equal(t, time.Minute, provider.ttls["c:1"])
+}
+
+func TestServiceImpl_PutAllIf(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:        "c",
+		Prefix:      "c",
+		Layers:      []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+		NegativeTTL: time.Minute,
+	})
+	ctx := context.Background()
+
+	lock := resolvedEntry("c", "lock", `"owner-1"`)
+	lock.IfAbsent = true
+	res, err := service.PutAllIf(ctx, []*dto.ResolvedCacheEntry{lock})
+	assert.NoError(t, err)
+	assert.Len(t, res.Applied, 1)
+	assert.Equal(t, time.Minute, provider.ttls["c:lock"])
+
+	// ключ уже есть — второй ifAbsent не проходит
+	other := resolvedEntry("c", "lock", `"owner-2"`)
+	other.IfAbsent = true
+	res, err = service.PutAllIf(ctx, []*dto.ResolvedCacheEntry{other})
+	assert.NoError(t, err)
+	assert.Len(t, res.Conflicts, 1)
+	assert.Equal(t, `"owner-1"`, provider.items["c:lock"])
+
+	// compare-and-set: проходит только с версией текущего значения
+	stale := resolvedEntry("c", "lock", `"owner-3"`)
+	stale.IfVersion = dto.ValueVersion(json.RawMessage(`"owner-2"`))
+	fresh := resolvedEntry("c", "lock", `"owner-3"`)
+	fresh.IfVersion = dto.ValueVersion(json.RawMessage(`"owner-1"`))
+	res, err = service.PutAllIf(ctx, []*dto.ResolvedCacheEntry{stale})
+	assert.NoError(t, err)
+	assert.Len(t, res.Conflicts, 1)
+	res, err = service.PutAllIf(ctx, []*dto.ResolvedCacheEntry{fresh})
+	assert.NoError(t, err)
+	assert.Len(t, res.Applied, 1)
+	assert.Equal(t, `"owner-3"`, provider.items["c:lock"])
+
+	// tombstone равнозначен отсутствию ключа
+	tombstone := resolvedEntry("c", "gone", "")
+	tombstone.Tombstone = true
+	_, err = service.PutAll(ctx, []*dto.ResolvedCacheEntry{tombstone})
+	assert.NoError(t, err)
+	versioned := resolvedEntry("c", "gone", `1`)
+	versioned.IfVersion = "1"
+	absent := resolvedEntry("c", "gone", `1`)
+	absent.IfAbsent = true
+	res, err = service.PutAllIf(ctx, []*dto.ResolvedCacheEntry{versioned})
+	assert.NoError(t, err)
+	assert.Len(t, res.Conflicts, 1)
+	res, err = service.PutAllIf(ctx, []*dto.ResolvedCacheEntry{absent})
+	assert.NoError(t, err)
+	assert.Len(t, res.Applied, 1)
+	assert.Equal(t, `1`, provider.items["c:gone"])
+}
+
+func TestServiceImpl_PutAllIf_Errors(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:   "c",
+		Prefix: "c",
+		Layers: []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+	})
+	ctx := context.Background()
+
+	// ключ неизвестного кэша возвращается в Errors, а не пропадает из результата
+	unknown := resolvedEntry("x", "1", `1`)
+	unknown.IfAbsent = true
+	tagged := resolvedEntry("c", "1", `1`)
+	tagged.IfAbsent = true
+	tagged.Tags = []string{"tenant:42"}
+	provider.tagErr = errors.New("tags down")
+
+	res, err := service.PutAllIf(ctx, []*dto.ResolvedCacheEntry{unknown, tagged})
+
+	// значение записано, ошибка индекса тегов возвращается вместе с результатом
+	assert.ErrorContains(t, err, "tags down")
+	assert.NotNil(t, res)
+	assert.Equal(t, []*dto.ResolvedCacheId{tagged.ResolvedCacheId}, res.Applied)
+	assert.Equal(t, `1`, provider.items["c:1"])
+	assert.Len(t, res.Errors, 1)
+	assert.Equal(t, unknown.ResolvedCacheId, res.Errors[0].ResolvedCacheId)
+}
+
+func TestServiceImpl_Tags(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:   "c",
+		Prefix: "c",
+		Layers: []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+	})
+	ctx := context.Background()
+
+	tagged := resolvedEntry("c", "1", `1`)
+	tagged.Tags = []string{"tenant:42"}
+	tombstone := resolvedEntry("c", "2", "")
+	tombstone.Tombstone = true
+	tombstone.Tags = []string{"tenant:42"}
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{tagged, tombstone, resolvedEntry("c", "3", `3`)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c:1"}, provider.tags["tenant:42"])
+
+	keys, err := service.EvictTag(ctx, "tenant:42")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c:1"}, keys)
+	assert.NotContains(t, provider.items, "c:1")
+	assert.Contains(t, provider.items, "c:3")
+}
+
+func TestServiceImpl_StaleWhileRevalidate(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:                 "c",
+		Prefix:               "c",
+		Layers:               []config.CacheLayerConfig{{Enabled: true, TTL: 50 * time.Millisecond}},
+		StaleWhileRevalidate: time.Minute,
+	})
+	ctx := context.Background()
+
+	entry := resolvedEntry("c", "1", `"v"`)
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{entry})
+	assert.NoError(t, err)
+
+	// запись живёт в слое TTL + окно staleWhileRevalidate
+	assert.Equal(t, 50*time.Millisecond+time.Minute, provider.ttls["c:1"])
+
+	res, err := service.GetAll(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	assert.False(t, res.Hits[0].Stale)
+	assert.Equal(t, `"v"`, string(*res.Hits[0].ResolvedCacheEntry.Value))
+
+	time.Sleep(60 * time.Millisecond)
+
+	res, err = service.GetAll(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	assert.True(t, res.Hits[0].Stale)
+	assert.Equal(t, `"v"`, string(*res.Hits[0].ResolvedCacheEntry.Value))
+}
+
+func TestDecodeValue_Legacy(t *testing.T) {
+	payload, meta := decodeValue(`{"a":1}`)
+	assert.Equal(t, `{"a":1}`, payload)
+	assert.True(t, meta.isEmpty())
+
+	encoded := encodeValue(`{"a":1}`, valueMeta{SoftExpiry: 42})
+	payload, meta = decodeValue(encoded)
+	assert.Equal(t, `{"a":1}`, payload)
+	assert.Equal(t, int64(42), meta.SoftExpiry)
+}
+
+func TestServiceImpl_Tombstone(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:        "c",
+		Prefix:      "c",
+		Layers:      []config.CacheLayerConfig{{Enabled: true, TTL: time.Hour}},
+		NegativeTTL: time.Minute,
+	})
+	ctx := context.Background()
+
+	id := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, StorageKey: "c:1"}
+	tombstone := &dto.ResolvedCacheEntry{ResolvedCacheId: id, Tombstone: true}
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{tombstone})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, provider.ttls["c:1"])
+
+	// tombstone возвращается как hit с Found = false и не уходит в misses
+	res, err := service.GetAll(ctx, []*dto.ResolvedCacheId{id})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Misses)
+	assert.Len(t, res.Hits, 1)
+	assert.False(t, res.Hits[0].Found)
+	assert.True(t, res.Hits[0].ResolvedCacheEntry.Tombstone)
+
+	// обычная запись заменяет tombstone
+	_, err = service.PutAll(ctx, []*dto.ResolvedCacheEntry{resolvedEntry("c", "1", `"v"`)})
+	assert.NoError(t, err)
+	res, err = service.GetAll(ctx, []*dto.ResolvedCacheId{id})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	assert.True(t, res.Hits[0].Found)
+	assert.Equal(t, `"v"`, string(*res.Hits[0].ResolvedCacheEntry.Value))
+}
+
+func TestServiceImpl_TombstoneIgnoredWithoutNegativeTTL(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:   "c",
+		Prefix: "c",
+		Layers: []config.CacheLayerConfig{{Enabled: true, TTL: time.Hour}},
+	})
+
+	id := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, StorageKey: "c:1"}
+	tombstone := &dto.ResolvedCacheEntry{ResolvedCacheId: id, Tombstone: true}
+	_, err := service.PutAll(context.Background(), []*dto.ResolvedCacheEntry{tombstone})
+	assert.NoError(t, err)
+	assert.Empty(t, provider.items)
+}
+
+func TestServiceImpl_RefreshAhead(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:         "c",
+		Prefix:       "c",
+		Layers:       []config.CacheLayerConfig{{Enabled: true, TTL: 100 * time.Millisecond}},
+		RefreshAhead: config.RefreshAheadConfig{Percent: 50},
+	})
+	ctx := context.Background()
+
+	entry := resolvedEntry("c", "1", `"v"`)
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{entry})
+	assert.NoError(t, err)
+
+	res, err := service.GetAll(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	assert.False(t, res.Hits[0].RefreshDue)
+
+	time.Sleep(60 * time.Millisecond)
+
+	// осталось меньше 50% TTL — значение пора обновить
+	res, err = service.GetAll(ctx, []*dto.ResolvedCacheId{entry.ResolvedCacheId})
+	assert.NoError(t, err)
+	assert.Len(t, res.Hits, 1)
+	assert.True(t, res.Hits[0].RefreshDue)
+	assert.Equal(t, `"v"`, string(*res.Hits[0].ResolvedCacheEntry.Value))
+}
+
+func TestServiceImpl_TouchAll(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:   "c",
+		Prefix: "c",
+		Layers: []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+		MaxTTL: time.Hour,
+	})
+	ctx := context.Background()
+
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{resolvedEntry("c", "1", `"v"`)})
+	assert.NoError(t, err)
+	provider.ttls["c:1"] = time.Second
+
+	ttl := int64(7200)
+	touch := resolvedEntry("c", "1", "")
+	touch.Value, touch.TTL = nil, &ttl
+	missing, skipped, err := service.TouchAll(ctx, []*dto.ResolvedCacheEntry{touch, resolvedEntry("c", "2", "")})
+	assert.NoError(t, err)
+	assert.Empty(t, skipped)
+	assert.Len(t, missing, 1)
+	assert.Equal(t, "c:2", missing[0].GetStorageKey())
+	// TTL из запроса ограничен maxTtl, значение не переписано
+	assert.Equal(t, time.Hour, provider.ttls["c:1"])
+	assert.Equal(t, `"v"`, provider.items["c:1"])
+}
+
+func TestServiceImpl_TouchAll_Rewrite(t *testing.T) {
+	provider := newMemoryProvider()
+	service := newTestService(provider, config.Cache{
+		Name:                 "c",
+		Prefix:               "c",
+		Layers:               []config.CacheLayerConfig{{Enabled: true, TTL: time.Minute}},
+		StaleWhileRevalidate: time.Minute,
+		NegativeTTL:          time.Minute,
+	})
+	ctx := context.Background()
+
+	gone := resolvedEntry("c", "gone", "")
+	gone.Tombstone = true
+	_, err := service.PutAll(ctx, []*dto.ResolvedCacheEntry{resolvedEntry("c", "1", `"v"`), gone})
+	assert.NoError(t, err)
+	_, before := decodeValue(provider.items["c:1"])
+	time.Sleep(time.Millisecond)
+
+	missing, _, err := service.TouchAll(ctx, []*dto.ResolvedCacheEntry{resolvedEntry("c", "1", ""), resolvedEntry("c", "gone", "")})
+	assert.NoError(t, err)
+	// tombstone не продлевается
+	assert.Len(t, missing, 1)
+	assert.Equal(t, "c:gone", missing[0].GetStorageKey())
+
+	// мягкое истечение сдвинуто вместе с TTL
+	payload, after := decodeValue(provider.items["c:1"])
+	assert.Equal(t, `"v"`, payload)
+	assert.Greater(t, after.SoftExpiry, before.SoftExpiry)
+	assert.Equal(t, 2*time.Minute, provider.ttls["c:1"])
+}
+
+func TestServiceImpl_ScanKeys(t *testing.T) {
+	ctx := context.Background()
+	cache := config.Cache{Name: "test", Prefix: "t", Layers: []config.CacheLayerConfig{{Enabled: true}}}
+
+	// memoryProvider не реализует Scanner
+	_, _, err := newTestService(newMemoryProvider(), cache).ScanKeys(ctx, "t:", "", "", 10)
+	assert.ErrorIs(t, err, ErrScanUnsupported)
+
+	keys, next, err := (&ServiceDisabled{}).ScanKeys(ctx, "t:", "", "", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.Empty(t, next)
+}
@@ -0,0 +1,34 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"aur-cache-service/internal/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// metricsUnaryInterceptor collects Prometheus metrics for each unary gRPC call,
+// the same way httpserver.MetricsMiddleware does for HTTP requests.
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	record(info.FullMethod, err, start)
+	return resp, err
+}
+
+// metricsStreamInterceptor collects Prometheus metrics for each streaming gRPC call.
+func metricsStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	record(info.FullMethod, err, start)
+	return err
+}
+
+func record(method string, err error, start time.Time) {
+	duration := time.Since(start).Seconds()
+	metrics.GRPCRequestsTotal.WithLabelValues(method, status.Code(err).String()).Inc()
+	metrics.GRPCRequestDuration.WithLabelValues(method).Observe(duration)
+}
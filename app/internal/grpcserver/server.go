@@ -0,0 +1,372 @@
+package grpcserver
+
+import (
+	"aur-cache-service/api/dto"
+	cachepb "aur-cache-service/api/grpc"
+	"aur-cache-service/internal/manager"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"telegram-alerts-go/alert"
+)
+
+const (
+	maxMsgSize      = 5 << 20 // Максимальный размер входящего сообщения: 5 МБ, как у тела HTTP-запроса
+	streamChunkSize = 500     // Сколько ключей StreamGetAll обрабатывает за один проход по кэшу
+)
+
+// NewServer возвращает gRPC-сервер с зарегистрированным CacheService.
+// Запросы обрабатываются тем же ManagerAdapter, что и REST API.
+func NewServer(adapter manager.ManagerAdapter) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.MaxRecvMsgSize(maxMsgSize),
+		grpc.UnaryInterceptor(metricsUnaryInterceptor),
+		grpc.StreamInterceptor(metricsStreamInterceptor),
+	)
+	cachepb.RegisterCacheServiceServer(srv, &cacheServer{adapter: adapter})
+	return srv
+}
+
+// Listen запускает gRPC-сервер на порту port и блокируется до его остановки.
+func Listen(srv *grpc.Server, port int) {
+	addr := fmt.Sprintf(":%d", port)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		zap.S().Fatalw(alert.Prefix("grpc listen error"), "addr", addr, "error", err)
+	}
+
+	zap.S().Infow("starting server", "name", "grpc", "addr", addr)
+	if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		zap.S().Fatalw(alert.Prefix("grpc server error"), "error", err)
+	}
+}
+
+type cacheServer struct {
+	cachepb.UnimplementedCacheServiceServer
+	adapter manager.ManagerAdapter
+}
+
+func (s *cacheServer) GetAll(ctx context.Context, req *cachepb.GetAllRequest) (*cachepb.GetAllResponse, error) {
+	if len(req.GetRequests()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty requests")
+	}
+
+	opts, err := toGetOptions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := toCacheIds(req.GetRequests())
+	hits := orderHits(ids, s.adapter.GetAllWithOptions(ctx, ids, opts))
+	zap.S().Infow("processed grpc get", "req", len(ids))
+
+	resp := &cachepb.GetAllResponse{Results: make([]*cachepb.CacheEntryHit, 0, len(hits))}
+	for _, hit := range hits {
+		resp.Results = append(resp.Results, toPbHit(hit))
+	}
+	return resp, nil
+}
+
+// StreamGetAll обрабатывает ключи частями по streamChunkSize и отправляет результаты
+// каждой части, не дожидаясь остальных.
+func (s *cacheServer) StreamGetAll(req *cachepb.GetAllRequest, stream cachepb.CacheService_StreamGetAllServer) error {
+	if len(req.GetRequests()) == 0 {
+		return status.Error(codes.InvalidArgument, "empty requests")
+	}
+
+	opts, err := toGetOptions(req)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ids := toCacheIds(req.GetRequests())
+	for start := 0; start < len(ids); start += streamChunkSize {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		end := min(start+streamChunkSize, len(ids))
+		chunk := ids[start:end]
+		for _, hit := range orderHits(chunk, s.adapter.GetAllWithOptions(ctx, chunk, opts)) {
+			if err := stream.Send(toPbHit(hit)); err != nil {
+				return err
+			}
+		}
+	}
+	zap.S().Infow("processed grpc stream get", "req", len(ids))
+	return nil
+}
+
+func (s *cacheServer) PutAll(ctx context.Context, req *cachepb.PutAllRequest) (*cachepb.WriteResponse, error) {
+	if len(req.GetRequests()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty requests")
+	}
+
+	entries := make([]*dto.CacheEntry, 0, len(req.GetRequests()))
+	for _, e := range req.GetRequests() {
+		if !json.Valid(e.GetValue()) {
+			return nil, status.Errorf(codes.InvalidArgument, "value of %s:%s is not valid JSON", e.GetCache(), e.GetKey())
+		}
+		value := json.RawMessage(e.GetValue())
+		entry := &dto.CacheEntry{
+			CacheId:   &dto.CacheId{CacheName: e.GetCache(), Key: e.GetKey()},
+			Value:     &value,
+			TTL:       e.Ttl,
+			TTLs:      toEntryTtls(e.GetTtls()),
+			IfAbsent:  e.GetIfAbsent(),
+			IfVersion: e.GetIfVersion(),
+			Tags:      e.GetTags(),
+		}
+		if err := entry.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s:%s: %v", e.GetCache(), e.GetKey(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if req.GetSync() {
+		report := s.adapter.PutAllSync(ctx, entries)
+		zap.S().Infow("processed grpc sync put", "records", len(entries), "upstreamFailed", len(report.Errors))
+		return toPbReport(report), nil
+	}
+
+	failed, err := s.adapter.PutAll(ctx, entries)
+	var conflict *manager.ConflictError
+	if errors.As(err, &conflict) {
+		// условие не выполнено для части ключей; остальные записи приняты
+		return &cachepb.WriteResponse{Errors: toPbErrors(failed), Conflicts: toPbIds(conflict.Keys)}, nil
+	}
+	if err != nil {
+		return nil, queueError(err)
+	}
+	zap.S().Infow("processed grpc put", "records", len(entries), "upstreamFailed", len(failed))
+	return &cachepb.WriteResponse{Errors: toPbErrors(failed)}, nil
+}
+
+// toEntryTtls переводит TTL по слоям из запроса: 0 — TTL слоя не задан.
+func toEntryTtls(ttls []int64) []*int64 {
+	if len(ttls) == 0 {
+		return nil
+	}
+	res := make([]*int64, len(ttls))
+	for i := range ttls {
+		if ttls[i] != 0 {
+			res[i] = &ttls[i]
+		}
+	}
+	return res
+}
+
+func (s *cacheServer) EvictAll(ctx context.Context, req *cachepb.EvictAllRequest) (*cachepb.WriteResponse, error) {
+	if len(req.GetRequests()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty requests")
+	}
+
+	ids := toCacheIds(req.GetRequests())
+	if req.GetSync() {
+		report := s.adapter.EvictAllSync(ctx, ids)
+		zap.S().Infow("processed grpc sync delete", "records", len(ids), "upstreamFailed", len(report.Errors))
+		return toPbReport(report), nil
+	}
+
+	failed, err := s.adapter.EvictAll(ctx, ids)
+	if err != nil {
+		return nil, queueError(err)
+	}
+	zap.S().Infow("processed grpc delete", "records", len(ids), "upstreamFailed", len(failed))
+	return &cachepb.WriteResponse{Errors: toPbErrors(failed)}, nil
+}
+
+func (s *cacheServer) TouchAll(ctx context.Context, req *cachepb.TouchAllRequest) (*cachepb.TouchAllResponse, error) {
+	if len(req.GetRequests()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty requests")
+	}
+
+	entries := make([]*dto.CacheEntry, 0, len(req.GetRequests()))
+	for _, e := range req.GetRequests() {
+		entry := &dto.CacheEntry{
+			CacheId: &dto.CacheId{CacheName: e.GetCache(), Key: e.GetKey()},
+			TTL:     e.Ttl,
+			TTLs:    toEntryTtls(e.GetTtls()),
+		}
+		if err := entry.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s:%s: %v", e.GetCache(), e.GetKey(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	results := s.adapter.TouchAll(ctx, entries)
+	resp := &cachepb.TouchAllResponse{Results: make([]*cachepb.TouchItemResult, 0, len(results))}
+	for _, item := range results {
+		resp.Results = append(resp.Results, &cachepb.TouchItemResult{
+			Cache:   item.CacheName,
+			Key:     item.Key,
+			Touched: item.Touched,
+			Layers:  toPbLayers(item.Layers),
+		})
+	}
+	zap.S().Infow("processed grpc touch", "records", len(entries))
+	return resp, nil
+}
+
+func (s *cacheServer) EvictByTag(ctx context.Context, req *cachepb.EvictByTagRequest) (*cachepb.EvictByTagResponse, error) {
+	if err := dto.ValidateTag(req.GetTag()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	report := s.adapter.EvictByTag(ctx, req.GetTag())
+	resp := &cachepb.EvictByTagResponse{Tag: report.Tag, Layers: make([]*cachepb.TagLayerStatus, 0, len(report.Layers))}
+	for _, l := range report.Layers {
+		resp.Layers = append(resp.Layers, &cachepb.TagLayerStatus{
+			Layer:   int32(l.Layer),
+			Status:  l.Status,
+			Evicted: int32(l.Evicted),
+			Error:   l.Error,
+		})
+	}
+	zap.S().Infow("processed grpc evict by tag", "tag", req.GetTag())
+	return resp, nil
+}
+
+// queueError переводит отказ очереди write-behind в gRPC-статус.
+// ErrQueueFull и ErrQueueClosed — UNAVAILABLE: клиенту стоит повторить запрос позже.
+func queueError(err error) error {
+	zap.S().Warnw("write rejected", "error", err)
+	if errors.Is(err, manager.ErrQueueFull) || errors.Is(err, manager.ErrQueueClosed) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+/* ---------- преобразование типов ---------- */
+
+// orderHits возвращает результаты в порядке запрошенных ключей;
+// ключи без результата считаются не найденными.
+func orderHits(ids []*dto.CacheId, hits []*dto.CacheEntryHit) []*dto.CacheEntryHit {
+	byKey := make(map[dto.CacheId]*dto.CacheEntryHit, len(hits))
+	for _, h := range hits {
+		if h != nil && h.CacheEntry != nil && h.CacheEntry.CacheId != nil {
+			byKey[*h.CacheEntry.CacheId] = h
+		}
+	}
+
+	results := make([]*dto.CacheEntryHit, len(ids))
+	for i, id := range ids {
+		if hit, ok := byKey[*id]; ok {
+			results[i] = hit
+			continue
+		}
+		results[i] = &dto.CacheEntryHit{CacheEntry: &dto.CacheEntry{CacheId: id}, Found: false, Status: dto.HitStatusNotFound}
+	}
+	return results
+}
+
+// toGetOptions переносит параметры чтения запроса; противоречивые сочетания — InvalidArgument.
+func toGetOptions(req *cachepb.GetAllRequest) (dto.GetOptions, error) {
+	opts := dto.GetOptions{
+		Meta:        req.GetMeta(),
+		BypassCache: req.GetBypassCache(),
+		NoUpstream:  req.GetNoUpstream(),
+		MinLayer:    int(req.GetMinLayer()),
+		NoBackfill:  req.GetNoBackfill(),
+	}
+	if req.MaxLayer != nil {
+		maxLayer := int(req.GetMaxLayer())
+		opts.MaxLayer = &maxLayer
+	}
+	switch {
+	case opts.BypassCache && opts.NoUpstream:
+		return dto.GetOptions{}, status.Error(codes.InvalidArgument, "bypass_cache and no_upstream are mutually exclusive")
+	case opts.MinLayer < 0, opts.MaxLayer != nil && *opts.MaxLayer < opts.MinLayer:
+		return dto.GetOptions{}, status.Error(codes.InvalidArgument, "invalid layer range")
+	}
+	return opts, nil
+}
+
+func toCacheIds(ids []*cachepb.CacheId) []*dto.CacheId {
+	res := make([]*dto.CacheId, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, &dto.CacheId{CacheName: id.GetCache(), Key: id.GetKey()})
+	}
+	return res
+}
+
+func toPbHit(hit *dto.CacheEntryHit) *cachepb.CacheEntryHit {
+	res := &cachepb.CacheEntryHit{
+		Cache:  hit.CacheEntry.CacheId.CacheName,
+		Key:    hit.CacheEntry.CacheId.Key,
+		Found:  hit.Found,
+		Status: hit.Status,
+		Error:  hit.Error,
+	}
+	if hit.Found && hit.Value != nil {
+		res.Value = *hit.Value
+	}
+	if hit.Layer != nil {
+		layer := int32(*hit.Layer)
+		res.Layer = &layer
+	}
+	if hit.Meta != nil {
+		res.Meta = toPbMeta(hit.Meta)
+	}
+	return res
+}
+
+func toPbMeta(meta *dto.HitMeta) *cachepb.HitMeta {
+	res := &cachepb.HitMeta{
+		Layer:    int32(meta.Layer),
+		Provider: meta.Provider,
+		Upstream: meta.Upstream,
+		TtlMs:    meta.TtlMs,
+		Version:  meta.Version,
+	}
+	if meta.WrittenAt != nil {
+		writtenAt := meta.WrittenAt.UnixMilli()
+		res.WrittenAtMs = &writtenAt
+	}
+	return res
+}
+
+func toPbErrors(errs []*dto.UpstreamError) []*cachepb.UpstreamError {
+	res := make([]*cachepb.UpstreamError, 0, len(errs))
+	for _, e := range errs {
+		res = append(res, &cachepb.UpstreamError{Cache: e.CacheName, Key: e.Key, Error: e.Error})
+	}
+	return res
+}
+
+func toPbIds(ids []*dto.CacheId) []*cachepb.CacheId {
+	res := make([]*cachepb.CacheId, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, &cachepb.CacheId{Cache: id.CacheName, Key: id.Key})
+	}
+	return res
+}
+
+func toPbReport(report *dto.WriteReport) *cachepb.WriteResponse {
+	resp := &cachepb.WriteResponse{
+		Results: make([]*cachepb.WriteItemResult, 0, len(report.Results)),
+		Errors:  toPbErrors(report.Errors),
+	}
+	if len(report.Conflicts) > 0 {
+		resp.Conflicts = toPbIds(report.Conflicts)
+	}
+	for _, item := range report.Results {
+		resp.Results = append(resp.Results, &cachepb.WriteItemResult{Cache: item.CacheName, Key: item.Key, Layers: toPbLayers(item.Layers)})
+	}
+	return resp
+}
+
+func toPbLayers(statuses []*dto.LayerWriteStatus) []*cachepb.LayerWriteStatus {
+	res := make([]*cachepb.LayerWriteStatus, 0, len(statuses))
+	for _, l := range statuses {
+		res = append(res, &cachepb.LayerWriteStatus{Layer: int32(l.Layer), Status: l.Status, Error: l.Error})
+	}
+	return res
+}
@@ -0,0 +1,334 @@
+package grpcserver
+
+import (
+	"aur-cache-service/api/dto"
+	cachepb "aur-cache-service/api/grpc"
+	"aur-cache-service/internal/manager"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mockAdapter struct {
+	getAllCalled   [][]*dto.CacheId
+	putAllCalled   [][]*dto.CacheEntry
+	evictAllCalled [][]*dto.CacheId
+	touchCalled    [][]*dto.CacheEntry
+	tagCalled      []string
+	syncCalled     int
+
+	found      map[string]string
+	putFailed  []*dto.UpstreamError
+	writeErr   error
+	syncReport *dto.WriteReport
+}
+
+func (m *mockAdapter) Get(context.Context, *dto.CacheId) *dto.CacheEntryHit { return nil }
+func (m *mockAdapter) Put(context.Context, *dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	return nil, nil
+}
+func (m *mockAdapter) Evict(context.Context, *dto.CacheId) ([]*dto.UpstreamError, error) {
+	return nil, nil
+}
+
+// GetAll возвращает найденными ключи из found, в обратном порядке, чтобы проверить сортировку.
+func (m *mockAdapter) GetAll(_ context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit {
+	m.getAllCalled = append(m.getAllCalled, ids)
+	hits := make([]*dto.CacheEntryHit, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		if v, ok := m.found[ids[i].Key]; ok {
+			raw := json.RawMessage(v)
+			hits = append(hits, &dto.CacheEntryHit{CacheEntry: &dto.CacheEntry{CacheId: ids[i], Value: &raw}, Found: true})
+		}
+	}
+	return hits
+}
+
+func (m *mockAdapter) GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, _ dto.GetOptions) []*dto.CacheEntryHit {
+	return m.GetAll(ctx, ids)
+}
+
+func (m *mockAdapter) PutAll(_ context.Context, entries []*dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	m.putAllCalled = append(m.putAllCalled, entries)
+	return m.putFailed, m.writeErr
+}
+
+func (m *mockAdapter) EvictAll(_ context.Context, ids []*dto.CacheId) ([]*dto.UpstreamError, error) {
+	m.evictAllCalled = append(m.evictAllCalled, ids)
+	return nil, m.writeErr
+}
+
+func (m *mockAdapter) PutAllSync(context.Context, []*dto.CacheEntry) *dto.WriteReport {
+	m.syncCalled++
+	return m.syncReport
+}
+
+func (m *mockAdapter) EvictAllSync(context.Context, []*dto.CacheId) *dto.WriteReport {
+	m.syncCalled++
+	return m.syncReport
+}
+
+func (m *mockAdapter) TouchAll(_ context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult {
+	m.touchCalled = append(m.touchCalled, entries)
+	res := make([]*dto.TouchItemResult, 0, len(entries))
+	for _, e := range entries {
+		_, ok := m.found[e.Key]
+		layer := dto.LayerStatusMissing
+		if ok {
+			layer = dto.LayerStatusOk
+		}
+		res = append(res, &dto.TouchItemResult{
+			CacheId: e.CacheId,
+			Touched: ok,
+			Layers:  []*dto.LayerWriteStatus{{Layer: 0, Status: layer}},
+		})
+	}
+	return res
+}
+
+func (m *mockAdapter) EvictByTag(_ context.Context, tag string) *dto.TagEvictReport {
+	m.tagCalled = append(m.tagCalled, tag)
+	return &dto.TagEvictReport{Tag: tag, Layers: []*dto.TagLayerStatus{{Layer: 0, Status: dto.LayerStatusOk, Evicted: 2}}}
+}
+
+func newClient(t *testing.T, adapter manager.ManagerAdapter) cachepb.CacheServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(adapter)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return cachepb.NewCacheServiceClient(conn)
+}
+
+func TestGetAll(t *testing.T) {
+	adapter := &mockAdapter{found: map[string]string{"1": `{"name":"Ann"}`}}
+	client := newClient(t, adapter)
+
+	resp, err := client.GetAll(context.Background(), &cachepb.GetAllRequest{Requests: []*cachepb.CacheId{
+		{Cache: "user", Key: "1"}, {Cache: "user", Key: "2"},
+	}})
+	if err != nil {
+		t.Fatalf("get all: %v", err)
+	}
+	if len(resp.Results) != 2 || !resp.Results[0].Found || resp.Results[1].Found {
+		t.Fatalf("unexpected results: %v", resp.Results)
+	}
+	if string(resp.Results[0].Value) != `{"name":"Ann"}` || resp.Results[1].Key != "2" {
+		t.Fatalf("unexpected results: %v", resp.Results)
+	}
+	if resp.Results[1].GetStatus() != dto.HitStatusNotFound {
+		t.Fatalf("status=%q", resp.Results[1].GetStatus())
+	}
+}
+
+func TestGetAll_Empty(t *testing.T) {
+	client := newClient(t, &mockAdapter{})
+	_, err := client.GetAll(context.Background(), &cachepb.GetAllRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("code=%v", status.Code(err))
+	}
+}
+
+func TestGetAll_ConflictingOptions(t *testing.T) {
+	client := newClient(t, &mockAdapter{})
+	_, err := client.GetAll(context.Background(), &cachepb.GetAllRequest{
+		Requests:    []*cachepb.CacheId{{Cache: "user", Key: "1"}},
+		BypassCache: true,
+		NoUpstream:  true,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("code=%v", status.Code(err))
+	}
+}
+
+func TestStreamGetAll(t *testing.T) {
+	adapter := &mockAdapter{found: map[string]string{"0": `0`}}
+	client := newClient(t, adapter)
+
+	total := streamChunkSize*2 + 1
+	req := &cachepb.GetAllRequest{}
+	for i := 0; i < total; i++ {
+		req.Requests = append(req.Requests, &cachepb.CacheId{Cache: "c", Key: strconv.Itoa(i)})
+	}
+	stream, err := client.StreamGetAll(context.Background(), req)
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+
+	received := 0
+	for {
+		hit, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("recv: %v", err)
+		}
+		if hit.Key != strconv.Itoa(received) {
+			t.Fatalf("out of order: got %s at %d", hit.Key, received)
+		}
+		received++
+	}
+	if received != total {
+		t.Fatalf("received=%d", received)
+	}
+	if len(adapter.getAllCalled) != 3 {
+		t.Fatalf("chunks=%d", len(adapter.getAllCalled))
+	}
+}
+
+func TestPutAll(t *testing.T) {
+	adapter := &mockAdapter{putFailed: []*dto.UpstreamError{{CacheId: &dto.CacheId{CacheName: "c", Key: "2"}, Error: "boom"}}}
+	client := newClient(t, adapter)
+
+	resp, err := client.PutAll(context.Background(), &cachepb.PutAllRequest{Requests: []*cachepb.CacheEntry{
+		{Cache: "c", Key: "1", Value: []byte(`1`)},
+		{Cache: "c", Key: "2", Value: []byte(`2`), Tags: []string{"tenant:42"}},
+	}})
+	if err != nil {
+		t.Fatalf("put all: %v", err)
+	}
+	if len(adapter.putAllCalled) != 1 || len(adapter.putAllCalled[0]) != 2 {
+		t.Fatalf("putAll not called")
+	}
+	if tags := adapter.putAllCalled[0][1].Tags; len(tags) != 1 || tags[0] != "tenant:42" {
+		t.Fatalf("tags not passed: %v", tags)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Key != "2" {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+}
+
+func TestPutAll_InvalidJSON(t *testing.T) {
+	adapter := &mockAdapter{}
+	client := newClient(t, adapter)
+
+	_, err := client.PutAll(context.Background(), &cachepb.PutAllRequest{Requests: []*cachepb.CacheEntry{
+		{Cache: "c", Key: "1", Value: []byte(`not json`)},
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("code=%v", status.Code(err))
+	}
+	if len(adapter.putAllCalled) != 0 {
+		t.Fatalf("putAll should not be called")
+	}
+}
+
+func TestPutAll_Conflict(t *testing.T) {
+	adapter := &mockAdapter{writeErr: &manager.ConflictError{Keys: []*dto.CacheId{{CacheName: "c", Key: "2"}}}}
+	client := newClient(t, adapter)
+
+	resp, err := client.PutAll(context.Background(), &cachepb.PutAllRequest{Requests: []*cachepb.CacheEntry{
+		{Cache: "c", Key: "1", Value: []byte(`1`), IfAbsent: true},
+		{Cache: "c", Key: "2", Value: []byte(`2`), IfVersion: "42"},
+	}})
+	if err != nil {
+		t.Fatalf("put all: %v", err)
+	}
+	entries := adapter.putAllCalled[0]
+	if !entries[0].IfAbsent || entries[1].IfVersion != "42" {
+		t.Fatalf("conditions not passed: %+v %+v", entries[0], entries[1])
+	}
+	if len(resp.Conflicts) != 1 || resp.Conflicts[0].Key != "2" {
+		t.Fatalf("unexpected conflicts: %v", resp.Conflicts)
+	}
+}
+
+func TestPutAll_QueueFull(t *testing.T) {
+	client := newClient(t, &mockAdapter{writeErr: manager.ErrQueueFull})
+	_, err := client.PutAll(context.Background(), &cachepb.PutAllRequest{Requests: []*cachepb.CacheEntry{
+		{Cache: "c", Key: "1", Value: []byte(`1`)},
+	}})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("code=%v", status.Code(err))
+	}
+}
+
+func TestEvictAll_Sync(t *testing.T) {
+	adapter := &mockAdapter{syncReport: &dto.WriteReport{Results: []*dto.WriteItemResult{{
+		CacheId: &dto.CacheId{CacheName: "c", Key: "1"},
+		Layers:  []*dto.LayerWriteStatus{{Layer: 0, Status: dto.LayerStatusOk}},
+	}}}}
+	client := newClient(t, adapter)
+
+	resp, err := client.EvictAll(context.Background(), &cachepb.EvictAllRequest{
+		Requests: []*cachepb.CacheId{{Cache: "c", Key: "1"}},
+		Sync:     true,
+	})
+	if err != nil {
+		t.Fatalf("evict all: %v", err)
+	}
+	if adapter.syncCalled != 1 || len(adapter.evictAllCalled) != 0 {
+		t.Fatalf("sync path not used")
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Layers[0].Status != dto.LayerStatusOk {
+		t.Fatalf("unexpected results: %v", resp.Results)
+	}
+}
+
+func TestTouchAll(t *testing.T) {
+	adapter := &mockAdapter{found: map[string]string{"1": `1`}}
+	client := newClient(t, adapter)
+
+	ttl := int64(60)
+	resp, err := client.TouchAll(context.Background(), &cachepb.TouchAllRequest{Requests: []*cachepb.CacheEntry{
+		{Cache: "c", Key: "1", Ttl: &ttl},
+		{Cache: "c", Key: "2", Ttls: []int64{0, 30}},
+	}})
+	if err != nil {
+		t.Fatalf("touch all: %v", err)
+	}
+	entries := adapter.touchCalled[0]
+	if *entries[0].TTL != 60 || entries[1].TTLs[0] != nil || *entries[1].TTLs[1] != 30 {
+		t.Fatalf("ttl not passed: %+v %+v", entries[0], entries[1])
+	}
+	if len(resp.Results) != 2 || !resp.Results[0].Touched || resp.Results[1].Touched {
+		t.Fatalf("unexpected results: %v", resp.Results)
+	}
+	if resp.Results[1].Layers[0].Status != dto.LayerStatusMissing {
+		t.Fatalf("unexpected layers: %v", resp.Results[1].Layers)
+	}
+
+	_, err = client.TouchAll(context.Background(), &cachepb.TouchAllRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("code=%v", status.Code(err))
+	}
+}
+
+func TestEvictByTag(t *testing.T) {
+	adapter := &mockAdapter{}
+	client := newClient(t, adapter)
+
+	resp, err := client.EvictByTag(context.Background(), &cachepb.EvictByTagRequest{Tag: "tenant:42"})
+	if err != nil {
+		t.Fatalf("evict by tag: %v", err)
+	}
+	if len(adapter.tagCalled) != 1 || adapter.tagCalled[0] != "tenant:42" {
+		t.Fatalf("unexpected tags: %v", adapter.tagCalled)
+	}
+	if resp.Tag != "tenant:42" || len(resp.Layers) != 1 || resp.Layers[0].Evicted != 2 || resp.Layers[0].Status != dto.LayerStatusOk {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+
+	_, err = client.EvictByTag(context.Background(), &cachepb.EvictByTagRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("code=%v", status.Code(err))
+	}
+}
@@ -0,0 +1,174 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"aur-cache-service/internal/manager"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// Административные эндпоинты: операции над кэшем целиком, а не над отдельными ключами.
+const (
+	baseAdminPath       = "/api/v1/admin"                              // Базовый путь административных эндпоинтов
+	cacheFlushPath      = baseAdminPath + "/caches/{cache}/flush"      // POST|GET|DELETE /api/v1/admin/caches/{cache}/flush - очистка кэша
+	cacheGenerationPath = baseAdminPath + "/caches/{cache}/generation" // POST|GET /api/v1/admin/caches/{cache}/generation - поколение кэша
+	cacheKeysPath       = baseAdminPath + "/caches/{cache}/keys"       // GET /api/v1/admin/caches/{cache}/keys - ключи кэша на слое
+	queryPrefix         = "prefix"                                     // Параметр flush: очистить только ключи с префиксом
+	querySweep          = "sweep"                                      // Параметр generation: удалить записи предыдущего поколения
+	queryLayer          = "layer"                                      // Параметр keys: номер слоя (обязателен)
+	queryCursor         = "cursor"                                     // Параметр keys: курсор следующей страницы
+	queryMatch          = "match"                                      // Параметр keys: glob-шаблон ключа
+	queryCount          = "count"                                      // Параметр keys: ключей на странице
+)
+
+// cacheNameFromPath извлекает имя кэша из пути /api/v1/admin/caches/{cache}/...
+func cacheNameFromPath(r *http.Request) (string, error) {
+	return url.PathUnescape(chi.URLParam(r, "cache"))
+}
+
+// handleFlush запускает фоновую очистку кэша (?prefix= — только ключей с префиксом) и отвечает 202
+// с состоянием задачи; 404 — кэш не настроен, 409 — очистка уже идёт (в теле — её состояние).
+func handleFlush(w http.ResponseWriter, r *http.Request, admin manager.AdminAdapter) {
+	cacheName, err := cacheNameFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	job, err := admin.FlushCache(cacheName, r.URL.Query().Get(queryPrefix))
+	switch {
+	case errors.Is(err, manager.ErrUnknownCache):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, manager.ErrFlushRunning):
+		writeBody(w, r, http.StatusConflict, job)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		zap.S().Infow("accepted cache flush", "cache", cacheName, "prefix", job.Prefix)
+		writeBody(w, r, http.StatusAccepted, job)
+	}
+}
+
+// handleFlushStatus отдаёт состояние последней очистки кэша или 404, если очисток не было.
+func handleFlushStatus(w http.ResponseWriter, r *http.Request, admin manager.AdminAdapter) {
+	cacheName, err := cacheNameFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	job := admin.FlushStatus(cacheName)
+	if job == nil {
+		http.Error(w, "flush not found", http.StatusNotFound)
+		return
+	}
+	writeBody(w, r, http.StatusOK, job)
+}
+
+// handleFlushCancel отменяет очистку кэша и отвечает 202 с её состоянием; 404 — очисток не было.
+func handleFlushCancel(w http.ResponseWriter, r *http.Request, admin manager.AdminAdapter) {
+	cacheName, err := cacheNameFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	job := admin.CancelFlush(cacheName)
+	if job == nil {
+		http.Error(w, "flush not found", http.StatusNotFound)
+		return
+	}
+	zap.S().Infow("cancelled cache flush", "cache", cacheName)
+	writeBody(w, r, http.StatusAccepted, job)
+}
+
+// handleGeneration отдаёт текущее поколение кэша; 404 — кэш не настроен, 400 — поколения для него не включены.
+func handleGeneration(w http.ResponseWriter, r *http.Request, admin manager.AdminAdapter) {
+	cacheName, err := cacheNameFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	gen, err := admin.CacheGeneration(cacheName)
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	writeBody(w, r, http.StatusOK, gen)
+}
+
+// handleBumpGeneration увеличивает поколение кэша (?sweep=true — и запускает очистку записей
+// предыдущего поколения) и отвечает 200 с новым поколением.
+func handleBumpGeneration(w http.ResponseWriter, r *http.Request, admin manager.AdminAdapter) {
+	cacheName, err := cacheNameFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var sweep bool
+	if value := r.URL.Query().Get(querySweep); value != "" {
+		if sweep, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %q", querySweep, value), http.StatusBadRequest)
+			return
+		}
+	}
+	gen, err := admin.BumpGeneration(r.Context(), cacheName, sweep)
+	if err != nil {
+		writeGenerationError(w, err)
+		return
+	}
+	zap.S().Infow("bumped cache generation", "cache", cacheName, "generation", gen.Generation, "sweep", gen.Sweep != nil)
+	writeBody(w, r, http.StatusOK, gen)
+}
+
+func writeGenerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, manager.ErrUnknownCache):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, manager.ErrGenerationsDisabled):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleKeys отдаёт страницу ключей кэша на слое ?layer= (?match= — glob-шаблон ключа, ?cursor= — курсор
+// из предыдущего ответа, ?count= — размер страницы). 404 — кэш не настроен, 400 — неверные параметры
+// или курсор, 501 — слой не умеет перечислять ключи.
+func handleKeys(w http.ResponseWriter, r *http.Request, admin manager.AdminAdapter) {
+	cacheName, err := cacheNameFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	layer, err := strconv.Atoi(query.Get(queryLayer))
+	if err != nil || layer < 0 {
+		http.Error(w, fmt.Sprintf("invalid %s: %q", queryLayer, query.Get(queryLayer)), http.StatusBadRequest)
+		return
+	}
+	var count int
+	if value := query.Get(queryCount); value != "" {
+		if count, err = strconv.Atoi(value); err != nil || count <= 0 {
+			http.Error(w, fmt.Sprintf("invalid %s: %q", queryCount, value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := admin.ScanKeys(r.Context(), cacheName, layer, query.Get(queryMatch), query.Get(queryCursor), count)
+	switch {
+	case errors.Is(err, manager.ErrUnknownCache):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, manager.ErrUnknownLayer), errors.Is(err, manager.ErrInvalidCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, manager.ErrScanUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		writeBody(w, r, http.StatusOK, page)
+	}
+}
@@ -0,0 +1,265 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"aur-cache-service/api/dto"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	contentTypeMsgpack    = "application/msgpack"   // MIME-тип для MessagePack
+	contentTypeMsgpackAlt = "application/x-msgpack" // Распространённый синоним application/msgpack
+	contentTypeCBOR       = "application/cbor"      // MIME-тип для CBOR
+	headerAccept          = "Accept"                // HTTP заголовок с форматами, которые принимает клиент
+)
+
+// codec — формат тела запроса и ответа. Имена полей во всех форматах совпадают с JSON
+// (msgpack и CBOR используют json-теги структур).
+type codec struct {
+	contentType string
+	decode      func(r io.Reader, v interface{}) error
+	encode      func(w io.Writer, v interface{}) error
+}
+
+var (
+	jsonCodec = &codec{
+		contentType: contentTypeJSON,
+		decode:      func(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) },
+		encode:      func(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) },
+	}
+	msgpackCodec = &codec{
+		contentType: contentTypeMsgpack,
+		decode: func(r io.Reader, v interface{}) error {
+			dec := msgpack.NewDecoder(r)
+			dec.SetCustomStructTag("json")
+			return dec.Decode(v)
+		},
+		encode: func(w io.Writer, v interface{}) error {
+			enc := msgpack.NewEncoder(w)
+			enc.SetCustomStructTag("json")
+			return enc.Encode(v)
+		},
+	}
+	cborCodec = &codec{
+		contentType: contentTypeCBOR,
+		decode:      func(r io.Reader, v interface{}) error { return cbor.NewDecoder(r).Decode(v) },
+		encode:      func(w io.Writer, v interface{}) error { return cbor.NewEncoder(w).Encode(v) },
+	}
+)
+
+func codecByMediaType(mediaType string) *codec {
+	switch mediaType {
+	case contentTypeJSON:
+		return jsonCodec
+	case contentTypeMsgpack, contentTypeMsgpackAlt:
+		return msgpackCodec
+	case contentTypeCBOR:
+		return cborCodec
+	}
+	return nil
+}
+
+// requestCodec возвращает формат тела запроса по Content-Type или nil, если формат не поддерживается.
+func requestCodec(r *http.Request) *codec {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+	return codecByMediaType(mediaType)
+}
+
+// responseCodec выбирает формат ответа по Accept: первый поддерживаемый из перечисленных.
+// Без Accept (или с */*) ответ отдаётся в формате запроса, для запросов без тела — в JSON.
+func responseCodec(r *http.Request) *codec {
+	for _, part := range strings.Split(r.Header.Get(headerAccept), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if c := codecByMediaType(mediaType); c != nil {
+			return c
+		}
+	}
+	if c := requestCodec(r); c != nil {
+		return c
+	}
+	return jsonCodec
+}
+
+/* ---------- значения ---------- */
+
+// value — значение кэша в теле запроса или ответа. Внутри сервиса и в слоях кэша
+// значения хранятся в JSON независимо от формата запроса; для msgpack и CBOR значение
+// перекодируется на границе HTTP, поэтому записанное в одном формате читается в любом.
+type value json.RawMessage
+
+func (v value) MarshalJSON() ([]byte, error) {
+	return json.RawMessage(v).MarshalJSON()
+}
+
+func (v *value) UnmarshalJSON(data []byte) error {
+	*v = append((*v)[:0], data...)
+	return nil
+}
+
+func (v value) EncodeMsgpack(enc *msgpack.Encoder) error {
+	native, err := fromJSON(v)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(native)
+}
+
+func (v *value) DecodeMsgpack(dec *msgpack.Decoder) error {
+	native, err := dec.DecodeInterface()
+	if err != nil {
+		return err
+	}
+	return v.set(native)
+}
+
+func (v value) MarshalCBOR() ([]byte, error) {
+	native, err := fromJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(native)
+}
+
+func (v *value) UnmarshalCBOR(data []byte) error {
+	var native interface{}
+	if err := cbor.Unmarshal(data, &native); err != nil {
+		return err
+	}
+	return v.set(native)
+}
+
+// set сохраняет значение, декодированное из msgpack или CBOR, в JSON.
+// Двоичные строки становятся строками base64, ключи словарей — строками.
+func (v *value) set(native interface{}) error {
+	data, err := json.Marshal(toJSONCompatible(native))
+	if err != nil {
+		return fmt.Errorf("value cannot be represented as JSON: %w", err)
+	}
+	*v = data
+	return nil
+}
+
+func toJSONCompatible(x interface{}) interface{} {
+	switch t := x.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = toJSONCompatible(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = toJSONCompatible(e)
+		}
+		return t
+	case []interface{}:
+		for i, e := range t {
+			t[i] = toJSONCompatible(e)
+		}
+		return t
+	}
+	return x
+}
+
+// fromJSON декодирует JSON в значения Go для кодирования в msgpack или CBOR.
+// Целые числа остаются целыми, остальные числа становятся float64.
+func fromJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var native interface{}
+	if err := dec.Decode(&native); err != nil {
+		return nil, err
+	}
+	return fromJSONNumbers(native), nil
+}
+
+func fromJSONNumbers(x interface{}) interface{} {
+	switch t := x.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(t), 10, 64); err == nil {
+			return u
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = fromJSONNumbers(e)
+		}
+	}
+	return x
+}
+
+/* ---------- элементы запросов и ответов ---------- */
+
+// wireEntry — элемент запроса put_all (dto.CacheEntry со значением в формате запроса).
+type wireEntry struct {
+	*dto.CacheId
+	Value     *value   `json:"v"`
+	TTL       *int64   `json:"ttl,omitempty"`
+	TTLs      []*int64 `json:"ttls,omitempty"`
+	IfAbsent  bool     `json:"ifAbsent,omitempty"`
+	IfVersion string   `json:"ifVersion,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+func (e *wireEntry) toEntry() *dto.CacheEntry {
+	return &dto.CacheEntry{
+		CacheId:   e.CacheId,
+		Value:     (*json.RawMessage)(e.Value),
+		TTL:       e.TTL,
+		TTLs:      e.TTLs,
+		IfAbsent:  e.IfAbsent,
+		IfVersion: e.IfVersion,
+		Tags:      e.Tags,
+	}
+}
+
+// wireHit — элемент ответа get_all (dto.CacheEntryHit со значением в формате ответа).
+type wireHit struct {
+	*dto.CacheId
+	Value  *value       `json:"v"`
+	Found  bool         `json:"f"`
+	Status string       `json:"status,omitempty"`
+	Layer  *int         `json:"layer,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Meta   *dto.HitMeta `json:"meta,omitempty"`
+}
+
+func toWireHits(hits []*dto.CacheEntryHit) []*wireHit {
+	res := make([]*wireHit, len(hits))
+	for i, h := range hits {
+		res[i] = &wireHit{
+			CacheId: h.CacheEntry.CacheId,
+			Value:   (*value)(h.CacheEntry.Value),
+			Found:   h.Found,
+			Status:  h.Status,
+			Layer:   h.Layer,
+			Error:   h.Error,
+			Meta:    h.Meta,
+		}
+	}
+	return res
+}
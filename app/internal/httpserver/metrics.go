@@ -6,8 +6,13 @@ import (
 	"time"
 
 	"aur-cache-service/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// unmatchedRoute — метка path для запросов, не попавших ни в один маршрут.
+const unmatchedRoute = "unmatched"
+
 // statusRecorder wraps http.ResponseWriter to capture response status code.
 type statusRecorder struct {
 	http.ResponseWriter
@@ -19,15 +24,34 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap gives http.ResponseController access to Flush and EnableFullDuplex
+// of the underlying writer (used by the streaming endpoints).
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // MetricsMiddleware collects Prometheus metrics for each HTTP request.
+// The path label is the chi route pattern (e.g. /api/v1/cache/{cache}/{key}),
+// so that keys in the URL do not blow up label cardinality.
 func MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 		start := time.Now()
 		next.ServeHTTP(rec, req)
 		duration := time.Since(start).Seconds()
-		path := req.URL.Path
+		path := routePattern(req)
 		metrics.HTTPRequestsTotal.WithLabelValues(path, req.Method, strconv.Itoa(rec.status)).Inc()
 		metrics.HTTPRequestDuration.WithLabelValues(path, req.Method).Observe(duration)
 	})
 }
+
+func routePattern(req *http.Request) string {
+	rctx := chi.RouteContext(req.Context())
+	if rctx == nil {
+		return req.URL.Path
+	}
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+	return unmatchedRoute
+}
@@ -5,9 +5,13 @@ import (
 	"aur-cache-service/internal/manager"
 	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -18,19 +22,35 @@ import (
 )
 
 const (
-	maxBodySize           = 5 << 20                    // Максимальный размер тела запроса: 5 МБ (5 * 2^20 байт)
-	gzipThreshold         = 500                        // Минимальный размер ответа для сжатия gzip: 500 байт
-	baseAPIPath           = "/api/v1/cache"            // Базовый путь для всех API эндпоинтов
-	getAllPath            = baseAPIPath + "/get_all"   // POST /api/v1/cache/get_all - массовое получение
-	putAllPath            = baseAPIPath + "/put_all"   // POST /api/v1/cache/put_all - массовое сохранение
-	evictAllPath          = baseAPIPath + "/evict_all" // POST /api/v1/cache/evict_all - массовое удаление
-	contentTypeJSON       = "application/json"         // MIME-тип для JSON
-	headerContentEncoding = "Content-Encoding"         // HTTP заголовок для указания кодировки
-	headerAcceptEncoding  = "Accept-Encoding"          // HTTP заголовок с поддерживаемыми кодировками
-	headerVary            = "Vary"                     // HTTP заголовок для указания зависимости от других заголовков
-	encodingGzip          = "gzip"                     // Название gzip кодировки
-	metricsPath           = "/metrics"                 // Путь для метрик Prometheus
-	metricsHealthPath     = "/metrics/health"          // Путь для проверки состояния
+	maxBodySize           = 5 << 20                        // Максимальный размер тела запроса: 5 МБ (5 * 2^20 байт)
+	gzipThreshold         = 500                            // Минимальный размер ответа для сжатия gzip: 500 байт
+	baseAPIPath           = "/api/v1/cache"                // Базовый путь для всех API эндпоинтов
+	getAllPath            = baseAPIPath + "/get_all"       // POST /api/v1/cache/get_all - массовое получение
+	putAllPath            = baseAPIPath + "/put_all"       // POST /api/v1/cache/put_all - массовое сохранение
+	evictAllPath          = baseAPIPath + "/evict_all"     // POST /api/v1/cache/evict_all - массовое удаление
+	touchAllPath          = baseAPIPath + "/touch_all"     // POST /api/v1/cache/touch_all - массовое продление TTL
+	evictByTagPath        = baseAPIPath + "/evict_by_tag"  // POST /api/v1/cache/evict_by_tag - удаление по тегу
+	keyPath               = baseAPIPath + "/{cache}/{key}" // GET|PUT|DELETE /api/v1/cache/{cache}/{key} - один ключ
+	contentTypeJSON       = "application/json"             // MIME-тип для JSON
+	headerContentEncoding = "Content-Encoding"             // HTTP заголовок для указания кодировки
+	headerAcceptEncoding  = "Accept-Encoding"              // HTTP заголовок с поддерживаемыми кодировками
+	headerVary            = "Vary"                         // HTTP заголовок для указания зависимости от других заголовков
+	headerRetryAfter      = "Retry-After"                  // HTTP заголовок с рекомендуемой паузой перед повтором
+	retryAfterSeconds     = "1"                            // Пауза перед повтором при заполненной очереди записи
+	headerSync            = "X-Cache-Sync"                 // HTTP заголовок синхронной записи (аналог ?sync=true)
+	querySync             = "sync"                         // Параметр запроса синхронной записи
+	queryMeta             = "meta"                         // Параметр get_all: вернуть метаданные найденных значений
+	queryBypassCache      = "bypassCache"                  // Параметр get_all: читать из внешнего API в обход слоёв
+	queryNoUpstream       = "noUpstream"                   // Параметр get_all: читать только слои кэша
+	queryMinLayer         = "minLayer"                     // Параметр get_all: первый читаемый слой
+	queryMaxLayer         = "maxLayer"                     // Параметр get_all: последний читаемый слой
+	queryNoBackfill       = "noBackfill"                   // Параметр get_all: не дозаписывать верхние слои
+	headerETag            = "ETag"                         // HTTP заголовок с версией значения
+	headerIfMatch         = "If-Match"                     // HTTP заголовок условной записи по версии
+	headerIfNoneMatch     = "If-None-Match"                // HTTP заголовок условной записи (* — только если ключа нет)
+	encodingGzip          = "gzip"                         // Название gzip кодировки
+	metricsPath           = "/metrics"                     // Путь для метрик Prometheus
+	metricsHealthPath     = "/metrics/health"              // Путь для проверки состояния
 )
 
 func NewMetricRouter() http.Handler {
@@ -47,27 +67,77 @@ func NewMetricRouter() http.Handler {
 	return metric_router
 }
 
-// NewRouter возвращает http.Handler с зарегистрированными эндпоинтами.
-func NewRouter(adapter manager.ManagerAdapter) http.Handler {
+// NewRouter возвращает http.Handler с зарегистрированными эндпоинтами;
+// административные эндпоинты (/api/v1/admin) обслуживает admin.
+func NewRouter(adapter manager.ManagerAdapter, admin manager.AdminAdapter) http.Handler {
 	api_router := chi.NewRouter()
-
-	api_router.Use(limitBody(maxBodySize))
-	api_router.Use(decompressGzip)
-	api_router.Use(compressGzip(gzipThreshold))
 	api_router.Use(MetricsMiddleware)
 
-	api_router.Post(getAllPath, func(w http.ResponseWriter, r *http.Request) {
-		handleBatchGet(w, r, adapter)
-	})
-	api_router.Post(putAllPath, func(w http.ResponseWriter, r *http.Request) {
-		handleBatchPut(w, r, adapter)
-	})
-	api_router.Post(evictAllPath, func(w http.ResponseWriter, r *http.Request) {
-		handleBatchDelete(w, r, adapter)
+	api_router.Group(func(group chi.Router) {
+		group.Use(limitBody(maxBodySize))
+		group.Use(decompressGzip)
+		group.Use(compressGzip(gzipThreshold))
+
+		group.Post(getAllPath, func(w http.ResponseWriter, r *http.Request) {
+			handleBatchGet(w, r, adapter)
+		})
+		group.Post(putAllPath, func(w http.ResponseWriter, r *http.Request) {
+			handleBatchPut(w, r, adapter)
+		})
+		group.Post(evictAllPath, func(w http.ResponseWriter, r *http.Request) {
+			handleBatchDelete(w, r, adapter)
+		})
+		group.Post(touchAllPath, func(w http.ResponseWriter, r *http.Request) {
+			handleBatchTouch(w, r, adapter)
+		})
+		group.Post(evictByTagPath, func(w http.ResponseWriter, r *http.Request) {
+			handleEvictByTag(w, r, adapter)
+		})
+
+		group.Post(cacheFlushPath, func(w http.ResponseWriter, r *http.Request) {
+			handleFlush(w, r, admin)
+		})
+		group.Get(cacheFlushPath, func(w http.ResponseWriter, r *http.Request) {
+			handleFlushStatus(w, r, admin)
+		})
+		group.Delete(cacheFlushPath, func(w http.ResponseWriter, r *http.Request) {
+			handleFlushCancel(w, r, admin)
+		})
+		group.Post(cacheGenerationPath, func(w http.ResponseWriter, r *http.Request) {
+			handleBumpGeneration(w, r, admin)
+		})
+		group.Get(cacheGenerationPath, func(w http.ResponseWriter, r *http.Request) {
+			handleGeneration(w, r, admin)
+		})
+		group.Get(cacheKeysPath, func(w http.ResponseWriter, r *http.Request) {
+			handleKeys(w, r, admin)
+		})
+
+		group.Get(keyPath, func(w http.ResponseWriter, r *http.Request) {
+			handleGet(w, r, adapter)
+		})
+		group.Put(keyPath, func(w http.ResponseWriter, r *http.Request) {
+			handlePut(w, r, adapter)
+		})
+		group.Delete(keyPath, func(w http.ResponseWriter, r *http.Request) {
+			handleDelete(w, r, adapter)
+		})
 	})
 
-	// ранее здесь регистрировались одиночные операции GET, PUT и DELETE.
-	// Они убраны, чтобы оставались только batch эндпоинты.
+	// потоковые эндпоинты: без ограничения тела и без буферизации ответа для gzip
+	api_router.Group(func(group chi.Router) {
+		group.Use(decompressGzip)
+
+		group.Post(streamGetAllPath, func(w http.ResponseWriter, r *http.Request) {
+			handleStreamGet(w, r, adapter)
+		})
+		group.Post(streamPutAllPath, func(w http.ResponseWriter, r *http.Request) {
+			handleStreamPut(w, r, adapter)
+		})
+		group.Post(streamEvictAllPath, func(w http.ResponseWriter, r *http.Request) {
+			handleStreamDelete(w, r, adapter)
+		})
+	})
 
 	return api_router
 }
@@ -82,14 +152,15 @@ type batchDeleteRequest struct {
 
 func handleBatchGet(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
 	defer r.Body.Close()
-	if !strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeJSON) {
+	in := requestCodec(r)
+	if in == nil {
 		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
 		return
 	}
 	var req struct {
 		Requests []dto.CacheId `json:"requests"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := in.decode(r.Body, &req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -98,12 +169,26 @@ func handleBatchGet(w http.ResponseWriter, r *http.Request, adapter manager.Mana
 		return
 	}
 
+	opts, err := getOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	ids := make([]*dto.CacheId, len(req.Requests))
 	for i := range req.Requests {
 		ids[i] = &req.Requests[i]
 	}
-	hits := adapter.GetAll(r.Context(), ids)
+	results := orderHits(ids, adapter.GetAllWithOptions(r.Context(), ids, opts))
+
+	zap.S().Infow("processed batch get", "req", len(req.Requests), "results", len(results))
+
+	writeBody(w, r, http.StatusOK, map[string]interface{}{"results": toWireHits(results)})
+}
 
+// orderHits раскладывает результаты GetAll в порядке запрошенных ids;
+// для ключей без результата возвращается промах.
+func orderHits(ids []*dto.CacheId, hits []*dto.CacheEntryHit) []*dto.CacheEntryHit {
 	type cacheKey struct {
 		cache string
 		key   string
@@ -125,28 +210,24 @@ func handleBatchGet(w http.ResponseWriter, r *http.Request, adapter manager.Mana
 			results[i] = &dto.CacheEntryHit{
 				CacheEntry: &dto.CacheEntry{CacheId: id, Value: nil},
 				Found:      false,
+				Status:     dto.HitStatusNotFound,
 			}
 		}
 	}
-
-	zap.S().Infow("processed batch get", "req", len(req.Requests), "results", len(results))
-
-	w.Header().Set("Content-Type", contentTypeJSON)
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{"results": results}); err != nil {
-		zap.S().Errorw(alert.Prefix("encode error"), "error", err)
-	}
+	return results
 }
 
 func handleBatchPut(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
 	defer r.Body.Close()
-	if !strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeJSON) {
+	in := requestCodec(r)
+	if in == nil {
 		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
 		return
 	}
 	var req struct {
-		Requests []dto.CacheEntry `json:"requests"`
+		Requests []wireEntry `json:"requests"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := in.decode(r.Body, &req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -157,23 +238,143 @@ func handleBatchPut(w http.ResponseWriter, r *http.Request, adapter manager.Mana
 
 	entries := make([]*dto.CacheEntry, len(req.Requests))
 	for i := range req.Requests {
-		entries[i] = &req.Requests[i]
+		entries[i] = req.Requests[i].toEntry()
+		if err := entries[i].Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("requests[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+	if isSync(r) {
+		report := adapter.PutAllSync(r.Context(), entries)
+		zap.S().Infow("processed sync batch put", "records", len(req.Requests), "upstreamFailed", len(report.Errors))
+		writeReport(w, r, report)
+		return
+	}
+	failed, err := adapter.PutAll(r.Context(), entries)
+	var conflict *manager.ConflictError
+	if errors.As(err, &conflict) {
+		// условие не выполнено для части ключей; остальные записи приняты
+		writeBody(w, r, http.StatusConflict, map[string]interface{}{"conflicts": conflict.Keys, "errors": failed})
+		return
+	}
+	if err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	zap.S().Infow("processed batch put", "records", len(req.Requests), "upstreamFailed", len(failed))
+	if len(failed) > 0 {
+		// внешний API не принял часть записей: слои кэша для них не изменены
+		writeBody(w, r, http.StatusBadGateway, map[string]interface{}{"errors": failed})
+		return
 	}
-	adapter.PutAll(r.Context(), entries)
-	zap.S().Infow("processed batch put", "records", len(req.Requests))
 	w.WriteHeader(http.StatusOK)
 }
 
+// isSync сообщает, что клиент ждёт записи в слои кэша до ответа (?sync=true или X-Cache-Sync: true).
+func isSync(r *http.Request) bool {
+	value := r.URL.Query().Get(querySync)
+	if value == "" {
+		value = r.Header.Get(headerSync)
+	}
+	enabled, _ := strconv.ParseBool(value)
+	return enabled
+}
+
+// getOptions разбирает параметры чтения get_all (?meta, ?bypassCache, ?noUpstream,
+// ?minLayer, ?maxLayer, ?noBackfill). Невалидные значения и противоречивые сочетания — ошибка.
+func getOptions(r *http.Request) (opts dto.GetOptions, err error) {
+	query := r.URL.Query()
+	parseBool := func(name string) bool {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return false
+		}
+		enabled, parseErr := strconv.ParseBool(value)
+		if parseErr != nil {
+			err = fmt.Errorf("invalid %s: %q", name, value)
+		}
+		return enabled
+	}
+	parseLayer := func(name string) *int {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return nil
+		}
+		layer, parseErr := strconv.Atoi(value)
+		if parseErr != nil || layer < 0 {
+			err = fmt.Errorf("invalid %s: %q", name, value)
+			return nil
+		}
+		return &layer
+	}
+
+	opts.Meta = parseBool(queryMeta)
+	opts.BypassCache = parseBool(queryBypassCache)
+	opts.NoUpstream = parseBool(queryNoUpstream)
+	opts.NoBackfill = parseBool(queryNoBackfill)
+	if minLayer := parseLayer(queryMinLayer); minLayer != nil {
+		opts.MinLayer = *minLayer
+	}
+	opts.MaxLayer = parseLayer(queryMaxLayer)
+	if err != nil {
+		return dto.GetOptions{}, err
+	}
+
+	if opts.BypassCache && opts.NoUpstream {
+		return dto.GetOptions{}, fmt.Errorf("%s and %s are mutually exclusive", queryBypassCache, queryNoUpstream)
+	}
+	if opts.MaxLayer != nil && *opts.MaxLayer < opts.MinLayer {
+		return dto.GetOptions{}, fmt.Errorf("%s must not be less than %s", queryMaxLayer, queryMinLayer)
+	}
+	return opts, nil
+}
+
+// writeReport отдаёт результат синхронной записи по ключам и слоям.
+// 502 — внешний API не принял часть ключей, 409 — не выполнено условие записи (ifAbsent, ifVersion);
+// ошибки отдельных слоёв код ответа не меняют.
+func writeReport(w http.ResponseWriter, r *http.Request, report *dto.WriteReport) {
+	status := http.StatusOK
+	switch {
+	case len(report.Errors) > 0:
+		status = http.StatusBadGateway
+	case len(report.Conflicts) > 0:
+		status = http.StatusConflict
+	}
+	writeBody(w, r, status, report)
+}
+
+// writeQueueError сообщает клиенту, что запись не принята очередью write-behind.
+func writeQueueError(w http.ResponseWriter, err error) {
+	zap.S().Warnw("write rejected", "error", err)
+	if errors.Is(err, manager.ErrQueueFull) || errors.Is(err, manager.ErrQueueClosed) {
+		w.Header().Set(headerRetryAfter, retryAfterSeconds)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// writeBody отдаёт body в формате, выбранном по Accept (см. responseCodec).
+func writeBody(w http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	out := responseCodec(r)
+	w.Header().Set("Content-Type", out.contentType)
+	w.WriteHeader(status)
+	if err := out.encode(w, body); err != nil {
+		zap.S().Errorw(alert.Prefix("encode error"), "error", err)
+	}
+}
+
 func handleBatchDelete(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
 	defer r.Body.Close()
-	if !strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeJSON) {
+	in := requestCodec(r)
+	if in == nil {
 		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
 		return
 	}
 	var req struct {
 		Requests []dto.CacheId `json:"requests"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := in.decode(r.Body, &req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -186,11 +387,221 @@ func handleBatchDelete(w http.ResponseWriter, r *http.Request, adapter manager.M
 	for i := range req.Requests {
 		ids[i] = &req.Requests[i]
 	}
-	adapter.EvictAll(r.Context(), ids)
-	zap.S().Infow("processed batch delete", "records", len(req.Requests))
+	if isSync(r) {
+		report := adapter.EvictAllSync(r.Context(), ids)
+		zap.S().Infow("processed sync batch delete", "records", len(req.Requests), "upstreamFailed", len(report.Errors))
+		writeReport(w, r, report)
+		return
+	}
+	failed, err := adapter.EvictAll(r.Context(), ids)
+	if err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	zap.S().Infow("processed batch delete", "records", len(req.Requests), "upstreamFailed", len(failed))
+	if len(failed) > 0 {
+		// из слоёв кэша ключи удалены, но во внешнем API удалить их не удалось
+		writeBody(w, r, http.StatusBadGateway, map[string]interface{}{"errors": failed})
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleBatchTouch продлевает TTL ключей (ttl, ttls — как в put_all; без них — TTL слоёв)
+// и отвечает результатом по каждому ключу и слою. Запись выполняется сразу, минуя очередь.
+func handleBatchTouch(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	defer r.Body.Close()
+	in := requestCodec(r)
+	if in == nil {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	var req struct {
+		Requests []struct {
+			*dto.CacheId
+			TTL  *int64   `json:"ttl,omitempty"`
+			TTLs []*int64 `json:"ttls,omitempty"`
+		} `json:"requests"`
+	}
+	if err := in.decode(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Requests) == 0 {
+		http.Error(w, "empty requests", http.StatusBadRequest)
+		return
+	}
+
+	entries := make([]*dto.CacheEntry, len(req.Requests))
+	for i, e := range req.Requests {
+		entries[i] = &dto.CacheEntry{CacheId: e.CacheId, TTL: e.TTL, TTLs: e.TTLs}
+		if err := checkId(e.CacheId); err != nil {
+			http.Error(w, fmt.Sprintf("requests[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		if err := entries[i].Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("requests[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+	results := adapter.TouchAll(r.Context(), entries)
+	zap.S().Infow("processed batch touch", "records", len(entries))
+	writeBody(w, r, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+// handleEvictByTag удаляет из всех слоёв кэша записи с тегом и отвечает результатом по слоям.
+// Удаление выполняется сразу, минуя очередь; внешний API не вызывается.
+func handleEvictByTag(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	defer r.Body.Close()
+	in := requestCodec(r)
+	if in == nil {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	var req struct {
+		Tag string `json:"tag"`
+	}
+	if err := in.decode(r.Body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := dto.ValidateTag(req.Tag); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report := adapter.EvictByTag(r.Context(), req.Tag)
+	zap.S().Infow("processed evict by tag", "tag", req.Tag)
+	writeBody(w, r, http.StatusOK, report)
+}
+
+// cacheIdFromPath извлекает cacheName и key из пути /api/v1/cache/{cache}/{key}.
+// chi отдаёт параметры в экранированном виде, если путь содержит %-последовательности.
+func cacheIdFromPath(r *http.Request) (*dto.CacheId, error) {
+	cacheName, err := url.PathUnescape(chi.URLParam(r, "cache"))
+	if err != nil {
+		return nil, err
+	}
+	key, err := url.PathUnescape(chi.URLParam(r, "key"))
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CacheId{CacheName: cacheName, Key: key}, nil
+}
+
+// handleGet отдаёт значение ключа «как есть» (JSON) или 404, если ключ не найден
+// (или кэш неизвестен), и 502, если ключа нет в слоях, а внешний API вернул ошибку.
+// Если Accept требует msgpack или CBOR, значение перекодируется. ETag — версия значения для If-Match.
+func handleGet(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	id, err := cacheIdFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hit := adapter.Get(r.Context(), id)
+	if hit != nil && hit.Status == dto.HitStatusUpstreamError {
+		http.Error(w, "upstream error: "+hit.Error, http.StatusBadGateway)
+		return
+	}
+	if hit != nil && hit.Status == dto.HitStatusUnknownCache {
+		http.Error(w, "unknown cache", http.StatusNotFound)
+		return
+	}
+	if hit == nil || !hit.Found || hit.CacheEntry == nil || hit.Value == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set(headerETag, strconv.Quote(dto.ValueVersion(*hit.Value)))
+	if out := responseCodec(r); out != jsonCodec {
+		writeBody(w, r, http.StatusOK, value(*hit.Value))
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if _, err := w.Write(*hit.Value); err != nil {
+		zap.S().Errorw(alert.Prefix("write error"), "error", err)
+	}
+}
+
+// handlePut сохраняет тело запроса (JSON, msgpack или CBOR) как значение ключа.
+// Коды ответа: 204 — принято, 400 — неверный ключ или тело, 415 — формат тела не поддерживается,
+// 502 — отклонено внешним API (в теле — errors), 503 — очередь записи заполнена или закрыта.
+// If-None-Match: * и If-Match: "<версия>" делают запись условной; 412 — условие не выполнено.
+func handlePut(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	defer r.Body.Close()
+	in := requestCodec(r)
+	if in == nil {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	id, err := cacheIdFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var v value
+	if in == jsonCodec {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !json.Valid(body) {
+			http.Error(w, "body is not valid JSON", http.StatusBadRequest)
+			return
+		}
+		v = body
+	} else if err := in.decode(r.Body, &v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entry := &dto.CacheEntry{CacheId: id, Value: (*json.RawMessage)(&v)}
+	entry.IfAbsent = strings.TrimSpace(r.Header.Get(headerIfNoneMatch)) == "*"
+	if ifMatch := strings.TrimSpace(r.Header.Get(headerIfMatch)); ifMatch != "" {
+		entry.IfVersion = strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	}
+	if err := entry.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	failed, err := adapter.Put(r.Context(), entry)
+	if errors.As(err, new(*manager.ConflictError)) {
+		http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	if len(failed) > 0 {
+		writeBody(w, r, http.StatusBadGateway, map[string]interface{}{"errors": failed})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDelete удаляет ключ. Коды ответа совпадают с evict_all, успех — 204.
+func handleDelete(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	id, err := cacheIdFromPath(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	failed, err := adapter.Evict(r.Context(), id)
+	if err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	if len(failed) > 0 {
+		writeBody(w, r, http.StatusBadGateway, map[string]interface{}{"errors": failed})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // ---- middleware ----
 
 func limitBody(n int64) func(http.Handler) http.Handler {
@@ -2,15 +2,21 @@ package httpserver
 
 import (
 	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/manager"
 	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type mockAdapter struct {
@@ -18,11 +24,23 @@ type mockAdapter struct {
 	putCalled      []*dto.CacheEntry
 	evictCalled    []*dto.CacheId
 	getAllCalled   [][]*dto.CacheId
+	getAllOpts     []dto.GetOptions
 	putAllCalled   [][]*dto.CacheEntry
 	evictAllCalled [][]*dto.CacheId
+	touchCalled    [][]*dto.CacheEntry
+	tagCalled      []string
+	flushCalled    []string
+	flushCancelled bool
+	bumpCalled     []bool
+	scanCalled     []string
 
 	getResult     *dto.CacheEntryHit
 	getAllResults []*dto.CacheEntryHit
+	putAllFailed  []*dto.UpstreamError
+	evictFailed   []*dto.UpstreamError
+	writeErr      error
+	syncReport    *dto.WriteReport
+	syncCalled    int
 }
 
 func (m *mockAdapter) Get(_ context.Context, id *dto.CacheId) *dto.CacheEntryHit {
@@ -30,12 +48,14 @@ func (m *mockAdapter) Get(_ context.Context, id *dto.CacheId) *dto.CacheEntryHit
 	return m.getResult
 }
 
-func (m *mockAdapter) Put(_ context.Context, e *dto.CacheEntry) {
+func (m *mockAdapter) Put(_ context.Context, e *dto.CacheEntry) ([]*dto.UpstreamError, error) {
 	m.putCalled = append(m.putCalled, e)
+	return m.putAllFailed, m.writeErr
 }
 
-func (m *mockAdapter) Evict(_ context.Context, id *dto.CacheId) {
+func (m *mockAdapter) Evict(_ context.Context, id *dto.CacheId) ([]*dto.UpstreamError, error) {
 	m.evictCalled = append(m.evictCalled, id)
+	return m.evictFailed, m.writeErr
 }
 
 func (m *mockAdapter) GetAll(_ context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit {
@@ -43,12 +63,110 @@ func (m *mockAdapter) GetAll(_ context.Context, ids []*dto.CacheId) []*dto.Cache
 	return m.getAllResults
 }
 
-func (m *mockAdapter) PutAll(_ context.Context, entries []*dto.CacheEntry) {
+func (m *mockAdapter) GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, opts dto.GetOptions) []*dto.CacheEntryHit {
+	m.getAllOpts = append(m.getAllOpts, opts)
+	return m.GetAll(ctx, ids)
+}
+
+func (m *mockAdapter) PutAll(_ context.Context, entries []*dto.CacheEntry) ([]*dto.UpstreamError, error) {
 	m.putAllCalled = append(m.putAllCalled, entries)
+	return m.putAllFailed, m.writeErr
 }
 
-func (m *mockAdapter) EvictAll(_ context.Context, ids []*dto.CacheId) {
+func (m *mockAdapter) EvictAll(_ context.Context, ids []*dto.CacheId) ([]*dto.UpstreamError, error) {
 	m.evictAllCalled = append(m.evictAllCalled, ids)
+	return m.evictFailed, m.writeErr
+}
+
+func (m *mockAdapter) PutAllSync(_ context.Context, entries []*dto.CacheEntry) *dto.WriteReport {
+	m.syncCalled++
+	return m.syncReport
+}
+
+func (m *mockAdapter) TouchAll(_ context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult {
+	m.touchCalled = append(m.touchCalled, entries)
+	res := make([]*dto.TouchItemResult, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, &dto.TouchItemResult{CacheId: e.CacheId, Touched: e.Key != "missing"})
+	}
+	return res
+}
+
+func (m *mockAdapter) EvictByTag(_ context.Context, tag string) *dto.TagEvictReport {
+	m.tagCalled = append(m.tagCalled, tag)
+	return &dto.TagEvictReport{Tag: tag, Layers: []*dto.TagLayerStatus{{Layer: 0, Status: dto.LayerStatusOk, Evicted: 2}}}
+}
+
+// FlushCache: кэш "unknown" не настроен, для кэша "busy" очистка уже идёт.
+func (m *mockAdapter) FlushCache(cacheName, keyPrefix string) (*dto.FlushJob, error) {
+	job := &dto.FlushJob{Cache: cacheName, Prefix: cacheName + ":" + keyPrefix, Status: dto.FlushStatusRunning}
+	switch cacheName {
+	case "unknown":
+		return nil, manager.ErrUnknownCache
+	case "busy":
+		return job, manager.ErrFlushRunning
+	}
+	m.flushCalled = append(m.flushCalled, job.Prefix)
+	return job, nil
+}
+
+func (m *mockAdapter) FlushStatus(cacheName string) *dto.FlushJob {
+	if len(m.flushCalled) == 0 {
+		return nil
+	}
+	return &dto.FlushJob{Cache: cacheName, Prefix: m.flushCalled[0], Status: dto.FlushStatusDone}
+}
+
+func (m *mockAdapter) CancelFlush(cacheName string) *dto.FlushJob {
+	if len(m.flushCalled) == 0 {
+		return nil
+	}
+	m.flushCancelled = true
+	return &dto.FlushJob{Cache: cacheName, Prefix: m.flushCalled[0], Status: dto.FlushStatusRunning}
+}
+
+// CacheGeneration: кэш "unknown" не настроен, у кэша "plain" поколения не включены.
+func (m *mockAdapter) CacheGeneration(cacheName string) (*dto.CacheGeneration, error) {
+	switch cacheName {
+	case "unknown":
+		return nil, manager.ErrUnknownCache
+	case "plain":
+		return nil, manager.ErrGenerationsDisabled
+	}
+	return &dto.CacheGeneration{Cache: cacheName, Generation: int64(len(m.bumpCalled))}, nil
+}
+
+func (m *mockAdapter) BumpGeneration(_ context.Context, cacheName string, sweep bool) (*dto.CacheGeneration, error) {
+	if _, err := m.CacheGeneration(cacheName); err != nil {
+		return nil, err
+	}
+	m.bumpCalled = append(m.bumpCalled, sweep)
+	res := &dto.CacheGeneration{Cache: cacheName, Generation: int64(len(m.bumpCalled))}
+	if sweep {
+		res.Sweep = &dto.FlushJob{Cache: cacheName, Status: dto.FlushStatusRunning}
+	}
+	return res, nil
+}
+
+// ScanKeys: кэш "unknown" не настроен, слой 0 не умеет перечислять ключи, слоя 3 нет.
+func (m *mockAdapter) ScanKeys(_ context.Context, cacheName string, layer int, match, cursor string, count int) (*dto.KeyPage, error) {
+	switch {
+	case cacheName == "unknown":
+		return nil, manager.ErrUnknownCache
+	case layer == 0:
+		return nil, manager.ErrScanUnsupported
+	case layer == 3:
+		return nil, manager.ErrUnknownLayer
+	case cursor == "bad":
+		return nil, manager.ErrInvalidCursor
+	}
+	m.scanCalled = append(m.scanCalled, fmt.Sprintf("%s/%d/%s/%s/%d", cacheName, layer, match, cursor, count))
+	return &dto.KeyPage{Cache: cacheName, Layer: layer, Keys: []string{"1", "2"}, Cursor: "next"}, nil
+}
+
+func (m *mockAdapter) EvictAllSync(_ context.Context, ids []*dto.CacheId) *dto.WriteReport {
+	m.syncCalled++
+	return m.syncReport
 }
 
 func TestHandleBatchGet(t *testing.T) {
@@ -57,7 +175,7 @@ func TestHandleBatchGet(t *testing.T) {
 		{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}}, Found: true},
 		nil,
 	}
-	router := NewRouter(adapter)
+	router := NewRouter(adapter, adapter)
 	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1"},{"c":"c","k":"2"}]}`)
 	req := httptest.NewRequest(http.MethodPost, getAllPath, body)
 	req.Header.Set("Content-Type", contentTypeJSON)
@@ -80,9 +198,112 @@ func TestHandleBatchGet(t *testing.T) {
 	}
 }
 
+func TestHandleBatchGet_Statuses(t *testing.T) {
+	layer := 1
+	adapter := &mockAdapter{getAllResults: []*dto.CacheEntryHit{
+		{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}}, Found: true, Status: dto.HitStatusFound, Layer: &layer},
+		{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "2"}}, Status: dto.HitStatusUpstreamError, Error: "boom"},
+		{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "x", Key: "3"}}, Status: dto.HitStatusUnknownCache, Error: "unknown cache"},
+	}}
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1"},{"c":"c","k":"2"},{"c":"x","k":"3"},{"c":"c","k":"4"}]}`)
+	req := httptest.NewRequest(http.MethodPost, getAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	var resp struct {
+		Results []dto.CacheEntryHit `json:"results"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 4 {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+	got := []string{resp.Results[0].Status, resp.Results[1].Status, resp.Results[2].Status, resp.Results[3].Status}
+	want := []string{dto.HitStatusFound, dto.HitStatusUpstreamError, dto.HitStatusUnknownCache, dto.HitStatusNotFound}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("status[%d]=%q, want %q", i, got[i], want[i])
+		}
+	}
+	if resp.Results[0].Layer == nil || *resp.Results[0].Layer != 1 || resp.Results[1].Error != "boom" {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+}
+
+func TestHandleBatchGet_Meta(t *testing.T) {
+	ttl := int64(1500)
+	adapter := &mockAdapter{getAllResults: []*dto.CacheEntryHit{
+		{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}}, Found: true, Meta: &dto.HitMeta{Layer: 1, Provider: "redis", TtlMs: &ttl}},
+	}}
+	req := httptest.NewRequest(http.MethodPost, getAllPath+"?meta=true", bytes.NewBufferString(`{"requests":[{"c":"c","k":"1"}]}`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.getAllOpts) != 1 || !adapter.getAllOpts[0].Meta {
+		t.Fatalf("meta option not passed: %+v", adapter.getAllOpts)
+	}
+	var resp struct {
+		Results []dto.CacheEntryHit `json:"results"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	meta := resp.Results[0].Meta
+	if meta == nil || meta.Provider != "redis" || meta.Layer != 1 || meta.TtlMs == nil || *meta.TtlMs != 1500 {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+}
+
+func TestHandleBatchGet_ReadOptions(t *testing.T) {
+	adapter := &mockAdapter{}
+	body := `{"requests":[{"c":"c","k":"1"}]}`
+	req := httptest.NewRequest(http.MethodPost, getAllPath+"?noUpstream=true&minLayer=1&maxLayer=2&noBackfill=1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	opts := adapter.getAllOpts[0]
+	if !opts.NoUpstream || !opts.NoBackfill || opts.BypassCache || opts.MinLayer != 1 || opts.MaxLayer == nil || *opts.MaxLayer != 2 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	for _, query := range []string{"?bypassCache=true&noUpstream=true", "?minLayer=2&maxLayer=1", "?maxLayer=-1", "?meta=yes"} {
+		req := httptest.NewRequest(http.MethodPost, getAllPath+query, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentTypeJSON)
+		rr := httptest.NewRecorder()
+		NewRouter(adapter, adapter).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: code=%d", query, rr.Code)
+		}
+	}
+}
+
+func TestHandleGet_UpstreamError(t *testing.T) {
+	adapter := &mockAdapter{getResult: &dto.CacheEntryHit{
+		CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "user", Key: "1"}},
+		Status:     dto.HitStatusUpstreamError,
+		Error:      "timeout",
+	}}
+	req := httptest.NewRequest(http.MethodGet, baseAPIPath+"/user/1", nil)
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("code=%d", rr.Code)
+	}
+}
+
 func TestHandleBatchPut(t *testing.T) {
 	adapter := &mockAdapter{}
-	router := NewRouter(adapter)
+	router := NewRouter(adapter, adapter)
 	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1}]}`)
 	req := httptest.NewRequest(http.MethodPost, putAllPath, body)
 	req.Header.Set("Content-Type", contentTypeJSON)
@@ -96,9 +317,291 @@ func TestHandleBatchPut(t *testing.T) {
 	}
 }
 
+func TestHandleBatchPut_TTL(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1,"ttl":60,"ttls":[null,600]}]}`)
+	req := httptest.NewRequest(http.MethodPost, putAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	e := adapter.putAllCalled[0][0]
+	if e.TTL == nil || *e.TTL != 60 || len(e.TTLs) != 2 || e.TTLs[0] != nil || *e.TTLs[1] != 600 {
+		t.Fatalf("entry ttl not passed: %+v", e)
+	}
+
+	body = bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1,"ttl":0}]}`)
+	req = httptest.NewRequest(http.MethodPost, putAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("zero ttl: code=%d", rr.Code)
+	}
+	if len(adapter.putAllCalled) != 1 {
+		t.Fatalf("putAll called with invalid ttl")
+	}
+}
+
+func TestHandleBatchPut_Conflict(t *testing.T) {
+	taken := &dto.CacheId{CacheName: "c", Key: "2"}
+	adapter := &mockAdapter{writeErr: &manager.ConflictError{Keys: []*dto.CacheId{taken}}}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1,"ifAbsent":true},{"c":"c","k":"2","v":2,"ifVersion":"42"}]}`)
+	req := httptest.NewRequest(http.MethodPost, putAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), `"conflicts":[{"c":"c","k":"2"}]`) {
+		t.Fatalf("body=%s", rr.Body.String())
+	}
+	entries := adapter.putAllCalled[0]
+	if !entries[0].IfAbsent || entries[1].IfVersion != "42" {
+		t.Fatalf("conditions not passed: %+v %+v", entries[0], entries[1])
+	}
+
+	body = bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1,"ifAbsent":true,"ifVersion":"42"}]}`)
+	req = httptest.NewRequest(http.MethodPost, putAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("both conditions: code=%d", rr.Code)
+	}
+}
+
+func TestHandleBatchPut_UpstreamFailure(t *testing.T) {
+	adapter := &mockAdapter{putAllFailed: []*dto.UpstreamError{
+		{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, Error: "bad response (500)"},
+	}}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1}]}`)
+	req := httptest.NewRequest(http.MethodPost, putAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	var resp struct {
+		Errors []dto.UpstreamError `json:"errors"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Key != "1" || resp.Errors[0].Error == "" {
+		t.Fatalf("unexpected errors: %+v", resp.Errors)
+	}
+}
+
+func TestHandleBatchTouch(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"s","k":"1","ttl":600},{"c":"s","k":"missing"}]}`)
+	req := httptest.NewRequest(http.MethodPost, touchAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	entries := adapter.touchCalled[0]
+	if len(entries) != 2 || *entries[0].TTL != 600 || entries[1].TTL != nil || entries[0].Value != nil {
+		t.Fatalf("unexpected touch: %+v", entries)
+	}
+	want := `{"results":[{"c":"s","k":"1","touched":true,"layers":null},{"c":"s","k":"missing","touched":false,"layers":null}]}`
+	if strings.TrimSpace(rr.Body.String()) != want {
+		t.Fatalf("body=%s", rr.Body.String())
+	}
+
+	body = bytes.NewBufferString(`{"requests":[{"c":"s","k":"1","ttl":-1}]}`)
+	req = httptest.NewRequest(http.MethodPost, touchAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("negative ttl: code=%d", rr.Code)
+	}
+}
+
+func TestHandleEvictByTag(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"tag":"tenant:42"}`)
+	req := httptest.NewRequest(http.MethodPost, evictByTagPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.tagCalled) != 1 || adapter.tagCalled[0] != "tenant:42" {
+		t.Fatalf("unexpected tags: %v", adapter.tagCalled)
+	}
+	want := `{"tag":"tenant:42","layers":[{"layer":0,"status":"ok","evicted":2}]}`
+	if strings.TrimSpace(rr.Body.String()) != want {
+		t.Fatalf("body=%s", rr.Body.String())
+	}
+
+	body = bytes.NewBufferString(`{"tag":""}`)
+	req = httptest.NewRequest(http.MethodPost, evictByTagPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty tag: code=%d", rr.Code)
+	}
+}
+
+func TestHandleFlush(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/caches/user/flush", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status before flush: code=%d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/caches/user/flush?prefix=42-", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.flushCalled) != 1 || adapter.flushCalled[0] != "user:42-" {
+		t.Fatalf("unexpected flush: %v", adapter.flushCalled)
+	}
+	var job dto.FlushJob
+	if err := json.NewDecoder(rr.Body).Decode(&job); err != nil || job.Status != dto.FlushStatusRunning {
+		t.Fatalf("unexpected job: %+v %v", job, err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/caches/user/flush", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"done"`) {
+		t.Fatalf("status: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/admin/caches/user/flush", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusAccepted || !adapter.flushCancelled {
+		t.Fatalf("cancel: code=%d", rr.Code)
+	}
+
+	for name, code := range map[string]int{"unknown": http.StatusNotFound, "busy": http.StatusConflict} {
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/caches/"+name+"/flush", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != code {
+			t.Fatalf("%s: code=%d", name, rr.Code)
+		}
+	}
+}
+
+func TestHandleGeneration(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/caches/user/generation", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	var gen dto.CacheGeneration
+	if err := json.NewDecoder(rr.Body).Decode(&gen); err != nil || gen.Generation != 1 || gen.Sweep != nil {
+		t.Fatalf("unexpected generation: %+v %v", gen, err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/caches/user/generation?sweep=true", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"sweep":{`) {
+		t.Fatalf("sweep: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/caches/user/generation", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"generation":2`) {
+		t.Fatalf("get: code=%d body=%s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/caches/user/generation?sweep=maybe", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid sweep: code=%d", rr.Code)
+	}
+
+	for name, code := range map[string]int{"unknown": http.StatusNotFound, "plain": http.StatusBadRequest} {
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/caches/"+name+"/generation", nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != code {
+			t.Fatalf("%s: code=%d", name, rr.Code)
+		}
+	}
+	if len(adapter.bumpCalled) != 2 || adapter.bumpCalled[0] || !adapter.bumpCalled[1] {
+		t.Fatalf("unexpected bumps: %v", adapter.bumpCalled)
+	}
+}
+
+func TestHandleKeys(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/caches/user/keys?layer=1&match=42-*&cursor=c1&count=50", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d body=%s", rr.Code, rr.Body.String())
+	}
+	var page dto.KeyPage
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil || len(page.Keys) != 2 || page.Cursor != "next" {
+		t.Fatalf("unexpected page: %+v %v", page, err)
+	}
+	if len(adapter.scanCalled) != 1 || adapter.scanCalled[0] != "user/1/42-*/c1/50" {
+		t.Fatalf("unexpected scan: %v", adapter.scanCalled)
+	}
+
+	for query, code := range map[string]int{
+		"":                    http.StatusBadRequest,
+		"?layer=x":            http.StatusBadRequest,
+		"?layer=1&count=0":    http.StatusBadRequest,
+		"?layer=0":            http.StatusNotImplemented,
+		"?layer=3":            http.StatusBadRequest,
+		"?layer=1&cursor=bad": http.StatusBadRequest,
+	} {
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/caches/user/keys"+query, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != code {
+			t.Fatalf("%q: code=%d", query, rr.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/caches/unknown/keys?layer=1", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown: code=%d", rr.Code)
+	}
+}
+
 func TestHandleBatchDelete(t *testing.T) {
 	adapter := &mockAdapter{}
-	router := NewRouter(adapter)
+	router := NewRouter(adapter, adapter)
 	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1"}]}`)
 	req := httptest.NewRequest(http.MethodPost, evictAllPath, body)
 	req.Header.Set("Content-Type", contentTypeJSON)
@@ -112,9 +615,43 @@ func TestHandleBatchDelete(t *testing.T) {
 	}
 }
 
+func TestHandleBatchDelete_UpstreamFailure(t *testing.T) {
+	adapter := &mockAdapter{evictFailed: []*dto.UpstreamError{
+		{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, Error: "http request failed"},
+	}}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1"}]}`)
+	req := httptest.NewRequest(http.MethodPost, evictAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.evictAllCalled) != 1 || !strings.Contains(rr.Body.String(), `"error":"http request failed"`) {
+		t.Fatalf("unexpected response: %s", rr.Body.String())
+	}
+}
+
+func TestHandleBatchPut_QueueFull(t *testing.T) {
+	adapter := &mockAdapter{writeErr: manager.ErrQueueFull}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1}]}`)
+	req := httptest.NewRequest(http.MethodPost, putAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if rr.Header().Get(headerRetryAfter) == "" {
+		t.Fatalf("Retry-After header is missing")
+	}
+}
+
 func TestBodyLimit(t *testing.T) {
 	adapter := &mockAdapter{}
-	router := NewRouter(adapter)
+	router := NewRouter(adapter, adapter)
 	big := bytes.Repeat([]byte("a"), maxBodySize+1)
 	req := httptest.NewRequest(http.MethodPost, putAllPath, bytes.NewReader(big))
 	req.Header.Set("Content-Type", contentTypeJSON)
@@ -127,7 +664,7 @@ func TestBodyLimit(t *testing.T) {
 
 func TestGzipDecompress(t *testing.T) {
 	adapter := &mockAdapter{}
-	router := NewRouter(adapter)
+	router := NewRouter(adapter, adapter)
 	var buf bytes.Buffer
 	gz := gzip.NewWriter(&buf)
 	io.WriteString(gz, `{"requests":[]}`)
@@ -144,7 +681,7 @@ func TestGzipDecompress(t *testing.T) {
 
 func TestMetricsNoGzip(t *testing.T) {
 	adapter := &mockAdapter{}
-	router := NewRouter(adapter)
+	router := NewRouter(adapter, adapter)
 	req := httptest.NewRequest(http.MethodGet, metricsPath, nil)
 	req.Header.Set("Accept-Encoding", encodingGzip)
 	rr := httptest.NewRecorder()
@@ -166,3 +703,373 @@ func TestMetricsHealth(t *testing.T) {
 		t.Fatalf("unexpected body: %s", body)
 	}
 }
+
+func TestHandleBatchPutSync(t *testing.T) {
+	adapter := &mockAdapter{syncReport: &dto.WriteReport{Results: []*dto.WriteItemResult{{
+		CacheId: &dto.CacheId{CacheName: "c", Key: "1"},
+		Layers: []*dto.LayerWriteStatus{
+			{Layer: 0, Status: dto.LayerStatusOk},
+			{Layer: 1, Status: dto.LayerStatusError, Error: "down"},
+		},
+	}}}}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1","v":1}]}`)
+	req := httptest.NewRequest(http.MethodPost, putAllPath+"?sync=true", body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if adapter.syncCalled != 1 || len(adapter.putAllCalled) != 0 {
+		t.Fatalf("sync path not used: sync=%d async=%d", adapter.syncCalled, len(adapter.putAllCalled))
+	}
+	var resp dto.WriteReport
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 1 || len(resp.Results[0].Layers) != 2 || resp.Results[0].Layers[1].Status != dto.LayerStatusError {
+		t.Fatalf("unexpected report: %+v", resp.Results)
+	}
+}
+
+func TestHandleBatchDeleteSyncHeaderUpstreamFailure(t *testing.T) {
+	adapter := &mockAdapter{syncReport: &dto.WriteReport{
+		Results: []*dto.WriteItemResult{},
+		Errors:  []*dto.UpstreamError{{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, Error: "boom"}},
+	}}
+	router := NewRouter(adapter, adapter)
+	body := bytes.NewBufferString(`{"requests":[{"c":"c","k":"1"}]}`)
+	req := httptest.NewRequest(http.MethodPost, evictAllPath, body)
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set(headerSync, "true")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if adapter.syncCalled != 1 || len(adapter.evictAllCalled) != 0 {
+		t.Fatalf("sync path not used")
+	}
+}
+
+func TestHandleGet(t *testing.T) {
+	raw := json.RawMessage(`{"name":"Ann"}`)
+	adapter := &mockAdapter{getResult: &dto.CacheEntryHit{
+		CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "user", Key: "a/b"}, Value: &raw},
+		Found:      true,
+	}}
+	router := NewRouter(adapter, adapter)
+	req := httptest.NewRequest(http.MethodGet, baseAPIPath+"/user/a%2Fb", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if rr.Body.String() != `{"name":"Ann"}` {
+		t.Fatalf("body=%s", rr.Body.String())
+	}
+	if len(adapter.getCalled) != 1 || adapter.getCalled[0].CacheName != "user" || adapter.getCalled[0].Key != "a/b" {
+		t.Fatalf("unexpected get: %+v", adapter.getCalled)
+	}
+}
+
+func TestHandleGet_NotFound(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+	req := httptest.NewRequest(http.MethodGet, baseAPIPath+"/user/1", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("code=%d", rr.Code)
+	}
+}
+
+func TestHandlePut(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+	req := httptest.NewRequest(http.MethodPut, baseAPIPath+"/user/1", bytes.NewBufferString(`{"name":"Ann"}`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.putCalled) != 1 || string(*adapter.putCalled[0].Value) != `{"name":"Ann"}` {
+		t.Fatalf("unexpected put: %+v", adapter.putCalled)
+	}
+}
+
+func TestHandlePut_Conditional(t *testing.T) {
+	raw := json.RawMessage(`{"name":"Ann"}`)
+	adapter := &mockAdapter{getResult: &dto.CacheEntryHit{
+		CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "user", Key: "1"}, Value: &raw},
+		Found:      true,
+	}}
+	router := NewRouter(adapter, adapter)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, baseAPIPath+"/user/1", nil))
+	etag := rr.Header().Get("ETag")
+	if etag != strconv.Quote(dto.ValueVersion(raw)) {
+		t.Fatalf("etag=%q", etag)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, baseAPIPath+"/user/1", bytes.NewBufferString(`{"name":"Bob"}`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("If-Match", etag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if adapter.putCalled[0].IfVersion != dto.ValueVersion(raw) {
+		t.Fatalf("if-match not passed: %+v", adapter.putCalled[0])
+	}
+
+	adapter.writeErr = &manager.ConflictError{Keys: []*dto.CacheId{{CacheName: "user", Key: "1"}}}
+	req = httptest.NewRequest(http.MethodPut, baseAPIPath+"/user/1", bytes.NewBufferString(`{"name":"Bob"}`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("If-None-Match", "*")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if !adapter.putCalled[1].IfAbsent {
+		t.Fatalf("if-none-match not passed: %+v", adapter.putCalled[1])
+	}
+}
+
+func TestHandlePut_InvalidJSON(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+	req := httptest.NewRequest(http.MethodPut, baseAPIPath+"/user/1", bytes.NewBufferString(`not json`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.putCalled) != 0 {
+		t.Fatalf("put should not be called")
+	}
+}
+
+func TestHandleDelete(t *testing.T) {
+	adapter := &mockAdapter{}
+	router := NewRouter(adapter, adapter)
+	req := httptest.NewRequest(http.MethodDelete, baseAPIPath+"/user/1", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.evictCalled) != 1 || adapter.evictCalled[0].Key != "1" {
+		t.Fatalf("unexpected evict: %+v", adapter.evictCalled)
+	}
+}
+
+func streamRequest(path, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentTypeNDJSON)
+	return req
+}
+
+func decodeLines(t *testing.T, body io.Reader) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	dec := json.NewDecoder(body)
+	for dec.More() {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestStreamGetAll(t *testing.T) {
+	adapter := &mockAdapter{getAllResults: []*dto.CacheEntryHit{
+		{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "0"}}, Found: true},
+	}}
+	var body strings.Builder
+	for i := 0; i <= streamChunkSize; i++ {
+		body.WriteString(`{"c":"c","k":"` + strconv.Itoa(i) + `"}` + "\n\n")
+	}
+
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, streamRequest(streamGetAllPath, body.String()))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != contentTypeNDJSON {
+		t.Fatalf("code=%d content-type=%q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if len(adapter.getAllCalled) != 2 || len(adapter.getAllCalled[0]) != streamChunkSize {
+		t.Fatalf("expected two chunks, got %d", len(adapter.getAllCalled))
+	}
+	lines := decodeLines(t, rr.Body)
+	if len(lines) != streamChunkSize+1 {
+		t.Fatalf("lines=%d", len(lines))
+	}
+	if lines[0]["f"] != true || lines[1]["f"] != false || lines[streamChunkSize]["k"] != strconv.Itoa(streamChunkSize) {
+		t.Fatalf("unexpected lines: %v %v %v", lines[0], lines[1], lines[streamChunkSize])
+	}
+}
+
+func TestStreamPutAll_UpstreamFailureAndBadLine(t *testing.T) {
+	adapter := &mockAdapter{putAllFailed: []*dto.UpstreamError{
+		{CacheId: &dto.CacheId{CacheName: "c", Key: "2"}, Error: "rejected"},
+	}}
+	body := `{"c":"c","k":"1","v":1}` + "\n" + `{"c":"c","k":"2","v":2}` + "\n" + `{"c":"c"` + "\n" + `{"c":"c","k":"4","v":4}` + "\n"
+
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, streamRequest(streamPutAllPath, body))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d", rr.Code)
+	}
+	if len(adapter.putAllCalled) != 1 || len(adapter.putAllCalled[0]) != 2 {
+		t.Fatalf("lines before the bad one must be written: %v", adapter.putAllCalled)
+	}
+	lines := decodeLines(t, rr.Body)
+	if len(lines) != 3 {
+		t.Fatalf("lines=%v", lines)
+	}
+	if lines[0]["status"] != streamStatusOk || lines[1]["status"] != streamStatusUpstreamError || lines[1]["error"] != "rejected" {
+		t.Fatalf("unexpected results: %v", lines[:2])
+	}
+	if lines[2]["line"] != float64(3) || lines[2]["error"] == nil {
+		t.Fatalf("unexpected terminal line: %v", lines[2])
+	}
+}
+
+func TestStreamEvictAll_QueueFull(t *testing.T) {
+	adapter := &mockAdapter{writeErr: manager.ErrQueueFull}
+
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, streamRequest(streamEvictAllPath, `{"c":"c","k":"1"}`))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("code=%d", rr.Code)
+	}
+}
+
+func TestStreamEvictAllSync(t *testing.T) {
+	adapter := &mockAdapter{syncReport: &dto.WriteReport{Results: []*dto.WriteItemResult{{
+		CacheId: &dto.CacheId{CacheName: "c", Key: "1"},
+		Layers:  []*dto.LayerWriteStatus{{Layer: 0, Status: dto.LayerStatusOk}},
+	}}}}
+
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, streamRequest(streamEvictAllPath+"?sync=true", `{"c":"c","k":"1"}`))
+	lines := decodeLines(t, rr.Body)
+	if adapter.syncCalled != 1 || len(lines) != 1 || lines[0]["layers"] == nil {
+		t.Fatalf("unexpected sync result: %v", lines)
+	}
+}
+
+func TestHandleBatchPut_Msgpack(t *testing.T) {
+	adapter := &mockAdapter{}
+	body, err := msgpack.Marshal(map[string]interface{}{"requests": []interface{}{
+		map[string]interface{}{"c": "c", "k": "1", "v": map[string]interface{}{"n": 1, "tags": []string{"a"}}},
+	}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, putAllPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentTypeMsgpack)
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d body=%s", rr.Code, rr.Body.String())
+	}
+	got := adapter.putAllCalled[0][0]
+	if got.CacheId.Key != "1" || string(*got.Value) != `{"n":1,"tags":["a"]}` {
+		t.Fatalf("value must be stored as JSON: %s", *got.Value)
+	}
+}
+
+func TestHandleBatchPut_Tags(t *testing.T) {
+	msgpackBody, err := msgpack.Marshal(map[string]interface{}{"requests": []interface{}{
+		map[string]interface{}{"c": "c", "k": "1", "v": 1, "tags": []string{"tenant:42", "user"}},
+	}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"json", contentTypeJSON, []byte(`{"requests":[{"c":"c","k":"1","v":1,"tags":["tenant:42","user"]}]}`)},
+		{"msgpack", contentTypeMsgpack, msgpackBody},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := &mockAdapter{}
+			req := httptest.NewRequest(http.MethodPost, putAllPath, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			NewRouter(adapter, adapter).ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("code=%d body=%s", rr.Code, rr.Body.String())
+			}
+			got := adapter.putAllCalled[0][0].Tags
+			if len(got) != 2 || got[0] != "tenant:42" || got[1] != "user" {
+				t.Fatalf("tags were not passed to PutAll: %v", got)
+			}
+		})
+	}
+}
+
+func TestHandleBatchGet_AcceptMsgpack(t *testing.T) {
+	stored := json.RawMessage(`{"n":1.5,"ok":true}`)
+	adapter := &mockAdapter{getAllResults: []*dto.CacheEntryHit{
+		{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, Value: &stored}, Found: true},
+	}}
+	req := httptest.NewRequest(http.MethodPost, getAllPath, bytes.NewBufferString(`{"requests":[{"c":"c","k":"1"},{"c":"c","k":"2"}]}`))
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Accept", contentTypeMsgpack)
+	rr := httptest.NewRecorder()
+	NewRouter(adapter, adapter).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != contentTypeMsgpack {
+		t.Fatalf("code=%d content-type=%q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var resp struct {
+		Results []struct {
+			Key   string                 `msgpack:"k"`
+			Value map[string]interface{} `msgpack:"v"`
+			Found bool                   `msgpack:"f"`
+		} `msgpack:"results"`
+	}
+	if err := msgpack.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 2 || !resp.Results[0].Found || resp.Results[0].Value["n"] != 1.5 || resp.Results[1].Found {
+		t.Fatalf("unexpected results: %+v", resp.Results)
+	}
+}
+
+func TestHandleGetPut_CBOR(t *testing.T) {
+	stored := json.RawMessage(`[1,"x"]`)
+	adapter := &mockAdapter{getResult: &dto.CacheEntryHit{CacheEntry: &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, Value: &stored}, Found: true}}
+	router := NewRouter(adapter, adapter)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cache/c/1", nil)
+	req.Header.Set("Accept", contentTypeCBOR)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var got []interface{}
+	if err := cbor.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rr.Code != http.StatusOK || len(got) != 2 || got[0] != uint64(1) || got[1] != "x" {
+		t.Fatalf("code=%d value=%v", rr.Code, got)
+	}
+
+	body, _ := cbor.Marshal(map[string]interface{}{"name": "Ann"})
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/cache/c/2", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentTypeCBOR)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent || string(*adapter.putCalled[0].Value) != `{"name":"Ann"}` {
+		t.Fatalf("code=%d stored=%s", rr.Code, *adapter.putCalled[0].Value)
+	}
+}
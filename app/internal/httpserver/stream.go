@@ -0,0 +1,285 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/manager"
+
+	"go.uber.org/zap"
+	"telegram-alerts-go/alert"
+)
+
+// Потоковые (NDJSON) варианты get_all/put_all/evict_all: по одному элементу на строку
+// в запросе и по одному результату на строку в ответе. Тело запроса не ограничено
+// по размеру и читается по мере обработки, элементы обрабатываются пачками по
+// streamChunkSize, поэтому память не зависит от размера запроса.
+const (
+	baseStreamPath     = "/api/v1/stream"              // Базовый путь потоковых эндпоинтов
+	streamGetAllPath   = baseStreamPath + "/get_all"   // POST /api/v1/stream/get_all
+	streamPutAllPath   = baseStreamPath + "/put_all"   // POST /api/v1/stream/put_all
+	streamEvictAllPath = baseStreamPath + "/evict_all" // POST /api/v1/stream/evict_all
+	contentTypeNDJSON  = "application/x-ndjson"        // MIME-тип для NDJSON
+	streamChunkSize    = 500                           // Число элементов, передаваемых в ManagerAdapter за раз
+	maxStreamLineSize  = maxBodySize                   // Максимальная длина одной строки запроса
+)
+
+// Статусы записи (удаления) ключа в потоковом ответе
+const (
+	streamStatusOk            = "ok"             // принято (при sync — записано в слои)
+	streamStatusUpstreamError = "upstream_error" // внешний API не принял ключ
+	streamStatusConflict      = "conflict"       // не выполнено условие записи (ifAbsent, ifVersion)
+)
+
+// streamWriteResult — строка ответа потоковых put_all/evict_all.
+type streamWriteResult struct {
+	*dto.CacheId
+	Status string                  `json:"status"`
+	Error  string                  `json:"error,omitempty"`
+	Layers []*dto.LayerWriteStatus `json:"layers,omitempty"` // только при sync
+}
+
+// streamError — последняя строка ответа при прерванной обработке:
+// элементы, начиная со строки Line запроса, не обработаны.
+type streamError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// errStreamQueue — пачка не принята очередью записи (запрос можно продолжить позже с Line).
+var errStreamQueue = errors.New("write rejected")
+
+func handleStreamGet(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	opts, err := getOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	streamBatches(w, r, "get", checkId,
+		func(ctx context.Context, ids []*dto.CacheId) ([]interface{}, error) {
+			hits := orderHits(ids, adapter.GetAllWithOptions(ctx, ids, opts))
+			lines := make([]interface{}, len(hits))
+			for i, h := range hits {
+				lines[i] = h
+			}
+			return lines, nil
+		})
+}
+
+func handleStreamPut(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	syncWrite := isSync(r)
+	streamBatches(w, r, "put", checkEntry,
+		func(ctx context.Context, entries []*dto.CacheEntry) ([]interface{}, error) {
+			ids := make([]*dto.CacheId, len(entries))
+			for i, e := range entries {
+				ids[i] = e.CacheId
+			}
+			if syncWrite {
+				report := adapter.PutAllSync(ctx, entries)
+				return writeResults(ids, report.Errors, report.Results, report.Conflicts), nil
+			}
+			failed, err := adapter.PutAll(ctx, entries)
+			var conflict *manager.ConflictError
+			if errors.As(err, &conflict) {
+				return writeResults(ids, failed, nil, conflict.Keys), nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errStreamQueue, err)
+			}
+			return writeResults(ids, failed, nil, nil), nil
+		})
+}
+
+func handleStreamDelete(w http.ResponseWriter, r *http.Request, adapter manager.ManagerAdapter) {
+	syncWrite := isSync(r)
+	streamBatches(w, r, "delete", checkId,
+		func(ctx context.Context, ids []*dto.CacheId) ([]interface{}, error) {
+			if syncWrite {
+				report := adapter.EvictAllSync(ctx, ids)
+				return writeResults(ids, report.Errors, report.Results, nil), nil
+			}
+			failed, err := adapter.EvictAll(ctx, ids)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errStreamQueue, err)
+			}
+			return writeResults(ids, failed, nil, nil), nil
+		})
+}
+
+// writeResults формирует строки ответа записи в порядке ids.
+func writeResults(ids []*dto.CacheId, failed []*dto.UpstreamError, layers []*dto.WriteItemResult, conflicts []*dto.CacheId) []interface{} {
+	errs := make(map[dto.CacheId]string, len(failed))
+	for _, f := range failed {
+		if f != nil && f.CacheId != nil {
+			errs[*f.CacheId] = f.Error
+		}
+	}
+	byId := make(map[dto.CacheId][]*dto.LayerWriteStatus, len(layers))
+	for _, l := range layers {
+		if l != nil && l.CacheId != nil {
+			byId[*l.CacheId] = l.Layers
+		}
+	}
+	conflicted := make(map[dto.CacheId]bool, len(conflicts))
+	for _, id := range conflicts {
+		if id != nil {
+			conflicted[*id] = true
+		}
+	}
+
+	lines := make([]interface{}, len(ids))
+	for i, id := range ids {
+		res := &streamWriteResult{CacheId: id, Status: streamStatusOk, Layers: byId[*id]}
+		if msg, ok := errs[*id]; ok {
+			res.Status = streamStatusUpstreamError
+			res.Error = msg
+		} else if conflicted[*id] {
+			res.Status = streamStatusConflict
+		}
+		lines[i] = res
+	}
+	return lines
+}
+
+func checkId(id *dto.CacheId) error {
+	if id == nil || id.CacheName == "" || id.Key == "" {
+		return errors.New("cache name and key are required")
+	}
+	return nil
+}
+
+func checkEntry(e *dto.CacheEntry) error {
+	if err := checkId(e.CacheId); err != nil {
+		return err
+	}
+	return e.Validate()
+}
+
+// streamBatches читает элементы T построчно, передаёт их в process пачками по streamChunkSize
+// и пишет результаты пачки в ответ, сбрасывая его клиенту после каждой пачки.
+//
+// Ошибка до первой записи в ответ возвращается кодом HTTP (400, 503), после — строкой streamError,
+// которой ответ завершается. check проверяет элемент строки.
+func streamBatches[T any](w http.ResponseWriter, r *http.Request, op string,
+	check func(*T) error,
+	process func(ctx context.Context, items []*T) ([]interface{}, error)) {
+
+	defer r.Body.Close()
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeNDJSON) {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// HTTP/1.1 сервер по умолчанию перестаёт читать тело запроса после начала ответа
+	_ = rc.EnableFullDuplex()
+
+	ctx := r.Context()
+	enc := json.NewEncoder(w)
+	written := false
+	total := 0
+
+	// fail завершает обработку: элементы со строки line не обработаны
+	fail := func(line int, err error) {
+		zap.S().Warnw("stream "+op+" interrupted", "line", line, "processed", total, "error", err)
+		if !written {
+			if errors.Is(err, errStreamQueue) {
+				writeQueueError(w, err)
+				return
+			}
+			http.Error(w, fmt.Sprintf("line %d: %v", line, err), http.StatusBadRequest)
+			return
+		}
+		if err := enc.Encode(&streamError{Line: line, Error: err.Error()}); err != nil {
+			zap.S().Errorw(alert.Prefix("encode error"), "error", err)
+		}
+	}
+
+	chunk := make([]*T, 0, streamChunkSize)
+	chunkLine := 0 // строка запроса с первым элементом пачки
+
+	// flushChunk обрабатывает накопленную пачку; false — обработку нужно прекратить
+	flushChunk := func() bool {
+		if len(chunk) == 0 {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		lines, err := process(ctx, chunk)
+		if err != nil {
+			fail(chunkLine, err)
+			return false
+		}
+		if !written {
+			w.Header().Set("Content-Type", contentTypeNDJSON)
+			w.WriteHeader(http.StatusOK)
+			written = true
+		}
+		for _, line := range lines {
+			if err := enc.Encode(line); err != nil {
+				zap.S().Debugw("stream write error", "error", err)
+				return false
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return false
+		}
+		total += len(chunk)
+		chunk = chunk[:0]
+		return true
+	}
+
+	sc := bufio.NewScanner(r.Body)
+	sc.Buffer(make([]byte, 0, 64<<10), maxStreamLineSize)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		item := new(T)
+		err := json.Unmarshal(data, item)
+		if err == nil {
+			err = check(item)
+		}
+		if err != nil {
+			// элементы до ошибочной строки обрабатываются, чтобы ответ покрывал все строки до line
+			if flushChunk() {
+				fail(lineNo, err)
+			}
+			return
+		}
+
+		if len(chunk) == 0 {
+			chunkLine = lineNo
+		}
+		chunk = append(chunk, item)
+		if len(chunk) == streamChunkSize && !flushChunk() {
+			return
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if flushChunk() {
+			fail(lineNo+1, err)
+		}
+		return
+	}
+	if !flushChunk() {
+		return
+	}
+	if !written {
+		w.Header().Set("Content-Type", contentTypeNDJSON)
+		w.WriteHeader(http.StatusOK)
+	}
+	zap.S().Infow("processed stream "+op, "records", total)
+}
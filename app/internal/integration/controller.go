@@ -7,6 +7,8 @@ import (
 
 type Controller interface {
 	GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) *dto.GetResult
+	PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry) *dto.WriteResult
+	DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) *dto.WriteResult
 }
 
 func CreateController(service Service) Controller {
@@ -22,3 +24,11 @@ type ControllerImpl struct {
 func (c *ControllerImpl) GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) *dto.GetResult {
 	return c.service.GetAll(ctx, reqs)
 }
+
+func (c *ControllerImpl) PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry) *dto.WriteResult {
+	return c.service.PutAll(ctx, entries)
+}
+
+func (c *ControllerImpl) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) *dto.WriteResult {
+	return c.service.DeleteAll(ctx, reqs)
+}
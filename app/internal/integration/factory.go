@@ -17,8 +17,9 @@ func CreateHttpCacheController(configCacheService config.CacheService) Controlle
 	}
 
 	fetcher := CreateHttpBatchFetcher(client)
+	writer := CreateHttpBatchWriter(client)
 
-	service := NewIntegrationService(configCacheService, fetcher)
+	service := NewIntegrationService(configCacheService, fetcher, writer)
 
 	return CreateController(service)
 }
@@ -55,7 +55,7 @@ func (f *httpBatchFetcherImpl) GetAll(ctx context.Context, keys []string, cfg *c
 		return map[string]*json.RawMessage{}, nil
 	}
 
-	bodyBytes, err := f.prepareBody(keys, cfg)
+	bodyBytes, err := prepareKeysBody(keys, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -101,8 +101,9 @@ func (f *httpBatchFetcherImpl) GetAll(ctx context.Context, keys []string, cfg *c
 	return result, nil
 }
 
-// prepareBody формирует JSON-тело запроса с учётом типа ключей (строковые или числовые).
-func (f *httpBatchFetcherImpl) prepareBody(keys []string, cfg *config.ApiBatchConfig) (bodyBytes []byte, err error) {
+// prepareKeysBody формирует JSON-тело запроса с учётом типа ключей (строковые или числовые).
+// Используется всеми эндпоинтами с контрактом getBatch.
+func prepareKeysBody(keys []string, cfg *config.ApiBatchConfig) (bodyBytes []byte, err error) {
 
 	var payload map[string]interface{}
 
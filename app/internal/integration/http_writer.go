@@ -0,0 +1,152 @@
+package integration
+
+import (
+	"aur-cache-service/internal/cache/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// httpBatchWriter определяет интерфейс для HTTP-клиента,
+// который изменяет данные во внешнем API: запись (putBatch) и удаление (deleteBatch).
+type httpBatchWriter interface {
+
+	// PutAll отправляет значения по ключам одним запросом.
+	// Ошибка относится ко всей пачке: внешний API либо принял её, либо нет.
+	PutAll(ctx context.Context, values map[string]*json.RawMessage, cfg *config.ApiPutBatchConfig) error
+
+	// DeleteAll отправляет POST-запрос с массивом ключей (контракт getBatch).
+	// Ошибка относится ко всей пачке.
+	DeleteAll(ctx context.Context, keys []string, cfg *config.ApiBatchConfig) error
+}
+
+// httpBatchWriterImpl — реализация httpBatchWriter.
+//
+// Описание работы:
+//   - PutAll формирует тело запроса в форме, заданной shape, и отправляет его
+//     методом method (по умолчанию POST).
+//   - DeleteAll формирует тело запроса как getBatch: {prop: [key1, key2, ...]}
+//     и отправляет его методом POST.
+//   - Добавляет кастомные заголовки (если заданы в конфигурации).
+//   - Любой ответ с кодом 2xx считается успешным, тело ответа игнорируется.
+//
+// Пример тела запроса:
+//
+//	{ "1": {"name": "a"}, "2": {"name": "b"} }                    // shape: map
+//	[ {"id": 1, "value": {"name": "a"}}, {"id": 2, "value": ...} ] // shape: list, prop: id, valueProp: value
+type httpBatchWriterImpl struct {
+	client *http.Client
+}
+
+func CreateHttpBatchWriter(c *http.Client) *httpBatchWriterImpl {
+	return &httpBatchWriterImpl{client: c}
+}
+
+func (w *httpBatchWriterImpl) PutAll(ctx context.Context, values map[string]*json.RawMessage, cfg *config.ApiPutBatchConfig) error {
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	bodyBytes, err := w.prepareBody(values, cfg)
+	if err != nil {
+		return err
+	}
+
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+
+	return w.send(ctx, cfg.GetMethod(), cfg.URL, bodyBytes, cfg.Headers, timeout)
+}
+
+func (w *httpBatchWriterImpl) DeleteAll(ctx context.Context, keys []string, cfg *config.ApiBatchConfig) error {
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	bodyBytes, err := prepareKeysBody(keys, cfg)
+	if err != nil {
+		return err
+	}
+
+	timeout := defaultTimeout
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+
+	return w.send(ctx, http.MethodPost, cfg.URL, bodyBytes, cfg.Headers, timeout)
+}
+
+// send выполняет запрос и проверяет, что ответ имеет код 2xx.
+func (w *httpBatchWriterImpl) send(ctx context.Context, method, url string, body []byte, headers map[string]string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if url == "" {
+		return fmt.Errorf("API URL is empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("bad response (%d): %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// prepareBody формирует JSON-тело запроса в форме map или list.
+func (w *httpBatchWriterImpl) prepareBody(values map[string]*json.RawMessage, cfg *config.ApiPutBatchConfig) ([]byte, error) {
+
+	var payload interface{}
+
+	switch cfg.GetShape() {
+	case config.BodyShapeMap:
+		payload = values
+
+	case config.BodyShapeList:
+		items := make([]map[string]interface{}, 0, len(values))
+		for key, value := range values {
+			var k interface{} = key
+			if cfg.KeyType == config.KeyTypeNumber {
+				if _, err := strconv.ParseFloat(key, 64); err != nil {
+					return nil, fmt.Errorf("invalid numeric key %q: %w", key, err)
+				}
+				k = json.Number(key)
+			}
+			items = append(items, map[string]interface{}{cfg.Prop: k, cfg.ValueProp: value})
+		}
+		payload = items
+
+	default:
+		return nil, fmt.Errorf("unsupported body shape: %s", cfg.Shape)
+	}
+
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal body: %w", err)
+	}
+	return bodyBytes, nil
+}
@@ -22,23 +22,36 @@ import (
 // Поведение:
 //   - Если данные по ключу успешно получены — они попадают в Hits.
 //   - Если данные отсутствуют — ключ считается Miss.
-//   - Если возникла ошибка при запросе всей группы — все ключи из группы считаются Skipped.
+//   - Если возникла ошибка при запросе всей группы — все ключи из группы считаются Skipped,
+//     ошибка для каждого ключа попадает в Errors.
+//   - Если внешний API для кэша выключен — все ключи группы считаются Skipped без ошибки.
 //
 // Метод гарантирует:
 //   - Потокобезопасное слияние результатов.
 //   - Не более maxParallel параллельных HTTP-запросов.
+//
+// Метод PutAll записывает значения во внешний API (putBatch) по тем же правилам группировки:
+//   - Если для кэша putBatch не настроен — ключи группы попадают в Skipped.
+//   - Если запрос группы выполнен успешно — ключи попадают в Written.
+//   - Если возникла ошибка при запросе группы — все ключи группы попадают в Failed.
+//
+// Метод DeleteAll аналогично удаляет ключи во внешнем API (deleteBatch).
 type Service interface {
 	GetAll(ctx context.Context, reqs []*dto.ResolvedCacheId) *dto.GetResult
+	PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry) *dto.WriteResult
+	DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) *dto.WriteResult
 }
 
 type ServiceImpl struct {
 	fetcher       httpBatchFetcher
+	writer        httpBatchWriter
 	configService config.CacheService
 }
 
-func NewIntegrationService(cacheService config.CacheService, batchFetcher httpBatchFetcher) Service {
+func NewIntegrationService(cacheService config.CacheService, batchFetcher httpBatchFetcher, batchWriter httpBatchWriter) Service {
 	return &ServiceImpl{
 		fetcher:       batchFetcher,
+		writer:        batchWriter,
 		configService: cacheService,
 	}
 }
@@ -88,8 +101,13 @@ func (s *ServiceImpl) handleGroup(ctx context.Context, cacheName string, group [
 			Hits:    []*dto.ResolvedCacheHit{},
 			Misses:  []*dto.ResolvedCacheId{},
 			Skipped: group,
+			Errors:  failAll(group, err),
 		}
 	}
+	if !cache.Api.Enabled {
+		// внешнего источника нет: ключи не проверяются, это не ошибка
+		return &dto.GetResult{Skipped: group}
+	}
 
 	cfg := cache.Api.GetBatch
 
@@ -101,7 +119,7 @@ func (s *ServiceImpl) handleGroup(ctx context.Context, cacheName string, group [
 	metrics.RecordExternalRequest(cacheName, err, time.Since(start).Seconds())
 	if err != nil {
 		zap.S().Errorw(alert.Prefix("fetch error"), "cache", cacheName, "error", err)
-		return &dto.GetResult{Skipped: group}
+		return &dto.GetResult{Skipped: group, Errors: failAll(group, err)}
 	}
 
 	zap.S().Infow("fetched items", "count", len(respMap), "cache", cacheName)
@@ -109,6 +127,120 @@ func (s *ServiceImpl) handleGroup(ctx context.Context, cacheName string, group [
 	return s.classify(group, respMap)
 }
 
+// PutAll записывает значения во внешний API, параллельно обрабатывая группы по CacheName.
+// Одновременно выполняется не более 8 HTTP-запросов.
+func (s *ServiceImpl) PutAll(ctx context.Context, entries []*dto.ResolvedCacheEntry) *dto.WriteResult {
+	grouped := make(map[string][]*dto.ResolvedCacheEntry)
+	for _, e := range entries {
+		grouped[e.GetCacheName()] = append(grouped[e.GetCacheName()], e)
+	}
+	return writeGroups(grouped, func(name string, grp []*dto.ResolvedCacheEntry) *dto.WriteResult {
+		return s.handlePutGroup(ctx, name, grp)
+	})
+}
+
+// DeleteAll удаляет ключи во внешнем API, параллельно обрабатывая группы по CacheName.
+// Одновременно выполняется не более 8 HTTP-запросов.
+func (s *ServiceImpl) DeleteAll(ctx context.Context, reqs []*dto.ResolvedCacheId) *dto.WriteResult {
+	return writeGroups(s.group(reqs), func(name string, grp []*dto.ResolvedCacheId) *dto.WriteResult {
+		return s.handleDeleteGroup(ctx, name, grp)
+	})
+}
+
+// writeGroups обрабатывает группы параллельно (не более maxParallel) и сливает результаты.
+func writeGroups[T any](grouped map[string][]T, handle func(name string, grp []T) *dto.WriteResult) *dto.WriteResult {
+	final := &dto.WriteResult{}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	limiter := make(chan struct{}, maxParallel)
+
+	for cacheName, group := range grouped {
+		wg.Add(1)
+
+		limiter <- struct{}{} // занять слот
+
+		go func(name string, grp []T) {
+			defer wg.Done()
+			defer func() { <-limiter }() // освободить слот
+
+			result := handle(name, grp)
+
+			mu.Lock()
+			final.Merge(result)
+			mu.Unlock()
+		}(cacheName, group)
+	}
+
+	wg.Wait()
+	return final
+}
+
+// записывает одну группу значений одного кэша
+func (s *ServiceImpl) handlePutGroup(ctx context.Context, cacheName string, group []*dto.ResolvedCacheEntry) *dto.WriteResult {
+	ids := make([]*dto.ResolvedCacheId, len(group))
+	for i, e := range group {
+		ids[i] = e.ResolvedCacheId
+	}
+
+	cache, err := s.configService.GetCacheByName(cacheName)
+	if err != nil {
+		zap.S().Errorw(alert.Prefix("unknown cache"), "cache", cacheName, "error", err)
+		return &dto.WriteResult{Failed: failAll(ids, err)}
+	}
+	if !cache.Api.IsPutBatchEnabled() {
+		return &dto.WriteResult{Skipped: ids}
+	}
+
+	values := make(map[string]*json.RawMessage, len(group))
+	for _, e := range group {
+		values[e.GetKey()] = e.Value
+	}
+
+	start := time.Now()
+	zap.S().Infow("writing keys to external api", "count", len(values), "cache", cacheName)
+	err = s.writer.PutAll(ctx, values, cache.Api.PutBatch)
+	metrics.RecordExternalWrite(cacheName, metrics.ExternalOperationPut, err, time.Since(start).Seconds())
+	if err != nil {
+		zap.S().Errorw(alert.Prefix("external write error"), "cache", cacheName, "error", err)
+		return &dto.WriteResult{Failed: failAll(ids, err)}
+	}
+	return &dto.WriteResult{Written: ids}
+}
+
+// удаляет одну группу ключей одного кэша
+func (s *ServiceImpl) handleDeleteGroup(ctx context.Context, cacheName string, group []*dto.ResolvedCacheId) *dto.WriteResult {
+	cache, err := s.configService.GetCacheByName(cacheName)
+	if err != nil {
+		zap.S().Errorw(alert.Prefix("unknown cache"), "cache", cacheName, "error", err)
+		return &dto.WriteResult{Failed: failAll(group, err)}
+	}
+	if !cache.Api.IsDeleteBatchEnabled() {
+		return &dto.WriteResult{Skipped: group}
+	}
+
+	keys := s.extractKeys(group)
+
+	start := time.Now()
+	zap.S().Infow("deleting keys in external api", "count", len(keys), "cache", cacheName)
+	err = s.writer.DeleteAll(ctx, keys, cache.Api.DeleteBatch)
+	metrics.RecordExternalWrite(cacheName, metrics.ExternalOperationDelete, err, time.Since(start).Seconds())
+	if err != nil {
+		zap.S().Errorw(alert.Prefix("external delete error"), "cache", cacheName, "error", err)
+		return &dto.WriteResult{Failed: failAll(group, err)}
+	}
+	return &dto.WriteResult{Written: group}
+}
+
+// помечает все ключи группы одной ошибкой
+func failAll(ids []*dto.ResolvedCacheId, err error) []*dto.ResolvedCacheError {
+	failed := make([]*dto.ResolvedCacheError, len(ids))
+	for i, id := range ids {
+		failed[i] = &dto.ResolvedCacheError{ResolvedCacheId: id, Err: err}
+	}
+	return failed
+}
+
 // превращает []*ResolvedCacheId → []string ключей
 func (s *ServiceImpl) extractKeys(group []*dto.ResolvedCacheId) []string {
 	keys := make([]string, len(group))
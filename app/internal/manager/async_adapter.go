@@ -2,38 +2,91 @@ package manager
 
 import (
 	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/config"
 	"context"
+	"fmt"
 	"time"
 
 	"go.uber.org/zap"
-	"telegram-alerts-go/alert"
 )
 
-// Максимум 64 одновременных async-операций (PutAll/EvictAll).
-var tokens = make(chan struct{}, 64)
-
 // ManagerAdapter provides a simplified interface over Manager.
-// PutAll и EvictAll выполняются асинхронно, поэтому доступ лимитируем семафором.
+// Запись в слои кэша (PutAll и EvictAll) выполняется асинхронно через очередь write-behind.
+// Если очередь заполнена, операция не принимается и возвращается ErrQueueFull.
+// Запись во внешний API (putBatch, deleteBatch) выполняется синхронно: PutAll и EvictAll
+// возвращают ключи, которые внешний API не принял; пустой результат означает успех.
+// При writeOrder: after значения сначала ставятся в очередь записи в слои, а ключи,
+// которые внешний API затем не принял, удаляются из слоёв следующей операцией очереди.
+//
+// PutAllSync и EvictAllSync выполняют ту же операцию, но записывают слои кэша сразу,
+// минуя очередь, и возвращают результат по каждому ключу и слою (read-your-writes).
+//
+// Условные записи (ifAbsent, ifVersion) всегда выполняются сразу, минуя очередь: клиенту
+// нужен их результат. Если условие не выполнилось, PutAll возвращает *ConflictError,
+// PutAllSync — ключи в WriteReport.Conflicts.
+//
+// TouchAll продлевает TTL записей сразу, минуя очередь: значения при этом не передаются.
+// EvictByTag также выполняется сразу.
+//
+// Операции над кэшем целиком вынесены в AdminAdapter.
 type ManagerAdapter interface {
 	Get(ctx context.Context, id *dto.CacheId) *dto.CacheEntryHit
-	Put(ctx context.Context, entry *dto.CacheEntry)
-	Evict(ctx context.Context, id *dto.CacheId)
+	Put(ctx context.Context, entry *dto.CacheEntry) ([]*dto.UpstreamError, error)
+	Evict(ctx context.Context, id *dto.CacheId) ([]*dto.UpstreamError, error)
 
 	GetAll(ctx context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit
-	PutAll(ctx context.Context, entries []*dto.CacheEntry)
-	EvictAll(ctx context.Context, ids []*dto.CacheId)
+	GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, opts dto.GetOptions) []*dto.CacheEntryHit
+	PutAll(ctx context.Context, entries []*dto.CacheEntry) ([]*dto.UpstreamError, error)
+	EvictAll(ctx context.Context, ids []*dto.CacheId) ([]*dto.UpstreamError, error)
+
+	PutAllSync(ctx context.Context, entries []*dto.CacheEntry) *dto.WriteReport
+	EvictAllSync(ctx context.Context, ids []*dto.CacheId) *dto.WriteReport
+
+	TouchAll(ctx context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult
+
+	EvictByTag(ctx context.Context, tag string) *dto.TagEvictReport
+}
+
+// AdminAdapter — административные операции над кэшем целиком, доступные только через
+// административные эндпоинты HTTP. Выполняются сразу, минуя очередь write-behind.
+// FlushCache запускает фоновую очистку кэша, FlushStatus и CancelFlush — её состояние и отмена.
+// CacheGeneration и BumpGeneration — поколение кэша и его увеличение. ScanKeys — постраничный
+// список ключей кэша на слое.
+type AdminAdapter interface {
+	FlushCache(cacheName, keyPrefix string) (*dto.FlushJob, error)
+	FlushStatus(cacheName string) *dto.FlushJob
+	CancelFlush(cacheName string) *dto.FlushJob
+
+	CacheGeneration(cacheName string) (*dto.CacheGeneration, error)
+	BumpGeneration(ctx context.Context, cacheName string, sweep bool) (*dto.CacheGeneration, error)
+
+	ScanKeys(ctx context.Context, cacheName string, layer int, match, cursor string, count int) (*dto.KeyPage, error)
+}
+
+// ConflictError — условие записи (ifAbsent / ifVersion) не выполнилось для части ключей.
+// Эти ключи не записаны, остальные записи пачки выполнены.
+type ConflictError struct {
+	Keys []*dto.CacheId
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("write condition failed for %d key(s)", len(e.Keys))
 }
 
 type AsyncManagerAdapter struct {
-	manager         Manager
-	putAllTimeout   time.Duration
-	evictAllTimeout time.Duration
+	manager Manager
+	queue   *writeQueue
 }
 
 const defaultTimeout = 5 * time.Second
 
-// NewAsyncManagerAdapter создаёт адаптер с явными или дефолтными тайм-аутами.
+// NewAsyncManagerAdapter создаёт адаптер с явными или дефолтными тайм-аутами
+// и очередью write-behind в памяти (без журнала).
 func NewAsyncManagerAdapter(m Manager, putTO, evictTO time.Duration) *AsyncManagerAdapter {
+	return newAsyncManagerAdapter(m, putTO, evictTO, nil, nil, config.WriteBehindConfig{})
+}
+
+func newAsyncManagerAdapter(m Manager, putTO, evictTO time.Duration, j *journal, pending []*writeOp, cfg config.WriteBehindConfig) *AsyncManagerAdapter {
 	if putTO <= 0 {
 		zap.S().Warnf("putAllTimeout ≤ 0 set %s", defaultTimeout)
 		putTO = defaultTimeout
@@ -43,12 +96,16 @@ func NewAsyncManagerAdapter(m Manager, putTO, evictTO time.Duration) *AsyncManag
 		evictTO = defaultTimeout
 	}
 	return &AsyncManagerAdapter{
-		manager:         m,
-		putAllTimeout:   putTO,
-		evictAllTimeout: evictTO,
+		manager: m,
+		queue:   newWriteQueue(newApplyFunc(m, putTO, evictTO), j, pending, cfg.GetQueueSize(), cfg.GetWorkers()),
 	}
 }
 
+// Close перестаёт принимать записи и дожидается применения уже принятых.
+func (a *AsyncManagerAdapter) Close() error {
+	return a.queue.close()
+}
+
 /* ---------- синхронные обёртки ---------- */
 
 func (a *AsyncManagerAdapter) Get(ctx context.Context, id *dto.CacheId) *dto.CacheEntryHit {
@@ -62,45 +119,53 @@ func (a *AsyncManagerAdapter) Get(ctx context.Context, id *dto.CacheId) *dto.Cac
 	return res[0]
 }
 
-func (a *AsyncManagerAdapter) Put(ctx context.Context, e *dto.CacheEntry) {
-	if e != nil {
-		a.PutAll(ctx, []*dto.CacheEntry{e})
+func (a *AsyncManagerAdapter) Put(ctx context.Context, e *dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	if e == nil {
+		return nil, nil
 	}
+	return a.PutAll(ctx, []*dto.CacheEntry{e})
 }
 
-func (a *AsyncManagerAdapter) Evict(ctx context.Context, id *dto.CacheId) {
-	if id != nil {
-		a.EvictAll(ctx, []*dto.CacheId{id})
+func (a *AsyncManagerAdapter) Evict(ctx context.Context, id *dto.CacheId) ([]*dto.UpstreamError, error) {
+	if id == nil {
+		return nil, nil
 	}
+	return a.EvictAll(ctx, []*dto.CacheId{id})
 }
 
 func (a *AsyncManagerAdapter) GetAll(ctx context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit {
 	return a.manager.GetAll(ctx, ids)
 }
 
-/* ---------- ограниченный async-раннер ---------- */
-
-func (a *AsyncManagerAdapter) runAsync(name string, f func(ctx context.Context), d time.Duration) {
-	/* забираем токен — если буфер полон, ждём */
-	tokens <- struct{}{}
-
-	go func() {
-		/* освобождаем токен при выходе */
-		defer func() { <-tokens }()
-
-		ctx, cancel := makeCtx(d)
-		defer cancel()
+func (a *AsyncManagerAdapter) GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, opts dto.GetOptions) []*dto.CacheEntryHit {
+	return a.manager.GetAllWithOptions(ctx, ids, opts)
+}
 
-		defer func() {
-			if r := recover(); r != nil {
-				zap.S().Errorf(alert.Prefix("async panic: %v"), r)
-			}
-		}()
+/* ---------- очередь write-behind ---------- */
 
-		zap.S().Debugw("async started", "name", name)
-		f(ctx)
-		zap.S().Debugw("async finished", "name", name)
+// enqueue резервирует место в очереди, выполняет синхронную часть операции (prepare)
+// и ставит в очередь возвращённую ей операцию над слоями.
+// Место резервируется заранее, чтобы при заполненной очереди не менять внешний API.
+func (a *AsyncManagerAdapter) enqueue(prepare func() *writeOp) error {
+	if err := a.queue.acquire(); err != nil {
+		return err
+	}
+	submitted := false
+	defer func() {
+		if !submitted {
+			a.queue.release()
+		}
 	}()
+
+	op := prepare()
+	if len(op.Put) == 0 && len(op.Evict) == 0 {
+		return nil
+	}
+	if err := a.queue.submit(op); err != nil {
+		return err
+	}
+	submitted = true
+	return nil
 }
 
 func makeCtx(d time.Duration) (context.Context, context.CancelFunc) {
@@ -112,20 +177,207 @@ func makeCtx(d time.Duration) (context.Context, context.CancelFunc) {
 
 /* ---------- асинхронные методы ---------- */
 
-func (a *AsyncManagerAdapter) PutAll(_ context.Context, entries []*dto.CacheEntry) {
-	if len(entries) == 0 {
-		return
+func (a *AsyncManagerAdapter) PutAll(ctx context.Context, entries []*dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	plain, conditional := splitConditional(entries)
+
+	before, after := a.manager.SplitByWriteOrder(plain)
+
+	var failed []*dto.UpstreamError
+	if len(before) > 0 {
+		err := a.enqueue(func() *writeOp {
+			// сначала system of record: в слои попадает только то, что принял внешний API
+			upstream := a.manager.WriteUpstream(ctx, before)
+			failed = upstream.Failed
+			return &writeOp{Put: upstream.Put, Evict: upstream.Evict}
+		})
+		if err != nil {
+			return failed, err
+		}
+	}
+	if len(after) > 0 {
+		rejected, err := a.putAfter(ctx, after)
+		failed = append(failed, rejected...)
+		if err != nil {
+			return failed, err
+		}
+	}
+	if len(conditional) == 0 {
+		return failed, nil
+	}
+
+	report := a.manager.PutAllIf(ctx, conditional)
+	failed = append(failed, report.Errors...)
+	if len(report.Conflicts) > 0 {
+		return failed, &ConflictError{Keys: report.Conflicts}
+	}
+	return failed, nil
+}
+
+// putAfter ставит значения в очередь записи в слои и затем пишет их во внешний API
+// (writeOrder: after). Ключи, которые внешний API не принял, удаляются из слоёв следующей
+// операцией очереди. Место под неё резервируется заранее вместе с местом под запись:
+// иначе при заполненной очереди слои разошлись бы с внешним API.
+func (a *AsyncManagerAdapter) putAfter(ctx context.Context, entries []*dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	if err := a.queue.acquire(); err != nil {
+		return nil, err
+	}
+	if err := a.queue.acquire(); err != nil {
+		a.queue.release()
+		return nil, err
+	}
+	if err := a.queue.submit(&writeOp{Put: entries}); err != nil {
+		a.queue.release()
+		a.queue.release()
+		return nil, err
+	}
+
+	upstream := a.manager.WriteUpstream(ctx, entries)
+	if len(upstream.Failed) == 0 {
+		a.queue.release()
+		return nil, nil
+	}
+	if err := a.queue.submit(&writeOp{Evict: upstreamErrorIds(upstream.Failed)}); err != nil {
+		a.queue.release()
+		return upstream.Failed, err
 	}
-	a.runAsync("putAll", func(ctx context.Context) {
-		a.manager.PutAll(ctx, entries)
-	}, a.putAllTimeout)
+	return upstream.Failed, nil
 }
 
-func (a *AsyncManagerAdapter) EvictAll(_ context.Context, ids []*dto.CacheId) {
+func upstreamErrorIds(errs []*dto.UpstreamError) []*dto.CacheId {
+	ids := make([]*dto.CacheId, 0, len(errs))
+	for _, e := range errs {
+		ids = append(ids, e.CacheId)
+	}
+	return ids
+}
+
+// splitConditional отделяет условные записи (ifAbsent, ifVersion) от обычных.
+func splitConditional(entries []*dto.CacheEntry) (plain, conditional []*dto.CacheEntry) {
+	for _, e := range entries {
+		if e.IsConditional() {
+			conditional = append(conditional, e)
+		} else {
+			plain = append(plain, e)
+		}
+	}
+	return
+}
+
+func (a *AsyncManagerAdapter) EvictAll(ctx context.Context, ids []*dto.CacheId) ([]*dto.UpstreamError, error) {
 	if len(ids) == 0 {
-		return
+		return nil, nil
 	}
-	a.runAsync("evictAll", func(ctx context.Context) {
-		a.manager.EvictAll(ctx, ids)
-	}, a.evictAllTimeout)
+
+	var failed []*dto.UpstreamError
+	err := a.enqueue(func() *writeOp {
+		// удаление из слоёв безопасно и при ошибке внешнего API: значение будет перечитано при get_all
+		failed = a.manager.DeleteUpstream(ctx, ids)
+		return &writeOp{Evict: ids}
+	})
+	return failed, err
+}
+
+/* ---------- синхронные методы ---------- */
+
+// PutAllSync записывает значения во внешний API и сразу в слои кэша (в порядке writeOrder).
+// Операции, ранее принятые в очередь, не дожидаются: если в очереди есть запись того же ключа,
+// она будет применена позже и перезапишет результат.
+func (a *AsyncManagerAdapter) PutAllSync(ctx context.Context, entries []*dto.CacheEntry) *dto.WriteReport {
+	report := &dto.WriteReport{Results: []*dto.WriteItemResult{}}
+	plain, conditional := splitConditional(entries)
+
+	before, after := a.manager.SplitByWriteOrder(plain)
+
+	if len(before) > 0 {
+		upstream := a.manager.WriteUpstream(ctx, before)
+		report.Errors = upstream.Failed
+		if len(upstream.Evict) > 0 {
+			report.Results = append(report.Results, a.manager.EvictAll(ctx, upstream.Evict)...)
+		}
+		if len(upstream.Put) > 0 {
+			report.Results = append(report.Results, a.manager.PutAll(ctx, upstream.Put)...)
+		}
+	}
+	if len(after) > 0 {
+		// writeOrder: after — сначала слои, затем внешний API; отклонённые ключи удаляются из слоёв
+		written := a.manager.PutAll(ctx, after)
+		upstream := a.manager.WriteUpstream(ctx, after)
+		report.Errors = append(report.Errors, upstream.Failed...)
+
+		rejected := make(map[dto.CacheId]bool, len(upstream.Failed))
+		if len(upstream.Failed) > 0 {
+			for _, f := range upstream.Failed {
+				rejected[*f.CacheId] = true
+			}
+			a.manager.EvictAll(ctx, upstreamErrorIds(upstream.Failed))
+		}
+		for _, r := range written {
+			if !rejected[*r.CacheId] {
+				report.Results = append(report.Results, r)
+			}
+		}
+	}
+	if len(conditional) > 0 {
+		cond := a.manager.PutAllIf(ctx, conditional)
+		report.Results = append(report.Results, cond.Results...)
+		report.Errors = append(report.Errors, cond.Errors...)
+		report.Conflicts = cond.Conflicts
+	}
+	return report
+}
+
+// EvictAllSync удаляет ключи во внешнем API и сразу из слоёв кэша.
+func (a *AsyncManagerAdapter) EvictAllSync(ctx context.Context, ids []*dto.CacheId) *dto.WriteReport {
+	report := &dto.WriteReport{Results: []*dto.WriteItemResult{}}
+	if len(ids) == 0 {
+		return report
+	}
+
+	report.Errors = a.manager.DeleteUpstream(ctx, ids)
+	report.Results = a.manager.EvictAll(ctx, ids)
+	return report
+}
+
+// TouchAll продлевает TTL записей в слоях кэша. Операции, ранее принятые в очередь,
+// не дожидаются: запись, применённая позже, получит свой TTL.
+func (a *AsyncManagerAdapter) TouchAll(ctx context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult {
+	if len(entries) == 0 {
+		return []*dto.TouchItemResult{}
+	}
+	return a.manager.TouchAll(ctx, entries)
+}
+
+// EvictByTag удаляет записи с тегом из слоёв кэша. Операции, ранее принятые в очередь,
+// не дожидаются: запись с тегом, применённая позже, останется в кэше.
+func (a *AsyncManagerAdapter) EvictByTag(ctx context.Context, tag string) *dto.TagEvictReport {
+	return a.manager.EvictByTag(ctx, tag)
+}
+
+// FlushCache запускает фоновую очистку кэша. Операции, ранее принятые в очередь,
+// не дожидаются: запись, применённая после очистки, останется в кэше.
+func (a *AsyncManagerAdapter) FlushCache(cacheName, keyPrefix string) (*dto.FlushJob, error) {
+	return a.manager.FlushCache(cacheName, keyPrefix)
+}
+
+func (a *AsyncManagerAdapter) FlushStatus(cacheName string) *dto.FlushJob {
+	return a.manager.FlushStatus(cacheName)
+}
+
+func (a *AsyncManagerAdapter) CancelFlush(cacheName string) *dto.FlushJob {
+	return a.manager.CancelFlush(cacheName)
+}
+
+func (a *AsyncManagerAdapter) CacheGeneration(cacheName string) (*dto.CacheGeneration, error) {
+	return a.manager.CacheGeneration(cacheName)
+}
+
+// BumpGeneration увеличивает поколение кэша. Операции, ранее принятые в очередь, не дожидаются:
+// записи, применённые после увеличения, попадут в новое поколение.
+func (a *AsyncManagerAdapter) BumpGeneration(ctx context.Context, cacheName string, sweep bool) (*dto.CacheGeneration, error) {
+	return a.manager.BumpGeneration(ctx, cacheName, sweep)
+}
+
+// ScanKeys перечисляет ключи, уже записанные в слой: операции, ожидающие в очереди, не видны.
+func (a *AsyncManagerAdapter) ScanKeys(ctx context.Context, cacheName string, layer int, match, cursor string, count int) (*dto.KeyPage, error) {
+	return a.manager.ScanKeys(ctx, cacheName, layer, match, cursor, count)
 }
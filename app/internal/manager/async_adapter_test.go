@@ -2,6 +2,7 @@ package manager
 
 import (
 	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/config"
 	"context"
 	"sync"
 	"testing"
@@ -18,6 +19,29 @@ type mockManager struct {
 	wait           time.Duration
 	putWG          sync.WaitGroup
 	evictWG        sync.WaitGroup
+
+	// upstream — результат WriteUpstream; nil = все записи идут в слои
+	upstream *dto.UpstreamWriteResult
+
+	// deleteFailed — результат DeleteUpstream
+	deleteFailed []*dto.UpstreamError
+
+	// condEntries — записи, переданные в PutAllIf; conflicts — его результат
+	condEntries []*dto.CacheEntry
+	conflicts   []*dto.CacheId
+
+	// failKeys — ключи, для которых PutAll и EvictAll возвращают ошибку слоя
+	failKeys map[string]bool
+
+	// writeAfter — все записи идут с writeOrder: after; calls — порядок вызовов записи
+	writeAfter bool
+	calls      []string
+}
+
+func (m *mockManager) record(call string) {
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	m.mu.Unlock()
 }
 
 func (m *mockManager) GetAll(ctx context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit {
@@ -31,20 +55,101 @@ func (m *mockManager) GetAll(ctx context.Context, ids []*dto.CacheId) []*dto.Cac
 	return hits
 }
 
-func (m *mockManager) PutAll(ctx context.Context, entries []*dto.CacheEntry) {
+func (m *mockManager) GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, _ dto.GetOptions) []*dto.CacheEntryHit {
+	return m.GetAll(ctx, ids)
+}
+
+func (m *mockManager) PutAll(ctx context.Context, entries []*dto.CacheEntry) []*dto.WriteItemResult {
 	defer m.putWG.Done()
 	time.Sleep(m.wait)
 	m.mu.Lock()
 	m.putAllCalled++
+	m.calls = append(m.calls, "put")
 	m.mu.Unlock()
+	ids := make([]*dto.CacheId, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.CacheId)
+	}
+	return m.layerResults(ids)
 }
 
-func (m *mockManager) EvictAll(ctx context.Context, ids []*dto.CacheId) {
+func (m *mockManager) layerResults(ids []*dto.CacheId) []*dto.WriteItemResult {
+	res := make([]*dto.WriteItemResult, 0, len(ids))
+	for _, id := range ids {
+		item := &dto.WriteItemResult{CacheId: id}
+		if m.failKeys[id.Key] {
+			item.Layers = []*dto.LayerWriteStatus{{Layer: 0, Status: dto.LayerStatusError, Error: "down"}}
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+func writeItemResults(entries []*dto.CacheEntry) []*dto.WriteItemResult {
+	res := make([]*dto.WriteItemResult, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, &dto.WriteItemResult{CacheId: e.CacheId})
+	}
+	return res
+}
+
+func (m *mockManager) WriteUpstream(_ context.Context, entries []*dto.CacheEntry) *dto.UpstreamWriteResult {
+	m.record("upstream")
+	if m.upstream != nil {
+		return m.upstream
+	}
+	return &dto.UpstreamWriteResult{Put: entries}
+}
+
+func (m *mockManager) SplitByWriteOrder(entries []*dto.CacheEntry) (before, after []*dto.CacheEntry) {
+	if m.writeAfter {
+		return nil, entries
+	}
+	return entries, nil
+}
+
+func (m *mockManager) PutAllIf(_ context.Context, entries []*dto.CacheEntry) *dto.WriteReport {
+	m.mu.Lock()
+	m.condEntries = append(m.condEntries, entries...)
+	m.mu.Unlock()
+	return &dto.WriteReport{Results: writeItemResults(entries), Conflicts: m.conflicts}
+}
+
+func (m *mockManager) DeleteUpstream(context.Context, []*dto.CacheId) []*dto.UpstreamError {
+	return m.deleteFailed
+}
+
+func (m *mockManager) TouchAll(_ context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult {
+	res := make([]*dto.TouchItemResult, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, &dto.TouchItemResult{CacheId: e.CacheId, Touched: true})
+	}
+	return res
+}
+
+func (m *mockManager) EvictByTag(_ context.Context, tag string) *dto.TagEvictReport {
+	return &dto.TagEvictReport{Tag: tag}
+}
+
+func (m *mockManager) FlushCache(string, string) (*dto.FlushJob, error)     { return nil, nil }
+func (m *mockManager) FlushStatus(string) *dto.FlushJob                     { return nil }
+func (m *mockManager) CancelFlush(string) *dto.FlushJob                     { return nil }
+func (m *mockManager) CacheGeneration(string) (*dto.CacheGeneration, error) { return nil, nil }
+func (m *mockManager) BumpGeneration(context.Context, string, bool) (*dto.CacheGeneration, error) {
+	return nil, nil
+}
+func (m *mockManager) ScanKeys(context.Context, string, int, string, string, int) (*dto.KeyPage, error) {
+	return nil, nil
+}
+
+func (m *mockManager) EvictAll(ctx context.Context, ids []*dto.CacheId) []*dto.WriteItemResult {
 	defer m.evictWG.Done()
 	time.Sleep(m.wait)
 	m.mu.Lock()
 	m.evictAllCalled++
+	m.calls = append(m.calls, "evict")
 	m.mu.Unlock()
+	return m.layerResults(ids)
 }
 
 func TestAsyncAdapter_GetWrapsManager(t *testing.T) {
@@ -108,3 +213,154 @@ func TestAsyncAdapter_SingleHelpers(t *testing.T) {
 	assert.Equal(t, 1, mgr.getAllCalled)
 	assert.NotNil(t, res)
 }
+
+func TestAsyncAdapter_PutAllUpstream(t *testing.T) {
+	id := &dto.CacheId{CacheName: "c", Key: "k"}
+	failed := &dto.UpstreamError{CacheId: &dto.CacheId{CacheName: "c", Key: "bad"}, Error: "boom"}
+	mgr := &mockManager{upstream: &dto.UpstreamWriteResult{
+		Evict:  []*dto.CacheId{id},
+		Failed: []*dto.UpstreamError{failed},
+	}}
+	mgr.evictWG.Add(1)
+	f := NewAsyncManagerAdapter(mgr, 1*time.Second, 1*time.Second)
+
+	res, err := f.PutAll(context.Background(), []*dto.CacheEntry{{CacheId: id}, {CacheId: failed.CacheId}})
+	mgr.evictWG.Wait()
+
+	assert.NoError(t, err)
+	// write-around: значение удаляется из слоёв, а не записывается в них
+	assert.Equal(t, []*dto.UpstreamError{failed}, res)
+	assert.Equal(t, 0, mgr.putAllCalled)
+	assert.Equal(t, 1, mgr.evictAllCalled)
+}
+
+func TestAsyncAdapter_EvictAllUpstreamFailure(t *testing.T) {
+	id := &dto.CacheId{CacheName: "c", Key: "k"}
+	failed := []*dto.UpstreamError{{CacheId: id, Error: "boom"}}
+	mgr := &mockManager{deleteFailed: failed}
+	mgr.evictWG.Add(1)
+	f := NewAsyncManagerAdapter(mgr, 1*time.Second, 1*time.Second)
+
+	res, err := f.EvictAll(context.Background(), []*dto.CacheId{id})
+	mgr.evictWG.Wait()
+
+	assert.NoError(t, err)
+	// ошибка внешнего API возвращается клиенту, но из слоёв ключ всё равно удаляется
+	assert.Equal(t, failed, res)
+	assert.Equal(t, 1, mgr.evictAllCalled)
+}
+
+func TestAsyncAdapter_PutAllSync(t *testing.T) {
+	id := &dto.CacheId{CacheName: "c", Key: "k"}
+	failed := &dto.UpstreamError{CacheId: &dto.CacheId{CacheName: "c", Key: "bad"}, Error: "boom"}
+	mgr := &mockManager{wait: 50 * time.Millisecond, upstream: &dto.UpstreamWriteResult{
+		Put:    []*dto.CacheEntry{{CacheId: id}},
+		Failed: []*dto.UpstreamError{failed},
+	}}
+	mgr.putWG.Add(1)
+	f := NewAsyncManagerAdapter(mgr, 1*time.Second, 1*time.Second)
+	defer f.Close()
+
+	report := f.PutAllSync(context.Background(), []*dto.CacheEntry{{CacheId: id}, {CacheId: failed.CacheId}})
+
+	// слои записаны до возврата, без ожидания очереди
+	assert.Equal(t, 1, mgr.putAllCalled)
+	assert.Len(t, report.Results, 1)
+	assert.Equal(t, id, report.Results[0].CacheId)
+	assert.Equal(t, []*dto.UpstreamError{failed}, report.Errors)
+}
+
+func TestAsyncAdapter_PutAllWriteOrderAfter(t *testing.T) {
+	id := &dto.CacheId{CacheName: "c", Key: "k"}
+	failed := &dto.UpstreamError{CacheId: &dto.CacheId{CacheName: "c", Key: "bad"}, Error: "boom"}
+	mgr := &mockManager{writeAfter: true, upstream: &dto.UpstreamWriteResult{
+		Put:    []*dto.CacheEntry{{CacheId: id}},
+		Failed: []*dto.UpstreamError{failed},
+	}}
+	mgr.putWG.Add(1)
+	mgr.evictWG.Add(1)
+	// один воркер: оба ключа записываются одной операцией
+	f := newAsyncManagerAdapter(mgr, time.Second, time.Second, nil, nil, config.WriteBehindConfig{Workers: 1})
+
+	res, err := f.PutAll(context.Background(), []*dto.CacheEntry{{CacheId: id}, {CacheId: failed.CacheId}})
+	mgr.putWG.Wait()
+	mgr.evictWG.Wait()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*dto.UpstreamError{failed}, res)
+	// в слои ставятся все значения, отклонённые внешним API затем удаляются
+	assert.Equal(t, 1, mgr.putAllCalled)
+	assert.Equal(t, 1, mgr.evictAllCalled)
+}
+
+func TestAsyncAdapter_PutAllSyncWriteOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		writeAfter bool
+		calls      []string
+	}{
+		{"before", false, []string{"upstream", "put"}},
+		{"after", true, []string{"put", "upstream", "evict"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := &dto.CacheId{CacheName: "c", Key: "k"}
+			failed := &dto.UpstreamError{CacheId: &dto.CacheId{CacheName: "c", Key: "bad"}, Error: "boom"}
+			mgr := &mockManager{writeAfter: tt.writeAfter, upstream: &dto.UpstreamWriteResult{
+				Put:    []*dto.CacheEntry{{CacheId: id}},
+				Failed: []*dto.UpstreamError{failed},
+			}}
+			mgr.putWG.Add(1)
+			if tt.writeAfter {
+				mgr.evictWG.Add(1)
+			}
+			f := NewAsyncManagerAdapter(mgr, 1*time.Second, 1*time.Second)
+			defer f.Close()
+
+			report := f.PutAllSync(context.Background(), []*dto.CacheEntry{{CacheId: id}, {CacheId: failed.CacheId}})
+
+			assert.Equal(t, tt.calls, mgr.calls)
+			// ключ, отклонённый внешним API, не остаётся в слоях ни при каком порядке
+			assert.Len(t, report.Results, 1)
+			assert.Equal(t, id, report.Results[0].CacheId)
+			assert.Equal(t, []*dto.UpstreamError{failed}, report.Errors)
+		})
+	}
+}
+
+func TestAsyncAdapter_PutAllConditional(t *testing.T) {
+	id := &dto.CacheId{CacheName: "c", Key: "k"}
+	taken := &dto.CacheId{CacheName: "c", Key: "taken"}
+	mgr := &mockManager{conflicts: []*dto.CacheId{taken}}
+	f := NewAsyncManagerAdapter(mgr, 1*time.Second, 1*time.Second)
+	defer f.Close()
+
+	entries := []*dto.CacheEntry{{CacheId: id, IfAbsent: true}, {CacheId: taken, IfVersion: "1"}}
+	_, err := f.PutAll(context.Background(), entries)
+
+	var conflict *ConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, []*dto.CacheId{taken}, conflict.Keys)
+	// условная запись идёт мимо очереди write-behind
+	assert.Equal(t, entries, mgr.condEntries)
+	assert.Equal(t, 0, mgr.putAllCalled)
+
+	report := f.PutAllSync(context.Background(), entries)
+	assert.Equal(t, []*dto.CacheId{taken}, report.Conflicts)
+	assert.Len(t, report.Results, 2)
+}
+
+func TestAsyncAdapter_EvictAllSync(t *testing.T) {
+	id := &dto.CacheId{CacheName: "c", Key: "k"}
+	mgr := &mockManager{}
+	mgr.evictWG.Add(1)
+	f := NewAsyncManagerAdapter(mgr, 1*time.Second, 1*time.Second)
+	defer f.Close()
+
+	report := f.EvictAllSync(context.Background(), []*dto.CacheId{id})
+
+	assert.Equal(t, 1, mgr.evictAllCalled)
+	assert.Len(t, report.Results, 1)
+	assert.Empty(t, report.Errors)
+}
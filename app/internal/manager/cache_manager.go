@@ -3,7 +3,9 @@ package manager
 import (
 	"aur-cache-service/api/dto"
 	"aur-cache-service/internal/cache"
+	"aur-cache-service/internal/cache/config"
 	"aur-cache-service/internal/integration"
+	"aur-cache-service/internal/metrics"
 	"context"
 	"time"
 
@@ -25,54 +27,273 @@ type Manager interface {
 	// GetAll получает значения по заданным ключам.
 	// Выполняет поиск во всех слоях кэша сверху вниз, при промахе — запрашивает внешний источник.
 	// Затем актуализирует недостающие уровни кэша.
+	// Возвращает результат для каждого ключа в порядке ids, со статусом (dto.HitStatus*).
 	GetAll(ctx context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit
 
-	// PutAll вставляет записи во все уровни кэша.
-	PutAll(ctx context.Context, entries []*dto.CacheEntry)
+	// GetAllWithOptions — GetAll с параметрами чтения: метаданные найденных значений, чтение
+	// в обход кэша, без внешнего API, по диапазону слоёв, без дозаписи слоёв (см. dto.GetOptions).
+	GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, opts dto.GetOptions) []*dto.CacheEntryHit
 
-	// EvictAll удаляет записи со всех уровней кэша.
-	EvictAll(ctx context.Context, ids []*dto.CacheId)
+	// PutAll вставляет записи во все уровни кэша и возвращает результат записи каждого ключа по слоям.
+	PutAll(ctx context.Context, entries []*dto.CacheEntry) []*dto.WriteItemResult
+
+	// PutAllIf выполняет условную запись (ifAbsent / ifVersion): условие проверяется в нижнем
+	// включённом слое кэша, записанные значения копируются в слои выше и отправляются во внешний API.
+	// Возвращает результат по слоям для записанных ключей, конфликты и ошибки.
+	PutAllIf(ctx context.Context, entries []*dto.CacheEntry) *dto.WriteReport
+
+	// WriteUpstream записывает значения во внешний API (putBatch) для кэшей, где он настроен,
+	// и возвращает, какие записи сохранить в слоях, а какие ключи удалить из них (write-around).
+	// Слои кэша при этом не меняются.
+	WriteUpstream(ctx context.Context, entries []*dto.CacheEntry) *dto.UpstreamWriteResult
+
+	// SplitByWriteOrder делит записи по порядку записи во внешний API (config.WriteOrder):
+	// after — записи кэшей с putBatch и writeOrder: after, которые пишутся в слои до внешнего API;
+	// before — все остальные.
+	SplitByWriteOrder(entries []*dto.CacheEntry) (before, after []*dto.CacheEntry)
+
+	// DeleteUpstream удаляет ключи во внешнем API (deleteBatch) для кэшей, где он настроен,
+	// и возвращает ключи, которые удалить не удалось. Слои кэша при этом не меняются.
+	DeleteUpstream(ctx context.Context, ids []*dto.CacheId) []*dto.UpstreamError
+
+	// TouchAll продлевает TTL записей во всех слоях кэша, не перезаписывая значения (внешний API
+	// не вызывается), и возвращает результат по каждому ключу и слою.
+	TouchAll(ctx context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult
+
+	// EvictAll удаляет записи со всех уровней кэша и возвращает результат удаления каждого ключа по слоям.
+	EvictAll(ctx context.Context, ids []*dto.CacheId) []*dto.WriteItemResult
+
+	// EvictByTag удаляет записи с тегом со всех уровней кэша (внешний API не вызывается)
+	// и возвращает результат по каждому слою.
+	EvictByTag(ctx context.Context, tag string) *dto.TagEvictReport
+
+	// FlushCache запускает фоновую очистку кэша на всех уровнях: удаляются все ключи кэша или только
+	// ключи с префиксом keyPrefix (внешний API не вызывается). Для кэша выполняется не больше одной
+	// очистки: если она уже идёт, возвращается её состояние и ErrFlushRunning.
+	FlushCache(cacheName, keyPrefix string) (*dto.FlushJob, error)
+
+	// FlushStatus возвращает состояние последней очистки кэша (nil — очисток не было).
+	FlushStatus(cacheName string) *dto.FlushJob
+
+	// CancelFlush отменяет выполняющуюся очистку кэша и возвращает её состояние (nil — очисток не было).
+	// Отмена асинхронна: задача переходит в cancelled, когда прервётся очистка текущего слоя.
+	CancelFlush(cacheName string) *dto.FlushJob
+
+	// CacheGeneration возвращает текущее поколение кэша (ErrGenerationsDisabled — поколения не включены).
+	CacheGeneration(cacheName string) (*dto.CacheGeneration, error)
+
+	// BumpGeneration увеличивает поколение кэша: все его записи сразу становятся недоступны
+	// на всех уровнях. С sweep записи предыдущего поколения удаляются фоновой очисткой
+	// (см. FlushCache); если очистка кэша уже идёт, они истекают по TTL.
+	BumpGeneration(ctx context.Context, cacheName string, sweep bool) (*dto.CacheGeneration, error)
+
+	// ScanKeys возвращает страницу ключей кэша (текущего поколения), хранящихся на слое layer:
+	// около count ключей (0 — DefaultScanCount, не больше MaxScanCount), подходящих под glob-шаблон
+	// match, начиная с cursor. ErrUnknownLayer — у кэша нет такого слоя, ErrScanUnsupported —
+	// слой не умеет перечислять ключи, ErrInvalidCursor — чужой курсор.
+	ScanKeys(ctx context.Context, cacheName string, layer int, match, cursor string, count int) (*dto.KeyPage, error)
 }
 
 type ManagerImpl struct {
 	cacheController    cache.Controller
 	externalController integration.Controller
 	mapper             *dto.ResolverMapper
+	configService      config.CacheService
+	inflight           flightGroup
+	refresher          *refresher
+	flushes            flushJobs
+}
+
+// NewManager создаёт ManagerImpl с планировщиком фоновых обновлений
+// (stale-while-revalidate, refresh-ahead).
+func NewManager(mapper *dto.ResolverMapper, configService config.CacheService, cacheController cache.Controller, externalController integration.Controller) *ManagerImpl {
+	m := &ManagerImpl{
+		cacheController:    cacheController,
+		externalController: externalController,
+		mapper:             mapper,
+		configService:      configService,
+	}
+	m.refresher = newRefresher(m.refreshNow)
+	return m
 }
 
 func (m *ManagerImpl) GetAll(ctx context.Context, cacheIds []*dto.CacheId) []*dto.CacheEntryHit {
+	return m.GetAllWithOptions(ctx, cacheIds, dto.GetOptions{})
+}
+
+func (m *ManagerImpl) GetAllWithOptions(ctx context.Context, cacheIds []*dto.CacheId, opts dto.GetOptions) []*dto.CacheEntryHit {
 
 	zap.S().Infow("manager GetAll", "count", len(cacheIds))
 
-	resolvedIds := m.mapper.MapAllResolvedCacheId(cacheIds)
-	getResults := m.cacheController.GetAll(ctx, resolvedIds)
+	resolvedIds, unresolved := m.mapper.MapAllResolvedCacheIdWithErrors(cacheIds)
+	getResults := m.cacheController.GetAll(ctx, resolvedIds, opts)
 
 	// collect
 	finalHits := make([]*dto.ResolvedCacheHit, 0, len(cacheIds))
+	staleIds := make([]*dto.ResolvedCacheId, 0)
+	dueIds := make([]*dto.ResolvedCacheId, 0)
 	for _, r := range getResults {
 		finalHits = append(finalHits, r.Hits...)
+		for _, hit := range r.Hits {
+			switch {
+			case hit.Stale:
+				staleIds = append(staleIds, hit.ResolvedCacheEntry.ResolvedCacheId)
+			case hit.RefreshDue:
+				dueIds = append(dueIds, hit.ResolvedCacheEntry.ResolvedCacheId)
+			}
+		}
 	}
 
 	if len(getResults) == 0 {
 		return []*dto.CacheEntryHit{}
 	}
 
-	if len(getResults[len(getResults)-1].Misses) > 0 {
-		zap.S().Infow("fetching from external source", "count", len(getResults[len(getResults)-1].Misses))
+	// устаревшие значения отдаются сразу, а обновляются в фоне;
+	// значения, близкие к истечению TTL, обновляются заранее (refresh-ahead).
+	// При noUpstream фоновое обновление не выполняется: оно тоже обращается к внешнему API.
+	if !opts.NoUpstream {
+		m.scheduleRefresh(refreshReasonStale, staleIds)
+		m.scheduleRefresh(refreshReasonAhead, dueIds)
+	}
+
+	// во внешний источник уходят и промахи последнего слоя, и ключи, которые он не проверял
+	// (слой отключён для кэша, недоступен или не читался по opts)
+	last := getResults[len(getResults)-1]
+	toFetch := make([]*dto.ResolvedCacheId, 0, len(last.Misses)+len(last.Skipped))
+	toFetch = append(toFetch, last.Misses...)
+	toFetch = append(toFetch, last.Skipped...)
+	var fromExternal *dto.GetResult
+	if opts.NoUpstream {
+		// ключи не проверялись: без ошибки они считаются не найденными
+		fromExternal = &dto.GetResult{Skipped: toFetch}
+	} else {
+		if len(toFetch) > 0 {
+			zap.S().Infow("fetching from external source", "count", len(toFetch))
+		}
+		fromExternal = m.fetchExternal(ctx, toFetch)
+	}
+	for _, hit := range fromExternal.Hits {
+		hit.Layer = len(getResults)
+		if opts.Meta {
+			hit.Meta = &dto.HitMeta{Upstream: true}
+		}
 	}
-	fromExternal := m.externalController.GetAll(ctx, getResults[len(getResults)-1].Misses)
 	finalHits = append(finalHits, fromExternal.Hits...)
 
-	derivedCtx, cancel := context.WithTimeout(ctx, 1000*time.Millisecond)
-	defer cancel()
+	if !opts.NoBackfill {
+		// контекст отвязан от запроса: ответ клиенту уходит раньше, чем завершится дозапись слоёв
+		derivedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 1000*time.Millisecond)
+
+		zap.S().Infow("start fillMissingLevels goroutine")
+		go func() {
+			defer cancel()
+			if opts.BypassCache {
+				m.repopulateLevels(derivedCtx, fromExternal, len(getResults)-1)
+				return
+			}
+			m.fillMissingLevels(derivedCtx, finalHits, fromExternal.Misses, getResults)
+		}()
+	}
+
+	return m.toEntryHits(cacheIds, resolvedIds, unresolved, finalHits, getResults, fromExternal)
+}
+
+// toEntryHits формирует результат для каждого запрошенного ключа в порядке cacheIds:
+//   - найден в слое или во внешнем API (в том числе tombstone) — found / not_found с номером слоя;
+//   - отсутствует во внешнем API или API не настроен — not_found (с ошибкой слоя, если слой был недоступен);
+//   - внешний API вернул ошибку — upstream_error;
+//   - кэш не найден в конфигурации — unknown_cache.
+func (m *ManagerImpl) toEntryHits(cacheIds []*dto.CacheId, resolvedIds []*dto.ResolvedCacheId, unresolved []*dto.UnresolvedCacheId,
+	finalHits []*dto.ResolvedCacheHit, getResults []*dto.GetResult, fromExternal *dto.GetResult) []*dto.CacheEntryHit {
+
+	hits := make(map[string]*dto.ResolvedCacheHit, len(finalHits))
+	for _, hit := range finalHits {
+		if _, ok := hits[hit.GetStorageKey()]; !ok {
+			hits[hit.GetStorageKey()] = hit
+		}
+	}
+	// первая по порядку ошибка слоя для ключа
+	layerErrs := make(map[string]error)
+	for _, r := range getResults {
+		for _, e := range r.Errors {
+			if _, ok := layerErrs[e.GetStorageKey()]; !ok {
+				layerErrs[e.GetStorageKey()] = e.Err
+			}
+		}
+	}
+	// Skipped без ошибки — для кэша не настроен внешний API: ключа нет
+	upstreamErrs := make(map[string]error, len(fromExternal.Errors))
+	for _, e := range fromExternal.Errors {
+		upstreamErrs[e.GetStorageKey()] = e.Err
+	}
+	unknown := make(map[*dto.CacheId]error, len(unresolved))
+	for _, u := range unresolved {
+		unknown[u.CacheId] = u.Err
+	}
+
+	results := make([]*dto.CacheEntryHit, 0, len(cacheIds))
+	next := 0
+	for _, cacheId := range cacheIds {
+		if err, ok := unknown[cacheId]; ok {
+			results = append(results, &dto.CacheEntryHit{
+				CacheEntry: &dto.CacheEntry{CacheId: cacheId},
+				Status:     dto.HitStatusUnknownCache,
+				Error:      err.Error(),
+			})
+			continue
+		}
+		id := resolvedIds[next]
+		next++
+
+		if hit, ok := hits[id.GetStorageKey()]; ok {
+			res := m.mapper.MapAllCacheEntryHit([]*dto.ResolvedCacheHit{hit})[0]
+			res.CacheEntry.CacheId = id.CacheId
+			results = append(results, res)
+			continue
+		}
+		res := &dto.CacheEntryHit{CacheEntry: &dto.CacheEntry{CacheId: id.CacheId}, Status: dto.HitStatusNotFound}
+		if err, ok := upstreamErrs[id.GetStorageKey()]; ok {
+			res.Status = dto.HitStatusUpstreamError
+			res.Error = err.Error()
+		} else if err, ok := layerErrs[id.GetStorageKey()]; ok {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// fetchExternal запрашивает промахнувшиеся ключи во внешнем источнике.
+// Одновременные запросы за одним и тем же StorageKey объединяются: во внешний API
+// уходит только первый из них, остальные ждут его результат.
+func (m *ManagerImpl) fetchExternal(ctx context.Context, misses []*dto.ResolvedCacheId) *dto.GetResult {
+	leaders, waiters := m.inflight.acquire(misses)
+	for req := range waiters {
+		metrics.RecordCoalescedFetch(req.GetCacheName())
+	}
 
-	zap.S().Infow("start fillMissingLevels goroutine")
-	go m.fillMissingLevels(derivedCtx, finalHits, getResults)
+	var fromLeaders *dto.GetResult
+	func() {
+		// release выполняется и при панике, чтобы ожидающие не зависли навсегда
+		defer func() { m.inflight.release(leaders, fromLeaders) }()
+		fromLeaders = m.externalController.GetAll(ctx, leaders)
+	}()
 
-	return m.mapper.MapAllCacheEntryHit(finalHits)
+	if len(waiters) == 0 {
+		return fromLeaders
+	}
+
+	zap.S().Infow("waiting for coalesced external fetches", "count", len(waiters))
+	result := &dto.GetResult{}
+	result.Merge(fromLeaders)
+	result.Merge(m.inflight.wait(ctx, waiters))
+	return result
 }
 
-func (m *ManagerImpl) fillMissingLevels(ctx context.Context, finalHits []*dto.ResolvedCacheHit, getResults []*dto.GetResult) {
+// fillMissingLevels копирует найденные значения на уровни, где они отсутствовали,
+// и сохраняет tombstone для ключей, которых нет во внешнем источнике (absent).
+// Будут ли tombstone записаны, решает слой по negativeTTL кэша.
+func (m *ManagerImpl) fillMissingLevels(ctx context.Context, finalHits []*dto.ResolvedCacheHit, absent []*dto.ResolvedCacheId, getResults []*dto.GetResult) {
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -82,6 +303,11 @@ func (m *ManagerImpl) fillMissingLevels(ctx context.Context, finalHits []*dto.Re
 
 	hitMap := make(map[string]*dto.ResolvedCacheHit, len(finalHits))
 	for _, hit := range finalHits {
+		// устаревшие значения не копируются на верхние уровни:
+		// там они получили бы свежий TTL. Их перезапишет фоновое обновление.
+		if hit.Stale {
+			continue
+		}
 		hitMap[hit.GetStorageKey()] = hit
 	}
 
@@ -101,14 +327,255 @@ func (m *ManagerImpl) fillMissingLevels(ctx context.Context, finalHits []*dto.Re
 			m.cacheController.PutAll(ctx, toPut, level-1)
 		}
 	}
+
+	if len(absent) > 0 {
+		tombstones := make([]*dto.ResolvedCacheEntry, 0, len(absent))
+		for _, id := range absent {
+			tombstones = append(tombstones, &dto.ResolvedCacheEntry{ResolvedCacheId: id, Tombstone: true})
+		}
+		m.cacheController.PutAll(ctx, tombstones, len(getResults)-1)
+	}
 }
 
-func (m *ManagerImpl) PutAll(ctx context.Context, entries []*dto.CacheEntry) {
+// repopulateLevels перезаписывает все слои результатом чтения в обход кэша (bypassCache)
+// или фонового обновления:
+// найденные значения — во все слои, отсутствующие ключи удаляются из всех слоёв
+// (и сохраняются как tombstone для кэшей с negativeTTL).
+// Ключи, по которым внешний API вернул ошибку, остаются в слоях как есть.
+func (m *ManagerImpl) repopulateLevels(ctx context.Context, fromExternal *dto.GetResult, lastLevel int) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			zap.S().Errorf(alert.Prefix("panic in goroutine repopulateLevels: %v"), r)
+		}
+	}()
+
+	if len(fromExternal.Hits) > 0 {
+		entries := make([]*dto.ResolvedCacheEntry, 0, len(fromExternal.Hits))
+		for _, hit := range fromExternal.Hits {
+			entries = append(entries, hit.ResolvedCacheEntry)
+		}
+		m.cacheController.PutAllToAllLevels(ctx, entries)
+	}
+	if len(fromExternal.Misses) > 0 {
+		m.cacheController.DeleteAll(ctx, fromExternal.Misses)
+		tombstones := make([]*dto.ResolvedCacheEntry, 0, len(fromExternal.Misses))
+		for _, id := range fromExternal.Misses {
+			tombstones = append(tombstones, &dto.ResolvedCacheEntry{ResolvedCacheId: id, Tombstone: true})
+		}
+		m.cacheController.PutAll(ctx, tombstones, lastLevel)
+	}
+}
+
+func (m *ManagerImpl) PutAll(ctx context.Context, entries []*dto.CacheEntry) []*dto.WriteItemResult {
+	resolvedEntries := m.mapper.MapAllResolvedCacheEntry(entries)
+	layers := m.cacheController.PutAllToAllLevels(ctx, resolvedEntries)
+
+	ids := make([]*dto.ResolvedCacheId, 0, len(resolvedEntries))
+	for _, e := range resolvedEntries {
+		ids = append(ids, e.ResolvedCacheId)
+	}
+	return toWriteItemResults(ids, layers)
+}
+
+// PutAllIf пишет во внешний API только значения, прошедшие проверку условия в слоях кэша:
+// условие нельзя проверить во внешнем API. Если внешний API не принял значение, ключ удаляется
+// из слоёв, чтобы они не расходились с system of record. Режим writeAround для условной записи
+// не применяется: значение остаётся в слоях, иначе следующая проверка условия его не увидит.
+func (m *ManagerImpl) PutAllIf(ctx context.Context, entries []*dto.CacheEntry) *dto.WriteReport {
+	resolvedEntries := m.mapper.MapAllResolvedCacheEntry(entries)
+	written := m.cacheController.PutAllIf(ctx, resolvedEntries)
+
+	report := &dto.WriteReport{Results: []*dto.WriteItemResult{}, Errors: toUpstreamErrors(written.Errors)}
+	for _, id := range written.Conflicts {
+		report.Conflicts = append(report.Conflicts, id.CacheId)
+	}
+	if len(written.Applied) == 0 {
+		return report
+	}
+
+	byKey := make(map[string]*dto.ResolvedCacheEntry, len(resolvedEntries))
+	for _, e := range resolvedEntries {
+		byKey[e.GetStorageKey()] = e
+	}
+	applied := make([]*dto.ResolvedCacheEntry, 0, len(written.Applied))
+	for _, id := range written.Applied {
+		applied = append(applied, byKey[id.GetStorageKey()])
+	}
+	upstream := m.externalController.PutAll(ctx, applied)
+
+	rejected := make(map[string]bool, len(upstream.Failed))
+	if len(upstream.Failed) > 0 {
+		ids := make([]*dto.ResolvedCacheId, 0, len(upstream.Failed))
+		for _, f := range upstream.Failed {
+			rejected[f.GetStorageKey()] = true
+			ids = append(ids, f.ResolvedCacheId)
+		}
+		m.cacheController.DeleteAll(ctx, ids)
+		report.Errors = append(report.Errors, toUpstreamErrors(upstream.Failed)...)
+	}
+	for _, id := range written.Applied {
+		if !rejected[id.GetStorageKey()] {
+			report.Results = append(report.Results, &dto.WriteItemResult{CacheId: id.CacheId, Layers: written.Layers[id.GetStorageKey()]})
+		}
+	}
+	return report
+}
+
+func (m *ManagerImpl) WriteUpstream(ctx context.Context, entries []*dto.CacheEntry) *dto.UpstreamWriteResult {
 	resolvedEntries := m.mapper.MapAllResolvedCacheEntry(entries)
-	m.cacheController.PutAllToAllLevels(ctx, resolvedEntries)
+	written := m.externalController.PutAll(ctx, resolvedEntries)
+
+	byKey := make(map[string]*dto.ResolvedCacheEntry, len(resolvedEntries))
+	for _, e := range resolvedEntries {
+		byKey[e.GetStorageKey()] = e
+	}
+	toEntry := func(id *dto.ResolvedCacheId) *dto.CacheEntry {
+		e := byKey[id.GetStorageKey()]
+		return &dto.CacheEntry{CacheId: id.CacheId, Value: e.Value, TTL: e.TTL, TTLs: e.TTLs, Tags: e.Tags}
+	}
+
+	result := &dto.UpstreamWriteResult{}
+	for _, id := range written.Skipped {
+		result.Put = append(result.Put, toEntry(id))
+	}
+	for _, id := range written.Written {
+		if m.getWriteMode(id) == config.WriteModeAround {
+			result.Evict = append(result.Evict, id.CacheId)
+		} else {
+			result.Put = append(result.Put, toEntry(id))
+		}
+	}
+	result.Failed = toUpstreamErrors(written.Failed)
+	return result
+}
+
+func (m *ManagerImpl) SplitByWriteOrder(entries []*dto.CacheEntry) (before, after []*dto.CacheEntry) {
+	for _, e := range entries {
+		cache, err := m.configService.GetCache(e.CacheId)
+		if err == nil && cache.Api.IsPutBatchEnabled() && cache.Api.GetWriteOrder() == config.WriteOrderAfter {
+			after = append(after, e)
+		} else {
+			before = append(before, e)
+		}
+	}
+	return
 }
 
-func (m *ManagerImpl) EvictAll(ctx context.Context, ids []*dto.CacheId) {
+func (m *ManagerImpl) DeleteUpstream(ctx context.Context, ids []*dto.CacheId) []*dto.UpstreamError {
 	resolvedIds := m.mapper.MapAllResolvedCacheId(ids)
-	m.cacheController.DeleteAll(ctx, resolvedIds)
+	deleted := m.externalController.DeleteAll(ctx, resolvedIds)
+	return toUpstreamErrors(deleted.Failed)
+}
+
+func toUpstreamErrors(failed []*dto.ResolvedCacheError) []*dto.UpstreamError {
+	errs := make([]*dto.UpstreamError, 0, len(failed))
+	for _, f := range failed {
+		errs = append(errs, &dto.UpstreamError{
+			CacheId: f.ResolvedCacheId.CacheId,
+			Error:   f.Err.Error(),
+		})
+	}
+	return errs
+}
+
+func (m *ManagerImpl) getWriteMode(cacheId dto.CacheIdRef) config.WriteMode {
+	cache, err := m.configService.GetCache(cacheId)
+	if err != nil {
+		return config.WriteModeThrough
+	}
+	return cache.Api.GetWriteMode()
+}
+
+func (m *ManagerImpl) EvictAll(ctx context.Context, ids []*dto.CacheId) []*dto.WriteItemResult {
+	resolvedIds := m.mapper.MapAllResolvedCacheId(ids)
+	layers := m.cacheController.DeleteAll(ctx, resolvedIds)
+	return toWriteItemResults(resolvedIds, layers)
+}
+
+func (m *ManagerImpl) EvictByTag(ctx context.Context, tag string) *dto.TagEvictReport {
+	layers := m.cacheController.EvictByTag(ctx, tag)
+	report := &dto.TagEvictReport{Tag: tag, Layers: make([]*dto.TagLayerStatus, 0, len(layers))}
+	for i, layer := range layers {
+		status := &dto.TagLayerStatus{Layer: i, Status: dto.LayerStatusOk, Evicted: len(layer.Keys)}
+		if layer.Err != nil {
+			status.Status = dto.LayerStatusError
+			status.Error = layer.Err.Error()
+		}
+		report.Layers = append(report.Layers, status)
+	}
+	return report
+}
+
+func (m *ManagerImpl) TouchAll(ctx context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult {
+	resolvedEntries := m.mapper.MapAllResolvedCacheEntry(entries)
+	layers := m.cacheController.TouchAll(ctx, resolvedEntries)
+	return toTouchItemResults(resolvedEntries, layers)
+}
+
+// toTouchItemResults раскладывает результаты продления TTL по ключам и слоям.
+// Ключ продлён (Touched), если хотя бы один слой нашёл его.
+func toTouchItemResults(entries []*dto.ResolvedCacheEntry, layers []*dto.TouchLayerResult) []*dto.TouchItemResult {
+	skipped := make([]map[string]struct{}, len(layers))
+	missing := make([]map[string]struct{}, len(layers))
+	for i, layer := range layers {
+		skipped[i] = make(map[string]struct{}, len(layer.Skipped))
+		for _, id := range layer.Skipped {
+			skipped[i][id.GetStorageKey()] = struct{}{}
+		}
+		missing[i] = make(map[string]struct{}, len(layer.Missing))
+		for _, id := range layer.Missing {
+			missing[i][id.GetStorageKey()] = struct{}{}
+		}
+	}
+
+	results := make([]*dto.TouchItemResult, 0, len(entries))
+	for _, e := range entries {
+		result := &dto.TouchItemResult{CacheId: e.ResolvedCacheId.CacheId, Layers: make([]*dto.LayerWriteStatus, 0, len(layers))}
+		for i, layer := range layers {
+			status := &dto.LayerWriteStatus{Layer: i, Status: dto.LayerStatusOk}
+			if _, ok := skipped[i][e.GetStorageKey()]; ok {
+				status.Status = dto.LayerStatusSkipped
+			} else if layer.Err != nil {
+				status.Status = dto.LayerStatusError
+				status.Error = layer.Err.Error()
+			} else if _, ok := missing[i][e.GetStorageKey()]; ok {
+				status.Status = dto.LayerStatusMissing
+			} else {
+				result.Touched = true
+			}
+			result.Layers = append(result.Layers, status)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// toWriteItemResults раскладывает результаты слоёв по ключам:
+// ключ пропущен слоем, если попал в его Skipped, иначе получает ошибку слоя (или ok).
+func toWriteItemResults(ids []*dto.ResolvedCacheId, layers []*dto.LayerResult) []*dto.WriteItemResult {
+	skipped := make([]map[string]struct{}, len(layers))
+	for i, layer := range layers {
+		skipped[i] = make(map[string]struct{}, len(layer.Skipped))
+		for _, id := range layer.Skipped {
+			skipped[i][id.GetStorageKey()] = struct{}{}
+		}
+	}
+
+	results := make([]*dto.WriteItemResult, 0, len(ids))
+	for _, id := range ids {
+		statuses := make([]*dto.LayerWriteStatus, 0, len(layers))
+		for i, layer := range layers {
+			status := &dto.LayerWriteStatus{Layer: i, Status: dto.LayerStatusOk}
+			if _, ok := skipped[i][id.GetStorageKey()]; ok {
+				status.Status = dto.LayerStatusSkipped
+			} else if layer.Err != nil {
+				status.Status = dto.LayerStatusError
+				status.Error = layer.Err.Error()
+			}
+			statuses = append(statuses, status)
+		}
+		results = append(results, &dto.WriteItemResult{CacheId: id.CacheId, Layers: statuses})
+	}
+	return results
 }
@@ -2,27 +2,43 @@ package manager
 
 import (
 	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache"
 	"aur-cache-service/internal/cache/config"
+	"aur-cache-service/internal/cache/providers"
+	"aur-cache-service/internal/metrics"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
 type mockCacheService struct {
 	prefixMap map[string]string
+	caches    map[string]config.Cache
+	unknown   map[string]bool
 }
 
 func (m *mockCacheService) GetPrefix(id config.CacheNameable) (string, error) {
+	if m.unknown[id.GetCacheName()] {
+		return "", errors.New("cache not found")
+	}
 	return m.prefixMap[id.GetCacheName()], nil
 }
-func (m *mockCacheService) GetCache(config.CacheNameable) (config.Cache, error) {
-	return config.Cache{}, nil
+func (m *mockCacheService) GetCache(id config.CacheNameable) (config.Cache, error) {
+	return m.caches[id.GetCacheName()], nil
+}
+func (m *mockCacheService) GetCacheByName(name string) (config.Cache, error) {
+	if m.unknown[name] {
+		return config.Cache{}, errors.New("cache not found")
+	}
+	return m.caches[name], nil
 }
-func (m *mockCacheService) GetCacheByName(string) (config.Cache, error) { return config.Cache{}, nil }
 func (m *mockCacheService) GetTtl(config.CacheNameable, int) (time.Duration, error) {
 	return 0, nil
 }
@@ -34,6 +50,7 @@ func (m *mockCacheService) IsLevelEnabled(config.CacheNameable, int) (bool, erro
 
 type mockCacheController struct {
 	getReqs      []*dto.ResolvedCacheId
+	getOpts      dto.GetOptions
 	getReturn    []*dto.GetResult
 	putEntries   []*dto.ResolvedCacheEntry
 	putBound     []int
@@ -43,41 +60,137 @@ type mockCacheController struct {
 	deleteCalled int
 	putAllToAll  int
 	putAllWG     sync.WaitGroup
+
+	putAllToAllDone chan struct{}
+
+	// layers — результат записи (удаления) по слоям
+	layers []*dto.LayerResult
+
+	condEntries []*dto.ResolvedCacheEntry
+	condResult  *dto.CondWriteResult
+
+	touchEntries []*dto.ResolvedCacheEntry
+	touchLayers  []*dto.TouchLayerResult
+
+	tag       string
+	tagLayers []*dto.TagLayerResult
+
+	// flushErrs — результат FlushPrefix по слоям; flushBlock — каждый слой ждёт значения
+	// (или отмены), прежде чем «очиститься»
+	flushPrefix string
+	flushErrs   []error
+	flushBlock  chan struct{}
+
+	// scanKeys, scanNext, scanErr — результат ScanKeys; scanPrefix, scanCount — аргументы вызова
+	scanKeys   []string
+	scanNext   string
+	scanErr    error
+	scanPrefix string
+	scanCount  int
 }
 
-func (m *mockCacheController) GetAll(_ context.Context, reqs []*dto.ResolvedCacheId) []*dto.GetResult {
+func (m *mockCacheController) GetAll(_ context.Context, reqs []*dto.ResolvedCacheId, opts dto.GetOptions) []*dto.GetResult {
 	m.getCalled++
 	m.getReqs = reqs
+	m.getOpts = opts
 	return m.getReturn
 }
 
-func (m *mockCacheController) PutAll(_ context.Context, entries []*dto.ResolvedCacheEntry, bound int) {
+func (m *mockCacheController) PutAll(_ context.Context, entries []*dto.ResolvedCacheEntry, bound int) []*dto.LayerResult {
 	m.putAllCalled++
 	m.putEntries = append(m.putEntries, entries...)
 	m.putBound = append(m.putBound, bound)
 	m.putAllWG.Done()
+	return m.layers
 }
 
-func (m *mockCacheController) PutAllToAllLevels(_ context.Context, entries []*dto.ResolvedCacheEntry) {
+func (m *mockCacheController) PutAllToAllLevels(_ context.Context, entries []*dto.ResolvedCacheEntry) []*dto.LayerResult {
 	m.putAllToAll++
 	m.putEntries = entries
+	if m.putAllToAllDone != nil {
+		m.putAllToAllDone <- struct{}{}
+	}
+	return m.layers
 }
 
-func (m *mockCacheController) DeleteAll(_ context.Context, reqs []*dto.ResolvedCacheId) {
+func (m *mockCacheController) PutAllIf(_ context.Context, entries []*dto.ResolvedCacheEntry) *dto.CondWriteResult {
+	m.condEntries = entries
+	return m.condResult
+}
+
+func (m *mockCacheController) TouchAll(_ context.Context, entries []*dto.ResolvedCacheEntry) []*dto.TouchLayerResult {
+	m.touchEntries = entries
+	return m.touchLayers
+}
+
+func (m *mockCacheController) EvictByTag(_ context.Context, tag string) []*dto.TagLayerResult {
+	m.tag = tag
+	return m.tagLayers
+}
+
+func (m *mockCacheController) ScanKeys(_ context.Context, _ int, prefix, _, _ string, count int) ([]string, string, error) {
+	m.scanPrefix, m.scanCount = prefix, count
+	return m.scanKeys, m.scanNext, m.scanErr
+}
+
+func (m *mockCacheController) FlushPrefix(ctx context.Context, prefix string, progress func(int, int64)) []error {
+	m.flushPrefix = prefix
+	results := make([]error, len(m.flushErrs))
+	for i := len(m.flushErrs) - 1; i >= 0; i-- {
+		if m.flushBlock != nil {
+			select {
+			case <-m.flushBlock:
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			results[i] = err
+			continue
+		}
+		progress(i, 5)
+		results[i] = m.flushErrs[i]
+	}
+	return results
+}
+
+func (m *mockCacheController) DeleteAll(_ context.Context, reqs []*dto.ResolvedCacheId) []*dto.LayerResult {
 	m.deleteCalled++
 	m.deleteReqs = reqs
+	return m.layers
 }
 
 type mockExternalController struct {
 	reqs   []*dto.ResolvedCacheId
 	result *dto.GetResult
 	called int
+
+	putEntries []*dto.ResolvedCacheEntry
+	putResult  *dto.WriteResult
+
+	deleteReqs   []*dto.ResolvedCacheId
+	deleteResult *dto.WriteResult
+}
+
+func (m *mockExternalController) DeleteAll(_ context.Context, reqs []*dto.ResolvedCacheId) *dto.WriteResult {
+	m.deleteReqs = reqs
+	if m.deleteResult == nil {
+		return &dto.WriteResult{Skipped: reqs}
+	}
+	return m.deleteResult
 }
 
-func (m *mockExternalController) GetAll(reqs []*dto.ResolvedCacheId) *dto.GetResult {
+func (m *mockExternalController) PutAll(_ context.Context, entries []*dto.ResolvedCacheEntry) *dto.WriteResult {
+	m.putEntries = entries
+	if m.putResult == nil {
+		return &dto.WriteResult{}
+	}
+	return m.putResult
+}
+
+func (m *mockExternalController) GetAll(_ context.Context, reqs []*dto.ResolvedCacheId) *dto.GetResult {
 	m.called++
 	m.reqs = reqs
-	if m.result == nil {
+	if m.result == nil || len(reqs) == 0 {
 		return &dto.GetResult{}
 	}
 	return m.result
@@ -99,7 +212,7 @@ func TestManager_GetAll_FillMissing(t *testing.T) {
 
 	ext := &mockExternalController{}
 
-	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: *mapper}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
 
 	res := mgr.GetAll(context.Background(), []*dto.CacheId{id})
 	ctrl.putAllWG.Wait()
@@ -117,7 +230,7 @@ func TestManager_GetAll_Empty(t *testing.T) {
 	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
 	ctrl := &mockCacheController{getReturn: []*dto.GetResult{}}
 	ext := &mockExternalController{}
-	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: *mapper}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
 
 	res := mgr.GetAll(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "k"}})
 
@@ -129,7 +242,7 @@ func TestManager_GetAll_Empty(t *testing.T) {
 func TestManager_PutAndEvict(t *testing.T) {
 	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
 	ctrl := &mockCacheController{}
-	mgr := &ManagerImpl{cacheController: ctrl, externalController: &mockExternalController{}, mapper: *mapper}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: &mockExternalController{}, mapper: mapper}
 
 	raw := json.RawMessage(`"v"`)
 	entry := &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, Value: &raw}
@@ -142,3 +255,639 @@ func TestManager_PutAndEvict(t *testing.T) {
 	assert.Equal(t, 1, ctrl.deleteCalled)
 	assert.Equal(t, "p:2", ctrl.deleteReqs[0].StorageKey)
 }
+
+func TestManager_PutAllReportsLayers(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	skipped := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "2"}, StorageKey: "p:2"}
+	ctrl := &mockCacheController{layers: []*dto.LayerResult{
+		{Skipped: []*dto.ResolvedCacheId{skipped}},
+		{Err: errors.New("layer down")},
+	}}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: &mockExternalController{}, mapper: mapper}
+
+	raw := json.RawMessage(`"v"`)
+	res := mgr.PutAll(context.Background(), []*dto.CacheEntry{
+		{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, Value: &raw},
+		{CacheId: &dto.CacheId{CacheName: "c", Key: "2"}, Value: &raw},
+	})
+
+	assert.Len(t, res, 2)
+	assert.Equal(t, "1", res[0].Key)
+	assert.Equal(t, dto.LayerStatusOk, res[0].Layers[0].Status)
+	assert.Equal(t, dto.LayerStatusError, res[0].Layers[1].Status)
+	assert.Equal(t, "layer down", res[0].Layers[1].Error)
+	assert.Equal(t, dto.LayerStatusSkipped, res[1].Layers[0].Status)
+	assert.Equal(t, dto.LayerStatusError, res[1].Layers[1].Status)
+}
+
+// blockingExternalController отдаёт результат только после закрытия release.
+type blockingExternalController struct {
+	mu      sync.Mutex
+	called  int
+	started chan struct{}
+	release chan struct{}
+	value   *json.RawMessage
+}
+
+func (m *blockingExternalController) PutAll(context.Context, []*dto.ResolvedCacheEntry) *dto.WriteResult {
+	return &dto.WriteResult{}
+}
+
+func (m *blockingExternalController) DeleteAll(_ context.Context, reqs []*dto.ResolvedCacheId) *dto.WriteResult {
+	return &dto.WriteResult{Skipped: reqs}
+}
+
+func (m *blockingExternalController) GetAll(_ context.Context, reqs []*dto.ResolvedCacheId) *dto.GetResult {
+	m.mu.Lock()
+	m.called++
+	m.mu.Unlock()
+	if len(reqs) == 0 {
+		return &dto.GetResult{}
+	}
+	close(m.started)
+	<-m.release
+
+	res := &dto.GetResult{}
+	for _, r := range reqs {
+		res.Hits = append(res.Hits, &dto.ResolvedCacheHit{
+			ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: r, Value: m.value},
+			Found:              true,
+		})
+	}
+	return res
+}
+
+func TestManager_GetAll_CoalescesConcurrentMisses(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"coalesce": "p"}})
+	rid := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "coalesce", Key: "1"}, StorageKey: "p:1"}
+	ctrl := &mockCacheController{getReturn: []*dto.GetResult{
+		{Hits: []*dto.ResolvedCacheHit{}, Misses: []*dto.ResolvedCacheId{rid}},
+	}}
+
+	raw := json.RawMessage(`"v"`)
+	ext := &blockingExternalController{started: make(chan struct{}), release: make(chan struct{}), value: &raw}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
+
+	coalesced := metrics.ExternalCoalesced.WithLabelValues("coalesce")
+	before := testutil.ToFloat64(coalesced)
+
+	results := make([][]*dto.CacheEntryHit, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		results[0] = mgr.GetAll(context.Background(), []*dto.CacheId{{CacheName: "coalesce", Key: "1"}})
+	}()
+	<-ext.started
+
+	go func() {
+		defer wg.Done()
+		results[1] = mgr.GetAll(context.Background(), []*dto.CacheId{{CacheName: "coalesce", Key: "1"}})
+	}()
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(coalesced) == before+1 }, time.Second, 5*time.Millisecond)
+
+	close(ext.release)
+	wg.Wait()
+
+	// второй вызов дошёл до внешнего контроллера только с пустым списком ключей
+	assert.Equal(t, 2, ext.called)
+	for _, res := range results {
+		assert.Len(t, res, 1)
+		assert.True(t, res[0].Found)
+		assert.Equal(t, &raw, res[0].Value)
+	}
+	assert.Empty(t, mgr.inflight.calls)
+}
+
+func TestManager_GetAll_StaleServedAndRefreshed(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	stale := json.RawMessage(`"old"`)
+	fresh := json.RawMessage(`"new"`)
+	rid := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, StorageKey: "p:1"}
+	staleHit := &dto.ResolvedCacheHit{
+		ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: rid, Value: &stale},
+		Found:              true,
+		Stale:              true,
+	}
+
+	ctrl := &mockCacheController{
+		getReturn: []*dto.GetResult{
+			{Hits: []*dto.ResolvedCacheHit{staleHit}, Misses: []*dto.ResolvedCacheId{}},
+		},
+		putAllToAllDone: make(chan struct{}, 1),
+	}
+	ext := &mockExternalController{result: &dto.GetResult{Hits: []*dto.ResolvedCacheHit{{
+		ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: rid, Value: &fresh},
+		Found:              true,
+	}}}}
+	mgr := NewManager(mapper, &mockCacheService{}, ctrl, ext)
+
+	res := mgr.GetAll(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "1"}})
+	assert.Len(t, res, 1)
+	assert.Equal(t, &stale, res[0].Value)
+
+	select {
+	case <-ctrl.putAllToAllDone:
+	case <-time.After(time.Second):
+		t.Fatal("stale value was not refreshed")
+	}
+	assert.Equal(t, &fresh, ctrl.putEntries[0].Value)
+}
+
+func TestManager_GetAll_StoresTombstonesForExternalMisses(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	rid := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "404"}, StorageKey: "p:404"}
+	ctrl := &mockCacheController{getReturn: []*dto.GetResult{
+		{Hits: []*dto.ResolvedCacheHit{}, Misses: []*dto.ResolvedCacheId{rid}},
+	}}
+	ctrl.putAllWG.Add(1)
+	ext := &mockExternalController{result: &dto.GetResult{Misses: []*dto.ResolvedCacheId{rid}}}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
+
+	res := mgr.GetAll(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "404"}})
+	ctrl.putAllWG.Wait()
+
+	assert.Len(t, res, 1)
+	assert.False(t, res[0].Found)
+	assert.Equal(t, dto.HitStatusNotFound, res[0].Status)
+	assert.Equal(t, 1, ctrl.putAllCalled)
+	assert.Equal(t, 0, ctrl.putBound[0])
+	assert.True(t, ctrl.putEntries[0].Tombstone)
+	assert.Equal(t, "p:404", ctrl.putEntries[0].GetStorageKey())
+}
+
+func TestManager_GetAll_ReportsStatuses(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}, unknown: map[string]bool{"x": true}})
+	value := json.RawMessage(`"v"`)
+	rid := func(key string) *dto.ResolvedCacheId {
+		return &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: key}, StorageKey: "p:" + key}
+	}
+	found, absent, failed := rid("1"), rid("2"), rid("3")
+
+	ctrl := &mockCacheController{getReturn: []*dto.GetResult{
+		{Hits: []*dto.ResolvedCacheHit{{ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: found, Value: &value}, Found: true, Layer: 0}}},
+		{
+			Misses:  []*dto.ResolvedCacheId{absent},
+			Skipped: []*dto.ResolvedCacheId{failed},
+			Errors:  []*dto.ResolvedCacheError{{ResolvedCacheId: failed, Err: errors.New("layer 1: down")}},
+		},
+	}}
+	ctrl.putAllWG.Add(1)
+	ext := &mockExternalController{result: &dto.GetResult{
+		Misses:  []*dto.ResolvedCacheId{absent},
+		Skipped: []*dto.ResolvedCacheId{failed},
+		Errors:  []*dto.ResolvedCacheError{{ResolvedCacheId: failed, Err: errors.New("timeout")}},
+	}}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
+
+	res := mgr.GetAll(context.Background(), []*dto.CacheId{
+		{CacheName: "c", Key: "1"}, {CacheName: "x", Key: "1"}, {CacheName: "c", Key: "2"}, {CacheName: "c", Key: "3"},
+	})
+	ctrl.putAllWG.Wait()
+
+	assert.Len(t, res, 4)
+	assert.Equal(t, dto.HitStatusFound, res[0].Status)
+	assert.Equal(t, 0, *res[0].Layer)
+	assert.Equal(t, dto.HitStatusUnknownCache, res[1].Status)
+	assert.Equal(t, "x", res[1].CacheId.CacheName)
+	assert.Equal(t, dto.HitStatusNotFound, res[2].Status)
+	assert.Equal(t, dto.HitStatusUpstreamError, res[3].Status)
+	assert.Equal(t, "timeout", res[3].Error)
+	// во внешний API ушли и промахи последнего слоя, и ключи, которые он не проверил
+	assert.Len(t, ext.reqs, 2)
+}
+
+func TestManager_GetAllWithOptions_Meta(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	value := json.RawMessage(`"v"`)
+	cached := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, StorageKey: "p:1"}
+	fetched := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "2"}, StorageKey: "p:2"}
+
+	ctrl := &mockCacheController{getReturn: []*dto.GetResult{{
+		Hits: []*dto.ResolvedCacheHit{{
+			ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: cached, Value: &value},
+			Found:              true,
+			Meta:               &dto.HitMeta{Provider: "ristretto"},
+		}},
+		Misses: []*dto.ResolvedCacheId{fetched},
+	}}}
+	ext := &mockExternalController{result: &dto.GetResult{Hits: []*dto.ResolvedCacheHit{{
+		ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: fetched, Value: &value},
+		Found:              true,
+	}}}}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
+
+	res := mgr.GetAllWithOptions(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "1"}, {CacheName: "c", Key: "2"}}, dto.GetOptions{Meta: true})
+
+	assert.True(t, ctrl.getOpts.Meta)
+	assert.Len(t, res, 2)
+	version := dto.ValueVersion(value)
+	assert.Equal(t, &dto.HitMeta{Layer: 0, Provider: "ristretto", Version: version}, res[0].Meta)
+	assert.Equal(t, &dto.HitMeta{Layer: 1, Upstream: true, Version: version}, res[1].Meta)
+}
+
+func TestManager_GetAllWithOptions_BypassCache(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	fresh := json.RawMessage(`"new"`)
+	rid := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, StorageKey: "p:1"}
+
+	// слои не читаются: контроллер возвращает все ключи в Skipped
+	ctrl := &mockCacheController{
+		getReturn:       []*dto.GetResult{{Skipped: []*dto.ResolvedCacheId{rid}}, {Skipped: []*dto.ResolvedCacheId{rid}}},
+		putAllToAllDone: make(chan struct{}, 1),
+	}
+	ext := &mockExternalController{result: &dto.GetResult{Hits: []*dto.ResolvedCacheHit{{
+		ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: rid, Value: &fresh},
+		Found:              true,
+	}}}}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
+
+	res := mgr.GetAllWithOptions(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "1"}}, dto.GetOptions{BypassCache: true})
+	assert.Len(t, res, 1)
+	assert.Equal(t, &fresh, res[0].Value)
+	assert.Equal(t, 2, *res[0].Layer)
+
+	select {
+	case <-ctrl.putAllToAllDone:
+	case <-time.After(time.Second):
+		t.Fatal("layers were not repopulated")
+	}
+	assert.True(t, ctrl.getOpts.BypassCache)
+	assert.Equal(t, &fresh, ctrl.putEntries[0].Value)
+}
+
+func TestManager_GetAllWithOptions_NoUpstream(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	value := json.RawMessage(`"v"`)
+	rid := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, StorageKey: "p:1"}
+
+	ctrl := &mockCacheController{getReturn: []*dto.GetResult{{Misses: []*dto.ResolvedCacheId{rid}}}}
+	ext := &mockExternalController{result: &dto.GetResult{Hits: []*dto.ResolvedCacheHit{{
+		ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: rid, Value: &value},
+		Found:              true,
+	}}}}
+	mgr := &ManagerImpl{cacheController: ctrl, externalController: ext, mapper: mapper}
+
+	res := mgr.GetAllWithOptions(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "1"}}, dto.GetOptions{NoUpstream: true, NoBackfill: true})
+	assert.Len(t, res, 1)
+	assert.Equal(t, dto.HitStatusNotFound, res[0].Status)
+	assert.Empty(t, res[0].Error)
+	assert.Equal(t, 0, ext.called)
+}
+
+func TestManager_GetAll_RefreshAheadBatchesPerCache(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	value := json.RawMessage(`"v"`)
+	fresh := json.RawMessage(`"new"`)
+
+	hits := make([]*dto.ResolvedCacheHit, 0, 3)
+	fetched := make([]*dto.ResolvedCacheHit, 0, 3)
+	for _, key := range []string{"1", "2", "3"} {
+		rid := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: key}, StorageKey: "p:" + key}
+		hits = append(hits, &dto.ResolvedCacheHit{
+			ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: rid, Value: &value},
+			Found:              true,
+			RefreshDue:         true,
+		})
+		fetched = append(fetched, &dto.ResolvedCacheHit{
+			ResolvedCacheEntry: &dto.ResolvedCacheEntry{ResolvedCacheId: rid, Value: &fresh},
+			Found:              true,
+		})
+	}
+
+	ctrl := &mockCacheController{
+		getReturn:       []*dto.GetResult{{Hits: hits[:2], Misses: []*dto.ResolvedCacheId{}}},
+		putAllToAllDone: make(chan struct{}, 1),
+	}
+	ext := &mockExternalController{result: &dto.GetResult{Hits: fetched}}
+	mgr := NewManager(mapper, &mockCacheService{}, ctrl, ext)
+
+	res := mgr.GetAll(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "1"}, {CacheName: "c", Key: "2"}})
+	assert.Len(t, res, 2)
+	assert.Equal(t, &value, res[0].Value)
+
+	ctrl.getReturn = []*dto.GetResult{{Hits: hits[1:], Misses: []*dto.ResolvedCacheId{}}}
+	mgr.GetAll(context.Background(), []*dto.CacheId{{CacheName: "c", Key: "2"}, {CacheName: "c", Key: "3"}})
+
+	select {
+	case <-ctrl.putAllToAllDone:
+	case <-time.After(time.Second):
+		t.Fatal("refresh-ahead was not performed")
+	}
+
+	// оба запроса попали в одну пачку, ключ "2" запрошен один раз
+	assert.Equal(t, 1, ctrl.putAllToAll)
+	assert.Len(t, ext.reqs, 3)
+	assert.Equal(t, &fresh, ctrl.putEntries[0].Value)
+}
+
+func TestManager_WriteUpstream(t *testing.T) {
+	configService := &mockCacheService{
+		prefixMap: map[string]string{"through": "t", "around": "a", "local": "l", "broken": "b"},
+		caches: map[string]config.Cache{
+			"around": {Api: config.ApiConfig{WriteMode: config.WriteModeAround}},
+		},
+	}
+	mapper := dto.NewResolverMapper(configService)
+	rid := func(cacheName, prefix string) *dto.ResolvedCacheId {
+		return &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: cacheName, Key: "1"}, StorageKey: prefix + ":1"}
+	}
+	ext := &mockExternalController{putResult: &dto.WriteResult{
+		Written: []*dto.ResolvedCacheId{rid("through", "t"), rid("around", "a")},
+		Skipped: []*dto.ResolvedCacheId{rid("local", "l")},
+		Failed:  []*dto.ResolvedCacheError{{ResolvedCacheId: rid("broken", "b"), Err: errors.New("boom")}},
+	}}
+	mgr := NewManager(mapper, configService, &mockCacheController{}, ext)
+
+	raw := json.RawMessage(`"v"`)
+	entries := make([]*dto.CacheEntry, 0, 4)
+	for _, name := range []string{"through", "around", "local", "broken"} {
+		entries = append(entries, &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: name, Key: "1"}, Value: &raw})
+	}
+
+	res := mgr.WriteUpstream(context.Background(), entries)
+
+	assert.Len(t, ext.putEntries, 4)
+	assert.Len(t, res.Put, 2)
+	for _, e := range res.Put {
+		assert.Contains(t, []string{"through", "local"}, e.CacheName)
+		assert.Equal(t, &raw, e.Value)
+	}
+	assert.Len(t, res.Evict, 1)
+	assert.Equal(t, "around", res.Evict[0].CacheName)
+	assert.Len(t, res.Failed, 1)
+	assert.Equal(t, "broken", res.Failed[0].CacheName)
+	assert.Equal(t, "boom", res.Failed[0].Error)
+}
+
+func TestManager_SplitByWriteOrder(t *testing.T) {
+	putBatch := &config.ApiPutBatchConfig{URL: "http://x"}
+	configService := &mockCacheService{caches: map[string]config.Cache{
+		"before":  {Api: config.ApiConfig{Enabled: true, PutBatch: putBatch}},
+		"after":   {Api: config.ApiConfig{Enabled: true, PutBatch: putBatch, WriteOrder: config.WriteOrderAfter}},
+		"no-put":  {Api: config.ApiConfig{Enabled: true, WriteOrder: config.WriteOrderAfter}},
+		"api-off": {Api: config.ApiConfig{PutBatch: putBatch, WriteOrder: config.WriteOrderAfter}},
+	}}
+	mgr := NewManager(dto.NewResolverMapper(configService), configService, &mockCacheController{}, &mockExternalController{})
+
+	entries := make([]*dto.CacheEntry, 0, 4)
+	for _, name := range []string{"before", "after", "no-put", "api-off"} {
+		entries = append(entries, &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: name, Key: "1"}})
+	}
+
+	before, after := mgr.SplitByWriteOrder(entries)
+
+	assert.Equal(t, []*dto.CacheEntry{entries[0], entries[2], entries[3]}, before)
+	assert.Equal(t, []*dto.CacheEntry{entries[1]}, after)
+}
+
+func TestManager_PutAllIf(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	rid := func(key string) *dto.ResolvedCacheId {
+		return &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: key}, StorageKey: "p:" + key}
+	}
+	ok, rejected, conflict := rid("1"), rid("2"), rid("3")
+	layers := []*dto.LayerWriteStatus{{Layer: 0, Status: dto.LayerStatusOk}}
+	ctrl := &mockCacheController{condResult: &dto.CondWriteResult{
+		Applied:   []*dto.ResolvedCacheId{ok, rejected},
+		Layers:    map[string][]*dto.LayerWriteStatus{"p:1": layers, "p:2": layers},
+		Conflicts: []*dto.ResolvedCacheId{conflict},
+	}}
+	ext := &mockExternalController{putResult: &dto.WriteResult{
+		Written: []*dto.ResolvedCacheId{ok},
+		Failed:  []*dto.ResolvedCacheError{{ResolvedCacheId: rejected, Err: errors.New("boom")}},
+	}}
+	mgr := NewManager(mapper, &mockCacheService{}, ctrl, ext)
+
+	raw := json.RawMessage(`"v"`)
+	report := mgr.PutAllIf(context.Background(), []*dto.CacheEntry{
+		{CacheId: ok.CacheId, Value: &raw, IfAbsent: true},
+		{CacheId: rejected.CacheId, Value: &raw, IfAbsent: true},
+		{CacheId: conflict.CacheId, Value: &raw, IfVersion: "1"},
+	})
+
+	assert.Len(t, ctrl.condEntries, 3)
+	// во внешний API уходят только записи, прошедшие проверку условия
+	assert.Len(t, ext.putEntries, 2)
+	assert.Equal(t, []*dto.CacheId{conflict.CacheId}, report.Conflicts)
+	assert.Len(t, report.Results, 1)
+	assert.Equal(t, ok.CacheId, report.Results[0].CacheId)
+	assert.Equal(t, layers, report.Results[0].Layers)
+	// отклонённое внешним API значение удаляется из слоёв
+	assert.Equal(t, []*dto.ResolvedCacheId{rejected}, ctrl.deleteReqs)
+	assert.Equal(t, []*dto.UpstreamError{{CacheId: rejected.CacheId, Error: "boom"}}, report.Errors)
+}
+
+func TestManager_TouchAll(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	rid := func(key string) *dto.ResolvedCacheId {
+		return &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: key}, StorageKey: "p:" + key}
+	}
+	active, gone := rid("1"), rid("2")
+	ctrl := &mockCacheController{touchLayers: []*dto.TouchLayerResult{
+		{Missing: []*dto.ResolvedCacheId{gone}},
+		{Skipped: []*dto.ResolvedCacheId{active}, Err: errors.New("down")},
+	}}
+	ext := &mockExternalController{}
+	mgr := NewManager(mapper, &mockCacheService{}, ctrl, ext)
+
+	ttl := int64(60)
+	res := mgr.TouchAll(context.Background(), []*dto.CacheEntry{{CacheId: active.CacheId, TTL: &ttl}, {CacheId: gone.CacheId}})
+
+	assert.Len(t, ctrl.touchEntries, 2)
+	assert.Equal(t, &ttl, ctrl.touchEntries[0].TTL)
+	assert.Nil(t, ext.putEntries)
+	assert.Equal(t, []*dto.TouchItemResult{
+		{CacheId: active.CacheId, Touched: true, Layers: []*dto.LayerWriteStatus{
+			{Layer: 0, Status: dto.LayerStatusOk},
+			{Layer: 1, Status: dto.LayerStatusSkipped},
+		}},
+		{CacheId: gone.CacheId, Layers: []*dto.LayerWriteStatus{
+			{Layer: 0, Status: dto.LayerStatusMissing},
+			{Layer: 1, Status: dto.LayerStatusError, Error: "down"},
+		}},
+	}, res)
+}
+
+func TestManager_EvictByTag(t *testing.T) {
+	ctrl := &mockCacheController{tagLayers: []*dto.TagLayerResult{
+		{Keys: []string{"p:1", "p:2"}},
+		{Err: errors.New("down")},
+	}}
+	ext := &mockExternalController{}
+	mgr := NewManager(dto.NewResolverMapper(&mockCacheService{}), &mockCacheService{}, ctrl, ext)
+
+	report := mgr.EvictByTag(context.Background(), "tenant:42")
+
+	assert.Equal(t, "tenant:42", ctrl.tag)
+	assert.Nil(t, ext.deleteReqs)
+	assert.Equal(t, &dto.TagEvictReport{Tag: "tenant:42", Layers: []*dto.TagLayerStatus{
+		{Layer: 0, Status: dto.LayerStatusOk, Evicted: 2},
+		{Layer: 1, Status: dto.LayerStatusError, Error: "down"},
+	}}, report)
+}
+
+func TestManager_FlushCache(t *testing.T) {
+	services := &mockCacheService{
+		prefixMap: map[string]string{"c": "p"},
+		caches:    map[string]config.Cache{"c": {Name: "c", Prefix: "p", Layers: make([]config.CacheLayerConfig, 2)}},
+		unknown:   map[string]bool{"x": true},
+	}
+	ctrl := &mockCacheController{flushErrs: []error{nil, errors.New("down")}, flushBlock: make(chan struct{})}
+	mgr := NewManager(dto.NewResolverMapper(services), services, ctrl, &mockExternalController{})
+
+	_, err := mgr.FlushCache("x", "")
+	assert.ErrorIs(t, err, ErrUnknownCache)
+	assert.Nil(t, mgr.FlushStatus("c"))
+
+	job, err := mgr.FlushCache("c", "user-")
+	assert.NoError(t, err)
+	assert.Equal(t, "p:user-", job.Prefix)
+	assert.Equal(t, dto.FlushStatusRunning, job.Status)
+	assert.Equal(t, []*dto.FlushLayerStatus{
+		{Layer: 0, Status: dto.FlushStatusPending},
+		{Layer: 1, Status: dto.FlushStatusPending},
+	}, job.Layers)
+
+	// пока очистка идёт, вторая не запускается
+	_, err = mgr.FlushCache("c", "")
+	assert.ErrorIs(t, err, ErrFlushRunning)
+
+	close(ctrl.flushBlock)
+	assert.Eventually(t, func() bool { return mgr.FlushStatus("c").Status != dto.FlushStatusRunning }, time.Second, time.Millisecond)
+	job = mgr.FlushStatus("c")
+	assert.Equal(t, "p:user-", ctrl.flushPrefix)
+	assert.Equal(t, dto.FlushStatusFailed, job.Status)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, []*dto.FlushLayerStatus{
+		{Layer: 0, Status: dto.LayerStatusOk, Deleted: 5},
+		{Layer: 1, Status: dto.LayerStatusError, Deleted: 5, Error: "down"},
+	}, job.Layers)
+}
+
+func TestManager_CancelFlush(t *testing.T) {
+	services := &mockCacheService{
+		prefixMap: map[string]string{"c": "p"},
+		caches:    map[string]config.Cache{"c": {Name: "c", Prefix: "p", Layers: make([]config.CacheLayerConfig, 2)}},
+	}
+	ctrl := &mockCacheController{flushErrs: []error{nil, nil}, flushBlock: make(chan struct{})}
+	mgr := NewManager(dto.NewResolverMapper(services), services, ctrl, &mockExternalController{})
+
+	assert.Nil(t, mgr.CancelFlush("c"))
+	_, err := mgr.FlushCache("c", "")
+	assert.NoError(t, err)
+	mgr.CancelFlush("c")
+
+	assert.Eventually(t, func() bool { return mgr.FlushStatus("c").Status != dto.FlushStatusRunning }, time.Second, time.Millisecond)
+	job := mgr.FlushStatus("c")
+	assert.Equal(t, dto.FlushStatusCancelled, job.Status)
+	assert.Equal(t, dto.FlushStatusCancelled, job.Layers[0].Status)
+
+	// после завершения можно запустить новую очистку
+	close(ctrl.flushBlock)
+	_, err = mgr.FlushCache("c", "")
+	assert.NoError(t, err)
+}
+
+func TestManager_DeleteUpstream(t *testing.T) {
+	mapper := dto.NewResolverMapper(&mockCacheService{prefixMap: map[string]string{"c": "p"}})
+	ok := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "1"}, StorageKey: "p:1"}
+	bad := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "2"}, StorageKey: "p:2"}
+	ext := &mockExternalController{deleteResult: &dto.WriteResult{
+		Written: []*dto.ResolvedCacheId{ok},
+		Failed:  []*dto.ResolvedCacheError{{ResolvedCacheId: bad, Err: errors.New("boom")}},
+	}}
+	ctrl := &mockCacheController{}
+	mgr := NewManager(mapper, &mockCacheService{}, ctrl, ext)
+
+	failed := mgr.DeleteUpstream(context.Background(), []*dto.CacheId{ok.CacheId, bad.CacheId})
+
+	assert.Len(t, ext.deleteReqs, 2)
+	assert.Equal(t, []*dto.UpstreamError{{CacheId: bad.CacheId, Error: "boom"}}, failed)
+	// слои кэша не трогаются: это делает EvictAll
+	assert.Equal(t, 0, ctrl.deleteCalled)
+}
+
+func TestManager_BumpGeneration(t *testing.T) {
+	services := &mockCacheService{
+		prefixMap: map[string]string{"c": "p", "plain": "q"},
+		caches: map[string]config.Cache{
+			"c":     {Name: "c", Prefix: "p", Layers: make([]config.CacheLayerConfig, 2), Generations: true},
+			"plain": {Name: "plain", Prefix: "q", Layers: make([]config.CacheLayerConfig, 2)},
+		},
+		unknown: map[string]bool{"x": true},
+	}
+	ctrl := &mockCacheController{flushErrs: []error{nil, nil}}
+	mapper := dto.NewResolverMapper(services).WithGenerations(cache.NewGenerationStore(nil, "", []string{"c"}))
+	mgr := NewManager(mapper, services, ctrl, &mockExternalController{})
+
+	_, err := mgr.BumpGeneration(context.Background(), "x", false)
+	assert.ErrorIs(t, err, ErrUnknownCache)
+	_, err = mgr.BumpGeneration(context.Background(), "plain", false)
+	assert.ErrorIs(t, err, ErrGenerationsDisabled)
+
+	ids := mapper.MapAllResolvedCacheId([]*dto.CacheId{{CacheName: "c", Key: "1"}})
+	assert.Equal(t, "p:0:1", ids[0].StorageKey)
+
+	gen, err := mgr.BumpGeneration(context.Background(), "c", false)
+	assert.NoError(t, err)
+	assert.Equal(t, &dto.CacheGeneration{Cache: "c", Generation: 1}, gen)
+	assert.Nil(t, mgr.FlushStatus("c"))
+
+	ids = mapper.MapAllResolvedCacheId([]*dto.CacheId{{CacheName: "c", Key: "1"}})
+	assert.Equal(t, "p:1:1", ids[0].StorageKey)
+
+	// sweep удаляет записи предыдущего поколения
+	gen, err = mgr.BumpGeneration(context.Background(), "c", true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), gen.Generation)
+	if assert.NotNil(t, gen.Sweep) {
+		assert.Equal(t, "p:1:", gen.Sweep.Prefix)
+	}
+	assert.Eventually(t, func() bool { return mgr.FlushStatus("c").Status == dto.FlushStatusDone }, time.Second, time.Millisecond)
+
+	// flush с префиксом ключа затрагивает только текущее поколение
+	job, err := mgr.FlushCache("c", "user-")
+	assert.NoError(t, err)
+	assert.Equal(t, "p:2:user-", job.Prefix)
+
+	current, err := mgr.CacheGeneration("c")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), current.Generation)
+}
+
+func TestManager_ScanKeys(t *testing.T) {
+	services := &mockCacheService{
+		prefixMap: map[string]string{"c": "p"},
+		caches:    map[string]config.Cache{"c": {Name: "c", Prefix: "p", Layers: make([]config.CacheLayerConfig, 2), Generations: true}},
+		unknown:   map[string]bool{"x": true},
+	}
+	ctrl := &mockCacheController{scanKeys: []string{"p:3:1", "p:3:2"}, scanNext: "42"}
+	gens := cache.NewGenerationStore(nil, "", []string{"c"})
+	for i := 0; i < 3; i++ {
+		_, _ = gens.Bump(context.Background(), "c")
+	}
+	mgr := NewManager(dto.NewResolverMapper(services).WithGenerations(gens), services, ctrl, &mockExternalController{})
+
+	page, err := mgr.ScanKeys(context.Background(), "c", 1, "", "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &dto.KeyPage{Cache: "c", Layer: 1, Keys: []string{"1", "2"}, Cursor: "42"}, page)
+	assert.Equal(t, "p:3:", ctrl.scanPrefix)
+	assert.Equal(t, DefaultScanCount, ctrl.scanCount)
+
+	_, _ = mgr.ScanKeys(context.Background(), "c", 0, "", "", 1_000_000)
+	assert.Equal(t, MaxScanCount, ctrl.scanCount)
+
+	_, err = mgr.ScanKeys(context.Background(), "x", 0, "", "", 0)
+	assert.ErrorIs(t, err, ErrUnknownCache)
+	_, err = mgr.ScanKeys(context.Background(), "c", 2, "", "", 0)
+	assert.ErrorIs(t, err, ErrUnknownLayer)
+
+	ctrl.scanErr = fmt.Errorf("wrapped: %w", providers.ErrScanUnsupported)
+	_, err = mgr.ScanKeys(context.Background(), "c", 0, "", "", 0)
+	assert.ErrorIs(t, err, ErrScanUnsupported)
+
+	ctrl.scanErr = fmt.Errorf("wrapped: %w", providers.ErrInvalidCursor)
+	_, err = mgr.ScanKeys(context.Background(), "c", 0, "", "x", 0)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
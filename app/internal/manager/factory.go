@@ -3,21 +3,32 @@ package manager
 import (
 	"aur-cache-service/api/dto"
 	"aur-cache-service/internal/cache"
+	"aur-cache-service/internal/cache/config"
 	"aur-cache-service/internal/integration"
 	"time"
+
+	"go.uber.org/zap"
+	"telegram-alerts-go/alert"
 )
 
-func CreateAsyncManagerAdapter(mapper *dto.ResolverMapper, layerCacheController cache.Controller, httpCacheController integration.Controller, putAllTimeout time.Duration, evictAllTimeout time.Duration) AsyncManagerAdapter {
+func CreateAsyncManagerAdapter(mapper *dto.ResolverMapper, configService config.CacheService, layerCacheController cache.Controller, httpCacheController integration.Controller, putAllTimeout time.Duration, evictAllTimeout time.Duration, writeBehind config.WriteBehindConfig) *AsyncManagerAdapter {
 
-	manager := ManagerImpl{
-		cacheController:    layerCacheController,
-		externalController: httpCacheController,
-		mapper:             mapper,
-	}
+	manager := NewManager(mapper, configService, layerCacheController, httpCacheController)
 
-	return AsyncManagerAdapter{
-		manager:         &manager,
-		putAllTimeout:   putAllTimeout,
-		evictAllTimeout: evictAllTimeout,
+	var j *journal
+	var pending []*writeOp
+	if writeBehind.JournalDir != "" {
+		var err error
+		j, pending, err = openJournal(writeBehind.JournalDir)
+		if err != nil {
+			// без журнала принятые записи теряются при перезапуске — только по явному разрешению
+			if !writeBehind.AllowMemoryOnly {
+				zap.S().Fatalw(alert.Prefix("error opening write-behind journal"), "dir", writeBehind.JournalDir, "error", err)
+			}
+			zap.S().Errorw(alert.Prefix("error opening write-behind journal, writes are kept in memory only"), "dir", writeBehind.JournalDir, "error", err)
+			j, pending = nil, nil
+		}
 	}
+
+	return newAsyncManagerAdapter(manager, putAllTimeout, evictAllTimeout, j, pending, writeBehind)
 }
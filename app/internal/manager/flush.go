@@ -0,0 +1,186 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnknownCache — кэш с таким именем не настроен.
+	ErrUnknownCache = errors.New("cache not found")
+
+	// ErrFlushRunning — очистка кэша уже выполняется.
+	ErrFlushRunning = errors.New("flush is already running")
+)
+
+// flushJob — фоновая очистка одного кэша. Состояние меняется из горутины очистки,
+// поэтому наружу отдаются только копии (snapshot).
+type flushJob struct {
+	mu     sync.Mutex
+	state  dto.FlushJob
+	cancel context.CancelFunc
+}
+
+func newFlushJob(cacheName, prefix string, levels int, cancel context.CancelFunc) *flushJob {
+	layers := make([]*dto.FlushLayerStatus, levels)
+	for i := range layers {
+		layers[i] = &dto.FlushLayerStatus{Layer: i, Status: dto.FlushStatusPending}
+	}
+	return &flushJob{
+		state: dto.FlushJob{
+			Cache:     cacheName,
+			Prefix:    prefix,
+			Status:    dto.FlushStatusRunning,
+			StartedAt: time.Now(),
+			Layers:    layers,
+		},
+		cancel: cancel,
+	}
+}
+
+func (j *flushJob) snapshot() *dto.FlushJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	res := j.state
+	res.Layers = make([]*dto.FlushLayerStatus, len(j.state.Layers))
+	for i, layer := range j.state.Layers {
+		l := *layer
+		res.Layers[i] = &l
+	}
+	return &res
+}
+
+func (j *flushJob) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state.Status == dto.FlushStatusRunning
+}
+
+// progress отмечает ход очистки слоя level (см. cache.Controller.FlushPrefix).
+func (j *flushJob) progress(level int, deleted int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if level < 0 || level >= len(j.state.Layers) {
+		return
+	}
+	j.state.Layers[level].Status = dto.FlushStatusRunning
+	j.state.Layers[level].Deleted = deleted
+}
+
+// finish переводит задачу в итоговое состояние по ошибкам слоёв: отмена важнее ошибки слоя.
+func (j *flushJob) finish(errs []error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := dto.FlushStatusDone
+	for i, err := range errs {
+		if i >= len(j.state.Layers) {
+			break
+		}
+		layer := j.state.Layers[i]
+		switch {
+		case err == nil:
+			layer.Status = dto.LayerStatusOk
+		case errors.Is(err, context.Canceled):
+			layer.Status = dto.FlushStatusCancelled
+			status = dto.FlushStatusCancelled
+		default:
+			layer.Status = dto.LayerStatusError
+			layer.Error = err.Error()
+			if status == dto.FlushStatusDone {
+				status = dto.FlushStatusFailed
+			}
+		}
+	}
+	finishedAt := time.Now()
+	j.state.Status = status
+	j.state.FinishedAt = &finishedAt
+}
+
+// flushJobs хранит последнюю задачу очистки каждого кэша. Нулевое значение готово к использованию.
+type flushJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*flushJob
+}
+
+// start регистрирует задачу, если для кэша не выполняется другая; иначе возвращает выполняющуюся.
+func (f *flushJobs) start(job *flushJob) (running *flushJob, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.jobs == nil {
+		f.jobs = make(map[string]*flushJob)
+	}
+	if cur, exists := f.jobs[job.state.Cache]; exists && cur.running() {
+		return cur, false
+	}
+	f.jobs[job.state.Cache] = job
+	return job, true
+}
+
+func (f *flushJobs) get(cacheName string) *flushJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jobs[cacheName]
+}
+
+func (m *ManagerImpl) FlushCache(cacheName, keyPrefix string) (*dto.FlushJob, error) {
+	prefix, err := m.mapper.KeyPrefix(cacheName, keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCache, cacheName)
+	}
+	return m.startFlush(cacheName, prefix)
+}
+
+// startFlush запускает фоновое удаление ключей хранилища кэша cacheName с префиксом prefix.
+func (m *ManagerImpl) startFlush(cacheName, prefix string) (*dto.FlushJob, error) {
+	cache, err := m.configService.GetCacheByName(cacheName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCache, cacheName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job, ok := m.flushes.start(newFlushJob(cacheName, prefix, len(cache.Layers), cancel))
+	if !ok {
+		cancel()
+		return job.snapshot(), ErrFlushRunning
+	}
+
+	zap.S().Infow("cache flush started", "cache", cacheName, "prefix", prefix)
+	go m.runFlush(ctx, job)
+	return job.snapshot(), nil
+}
+
+func (m *ManagerImpl) runFlush(ctx context.Context, job *flushJob) {
+	defer job.cancel()
+
+	job.finish(m.cacheController.FlushPrefix(ctx, job.state.Prefix, job.progress))
+
+	state := job.snapshot()
+	zap.S().Infow("cache flush finished", "cache", state.Cache, "prefix", state.Prefix, "status", state.Status,
+		"duration", state.FinishedAt.Sub(state.StartedAt))
+}
+
+func (m *ManagerImpl) FlushStatus(cacheName string) *dto.FlushJob {
+	job := m.flushes.get(cacheName)
+	if job == nil {
+		return nil
+	}
+	return job.snapshot()
+}
+
+func (m *ManagerImpl) CancelFlush(cacheName string) *dto.FlushJob {
+	job := m.flushes.get(cacheName)
+	if job == nil {
+		return nil
+	}
+	job.cancel()
+	return job.snapshot()
+}
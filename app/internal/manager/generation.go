@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ErrGenerationsDisabled — для кэша не включены поколения (config.Cache.Generations).
+var ErrGenerationsDisabled = errors.New("generations are disabled for cache")
+
+func (m *ManagerImpl) CacheGeneration(cacheName string) (*dto.CacheGeneration, error) {
+	gen, err := m.generation(cacheName)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CacheGeneration{Cache: cacheName, Generation: gen}, nil
+}
+
+func (m *ManagerImpl) BumpGeneration(ctx context.Context, cacheName string, sweep bool) (*dto.CacheGeneration, error) {
+	old, err := m.generation(cacheName)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := m.mapper.Generations().Bump(ctx, cacheName)
+	if err != nil {
+		return nil, err
+	}
+	zap.S().Infow("cache generation bumped", "cache", cacheName, "generation", gen)
+
+	res := &dto.CacheGeneration{Cache: cacheName, Generation: gen}
+	if !sweep {
+		return res, nil
+	}
+	prefix, err := m.mapper.GenerationPrefix(cacheName, old)
+	if err != nil {
+		return nil, err
+	}
+	// поколение уже увеличено: если очистка не запустилась, записи старого поколения истекут по TTL
+	job, err := m.startFlush(cacheName, prefix)
+	if err != nil {
+		zap.S().Warnw("generation sweep not started", "cache", cacheName, "generation", old, "error", err)
+		return res, nil
+	}
+	res.Sweep = job
+	return res, nil
+}
+
+// generation возвращает текущее поколение кэша или ErrUnknownCache / ErrGenerationsDisabled.
+func (m *ManagerImpl) generation(cacheName string) (int64, error) {
+	if _, err := m.configService.GetCacheByName(cacheName); err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCache, cacheName)
+	}
+	generations := m.mapper.Generations()
+	if generations == nil {
+		return 0, fmt.Errorf("%w: %s", ErrGenerationsDisabled, cacheName)
+	}
+	gen, ok := generations.Generation(cacheName)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrGenerationsDisabled, cacheName)
+	}
+	return gen, nil
+}
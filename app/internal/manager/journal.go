@@ -0,0 +1,308 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	journalSegmentPrefix = "journal-"
+	journalSegmentSuffix = ".log"
+	checkpointFileName   = "checkpoint"
+
+	// journalSegmentSize — размер сегмента журнала, после которого новые операции
+	// пишутся в следующий сегмент.
+	journalSegmentSize = 64 << 20
+)
+
+// journal — журнал операций write-behind на диске.
+//
+// Формат: журнал разбит на сегменты journal-<Seq>.log, где Seq — номер первой операции
+// сегмента; в сегменте — JSON-строки writeOp в порядке Seq. checkpoint — Seq,
+// до которого (включительно) все операции применены к слоям.
+//
+// Операции подтверждаются (ack) не по порядку, поэтому checkpoint двигается только
+// по непрерывному префиксу подтверждённых Seq. После перезапуска операции с Seq > checkpoint
+// применяются повторно (at-least-once): put и evict идемпотентны.
+//
+// Запись идёт в последний сегмент; когда он дорастает до segmentSize, открывается следующий.
+// Сегмент, все операции которого не позже checkpoint, удаляется, поэтому журнал
+// не растёт, пока воркеры успевают применять операции.
+type journal struct {
+	mu          sync.Mutex
+	dir         string
+	segmentSize int64
+	segments    []uint64 // Seq первой операции каждого сегмента по возрастанию; последний — активный
+	file        *os.File // активный сегмент
+	size        int64    // размер активного сегмента
+
+	lastSeq    uint64              // последний записанный Seq
+	checkpoint uint64              // все Seq <= checkpoint применены
+	acked      map[uint64]struct{} // подтверждённые Seq > checkpoint
+}
+
+// openJournal открывает (или создаёт) журнал в dir и возвращает неподтверждённые операции.
+// Недописанный при аварии хвост сегмента отбрасывается.
+func openJournal(dir string) (*journal, []*writeOp, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("create journal dir: %w", err)
+	}
+
+	j := &journal{dir: dir, segmentSize: journalSegmentSize, acked: make(map[uint64]struct{})}
+
+	checkpoint, err := j.readCheckpoint()
+	if err != nil {
+		return nil, nil, err
+	}
+	j.checkpoint = checkpoint
+	j.lastSeq = checkpoint
+
+	segments, err := j.listSegments()
+	if err != nil {
+		return nil, nil, err
+	}
+	j.segments = segments
+
+	pending := make([]*writeOp, 0)
+	for _, first := range segments {
+		ops, err := j.readSegment(first)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, op := range ops {
+			if op.Seq > j.lastSeq {
+				j.lastSeq = op.Seq
+			}
+			if op.Seq > checkpoint {
+				pending = append(pending, op)
+			}
+		}
+	}
+
+	if len(j.segments) == 0 {
+		j.segments = []uint64{j.lastSeq + 1}
+	}
+	if err := j.openActive(); err != nil {
+		return nil, nil, err
+	}
+	if err := j.removeApplied(); err != nil {
+		j.file.Close()
+		return nil, nil, err
+	}
+
+	return j, pending, nil
+}
+
+func (j *journal) segmentPath(first uint64) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%s%020d%s", journalSegmentPrefix, first, journalSegmentSuffix))
+}
+
+// listSegments возвращает Seq первых операций сегментов в каталоге по возрастанию.
+func (j *journal) listSegments() ([]uint64, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list journal segments: %w", err)
+	}
+	segments := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, journalSegmentPrefix) || !strings.HasSuffix(name, journalSegmentSuffix) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, journalSegmentPrefix), journalSegmentSuffix), 10, 64)
+		if err != nil {
+			zap.S().Warnw("write-behind journal: skipping unknown file", "file", name)
+			continue
+		}
+		segments = append(segments, first)
+	}
+	sort.Slice(segments, func(a, b int) bool { return segments[a] < segments[b] })
+	return segments, nil
+}
+
+// readSegment читает операции сегмента и отрезает недописанный или повреждённый хвост.
+func (j *journal) readSegment(first uint64) ([]*writeOp, error) {
+	file, err := os.OpenFile(j.segmentPath(first), os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal segment: %w", err)
+	}
+	defer file.Close()
+
+	ops := make([]*writeOp, 0)
+	var valid int64
+	reader := bufio.NewReader(file)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil {
+			if len(line) > 0 {
+				zap.S().Warnw("write-behind journal: dropping incomplete tail", "segment", first, "bytes", len(line))
+			}
+			break
+		}
+		var op writeOp
+		if err := json.Unmarshal(line, &op); err != nil {
+			zap.S().Warnw("write-behind journal: dropping corrupted tail", "segment", first, "offset", valid, "error", err)
+			break
+		}
+		valid += int64(len(line))
+		ops = append(ops, &op)
+	}
+
+	if err := file.Truncate(valid); err != nil {
+		return nil, fmt.Errorf("truncate journal segment: %w", err)
+	}
+	return ops, nil
+}
+
+// openActive открывает последний сегмент для дозаписи.
+func (j *journal) openActive() error {
+	file, err := os.OpenFile(j.segmentPath(j.segments[len(j.segments)-1]), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open journal segment: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat journal segment: %w", err)
+	}
+	j.file = file
+	j.size = info.Size()
+	return nil
+}
+
+// rotate закрывает активный сегмент и начинает новый с операции first.
+func (j *journal) rotate(first uint64) error {
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf("close journal segment: %w", err)
+	}
+	j.segments = append(j.segments, first)
+	return j.openActive()
+}
+
+// removeApplied удаляет сегменты, все операции которых не позже checkpoint.
+// Активный сегмент не удаляется.
+func (j *journal) removeApplied() error {
+	for len(j.segments) > 1 && j.segments[1]-1 <= j.checkpoint {
+		if err := os.Remove(j.segmentPath(j.segments[0])); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove journal segment: %w", err)
+		}
+		j.segments = j.segments[1:]
+	}
+	return nil
+}
+
+// append дописывает операцию в журнал и сбрасывает её на диск.
+func (j *journal) append(op *writeOp) error {
+	line, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("marshal journal record: %w", err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.size >= j.segmentSize {
+		if err := j.rotate(op.Seq); err != nil {
+			return err
+		}
+		if err := j.removeApplied(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := j.file.Write(line); err != nil {
+		j.discardTail()
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		j.discardTail()
+		return fmt.Errorf("sync journal: %w", err)
+	}
+	j.size += int64(len(line))
+	if op.Seq > j.lastSeq {
+		j.lastSeq = op.Seq
+	}
+	return nil
+}
+
+// discardTail отрезает от активного сегмента запись, которую не удалось дописать,
+// чтобы следующая запись не склеилась с её началом.
+func (j *journal) discardTail() {
+	if err := j.file.Truncate(j.size); err != nil {
+		zap.S().Warnw("truncate journal segment", "error", err)
+	}
+}
+
+// ack подтверждает применение операции и сдвигает checkpoint.
+func (j *journal) ack(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if seq <= j.checkpoint {
+		return nil
+	}
+	j.acked[seq] = struct{}{}
+
+	moved := false
+	for {
+		if _, ok := j.acked[j.checkpoint+1]; !ok {
+			break
+		}
+		delete(j.acked, j.checkpoint+1)
+		j.checkpoint++
+		moved = true
+	}
+	if !moved {
+		return nil
+	}
+
+	if err := j.writeCheckpoint(); err != nil {
+		return err
+	}
+
+	return j.removeApplied()
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+func (j *journal) readCheckpoint() (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(j.dir, checkpointFileName))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read checkpoint: %w", err)
+	}
+	seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse checkpoint: %w", err)
+	}
+	return seq, nil
+}
+
+// writeCheckpoint атомарно (через rename) сохраняет checkpoint.
+func (j *journal) writeCheckpoint() error {
+	path := filepath.Join(j.dir, checkpointFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(j.checkpoint, 10)), 0o644); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/providers"
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	DefaultScanCount = 100  // ключей на странице ScanKeys по умолчанию
+	MaxScanCount     = 1000 // наибольшее число ключей на странице ScanKeys
+)
+
+var (
+	// ErrUnknownLayer — у кэша нет слоя с таким номером.
+	ErrUnknownLayer = errors.New("layer not found")
+
+	// ErrScanUnsupported — слой не умеет перечислять ключи (например, Ristretto).
+	ErrScanUnsupported = errors.New("layer does not support key scan")
+
+	// ErrInvalidCursor — курсор не выдавался ScanKeys для этого кэша и слоя.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+func (m *ManagerImpl) ScanKeys(ctx context.Context, cacheName string, layer int, match, cursor string, count int) (*dto.KeyPage, error) {
+	cache, err := m.configService.GetCacheByName(cacheName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCache, cacheName)
+	}
+	if layer < 0 || layer >= len(cache.Layers) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownLayer, layer)
+	}
+	prefix, err := m.mapper.CurrentPrefix(cacheName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCache, cacheName)
+	}
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+	count = min(count, MaxScanCount)
+
+	storageKeys, next, err := m.cacheController.ScanKeys(ctx, layer, prefix, match, cursor, count)
+	switch {
+	case errors.Is(err, providers.ErrScanUnsupported):
+		return nil, fmt.Errorf("%w: %d", ErrScanUnsupported, layer)
+	case errors.Is(err, providers.ErrInvalidCursor):
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	case err != nil:
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(storageKeys))
+	for _, key := range storageKeys {
+		keys = append(keys, key[len(prefix):])
+	}
+	return &dto.KeyPage{Cache: cacheName, Layer: layer, Keys: keys, Cursor: next}, nil
+}
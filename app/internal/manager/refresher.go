@@ -0,0 +1,205 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/metrics"
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"telegram-alerts-go/alert"
+)
+
+const (
+	// refreshTimeout ограничивает время одного фонового обновления (fetch + запись во все слои).
+	refreshTimeout = 10 * time.Second
+
+	// refreshBatchWindow — сколько ключи одного кэша копятся перед отправкой во внешний API.
+	refreshBatchWindow = 50 * time.Millisecond
+
+	// refreshBatchSize — максимальный размер пачки; полная пачка отправляется не дожидаясь окна.
+	refreshBatchSize = 100
+
+	// refreshConcurrency — максимум одновременно выполняющихся фоновых обновлений.
+	refreshConcurrency = 4
+
+	// refreshReasonStale — обновление значения, отданного клиенту в режиме stale-while-revalidate.
+	refreshReasonStale = "stale"
+
+	// refreshReasonAhead — заблаговременное обновление значения, близкого к истечению TTL.
+	refreshReasonAhead = "ahead"
+)
+
+// keySet — потокобезопасное множество StorageKey, для которых уже выполняется фоновое обновление.
+// Нулевое значение готово к использованию.
+type keySet struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// add добавляет ключи в множество и возвращает только те, которых в нём ещё не было.
+func (s *keySet) add(ids []*dto.ResolvedCacheId) []*dto.ResolvedCacheId {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keys == nil {
+		s.keys = make(map[string]struct{})
+	}
+	added := make([]*dto.ResolvedCacheId, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := s.keys[id.GetStorageKey()]; ok {
+			continue
+		}
+		s.keys[id.GetStorageKey()] = struct{}{}
+		added = append(added, id)
+	}
+	return added
+}
+
+func (s *keySet) remove(ids []*dto.ResolvedCacheId) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.keys, id.GetStorageKey())
+	}
+}
+
+// refreshGroup — ключ пачки: обновления копятся отдельно для каждого кэша и причины.
+type refreshGroup struct {
+	cacheName string
+	reason    string
+}
+
+// refreshBatch — накапливаемая пачка группы и таймер её отправки по истечении окна.
+type refreshBatch struct {
+	ids   []*dto.ResolvedCacheId
+	timer *time.Timer
+}
+
+// refresher планирует фоновые обновления ключей.
+//
+//   - ключи копятся в пачки по кэшу в течение refreshBatchWindow или до refreshBatchSize,
+//     после чего пачка обновляется одним запросом к внешнему API;
+//   - одновременно выполняется не более refreshConcurrency обновлений, остальные ждут слот;
+//   - ключ, обновление которого уже запланировано или выполняется, повторно не планируется.
+type refresher struct {
+	refresh func(ctx context.Context, reason string, ids []*dto.ResolvedCacheId)
+	slots   chan struct{}
+	window  time.Duration
+
+	scheduled keySet
+
+	mu      sync.Mutex
+	pending map[refreshGroup]*refreshBatch
+}
+
+func newRefresher(refresh func(ctx context.Context, reason string, ids []*dto.ResolvedCacheId)) *refresher {
+	return &refresher{
+		refresh: refresh,
+		slots:   make(chan struct{}, refreshConcurrency),
+		window:  refreshBatchWindow,
+		pending: make(map[refreshGroup]*refreshBatch),
+	}
+}
+
+// schedule добавляет ключи в очередь фонового обновления.
+func (r *refresher) schedule(reason string, ids []*dto.ResolvedCacheId) {
+	ids = r.scheduled.add(ids)
+	if len(ids) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		group := refreshGroup{cacheName: id.GetCacheName(), reason: reason}
+		batch := r.pending[group]
+		if batch == nil {
+			batch = &refreshBatch{}
+			batch.timer = time.AfterFunc(r.window, func() { r.flush(group, batch) })
+			r.pending[group] = batch
+		}
+		batch.ids = append(batch.ids, id)
+
+		if len(batch.ids) >= refreshBatchSize {
+			// таймер полной пачки не должен отправить раньше времени следующую пачку группы
+			batch.timer.Stop()
+			delete(r.pending, group)
+			go r.run(group.reason, batch.ids)
+		}
+	}
+}
+
+// flush отправляет накопленную пачку по истечении окна. Пачка, уже отправленная по размеру,
+// не отправляется повторно, даже если её таймер успел сработать до остановки.
+func (r *refresher) flush(group refreshGroup, batch *refreshBatch) {
+	r.mu.Lock()
+	if r.pending[group] != batch {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.pending, group)
+	r.mu.Unlock()
+
+	if len(batch.ids) > 0 {
+		r.run(group.reason, batch.ids)
+	}
+}
+
+func (r *refresher) run(reason string, ids []*dto.ResolvedCacheId) {
+	r.slots <- struct{}{}
+	defer func() { <-r.slots }()
+	defer r.scheduled.remove(ids)
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			zap.S().Errorf(alert.Prefix("panic in background refresh: %v"), rec)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	r.refresh(ctx, reason, ids)
+}
+
+// scheduleRefresh ставит ключи в очередь фонового обновления.
+func (m *ManagerImpl) scheduleRefresh(reason string, ids []*dto.ResolvedCacheId) {
+	if m.refresher == nil || len(ids) == 0 {
+		return
+	}
+	m.refresher.schedule(reason, ids)
+}
+
+// refreshNow перечитывает ключи во внешнем источнике и перезаписывает их во всех слоях.
+//
+// Исход для каждого ключа:
+//   - найден во внешнем источнике — значение перезаписывается во всех слоях;
+//   - отсутствует во внешнем источнике — значение удаляется из всех слоёв, а для кэшей
+//     с negativeTTL сохраняется tombstone (см. repopulateLevels);
+//   - ошибка внешнего источника — значение остаётся как есть до истечения TTL.
+//
+// Все ключи пачки относятся к одному кэшу (см. refreshGroup).
+func (m *ManagerImpl) refreshNow(ctx context.Context, reason string, ids []*dto.ResolvedCacheId) {
+	zap.S().Infow("background refresh started", "reason", reason, "count", len(ids))
+	result := m.fetchExternal(ctx, ids)
+
+	lastLevel := 0
+	if cache, err := m.configService.GetCache(ids[0]); err == nil && len(cache.Layers) > 0 {
+		lastLevel = len(cache.Layers) - 1
+	}
+	m.repopulateLevels(ctx, result, lastLevel)
+
+	for _, hit := range result.Hits {
+		metrics.RecordRefresh(hit.GetCacheName(), reason, metrics.RefreshStatusUpdated)
+	}
+	for _, miss := range result.Misses {
+		metrics.RecordRefresh(miss.GetCacheName(), reason, metrics.RefreshStatusRemoved)
+	}
+	for _, skipped := range result.Skipped {
+		metrics.RecordRefresh(skipped.GetCacheName(), reason, metrics.RefreshStatusFailed)
+	}
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/config"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefresher_FullBatchStopsItsTimer(t *testing.T) {
+	batches := make(chan int, 4)
+	r := newRefresher(func(_ context.Context, _ string, ids []*dto.ResolvedCacheId) {
+		batches <- len(ids)
+	})
+	r.window = 100 * time.Millisecond
+
+	id := func(i int) *dto.ResolvedCacheId {
+		key := strconv.Itoa(i)
+		return &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: key}, StorageKey: "p:" + key}
+	}
+	full := make([]*dto.ResolvedCacheId, 0, refreshBatchSize)
+	for i := 0; i < refreshBatchSize; i++ {
+		full = append(full, id(i))
+	}
+
+	r.schedule("stale", full)
+	assert.Equal(t, refreshBatchSize, <-batches)
+
+	// следующая пачка группы ждёт своё окно, а не срабатывание таймера полной пачки
+	time.Sleep(r.window / 2)
+	next := time.Now()
+	r.schedule("stale", []*dto.ResolvedCacheId{id(refreshBatchSize)})
+
+	select {
+	case n := <-batches:
+		assert.Equal(t, 1, n)
+		assert.GreaterOrEqual(t, time.Since(next), r.window)
+	case <-time.After(time.Second):
+		t.Fatal("batch was not flushed")
+	}
+}
+
+func TestManager_RefreshNow_StoresTombstonesForExternalMisses(t *testing.T) {
+	services := &mockCacheService{
+		prefixMap: map[string]string{"c": "p"},
+		caches:    map[string]config.Cache{"c": {Layers: []config.CacheLayerConfig{{}, {}}}},
+	}
+	rid := &dto.ResolvedCacheId{CacheId: &dto.CacheId{CacheName: "c", Key: "404"}, StorageKey: "p:404"}
+	ctrl := &mockCacheController{}
+	ctrl.putAllWG.Add(1)
+	ext := &mockExternalController{result: &dto.GetResult{Misses: []*dto.ResolvedCacheId{rid}}}
+	mgr := &ManagerImpl{configService: services, cacheController: ctrl, externalController: ext, mapper: dto.NewResolverMapper(services)}
+
+	mgr.refreshNow(context.Background(), "stale", []*dto.ResolvedCacheId{rid})
+
+	assert.Equal(t, 1, ctrl.deleteCalled)
+	assert.Equal(t, 1, ctrl.putAllCalled)
+	assert.Equal(t, 1, ctrl.putBound[0])
+	assert.True(t, ctrl.putEntries[0].Tombstone)
+	assert.Equal(t, "p:404", ctrl.putEntries[0].GetStorageKey())
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"context"
+	"errors"
+	"sync"
+)
+
+// flightGroup дедуплицирует одновременные запросы во внешний источник по StorageKey.
+//
+// Первый запрос, промахнувшийся по ключу, становится «лидером» и выполняет fetch.
+// Остальные запросы, пришедшие за тем же ключом, пока fetch не завершён, становятся
+// «ожидающими» и получают результат лидера, не обращаясь к upstream.
+//
+// Нулевое значение готово к использованию.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// errFetchAborted — fetch лидера прерван (например, паникой), результат неизвестен.
+var errFetchAborted = errors.New("upstream fetch aborted")
+
+// flightCall — один выполняющийся fetch по ключу.
+//
+// done закрывается лидером после того, как заполнены hit, skipped и err.
+//   - hit != nil  — значение найдено во внешнем источнике;
+//   - hit == nil && !skipped — ключ отсутствует во внешнем источнике (miss);
+//   - skipped — ключ не проверен (err — ошибка запроса лидера, если она была).
+type flightCall struct {
+	done    chan struct{}
+	hit     *dto.ResolvedCacheHit
+	skipped bool
+	err     error
+}
+
+// acquire делит ключи на те, за которые отвечает текущий вызов (leaders),
+// и те, которые уже запрашиваются другим вызовом (waiters).
+func (g *flightGroup) acquire(reqs []*dto.ResolvedCacheId) (leaders []*dto.ResolvedCacheId, waiters map[*dto.ResolvedCacheId]*flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	leaders = make([]*dto.ResolvedCacheId, 0, len(reqs))
+	waiters = make(map[*dto.ResolvedCacheId]*flightCall)
+	for _, req := range reqs {
+		key := req.GetStorageKey()
+		if call, ok := g.calls[key]; ok {
+			waiters[req] = call
+			continue
+		}
+		g.calls[key] = &flightCall{done: make(chan struct{})}
+		leaders = append(leaders, req)
+	}
+	return
+}
+
+// release публикует результат лидера для всех ожидающих и освобождает ключи.
+// Ключи, не попавшие ни в Hits, ни в Misses результата, считаются skipped.
+// Допускает result == nil (например, при панике во время fetch).
+func (g *flightGroup) release(leaders []*dto.ResolvedCacheId, result *dto.GetResult) {
+	hits := make(map[string]*dto.ResolvedCacheHit)
+	misses := make(map[string]bool)
+	errs := make(map[string]error)
+	if result != nil {
+		for _, hit := range result.Hits {
+			hits[hit.GetStorageKey()] = hit
+		}
+		for _, miss := range result.Misses {
+			misses[miss.GetStorageKey()] = true
+		}
+		for _, e := range result.Errors {
+			errs[e.GetStorageKey()] = e.Err
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, req := range leaders {
+		key := req.GetStorageKey()
+		call, ok := g.calls[key]
+		if !ok {
+			continue
+		}
+		delete(g.calls, key)
+
+		if hit, found := hits[key]; found {
+			call.hit = hit
+		} else if !misses[key] {
+			call.skipped = true
+			call.err = errs[key]
+			if result == nil {
+				call.err = errFetchAborted
+			}
+		}
+		close(call.done)
+	}
+}
+
+// wait дожидается результатов чужих fetch'ей и возвращает их в виде GetResult,
+// привязанного к ResolvedCacheId текущего вызова.
+// При отмене контекста ещё не завершённые ключи попадают в Skipped с ошибкой контекста.
+func (g *flightGroup) wait(ctx context.Context, waiters map[*dto.ResolvedCacheId]*flightCall) *dto.GetResult {
+	result := &dto.GetResult{
+		Hits:    []*dto.ResolvedCacheHit{},
+		Misses:  []*dto.ResolvedCacheId{},
+		Skipped: []*dto.ResolvedCacheId{},
+	}
+
+	for req, call := range waiters {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			result.Skipped = append(result.Skipped, req)
+			result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: req, Err: ctx.Err()})
+			continue
+		}
+
+		switch {
+		case call.hit != nil:
+			result.Hits = append(result.Hits, &dto.ResolvedCacheHit{
+				ResolvedCacheEntry: &dto.ResolvedCacheEntry{
+					ResolvedCacheId: req,
+					Value:           call.hit.ResolvedCacheEntry.Value,
+				},
+				Found: call.hit.Found,
+			})
+		case call.skipped:
+			result.Skipped = append(result.Skipped, req)
+			if call.err != nil {
+				result.Errors = append(result.Errors, &dto.ResolvedCacheError{ResolvedCacheId: req, Err: call.err})
+			}
+		default:
+			result.Misses = append(result.Misses, req)
+		}
+	}
+	return result
+}
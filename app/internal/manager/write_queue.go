@@ -0,0 +1,307 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/metrics"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"telegram-alerts-go/alert"
+)
+
+const (
+	// writeRetries — сколько раз повторяется применение ключей, для которых слой вернул ошибку.
+	writeRetries = 3
+
+	// writeRetryBackoff — пауза перед первым повтором, удваивается с каждым следующим.
+	writeRetryBackoff = 100 * time.Millisecond
+)
+
+var (
+	// ErrQueueFull — очередь write-behind заполнена, операция не принята. Клиенту стоит повторить позже.
+	ErrQueueFull = errors.New("write-behind queue is full")
+
+	// ErrQueueClosed — очередь write-behind остановлена.
+	ErrQueueClosed = errors.New("write-behind queue is closed")
+)
+
+// writeOp — одна принятая операция записи в слои кэша (put_all или evict_all).
+type writeOp struct {
+	Seq        uint64            `json:"seq"`
+	EnqueuedAt time.Time         `json:"at"`
+	Put        []*dto.CacheEntry `json:"put,omitempty"`
+	Evict      []*dto.CacheId    `json:"evict,omitempty"`
+}
+
+// writeQueue — очередь write-behind.
+//
+//   - место в очереди резервируется до записи во внешний API (acquire), поэтому при
+//     заполненной очереди клиент сразу получает ErrQueueFull, а внешний API не меняется;
+//   - принятая операция дописывается в журнал (если он настроен) и только затем
+//     передаётся воркерам;
+//   - ключи распределяются по воркерам по хэшу: все операции над одним ключом применяет
+//     один воркер в порядке приёма, поэтому старое значение не перезапишет новое.
+//     Операция с ключами разных воркеров делится на части;
+//   - ключи, для которых слой вернул ошибку, применяются повторно (writeRetries раз
+//     с растущей паузой); если слой так и не принял их, операция считается потерянной
+//     (write_behind_dropped_total{reason="layer_error"});
+//   - операция подтверждается в журнале, когда применены (или потеряны) все её части;
+//   - при создании очереди неподтверждённые операции из журнала применяются по порядку.
+type writeQueue struct {
+	apply   func(op *writeOp) *writeOp
+	journal *journal // nil = очередь только в памяти
+	backoff time.Duration
+
+	slots  chan struct{}
+	shards []chan *writePart // очередь каждого воркера
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	seq    uint64
+	closed bool
+}
+
+// writePart — часть операции с ключами одного воркера.
+type writePart struct {
+	op      *writeOp      // ключи части; Seq и EnqueuedAt — как у операции
+	pending *atomic.Int32 // сколько частей операции ещё не применено
+}
+
+// newWriteQueue создаёт очередь, применяет операции, оставшиеся в журнале, и запускает воркеры.
+func newWriteQueue(apply func(op *writeOp) *writeOp, j *journal, pending []*writeOp, size int, workers int) *writeQueue {
+	q := &writeQueue{
+		apply:   apply,
+		journal: j,
+		backoff: writeRetryBackoff,
+		slots:   make(chan struct{}, size),
+		shards:  make([]chan *writePart, workers),
+	}
+	if j != nil {
+		q.seq = j.lastSeq
+	}
+
+	if len(pending) > 0 {
+		zap.S().Infow("replaying write-behind journal", "count", len(pending))
+		for _, op := range pending {
+			q.process(op)
+			q.ack(op)
+		}
+	}
+
+	q.wg.Add(workers)
+	for i := range q.shards {
+		// каждая операция даёт воркеру не больше одной части, поэтому size частей хватает
+		shard := make(chan *writePart, size)
+		q.shards[i] = shard
+		go func() {
+			defer q.wg.Done()
+			for part := range shard {
+				q.process(part.op)
+				if part.pending.Add(-1) > 0 {
+					continue
+				}
+				q.ack(part.op)
+				metrics.WriteBehindLag.Observe(time.Since(part.op.EnqueuedAt).Seconds())
+				metrics.WriteBehindQueueDepth.Dec()
+				q.release()
+			}
+		}()
+	}
+	return q
+}
+
+// acquire резервирует место в очереди, не блокируясь.
+// Резерв нужно либо передать в submit, либо вернуть через release.
+func (q *writeQueue) acquire() error {
+	select {
+	case q.slots <- struct{}{}:
+		return nil
+	default:
+		metrics.RecordWriteBehindDrop(metrics.DropReasonQueueFull)
+		return ErrQueueFull
+	}
+}
+
+func (q *writeQueue) release() {
+	<-q.slots
+}
+
+// submit ставит операцию в очередь, используя ранее зарезервированное место.
+// При ошибке резерв остаётся за вызывающим: его нужно вернуть через release.
+func (q *writeQueue) submit(op *writeOp) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	q.seq++
+	op.Seq = q.seq
+	op.EnqueuedAt = time.Now()
+
+	if q.journal != nil {
+		if err := q.journal.append(op); err != nil {
+			metrics.RecordWriteBehindDrop(metrics.DropReasonJournalError)
+			zap.S().Errorw(alert.Prefix("write-behind journal error"), "error", err)
+			// номер уже выдан: без подтверждения checkpoint навсегда остановится перед ним
+			q.ack(op)
+			return err
+		}
+	}
+
+	metrics.WriteBehindQueueDepth.Inc()
+	parts := q.split(op)
+	pending := new(atomic.Int32)
+	pending.Store(int32(len(parts)))
+	for shard, part := range parts {
+		q.shards[shard] <- &writePart{op: part, pending: pending} // не блокируется: место зарезервировано в slots
+	}
+	return nil
+}
+
+// split делит операцию на части по воркерам, сохраняя порядок ключей внутри части.
+func (q *writeQueue) split(op *writeOp) map[int]*writeOp {
+	parts := make(map[int]*writeOp, 1)
+	part := func(shard int) *writeOp {
+		p, ok := parts[shard]
+		if !ok {
+			p = &writeOp{Seq: op.Seq, EnqueuedAt: op.EnqueuedAt}
+			parts[shard] = p
+		}
+		return p
+	}
+	for _, id := range op.Evict {
+		p := part(q.shardOf(id))
+		p.Evict = append(p.Evict, id)
+	}
+	for _, e := range op.Put {
+		p := part(q.shardOf(e.CacheId))
+		p.Put = append(p.Put, e)
+	}
+	return parts
+}
+
+// shardOf возвращает воркер ключа.
+func (q *writeQueue) shardOf(id *dto.CacheId) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id.CacheName))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(id.Key))
+	return int(h.Sum32() % uint32(len(q.shards)))
+}
+
+// process применяет операцию (или её часть) к слоям, повторяя ключи, которые слои не приняли.
+func (q *writeQueue) process(op *writeOp) {
+	backoff := q.backoff
+	rest := op
+	for attempt := 0; ; attempt++ {
+		rest = q.applyOnce(rest)
+		if rest == nil {
+			return
+		}
+		if attempt == writeRetries {
+			metrics.RecordWriteBehindDrop(metrics.DropReasonLayerError)
+			zap.S().Errorw(alert.Prefix("write-behind: cache layers failed, operation dropped"),
+				"seq", op.Seq, "put", len(rest.Put), "evict", len(rest.Evict))
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// applyOnce применяет операцию и возвращает её неприменённую часть (nil — применена вся).
+// Паника считается потерей операции: повтор, скорее всего, упадёт так же.
+func (q *writeQueue) applyOnce(op *writeOp) (rest *writeOp) {
+	defer func() {
+		if r := recover(); r != nil {
+			metrics.RecordWriteBehindDrop(metrics.DropReasonPanic)
+			zap.S().Errorf(alert.Prefix("write-behind panic: %v"), r)
+			rest = nil
+		}
+	}()
+
+	return q.apply(op)
+}
+
+// ack подтверждает операцию в журнале.
+func (q *writeQueue) ack(op *writeOp) {
+	if q.journal == nil {
+		return
+	}
+	if err := q.journal.ack(op.Seq); err != nil {
+		zap.S().Errorw(alert.Prefix("write-behind journal ack error"), "seq", op.Seq, "error", err)
+	}
+}
+
+// close перестаёт принимать операции, дожидается применения уже принятых и закрывает журнал.
+func (q *writeQueue) close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	for _, shard := range q.shards {
+		close(shard)
+	}
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	if q.journal != nil {
+		return q.journal.close()
+	}
+	return nil
+}
+
+// newApplyFunc возвращает функцию применения операции к слоям кэша через Manager.
+// Функция возвращает неприменённую часть операции — ключи, для которых хотя бы один слой
+// вернул ошибку, — или nil. Если не удалось удалить ключ, запись того же ключа в этой
+// операции тоже повторяется, чтобы сохранить порядок evict → put.
+func newApplyFunc(m Manager, putTimeout, evictTimeout time.Duration) func(op *writeOp) *writeOp {
+	return func(op *writeOp) *writeOp {
+		rest := &writeOp{Seq: op.Seq, EnqueuedAt: op.EnqueuedAt}
+		failed := make(map[dto.CacheId]bool)
+		if len(op.Evict) > 0 {
+			ctx, cancel := makeCtx(evictTimeout)
+			collectFailed(m.EvictAll(ctx, op.Evict), failed)
+			cancel()
+			for _, id := range op.Evict {
+				if failed[*id] {
+					rest.Evict = append(rest.Evict, id)
+				}
+			}
+		}
+		if len(op.Put) > 0 {
+			ctx, cancel := makeCtx(putTimeout)
+			collectFailed(m.PutAll(ctx, op.Put), failed)
+			cancel()
+			for _, e := range op.Put {
+				if failed[*e.CacheId] {
+					rest.Put = append(rest.Put, e)
+				}
+			}
+		}
+		if len(rest.Put) == 0 && len(rest.Evict) == 0 {
+			return nil
+		}
+		return rest
+	}
+}
+
+// collectFailed отмечает ключи, для которых хотя бы один слой вернул ошибку.
+func collectFailed(results []*dto.WriteItemResult, failed map[dto.CacheId]bool) {
+	for _, r := range results {
+		for _, layer := range r.Layers {
+			if layer.Status == dto.LayerStatusError {
+				failed[*r.CacheId] = true
+				break
+			}
+		}
+	}
+}
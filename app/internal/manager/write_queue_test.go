@@ -0,0 +1,347 @@
+package manager
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/config"
+	"aur-cache-service/internal/metrics"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteQueue_FullQueueRejectsWithoutUpstreamWrite(t *testing.T) {
+	mgr := &mockManager{wait: 100 * time.Millisecond}
+	mgr.putWG.Add(1)
+	f := newAsyncManagerAdapter(mgr, time.Second, time.Second, nil, nil, config.WriteBehindConfig{QueueSize: 1, Workers: 1})
+	defer f.Close()
+
+	dropped := metrics.WriteBehindDropped.WithLabelValues(metrics.DropReasonQueueFull)
+	before := testutil.ToFloat64(dropped)
+
+	entry := &dto.CacheEntry{CacheId: &dto.CacheId{CacheName: "c", Key: "k"}}
+	_, err := f.PutAll(context.Background(), []*dto.CacheEntry{entry})
+	assert.NoError(t, err)
+
+	// первая операция ещё применяется и занимает единственное место в очереди
+	mgr.upstream = &dto.UpstreamWriteResult{Failed: []*dto.UpstreamError{{CacheId: entry.CacheId, Error: "must not be called"}}}
+	failed, err := f.PutAll(context.Background(), []*dto.CacheEntry{entry})
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Empty(t, failed)
+	assert.Equal(t, before+1, testutil.ToFloat64(dropped))
+
+	mgr.putWG.Wait()
+}
+
+func TestWriteQueue_ReplaysUnackedJournal(t *testing.T) {
+	dir := t.TempDir()
+	raw := json.RawMessage(`{"a":1}`)
+
+	j, pending, err := openJournal(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	for seq := uint64(1); seq <= 3; seq++ {
+		op := &writeOp{Seq: seq, Put: []*dto.CacheEntry{{CacheId: &dto.CacheId{CacheName: "c", Key: "k"}, Value: &raw}}}
+		if seq == 3 {
+			op = &writeOp{Seq: seq, Evict: []*dto.CacheId{{CacheName: "c", Key: "k"}}}
+		}
+		assert.NoError(t, j.append(op))
+	}
+	// подтверждены 1 и 3: checkpoint не сдвигается через «дыру» во второй операции,
+	// поэтому после перезапуска повторяются 2 и 3 — по порядку, чтобы evict остался последним
+	assert.NoError(t, j.ack(1))
+	assert.NoError(t, j.ack(3))
+	assert.NoError(t, j.close())
+
+	j, pending, err = openJournal(dir)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	var mu sync.Mutex
+	applied := make([]*writeOp, 0)
+	q := newWriteQueue(func(op *writeOp) *writeOp {
+		mu.Lock()
+		applied = append(applied, op)
+		mu.Unlock()
+		return nil
+	}, j, pending, 10, 1)
+
+	// повтор выполняется при создании очереди, по порядку
+	assert.Equal(t, []uint64{2, 3}, []uint64{applied[0].Seq, applied[1].Seq})
+	assert.Equal(t, "k", applied[0].Put[0].Key)
+	assert.JSONEq(t, `{"a":1}`, string(*applied[0].Put[0].Value))
+	assert.Equal(t, "k", applied[1].Evict[0].Key)
+
+	// новые операции продолжают нумерацию
+	assert.NoError(t, q.acquire())
+	assert.NoError(t, q.submit(&writeOp{Evict: []*dto.CacheId{{CacheName: "c", Key: "x"}}}))
+	assert.NoError(t, q.close())
+	assert.Len(t, applied, 3)
+	assert.Equal(t, uint64(4), applied[2].Seq)
+
+	// после подтверждения всех операций повторять нечего
+	j, pending, err = openJournal(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.NoError(t, j.close())
+}
+
+func TestWriteQueue_JournalAppendFailureDoesNotStopCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	j, _, err := openJournal(dir)
+	assert.NoError(t, err)
+	q := newWriteQueue(func(op *writeOp) *writeOp { return nil }, j, nil, 10, 1)
+
+	submit := func(key string) error {
+		assert.NoError(t, q.acquire())
+		err := q.submit(&writeOp{Evict: []*dto.CacheId{{CacheName: "c", Key: key}}})
+		if err != nil {
+			q.release()
+		}
+		return err
+	}
+
+	assert.NoError(t, submit("1"))
+	// запись в журнал не удаётся: сегмент подменён открытым только на чтение
+	active := j.file
+	readOnly, err := os.Open(active.Name())
+	assert.NoError(t, err)
+	j.mu.Lock()
+	j.file = readOnly
+	j.mu.Unlock()
+	assert.Error(t, submit("2"))
+	j.mu.Lock()
+	j.file = active
+	j.mu.Unlock()
+	assert.NoError(t, readOnly.Close())
+
+	assert.NoError(t, submit("3"))
+	assert.NoError(t, q.close())
+	assert.Equal(t, uint64(3), j.checkpoint)
+	assert.Empty(t, j.acked)
+
+	// после перезапуска повторять нечего
+	j, pending, err := openJournal(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.Equal(t, uint64(3), j.lastSeq)
+	assert.NoError(t, j.close())
+}
+
+func TestWriteQueue_AppliesOperationsOnKeyInOrder(t *testing.T) {
+	value := func(v string) *json.RawMessage {
+		raw := json.RawMessage(v)
+		return &raw
+	}
+	a := &dto.CacheId{CacheName: "c", Key: "A"}
+
+	for i := 0; i < 20; i++ {
+		dir := t.TempDir()
+		j, _, err := openJournal(dir)
+		assert.NoError(t, err)
+
+		// слои: значение по ключу; воркеры применяют операции с разной задержкой
+		var mu sync.Mutex
+		state := make(map[dto.CacheId]string)
+		q := newWriteQueue(func(op *writeOp) *writeOp {
+			time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range op.Evict {
+				delete(state, *id)
+			}
+			for _, e := range op.Put {
+				state[*e.CacheId] = string(*e.Value)
+			}
+			return nil
+		}, j, nil, 10, 8)
+
+		ops := []*writeOp{
+			{Put: []*dto.CacheEntry{{CacheId: a, Value: value("1")}}},
+			{Put: []*dto.CacheEntry{{CacheId: a, Value: value("2")}}},
+			{Evict: []*dto.CacheId{a}},
+		}
+		// остальные ключи операции уходят другим воркерам
+		for k := 0; k < 8; k++ {
+			id := &dto.CacheId{CacheName: "c", Key: strconv.Itoa(k)}
+			ops[1].Put = append(ops[1].Put, &dto.CacheEntry{CacheId: id, Value: value("2")})
+		}
+		for _, op := range ops {
+			assert.NoError(t, q.acquire())
+			assert.NoError(t, q.submit(op))
+		}
+		assert.NoError(t, q.close())
+
+		_, ok := state[*a]
+		assert.False(t, ok, "evict(A) must be applied after put(A=2)")
+		assert.Len(t, state, 8)
+
+		// операция подтверждается, только когда применены все её части
+		j, pending, err := openJournal(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+		assert.NoError(t, j.close())
+	}
+}
+
+func TestWriteQueue_RetriesKeysRejectedByLayers(t *testing.T) {
+	ok := &dto.CacheId{CacheName: "c", Key: "ok"}
+	flaky := &dto.CacheId{CacheName: "c", Key: "flaky"}
+	broken := &dto.CacheId{CacheName: "c", Key: "broken"}
+
+	tests := []struct {
+		name     string
+		failures int // сколько раз слой отклоняет ключ
+		key      *dto.CacheId
+		dropped  float64
+	}{
+		{"recovers", 2, flaky, 0},
+		{"gives up", writeRetries + 1, broken, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			j, _, err := openJournal(dir)
+			assert.NoError(t, err)
+
+			dropped := metrics.WriteBehindDropped.WithLabelValues(metrics.DropReasonLayerError)
+			before := testutil.ToFloat64(dropped)
+
+			var mu sync.Mutex
+			attempts := make([][]*dto.CacheId, 0)
+			q := newWriteQueue(func(op *writeOp) *writeOp {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts = append(attempts, op.Evict)
+				if len(attempts) > tt.failures {
+					return nil
+				}
+				// остальные ключи приняты, повторяется только отклонённый
+				return &writeOp{Seq: op.Seq, Evict: []*dto.CacheId{tt.key}}
+			}, j, nil, 10, 1)
+			q.backoff = time.Millisecond
+
+			assert.NoError(t, q.acquire())
+			assert.NoError(t, q.submit(&writeOp{Evict: []*dto.CacheId{ok, tt.key}}))
+			assert.NoError(t, q.close())
+
+			assert.Len(t, attempts, min(tt.failures, writeRetries)+1)
+			assert.Equal(t, []*dto.CacheId{ok, tt.key}, attempts[0])
+			for _, retry := range attempts[1:] {
+				assert.Equal(t, []*dto.CacheId{tt.key}, retry)
+			}
+			assert.Equal(t, before+tt.dropped, testutil.ToFloat64(dropped))
+
+			// потерянная операция тоже подтверждается: повтор после перезапуска не поможет
+			j, pending, err := openJournal(dir)
+			assert.NoError(t, err)
+			assert.Empty(t, pending)
+			assert.NoError(t, j.close())
+		})
+	}
+}
+
+func TestApplyFunc_ReturnsKeysRejectedByLayers(t *testing.T) {
+	ok := &dto.CacheId{CacheName: "c", Key: "ok"}
+	bad := &dto.CacheId{CacheName: "c", Key: "bad"}
+	mgr := &mockManager{failKeys: map[string]bool{"bad": true}}
+	mgr.putWG.Add(1)
+	mgr.evictWG.Add(1)
+	apply := newApplyFunc(mgr, time.Second, time.Second)
+
+	op := &writeOp{
+		Seq:   7,
+		Evict: []*dto.CacheId{ok, bad},
+		Put:   []*dto.CacheEntry{{CacheId: ok}, {CacheId: bad}},
+	}
+	rest := apply(op)
+
+	assert.Equal(t, uint64(7), rest.Seq)
+	assert.Equal(t, []*dto.CacheId{bad}, rest.Evict)
+	assert.Equal(t, []*dto.CacheEntry{op.Put[1]}, rest.Put)
+}
+
+func TestJournal_RemovesAppliedSegments(t *testing.T) {
+	dir := t.TempDir()
+	segments := func() []string {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), journalSegmentPrefix) {
+				names = append(names, e.Name())
+			}
+		}
+		return names
+	}
+
+	j, _, err := openJournal(dir)
+	assert.NoError(t, err)
+	// каждая операция — в своём сегменте
+	j.segmentSize = 1
+	for seq := uint64(1); seq <= 3; seq++ {
+		assert.NoError(t, j.append(&writeOp{Seq: seq, Evict: []*dto.CacheId{{CacheName: "c", Key: "k"}}}))
+	}
+	assert.Len(t, segments(), 3)
+
+	assert.NoError(t, j.ack(1))
+	assert.Len(t, segments(), 2)
+
+	// checkpoint не сдвигается через «дыру» во второй операции — сегменты остаются
+	assert.NoError(t, j.ack(3))
+	assert.Len(t, segments(), 2)
+
+	// активный сегмент не удаляется, даже если все его операции применены
+	assert.NoError(t, j.ack(2))
+	assert.Equal(t, []string{filepath.Base(j.segmentPath(3))}, segments())
+	assert.NoError(t, j.close())
+
+	j, pending, err := openJournal(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.Equal(t, uint64(3), j.lastSeq)
+	assert.NoError(t, j.close())
+}
+
+func TestJournal_ReplaysAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+
+	j, _, err := openJournal(dir)
+	assert.NoError(t, err)
+	j.segmentSize = 1
+	for seq := uint64(1); seq <= 3; seq++ {
+		assert.NoError(t, j.append(&writeOp{Seq: seq, Evict: []*dto.CacheId{{CacheName: "c", Key: strconv.FormatUint(seq, 10)}}}))
+	}
+	assert.NoError(t, j.ack(1))
+	assert.NoError(t, j.close())
+
+	// недописанный хвост активного сегмента отбрасывается
+	f, err := os.OpenFile(j.segmentPath(3), os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"seq":4,"ev`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	j, pending, err := openJournal(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, []uint64{pending[0].Seq, pending[1].Seq})
+	assert.Equal(t, uint64(3), j.lastSeq)
+
+	// дозапись продолжается в активный сегмент после отрезанного хвоста
+	assert.NoError(t, j.append(&writeOp{Seq: 4, Evict: []*dto.CacheId{{CacheName: "c", Key: "4"}}}))
+	assert.NoError(t, j.close())
+	j, pending, err = openJournal(dir)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+	assert.NoError(t, j.close())
+}
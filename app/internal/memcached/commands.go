@@ -0,0 +1,311 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/manager"
+	"aur-cache-service/internal/metrics"
+	"aur-cache-service/internal/tcpserver"
+)
+
+// commandTimeout ограничивает выполнение одной команды (включая запрос во внешний API).
+const commandTimeout = 10 * time.Second
+
+const noReply = "noreply"
+
+var (
+	errKeyTooLong    = errors.New("key too long")
+	errUnknownPrefix = errors.New("no cache is mapped to the key prefix")
+	errBadFormat     = errors.New("bad command line format")
+	errBadChunk      = errors.New("bad data chunk")
+	errTooLarge      = errors.New("object too large for cache")
+	errNotSupported  = errors.New("command not supported")
+)
+
+// execute выполняет команду. quit — клиент завершил сессию; err — поток команд
+// нарушен (например, блок данных не завершён CRLF) и соединение нужно закрыть.
+func (s *Server) execute(r *bufio.Reader, w *bufio.Writer, args []string) (quit bool, err error) {
+	name := strings.ToLower(args[0])
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var cmdErr error
+	switch name {
+	case "get", "gets":
+		cmdErr = s.get(ctx, w, args[1:], name == "gets")
+	case "set", "add", "cas":
+		cmdErr, err = s.set(ctx, r, w, name, args[1:])
+	case "replace", "append", "prepend":
+		// команды с блоком данных: данные нужно дочитать, чтобы не принять их за следующую команду
+		cmdErr, err = skipData(r, args[1:])
+		if cmdErr == nil {
+			cmdErr = errNotSupported
+		}
+	case "delete":
+		cmdErr = s.delete(ctx, w, args[1:])
+	case "touch":
+		cmdErr = s.touch(ctx, w, args[1:])
+	case "version":
+		writeLine(w, "VERSION aur-cache-service")
+	case "quit":
+		return true, nil
+	default:
+		writeLine(w, "ERROR")
+		metrics.RecordMemcachedCommand("unknown", errors.New("unknown command"))
+		return false, nil
+	}
+
+	if cmdErr != nil {
+		writeLine(w, errorLine(cmdErr))
+	}
+	metrics.RecordMemcachedCommand(name, cmdErr)
+	return false, err
+}
+
+/* ---------- команды ---------- */
+
+// get отвечает VALUE <key> <flags> <bytes> [<cas>] для найденных ключей и END.
+// Флаги — сохранённые set (0 для значений, записанных не через memcached);
+// cas — версия значения (dto.ValueVersion) для команды cas.
+func (s *Server) get(ctx context.Context, w *bufio.Writer, keys []string, withCas bool) error {
+	if len(keys) == 0 {
+		return errBadFormat
+	}
+	ids := make([]*dto.CacheId, 0, len(keys))
+	for _, key := range keys {
+		id, err := s.resolve(key)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	hits := make(map[dto.CacheId]*dto.CacheEntryHit, len(ids))
+	for _, h := range s.adapter.GetAll(ctx, ids) {
+		if h != nil && h.Found && h.CacheEntry != nil && h.CacheId != nil && h.Value != nil {
+			hits[*h.CacheId] = h
+		}
+	}
+
+	for i, id := range ids {
+		hit, ok := hits[*id]
+		if !ok {
+			continue
+		}
+		value, flags := tcpserver.FromJSON(*hit.Value)
+		header := "VALUE " + keys[i] + " " + strconv.FormatUint(uint64(flags), 10) + " " + strconv.Itoa(len(value))
+		if withCas {
+			header += " " + dto.ValueVersion(*hit.Value)
+		}
+		writeLine(w, header)
+		w.Write(value)
+		w.WriteString("\r\n")
+	}
+	writeLine(w, "END")
+	return nil
+}
+
+// set <key> <flags> <exptime> <bytes> [noreply]. Флаги сохраняются вместе со значением
+// (см. tcpserver.ToJSON) и возвращаются get. Положительный exptime
+// задаёт время жизни записи вместо TTL слоёв: до 30 дней — в секундах, больше — как unix-время
+// истечения. exptime <= 0 — время жизни определяется TTL слоёв кэша.
+//
+// add — запись, только если ключа нет (иначе NOT_STORED); cas <key> <flags> <exptime> <bytes> <cas>
+// — только если версия значения совпадает с cas из gets (иначе EXISTS, в том числе если ключа нет).
+func (s *Server) set(ctx context.Context, r *bufio.Reader, w *bufio.Writer, name string, args []string) (cmdErr error, streamErr error) {
+	want := 4
+	if name == "cas" {
+		want = 5
+	}
+	if len(args) < want || len(args) > want+1 {
+		return errBadFormat, nil
+	}
+	size, err := strconv.Atoi(args[3])
+	if err != nil || size < 0 {
+		return errBadFormat, nil
+	}
+	if size > maxValueSize {
+		// данные дочитываются и отбрасываются, как в memcached
+		if _, err := io.CopyN(io.Discard, r, int64(size)+2); err != nil {
+			return errTooLarge, err
+		}
+		return errTooLarge, nil
+	}
+	data, err := readData(r, size)
+	if err != nil {
+		return errBadChunk, err
+	}
+	flags, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return errBadFormat, nil
+	}
+	exptime, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return errBadFormat, nil
+	}
+
+	id, err := s.resolve(args[0])
+	if err != nil {
+		return err, nil
+	}
+	value := tcpserver.ToJSON(data, uint32(flags))
+	entry := &dto.CacheEntry{CacheId: id, Value: &value, TTL: expirationTtl(exptime, time.Now())}
+	switch name {
+	case "add":
+		entry.IfAbsent = true
+	case "cas":
+		if _, err := strconv.ParseUint(args[4], 10, 64); err != nil {
+			return errBadFormat, nil
+		}
+		entry.IfVersion = args[4]
+	}
+
+	failed, err := s.adapter.Put(ctx, entry)
+	reply := "STORED"
+	if errors.As(err, new(*manager.ConflictError)) {
+		reply, err = "NOT_STORED", nil
+		if name == "cas" {
+			reply = "EXISTS"
+		}
+	}
+	if err != nil {
+		return err, nil
+	}
+	if len(failed) > 0 {
+		return upstreamError(failed), nil
+	}
+	if !isNoReply(args[want:]) {
+		writeLine(w, reply)
+	}
+	return nil, nil
+}
+
+// maxRelativeExptime — exptime больше 30 дней memcached считает unix-временем истечения
+const maxRelativeExptime = 30 * 24 * 60 * 60
+
+// expirationTtl переводит exptime команды в TTL записи (секунды); nil — TTL слоёв кэша.
+// Уже наступившее unix-время даёт минимальный TTL в 1 секунду.
+func expirationTtl(exptime int64, now time.Time) *int64 {
+	if exptime <= 0 {
+		return nil
+	}
+	ttl := exptime
+	if exptime > maxRelativeExptime {
+		ttl = max(exptime-now.Unix(), 1)
+	}
+	return &ttl
+}
+
+// delete <key> [0] [noreply]. Отвечает DELETED: существовал ли ключ, не проверяется.
+func (s *Server) delete(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) == 0 || len(args) > 3 {
+		return errBadFormat
+	}
+	id, err := s.resolve(args[0])
+	if err != nil {
+		return err
+	}
+	failed, err := s.adapter.Evict(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return upstreamError(failed)
+	}
+	if !isNoReply(args[1:]) {
+		writeLine(w, "DELETED")
+	}
+	return nil
+}
+
+// touch <key> <exptime> [noreply]. Продлевает время жизни записи в слоях кэша, не перезаписывая
+// значение; exptime — как в set (<= 0 — TTL слоёв). Внешний API не вызывается: TOUCHED, если ключ
+// был хотя бы в одном слое, иначе NOT_FOUND.
+func (s *Server) touch(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return errBadFormat
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return errBadFormat
+	}
+	id, err := s.resolve(args[0])
+	if err != nil {
+		return err
+	}
+	entry := &dto.CacheEntry{CacheId: id, TTL: expirationTtl(exptime, time.Now())}
+	results := s.adapter.TouchAll(ctx, []*dto.CacheEntry{entry})
+	if isNoReply(args[2:]) {
+		return nil
+	}
+	if len(results) > 0 && results[0].Touched {
+		writeLine(w, "TOUCHED")
+	} else {
+		writeLine(w, "NOT_FOUND")
+	}
+	return nil
+}
+
+/* ---------- протокол ---------- */
+
+func writeLine(w *bufio.Writer, s string) {
+	w.WriteString(s + "\r\n")
+}
+
+// readData читает блок данных команды хранения: size байт и CRLF.
+func readData(r *bufio.Reader, size int) ([]byte, error) {
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	if buf[size] != '\r' || buf[size+1] != '\n' {
+		return nil, errBadChunk
+	}
+	return buf[:size], nil
+}
+
+// skipData дочитывает блок данных неподдерживаемой команды хранения.
+func skipData(r *bufio.Reader, args []string) (cmdErr error, streamErr error) {
+	if len(args) < 4 {
+		return errBadFormat, nil
+	}
+	size, err := strconv.Atoi(args[3])
+	if err != nil || size < 0 {
+		return errBadFormat, nil
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(size)+2); err != nil {
+		return errBadChunk, err
+	}
+	return nil, nil
+}
+
+func isNoReply(args []string) bool {
+	return len(args) > 0 && args[len(args)-1] == noReply
+}
+
+// upstreamError сообщает, что внешний API не принял ключ (в REST — HTTP 502).
+func upstreamError(failed []*dto.UpstreamError) error {
+	first := failed[0]
+	return fmt.Errorf("upstream rejected %s: %s", first.Key, first.Error)
+}
+
+// errorLine формирует строку ошибки: CLIENT_ERROR — ошибка в запросе,
+// SERVER_ERROR — запрос корректен, но не может быть выполнен (в том числе очередь записи заполнена).
+func errorLine(err error) string {
+	msg := strings.NewReplacer("\r", " ", "\n", " ").Replace(err.Error())
+	switch {
+	case errors.Is(err, errBadFormat), errors.Is(err, errBadChunk), errors.Is(err, errKeyTooLong), errors.Is(err, errUnknownPrefix):
+		return "CLIENT_ERROR " + msg
+	default:
+		return "SERVER_ERROR " + msg
+	}
+}
@@ -0,0 +1,97 @@
+package memcached
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/config"
+	"aur-cache-service/internal/manager"
+	"aur-cache-service/internal/tcpserver"
+
+	"go.uber.org/zap"
+)
+
+const (
+	maxLineSize  = 8 << 10 // Максимальная длина строки команды
+	maxKeyLength = 250     // Максимальная длина ключа, как в memcached
+	maxValueSize = 5 << 20 // Максимальный размер значения: 5 МБ, как у тела HTTP-запроса
+)
+
+// Server — TCP-сервер текстового протокола memcached (get/gets/set/delete/touch),
+// выполняющий команды через ManagerAdapter.
+type Server struct {
+	*tcpserver.Server
+	adapter manager.ManagerAdapter
+
+	// prefixes отсортированы по убыванию длины: выигрывает самый длинный совпавший префикс
+	prefixes []string
+	caches   map[string]string
+}
+
+func NewServer(adapter manager.ManagerAdapter, cfg config.MemcachedConfig) *Server {
+	s := &Server{adapter: adapter, caches: make(map[string]string, len(cfg.Prefixes))}
+	for prefix, cacheName := range cfg.Prefixes {
+		s.prefixes = append(s.prefixes, prefix)
+		s.caches[prefix] = cacheName
+	}
+	sort.Slice(s.prefixes, func(i, j int) bool { return len(s.prefixes[i]) > len(s.prefixes[j]) })
+
+	s.Server = tcpserver.New("memcached", s.serveConn)
+	return s
+}
+
+// resolve сопоставляет ключ memcached с ключом кэша по префиксу.
+func (s *Server) resolve(key string) (*dto.CacheId, error) {
+	if len(key) > maxKeyLength {
+		return nil, errKeyTooLong
+	}
+	for _, prefix := range s.prefixes {
+		if rest, ok := strings.CutPrefix(key, prefix); ok && rest != "" {
+			return &dto.CacheId{CacheName: s.caches[prefix], Key: rest}, nil
+		}
+	}
+	return nil, errUnknownPrefix
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	r := bufio.NewReaderSize(conn, maxLineSize)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				writeLine(w, "CLIENT_ERROR line too long")
+				w.Flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				zap.S().Debugw("memcached connection error", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+			return
+		}
+		args := strings.Fields(string(line))
+		if len(args) == 0 {
+			continue
+		}
+
+		quit, err := s.execute(r, w, args)
+		if err != nil {
+			// клиент прислал данные, после которых поток команд не восстановить
+			w.Flush()
+			return
+		}
+
+		// ответы на команды, присланные пачкой, отправляются одной записью
+		if r.Buffered() == 0 || quit {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
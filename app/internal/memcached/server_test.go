@@ -0,0 +1,294 @@
+package memcached
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/cache/config"
+	"aur-cache-service/internal/manager"
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockAdapter хранит значения в памяти по ключу <cache>:<key>.
+type mockAdapter struct {
+	mu       sync.Mutex
+	values   map[string]json.RawMessage
+	ttls     map[string]int64
+	writeErr error
+}
+
+func newMockAdapter() *mockAdapter {
+	return &mockAdapter{values: make(map[string]json.RawMessage), ttls: make(map[string]int64)}
+}
+
+func storageKey(id *dto.CacheId) string { return id.CacheName + ":" + id.Key }
+
+func (m *mockAdapter) value(key string) (json.RawMessage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *mockAdapter) Get(ctx context.Context, id *dto.CacheId) *dto.CacheEntryHit {
+	return m.GetAll(ctx, []*dto.CacheId{id})[0]
+}
+
+func (m *mockAdapter) Put(ctx context.Context, e *dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	return m.PutAll(ctx, []*dto.CacheEntry{e})
+}
+
+func (m *mockAdapter) Evict(ctx context.Context, id *dto.CacheId) ([]*dto.UpstreamError, error) {
+	return m.EvictAll(ctx, []*dto.CacheId{id})
+}
+
+func (m *mockAdapter) GetAll(_ context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hits := make([]*dto.CacheEntryHit, 0, len(ids))
+	for _, id := range ids {
+		v, ok := m.values[storageKey(id)]
+		hit := &dto.CacheEntryHit{CacheEntry: &dto.CacheEntry{CacheId: id}, Found: ok}
+		if ok {
+			hit.Value = &v
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+func (m *mockAdapter) GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, _ dto.GetOptions) []*dto.CacheEntryHit {
+	return m.GetAll(ctx, ids)
+}
+
+func (m *mockAdapter) PutAll(_ context.Context, entries []*dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	if m.writeErr != nil {
+		return nil, m.writeErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range entries {
+		current, ok := m.values[storageKey(e.CacheId)]
+		if (e.IfAbsent && ok) || (e.IfVersion != "" && (!ok || dto.ValueVersion(current) != e.IfVersion)) {
+			return nil, &manager.ConflictError{Keys: []*dto.CacheId{e.CacheId}}
+		}
+		m.values[storageKey(e.CacheId)] = *e.Value
+		if e.TTL != nil {
+			m.ttls[storageKey(e.CacheId)] = *e.TTL
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockAdapter) EvictAll(_ context.Context, ids []*dto.CacheId) ([]*dto.UpstreamError, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.values, storageKey(id))
+	}
+	return nil, nil
+}
+
+func (m *mockAdapter) PutAllSync(context.Context, []*dto.CacheEntry) *dto.WriteReport { return nil }
+func (m *mockAdapter) EvictAllSync(context.Context, []*dto.CacheId) *dto.WriteReport  { return nil }
+
+func (m *mockAdapter) TouchAll(_ context.Context, entries []*dto.CacheEntry) []*dto.TouchItemResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]*dto.TouchItemResult, 0, len(entries))
+	for _, e := range entries {
+		_, ok := m.values[storageKey(e.CacheId)]
+		if ok && e.TTL != nil {
+			m.ttls[storageKey(e.CacheId)] = *e.TTL
+		}
+		res = append(res, &dto.TouchItemResult{CacheId: e.CacheId, Touched: ok})
+	}
+	return res
+}
+
+func (m *mockAdapter) EvictByTag(_ context.Context, tag string) *dto.TagEvictReport {
+	return &dto.TagEvictReport{Tag: tag}
+}
+
+// client — «сырой» TCP-клиент текстового протокола memcached.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startServer(t *testing.T, adapter manager.ManagerAdapter) *client {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := NewServer(adapter, config.MemcachedConfig{
+		Prefixes: map[string]string{"u:": "user", "u:admin:": "admin"},
+	})
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// expect отправляет raw (строки через \r\n) и проверяет строки ответа.
+func (c *client) expect(raw string, want ...string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(raw)); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+	for _, w := range want {
+		s, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("%q: read: %v", raw, err)
+		}
+		if got := strings.TrimRight(s, "\r\n"); got != w {
+			c.t.Fatalf("%q: got %q, want %q", raw, got, w)
+		}
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	c.expect("set u:1 5 0 14\r\n{\"name\":\"Ann\"}\r\n", "STORED")
+	c.expect("get u:1 u:2\r\n", `VALUE u:1 5 14`, `{"name":"Ann"}`, "END")
+
+	// значение, не являющееся JSON, хранится как JSON-строка и возвращается как есть
+	c.expect("set u:2 0 0 5 noreply\r\nhello\r\nget u:2\r\n", "VALUE u:2 0 5", "hello", "END")
+	if got, _ := adapter.value("user:2"); string(got) != `"hello"` {
+		t.Fatalf("stored %s", got)
+	}
+}
+
+func TestSetGetFlagsAndBinary(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	// значение, сериализованное клиентом (флаг 1) и сжатое (флаг 2), возвращается с теми же флагами
+	c.expect("set u:1 3 0 6\r\n\xff\x00\r\n\x80z\r\n", "STORED")
+	stored, _ := adapter.value("user:1")
+	if string(stored) != `{"$bytes":"/wANCoB6","$flags":3}` {
+		t.Fatalf("stored %s", stored)
+	}
+	c.expect("gets u:1\r\n", "VALUE u:1 3 6 "+dto.ValueVersion(stored))
+	data := make([]byte, 8)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != "\xff\x00\r\n\x80z\r\n" {
+		t.Fatalf("value changed: %q", data)
+	}
+	c.expect("", "END")
+
+	// JSON-строка с флагами тоже возвращается как была записана
+	c.expect("set u:2 16 0 8\r\n\"quoted\"\r\n", "STORED")
+	c.expect("get u:2\r\n", "VALUE u:2 16 8", `"quoted"`, "END")
+}
+
+func TestSetExptime(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	c.expect("set u:1 0 60 1\r\n1\r\n", "STORED")
+	c.expect("set u:2 0 0 1\r\n2\r\n", "STORED")
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+	if adapter.ttls["user:1"] != 60 {
+		t.Fatalf("ttls=%v", adapter.ttls)
+	}
+	if _, ok := adapter.ttls["user:2"]; ok {
+		t.Fatalf("ttl set for exptime 0")
+	}
+}
+
+func TestExpirationTtl(t *testing.T) {
+	now := time.Unix(2_000_000_000, 0)
+	if ttl := expirationTtl(0, now); ttl != nil {
+		t.Fatalf("exptime 0: %d", *ttl)
+	}
+	if ttl := expirationTtl(100, now); *ttl != 100 {
+		t.Fatalf("relative: %d", *ttl)
+	}
+	if ttl := expirationTtl(now.Unix()+300, now); *ttl != 300 {
+		t.Fatalf("absolute: %d", *ttl)
+	}
+	if ttl := expirationTtl(now.Unix()-300, now); *ttl != 1 {
+		t.Fatalf("past: %d", *ttl)
+	}
+}
+
+func TestLongestPrefixWins(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	c.expect("set u:admin:7 0 0 1\r\n1\r\n", "STORED")
+	if _, ok := adapter.value("admin:7"); !ok {
+		t.Fatalf("value not stored in admin cache")
+	}
+	c.expect("get x:1\r\n", "CLIENT_ERROR no cache is mapped to the key prefix")
+}
+
+func TestGetsDeleteTouch(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	c.expect("set u:1 0 0 2\r\n42\r\n", "STORED")
+	c.expect("gets u:1\r\n", "VALUE u:1 0 2 "+dto.ValueVersion(json.RawMessage(`"42"`)), "42", "END")
+	c.expect("touch u:1 100\r\n", "TOUCHED")
+	adapter.mu.Lock()
+	ttl := adapter.ttls["user:1"]
+	adapter.mu.Unlock()
+	if ttl != 100 {
+		t.Fatalf("ttl not extended: %d", ttl)
+	}
+	c.expect("delete u:1\r\n", "DELETED")
+	c.expect("touch u:1 100\r\n", "NOT_FOUND")
+	c.expect("get u:1\r\n", "END")
+}
+
+func TestAddCas(t *testing.T) {
+	c := startServer(t, newMockAdapter())
+	version := dto.ValueVersion(json.RawMessage(`"42"`))
+
+	c.expect("add u:1 0 0 2\r\n42\r\n", "STORED")
+	c.expect("add u:1 0 0 2\r\n43\r\n", "NOT_STORED")
+	c.expect("cas u:1 0 0 2 1\r\n43\r\n", "EXISTS")
+	c.expect("cas u:1 0 0 2 "+version+"\r\n43\r\n", "STORED")
+	c.expect("cas u:1 0 0 2 "+version+" noreply\r\n44\r\nget u:1\r\n", "VALUE u:1 0 2", "43", "END")
+}
+
+func TestErrors(t *testing.T) {
+	c := startServer(t, newMockAdapter())
+
+	c.expect("flush_all\r\n", "ERROR")
+	c.expect("set u:1 0 0\r\n", "CLIENT_ERROR bad command line format")
+	c.expect("append u:1 0 0 2\r\nab\r\nversion\r\n", "SERVER_ERROR command not supported", "VERSION aur-cache-service")
+	c.expect("cas u:1 0 0 2 abc\r\n42\r\n", "CLIENT_ERROR bad command line format")
+	c.expect("get u:"+strings.Repeat("k", maxKeyLength)+"\r\n", "CLIENT_ERROR key too long")
+
+	// блок данных не завершён CRLF: ответ об ошибке и закрытие соединения
+	c.expect("set u:1 0 0 1\r\n12\r\n", "CLIENT_ERROR bad data chunk")
+}
+
+func TestQueueFull(t *testing.T) {
+	adapter := newMockAdapter()
+	adapter.writeErr = manager.ErrQueueFull
+	c := startServer(t, adapter)
+
+	c.expect("set u:1 0 0 1\r\n1\r\n", "SERVER_ERROR "+manager.ErrQueueFull.Error())
+}
@@ -26,6 +26,44 @@ var (
 		[]string{"path", "method"},
 	)
 
+	// GRPCRequestsTotal counts all gRPC calls processed by the service.
+	// Mirrors HTTPRequestsTotal: method is the full gRPC method name, code is the status code.
+	GRPCRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_requests_total",
+			Help: "Total number of gRPC requests handled by the service.",
+		},
+		[]string{"method", "code"},
+	)
+
+	// GRPCRequestDuration measures how long gRPC handlers take to respond.
+	GRPCRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_request_duration_seconds",
+			Help:    "Histogram of latencies for gRPC requests.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+
+	// RESPCommandsTotal counts commands processed by the Redis-protocol listener.
+	RESPCommandsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resp_commands_total",
+			Help: "Total number of Redis-protocol commands handled by the service.",
+		},
+		[]string{"command", "status"},
+	)
+
+	// MemcachedCommandsTotal counts commands processed by the memcached text-protocol listener.
+	MemcachedCommandsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "memcached_commands_total",
+			Help: "Total number of memcached-protocol commands handled by the service.",
+		},
+		[]string{"command", "status"},
+	)
+
 	// ProviderOperations tracks operations performed by cache providers.
 	ProviderOperations = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -65,6 +103,71 @@ var (
 		[]string{"cache"},
 	)
 
+	// ExternalWrites counts write requests (putBatch, deleteBatch) to external HTTP APIs.
+	ExternalWrites = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "external_write_requests_total",
+			Help: "Count of write requests to external API service.",
+		},
+		[]string{"cache", "operation", "status"},
+	)
+
+	// ExternalWriteDuration measures duration of write requests to external HTTP APIs.
+	ExternalWriteDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "external_write_request_duration_seconds",
+			Help:    "Histogram of external API write request durations.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"cache", "operation"},
+	)
+
+	// ExternalCoalesced counts external fetches that were not sent because
+	// the same key was already being fetched by a concurrent request.
+	ExternalCoalesced = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "external_coalesced_total",
+			Help: "Number of external API fetches coalesced into an in-flight request.",
+		},
+		[]string{"cache"},
+	)
+
+	// BackgroundRefreshes counts keys re-fetched from the external API in
+	// the background, e.g. stale values served in stale-while-revalidate mode.
+	BackgroundRefreshes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_background_refresh_total",
+			Help: "Number of keys refreshed from the external API in the background.",
+		},
+		[]string{"cache", "reason", "status"},
+	)
+
+	// WriteBehindQueueDepth shows how many write-behind operations wait to be applied.
+	WriteBehindQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "write_behind_queue_depth",
+			Help: "Number of write-behind operations waiting to be applied to cache layers.",
+		},
+	)
+
+	// WriteBehindLag measures time from accepting a write to applying it to cache layers.
+	WriteBehindLag = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "write_behind_lag_seconds",
+			Help:    "Histogram of delays between accepting a write and applying it to cache layers.",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	// WriteBehindDropped counts writes that were rejected or lost by the write-behind queue.
+	WriteBehindDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "write_behind_dropped_total",
+			Help: "Number of write-behind operations rejected or not applied.",
+		},
+		[]string{"reason"},
+	)
+
 	// CacheLayerHits counts how many values were found on each cache layer.
 	CacheLayerHits = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -89,15 +192,44 @@ func Register() {
 	prometheus.MustRegister(
 		HTTPRequestsTotal,
 		HTTPRequestDuration,
+		GRPCRequestsTotal,
+		GRPCRequestDuration,
+		RESPCommandsTotal,
+		MemcachedCommandsTotal,
 		ProviderOperations,
 		ProviderOperationDuration,
 		ExternalRequests,
 		ExternalRequestDuration,
+		ExternalWrites,
+		ExternalWriteDuration,
+		ExternalCoalesced,
+		BackgroundRefreshes,
+		WriteBehindQueueDepth,
+		WriteBehindLag,
+		WriteBehindDropped,
 		CacheLayerHits,
 		CacheLayerMisses,
 	)
 }
 
+// RecordRESPCommand increments RESPCommandsTotal with result status.
+func RecordRESPCommand(command string, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	RESPCommandsTotal.WithLabelValues(command, status).Inc()
+}
+
+// RecordMemcachedCommand increments MemcachedCommandsTotal with result status.
+func RecordMemcachedCommand(command string, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	MemcachedCommandsTotal.WithLabelValues(command, status).Inc()
+}
+
 // RecordProviderOp increments ProviderOperations with result status.
 func RecordProviderOp(provider, operation string, err error) {
 	status := "success"
@@ -122,6 +254,52 @@ func RecordExternalRequest(cacheName string, err error, durationSeconds float64)
 	ExternalRequestDuration.WithLabelValues(cacheName).Observe(durationSeconds)
 }
 
+// External write operations.
+const (
+	ExternalOperationPut    = "put"
+	ExternalOperationDelete = "delete"
+)
+
+// RecordExternalWrite records metrics for an external API write call.
+func RecordExternalWrite(cacheName, operation string, err error, durationSeconds float64) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	ExternalWrites.WithLabelValues(cacheName, operation, status).Inc()
+	ExternalWriteDuration.WithLabelValues(cacheName, operation).Observe(durationSeconds)
+}
+
+// RecordCoalescedFetch records a key that reused an in-flight external fetch.
+func RecordCoalescedFetch(cacheName string) {
+	ExternalCoalesced.WithLabelValues(cacheName).Inc()
+}
+
+// Background refresh outcomes.
+const (
+	RefreshStatusUpdated = "updated" // new value written to all layers
+	RefreshStatusRemoved = "removed" // key is gone upstream, removed from all layers
+	RefreshStatusFailed  = "failed"  // external API error, value left as is
+)
+
+// RecordRefresh records the outcome of a background refresh of one key.
+func RecordRefresh(cacheName, reason, status string) {
+	BackgroundRefreshes.WithLabelValues(cacheName, reason, status).Inc()
+}
+
+// Write-behind drop reasons.
+const (
+	DropReasonQueueFull    = "queue_full"    // queue is full, caller got an error
+	DropReasonJournalError = "journal_error" // journal write failed, caller got an error
+	DropReasonPanic        = "panic"         // applying the operation panicked
+	DropReasonLayerError   = "layer_error"   // cache layers kept failing, retries exhausted
+)
+
+// RecordWriteBehindDrop records a write-behind operation that was rejected or not applied.
+func RecordWriteBehindDrop(reason string) {
+	WriteBehindDropped.WithLabelValues(reason).Inc()
+}
+
 // RecordCacheLayer records hits/misses for a cache layer.
 func RecordCacheLayer(level int, hits, misses int) {
 	CacheLayerHits.WithLabelValues(fmt.Sprintf("%d", level)).Add(float64(hits))
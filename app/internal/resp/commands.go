@@ -0,0 +1,324 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/manager"
+	"aur-cache-service/internal/metrics"
+	"aur-cache-service/internal/tcpserver"
+)
+
+// commandTimeout ограничивает выполнение одной команды (включая запрос во внешний API).
+const commandTimeout = 10 * time.Second
+
+// keySeparator отделяет имя кэша от ключа: <cache>:<key>.
+const keySeparator = ":"
+
+type handler func(ctx context.Context, w *bufio.Writer, args []string) error
+
+// execute выполняет команду и пишет ответ в w. Возвращает true, если соединение нужно закрыть.
+func (s *Server) execute(w *bufio.Writer, args []string) (quit bool) {
+	name := strings.ToUpper(args[0])
+
+	var h handler
+	switch name {
+	case "GET":
+		h = s.get
+	case "MGET":
+		h = s.mget
+	case "SET":
+		h = s.set
+	case "MSET":
+		h = s.mset
+	case "DEL", "UNLINK":
+		h = s.del
+	case "EXISTS":
+		h = s.exists
+	case "TTL":
+		h = s.ttl
+	case "PING":
+		h = ping
+	case "QUIT":
+		writeSimple(w, "OK")
+		return true
+	default:
+		writeError(w, "ERR unknown command '"+args[0]+"'")
+		metrics.RecordRESPCommand("unknown", errors.New("unknown command"))
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	err := h(ctx, w, args[1:])
+	if err != nil {
+		writeError(w, errorMessage(name, err))
+	}
+	metrics.RecordRESPCommand(strings.ToLower(name), err)
+	return false
+}
+
+/* ---------- команды ---------- */
+
+func ping(_ context.Context, w *bufio.Writer, args []string) error {
+	switch len(args) {
+	case 0:
+		writeSimple(w, "PONG")
+	case 1:
+		writeBulk(w, []byte(args[0]))
+	default:
+		return errArgs
+	}
+	return nil
+}
+
+// get возвращает значение ключа или nil, если его нет ни в кэше, ни во внешнем API.
+func (s *Server) get(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) != 1 {
+		return errArgs
+	}
+	ids, err := parseKeys(args)
+	if err != nil {
+		return err
+	}
+	hit := s.adapter.Get(ctx, ids[0])
+	writeHit(w, hit)
+	return nil
+}
+
+func (s *Server) mget(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) == 0 {
+		return errArgs
+	}
+	ids, err := parseKeys(args)
+	if err != nil {
+		return err
+	}
+	hits := indexHits(s.adapter.GetAll(ctx, ids))
+
+	writeArrayHeader(w, len(ids))
+	for _, id := range ids {
+		writeHit(w, hits[*id])
+	}
+	return nil
+}
+
+// set сохраняет значение. EX и PX задают время жизни записи вместо TTL слоёв
+// (PX округляется вверх до секунды), NX — запись, только если ключа нет (ответ nil, если он есть).
+// Остальные опции (XX, GET, KEEPTTL, ...) не поддерживаются.
+func (s *Server) set(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) < 2 {
+		return errArgs
+	}
+	if len(args) == 2 {
+		return s.putAll(ctx, w, args)
+	}
+
+	id, err := parseKey(args[0])
+	if err != nil {
+		return err
+	}
+	value := tcpserver.ToJSON([]byte(args[1]), 0)
+	entry := &dto.CacheEntry{CacheId: id, Value: &value}
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "NX" && !entry.IfAbsent:
+			entry.IfAbsent = true
+		case (opt == "EX" || opt == "PX") && entry.TTL == nil && i+1 < len(args):
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return errNotInteger
+			}
+			if n <= 0 {
+				return errExpireTime
+			}
+			ttl := n
+			if opt == "PX" {
+				ttl = (n + 999) / 1000
+			}
+			entry.TTL = &ttl
+		default:
+			return errSyntax
+		}
+	}
+
+	return s.put(ctx, w, []*dto.CacheEntry{entry})
+}
+
+func (s *Server) mset(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return errArgs
+	}
+	return s.putAll(ctx, w, args)
+}
+
+// putAll сохраняет пары key value из args.
+func (s *Server) putAll(ctx context.Context, w *bufio.Writer, args []string) error {
+	entries := make([]*dto.CacheEntry, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		id, err := parseKey(args[i])
+		if err != nil {
+			return err
+		}
+		value := tcpserver.ToJSON([]byte(args[i+1]), 0)
+		entries = append(entries, &dto.CacheEntry{CacheId: id, Value: &value})
+	}
+	return s.put(ctx, w, entries)
+}
+
+// put сохраняет записи; nil в ответ — не выполнено условие записи (SET NX).
+func (s *Server) put(ctx context.Context, w *bufio.Writer, entries []*dto.CacheEntry) error {
+	failed, err := s.adapter.PutAll(ctx, entries)
+	if errors.As(err, new(*manager.ConflictError)) {
+		writeNil(w)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return upstreamError(failed)
+	}
+	writeSimple(w, "OK")
+	return nil
+}
+
+// del удаляет ключи и возвращает число ключей, принятых к удалению.
+// Существовали ли ключи, не проверяется: это потребовало бы чтения из слоёв и внешнего API.
+func (s *Server) del(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) == 0 {
+		return errArgs
+	}
+	ids, err := parseKeys(args)
+	if err != nil {
+		return err
+	}
+	failed, err := s.adapter.EvictAll(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return upstreamError(failed)
+	}
+	writeInt(w, len(ids))
+	return nil
+}
+
+// exists возвращает число найденных ключей (повторяющиеся ключи считаются несколько раз, как в Redis).
+func (s *Server) exists(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) == 0 {
+		return errArgs
+	}
+	ids, err := parseKeys(args)
+	if err != nil {
+		return err
+	}
+	hits := indexHits(s.adapter.GetAll(ctx, ids))
+
+	count := 0
+	for _, id := range ids {
+		if hit := hits[*id]; hit != nil && hit.Found {
+			count++
+		}
+	}
+	writeInt(w, count)
+	return nil
+}
+
+// ttl возвращает -2, если ключа нет, и -1, если он есть: оставшееся время жизни
+// различается по слоям и через ManagerAdapter недоступно.
+func (s *Server) ttl(ctx context.Context, w *bufio.Writer, args []string) error {
+	if len(args) != 1 {
+		return errArgs
+	}
+	ids, err := parseKeys(args)
+	if err != nil {
+		return err
+	}
+	if hit := s.adapter.Get(ctx, ids[0]); hit != nil && hit.Found {
+		writeInt(w, -1)
+	} else {
+		writeInt(w, -2)
+	}
+	return nil
+}
+
+/* ---------- ключи и значения ---------- */
+
+// parseKey разбирает ключ вида <cache>:<key>. Двоеточия после первого относятся к key.
+func parseKey(s string) (*dto.CacheId, error) {
+	cacheName, key, found := strings.Cut(s, keySeparator)
+	if !found || cacheName == "" || key == "" {
+		return nil, errKeyFormat
+	}
+	return &dto.CacheId{CacheName: cacheName, Key: key}, nil
+}
+
+func parseKeys(args []string) ([]*dto.CacheId, error) {
+	ids := make([]*dto.CacheId, 0, len(args))
+	for _, arg := range args {
+		id, err := parseKey(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func indexHits(hits []*dto.CacheEntryHit) map[dto.CacheId]*dto.CacheEntryHit {
+	res := make(map[dto.CacheId]*dto.CacheEntryHit, len(hits))
+	for _, h := range hits {
+		if h != nil && h.CacheEntry != nil && h.CacheEntry.CacheId != nil {
+			res[*h.CacheEntry.CacheId] = h
+		}
+	}
+	return res
+}
+
+func writeHit(w *bufio.Writer, hit *dto.CacheEntryHit) {
+	if hit == nil || !hit.Found || hit.CacheEntry == nil || hit.Value == nil {
+		writeNil(w)
+		return
+	}
+	value, _ := tcpserver.FromJSON(*hit.Value)
+	writeBulk(w, value)
+}
+
+/* ---------- ошибки ---------- */
+
+var (
+	errArgs      = errors.New("wrong number of arguments")
+	errSyntax    = errors.New("syntax error")
+	errKeyFormat = errors.New("key must have the form <cache>:<key>")
+
+	errNotInteger = errors.New("value is not an integer or out of range")
+	errExpireTime = errors.New("invalid expire time in 'set' command")
+)
+
+// upstreamError сообщает, что внешний API не принял часть ключей (в REST — HTTP 502).
+func upstreamError(failed []*dto.UpstreamError) error {
+	first := failed[0]
+	return fmt.Errorf("upstream rejected %d key(s), first %s%s%s: %s", len(failed), first.CacheName, keySeparator, first.Key, first.Error)
+}
+
+// errorMessage формирует текст ошибки RESP с кодом в начале.
+// Заполненная очередь записи — TRYAGAIN: клиенту стоит повторить команду позже.
+func errorMessage(command string, err error) string {
+	switch {
+	case errors.Is(err, errArgs):
+		return "ERR wrong number of arguments for '" + strings.ToLower(command) + "' command"
+	case errors.Is(err, manager.ErrQueueFull), errors.Is(err, manager.ErrQueueClosed):
+		return "TRYAGAIN " + err.Error()
+	default:
+		return "ERR " + err.Error()
+	}
+}
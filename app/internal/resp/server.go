@@ -0,0 +1,58 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+
+	"aur-cache-service/internal/manager"
+	"aur-cache-service/internal/tcpserver"
+
+	"go.uber.org/zap"
+)
+
+// Server — TCP-сервер, принимающий команды по протоколу Redis (RESP) и выполняющий их
+// через ManagerAdapter: GET/MGET/SET/MSET/DEL/EXISTS/TTL над ключами вида <cache>:<key>.
+type Server struct {
+	*tcpserver.Server
+	adapter manager.ManagerAdapter
+}
+
+func NewServer(adapter manager.ManagerAdapter) *Server {
+	s := &Server{adapter: adapter}
+	s.Server = tcpserver.New("resp", s.serveConn)
+	return s
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	r := bufio.NewReaderSize(conn, maxLineSize)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				writeError(w, "ERR "+err.Error())
+				w.Flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				zap.S().Debugw("resp connection error", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.execute(w, args)
+
+		// ответы на команды, присланные пачкой (pipelining), отправляются одной записью
+		if r.Buffered() == 0 || quit {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
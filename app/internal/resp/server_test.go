@@ -0,0 +1,268 @@
+package resp
+
+import (
+	"aur-cache-service/api/dto"
+	"aur-cache-service/internal/manager"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockAdapter хранит значения в памяти по ключу <cache>:<key>.
+type mockAdapter struct {
+	mu        sync.Mutex
+	values    map[string]json.RawMessage
+	ttls      map[string]int64
+	putFailed []*dto.UpstreamError
+	writeErr  error
+}
+
+func newMockAdapter() *mockAdapter {
+	return &mockAdapter{values: make(map[string]json.RawMessage), ttls: make(map[string]int64)}
+}
+
+func storageKey(id *dto.CacheId) string { return id.CacheName + ":" + id.Key }
+
+func (m *mockAdapter) Get(ctx context.Context, id *dto.CacheId) *dto.CacheEntryHit {
+	return m.GetAll(ctx, []*dto.CacheId{id})[0]
+}
+
+func (m *mockAdapter) Put(ctx context.Context, e *dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	return m.PutAll(ctx, []*dto.CacheEntry{e})
+}
+
+func (m *mockAdapter) Evict(ctx context.Context, id *dto.CacheId) ([]*dto.UpstreamError, error) {
+	return m.EvictAll(ctx, []*dto.CacheId{id})
+}
+
+func (m *mockAdapter) GetAll(_ context.Context, ids []*dto.CacheId) []*dto.CacheEntryHit {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hits := make([]*dto.CacheEntryHit, 0, len(ids))
+	for _, id := range ids {
+		v, ok := m.values[storageKey(id)]
+		hit := &dto.CacheEntryHit{CacheEntry: &dto.CacheEntry{CacheId: id}, Found: ok}
+		if ok {
+			hit.Value = &v
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+func (m *mockAdapter) GetAllWithOptions(ctx context.Context, ids []*dto.CacheId, _ dto.GetOptions) []*dto.CacheEntryHit {
+	return m.GetAll(ctx, ids)
+}
+
+func (m *mockAdapter) PutAll(_ context.Context, entries []*dto.CacheEntry) ([]*dto.UpstreamError, error) {
+	if m.writeErr != nil || m.putFailed != nil {
+		return m.putFailed, m.writeErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range entries {
+		if _, ok := m.values[storageKey(e.CacheId)]; ok && e.IfAbsent {
+			return nil, &manager.ConflictError{Keys: []*dto.CacheId{e.CacheId}}
+		}
+		m.values[storageKey(e.CacheId)] = *e.Value
+		if e.TTL != nil {
+			m.ttls[storageKey(e.CacheId)] = *e.TTL
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockAdapter) EvictAll(_ context.Context, ids []*dto.CacheId) ([]*dto.UpstreamError, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.values, storageKey(id))
+	}
+	return nil, nil
+}
+
+func (m *mockAdapter) PutAllSync(context.Context, []*dto.CacheEntry) *dto.WriteReport { return nil }
+func (m *mockAdapter) EvictAllSync(context.Context, []*dto.CacheId) *dto.WriteReport  { return nil }
+func (m *mockAdapter) TouchAll(context.Context, []*dto.CacheEntry) []*dto.TouchItemResult {
+	return nil
+}
+
+func (m *mockAdapter) EvictByTag(_ context.Context, tag string) *dto.TagEvictReport {
+	return &dto.TagEvictReport{Tag: tag}
+}
+
+// client — «сырой» TCP-клиент: отправляет команды в формате RESP и читает ответы построчно.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func startServer(t *testing.T, adapter manager.ManagerAdapter) *client {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := NewServer(adapter)
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *client) send(args ...string) {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+// line читает одну строку ответа без CRLF.
+func (c *client) line() string {
+	s, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return strings.TrimRight(s, "\r\n")
+}
+
+func (c *client) expect(args []string, want ...string) {
+	c.t.Helper()
+	c.send(args...)
+	for _, w := range want {
+		if got := c.line(); got != w {
+			c.t.Fatalf("%v: got %q, want %q", args, got, w)
+		}
+	}
+}
+
+func TestSetGet(t *testing.T) {
+	c := startServer(t, newMockAdapter())
+
+	c.expect([]string{"SET", "user:1", `{"name":"Ann"}`}, "+OK")
+	c.expect([]string{"GET", "user:1"}, "$14", `{"name":"Ann"}`)
+
+	// строка, не являющаяся JSON, хранится как JSON-строка и возвращается как есть
+	c.expect([]string{"set", "user:2", "plain text"}, "+OK")
+	c.expect([]string{"GET", "user:2"}, "$10", "plain text")
+
+	c.expect([]string{"GET", "user:3"}, "$-1")
+}
+
+func TestSetGetBinarySafe(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	// JSON-строка возвращается вместе с кавычками, как была записана
+	c.expect([]string{"SET", "user:1", `"quoted"`}, "+OK")
+	c.expect([]string{"GET", "user:1"}, "$8", `"quoted"`)
+
+	// байты не в UTF-8 хранятся в base64 и возвращаются без изменений
+	binary := "\xff\x00\xfe\x80bin"
+	c.expect([]string{"SET", "user:2", binary}, "+OK")
+	c.expect([]string{"GET", "user:2"}, "$7", binary)
+	if got := string(adapter.values["user:2"]); got != `{"$bytes":"/wD+gGJpbg=="}` {
+		t.Fatalf("stored %s", got)
+	}
+}
+
+func TestMsetMgetExistsDel(t *testing.T) {
+	c := startServer(t, newMockAdapter())
+
+	c.expect([]string{"MSET", "c:1", "1", "c:2", "2"}, "+OK")
+	c.expect([]string{"MGET", "c:1", "c:missing", "c:2"}, "*3", "$1", "1", "$-1", "$1", "2")
+	c.expect([]string{"EXISTS", "c:1", "c:2", "c:missing"}, ":2")
+	c.expect([]string{"DEL", "c:1", "c:2"}, ":2")
+	c.expect([]string{"EXISTS", "c:1"}, ":0")
+}
+
+func TestTTL(t *testing.T) {
+	c := startServer(t, newMockAdapter())
+
+	c.expect([]string{"SET", "c:1", "1"}, "+OK")
+	c.expect([]string{"TTL", "c:1"}, ":-1")
+	c.expect([]string{"TTL", "c:2"}, ":-2")
+}
+
+func TestSetExpiry(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	c.expect([]string{"SET", "c:1", "1", "EX", "10"}, "+OK")
+	c.expect([]string{"SET", "c:2", "2", "px", "1500"}, "+OK")
+	c.expect([]string{"SET", "c:3", "3"}, "+OK")
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+	if adapter.ttls["c:1"] != 10 || adapter.ttls["c:2"] != 2 {
+		t.Fatalf("ttls=%v", adapter.ttls)
+	}
+	if _, ok := adapter.ttls["c:3"]; ok {
+		t.Fatalf("ttl set without EX/PX")
+	}
+}
+
+func TestSetNX(t *testing.T) {
+	adapter := newMockAdapter()
+	c := startServer(t, adapter)
+
+	c.expect([]string{"SET", "c:1", "1", "NX", "EX", "10"}, "+OK")
+	c.expect([]string{"SET", "c:1", "2", "NX"}, "$-1")
+	c.expect([]string{"GET", "c:1"}, "$1", "1")
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+	if adapter.ttls["c:1"] != 10 {
+		t.Fatalf("ttls=%v", adapter.ttls)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := startServer(t, newMockAdapter())
+
+	c.expect([]string{"FLUSHALL"}, "-ERR unknown command 'FLUSHALL'")
+	c.expect([]string{"GET", "nocache"}, "-ERR key must have the form <cache>:<key>")
+	c.expect([]string{"GET"}, "-ERR wrong number of arguments for 'get' command")
+	c.expect([]string{"SET", "c:1", "1", "XX"}, "-ERR syntax error")
+	c.expect([]string{"SET", "c:1", "1", "EX", "10", "EX", "10"}, "-ERR syntax error")
+	c.expect([]string{"SET", "c:1", "1", "EX", "ten"}, "-ERR value is not an integer or out of range")
+	c.expect([]string{"SET", "c:1", "1", "EX", "0"}, "-ERR invalid expire time in 'set' command")
+	c.expect([]string{"PING"}, "+PONG")
+}
+
+func TestQueueFull(t *testing.T) {
+	adapter := newMockAdapter()
+	adapter.writeErr = manager.ErrQueueFull
+	c := startServer(t, adapter)
+
+	c.expect([]string{"SET", "c:1", "1"}, "-TRYAGAIN write-behind queue is full")
+}
+
+func TestInlineAndPipeline(t *testing.T) {
+	c := startServer(t, newMockAdapter())
+
+	if _, err := c.conn.Write([]byte("SET c:1 42\r\nGET c:1\r\nPING\r\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for _, want := range []string{"+OK", "$2", "42", "+PONG"} {
+		if got := c.line(); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package tcpserver — общий TCP-сервер для текстовых протоколов (RESP, memcached):
+// принимает соединения, обрабатывает каждое в отдельной горутине и закрывает их при остановке.
+package tcpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"go.uber.org/zap"
+	"telegram-alerts-go/alert"
+)
+
+type Server struct {
+	name   string
+	handle func(conn net.Conn)
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// New создаёт сервер; handle обслуживает одно соединение и возвращается, когда оно закрыто.
+func New(name string, handle func(conn net.Conn)) *Server {
+	return &Server{name: name, handle: handle, conns: make(map[net.Conn]struct{})}
+}
+
+// Listen запускает сервер на порту port и блокируется до его остановки.
+func (s *Server) Listen(port int) {
+	addr := fmt.Sprintf(":%d", port)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		zap.S().Fatalw(alert.Prefix("listen error"), "name", s.name, "addr", addr, "error", err)
+	}
+
+	zap.S().Infow("starting server", "name", s.name, "addr", addr)
+	if err := s.Serve(lis); err != nil {
+		zap.S().Fatalw(alert.Prefix("server error"), "name", s.name, "error", err)
+	}
+}
+
+// Serve принимает соединения, пока сервер не будет закрыт через Close.
+func (s *Server) Serve(lis net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return lis.Close()
+	}
+	s.listener = lis
+	s.mu.Unlock()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close перестаёт принимать соединения, закрывает открытые и дожидается их обработчиков.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			zap.S().Errorf(alert.Prefix("panic in %s connection: %v"), s.name, r)
+		}
+	}()
+
+	s.handle(conn)
+}
@@ -0,0 +1,64 @@
+package tcpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Значения в слоях кэша хранятся как JSON, а клиенты текстовых протоколов (Redis, memcached)
+// передают произвольные байты, которые должны вернуться клиенту без изменений, а клиенты
+// memcached — ещё и флаги (ими библиотеки помечают сериализованные и сжатые значения).
+// Преобразование:
+//   - байты в UTF-8 без флагов сохраняются как JSON-строка, даже если сами являются JSON;
+//   - остальные значения сохраняются как объект {"$bytes": "<base64>", "$flags": <флаги>};
+//   - при чтении JSON-строка отдаётся без кавычек, объект {"$bytes": ...} — декодированным
+//     вместе с флагами, остальной JSON (например, записанный через REST) — как есть с флагами 0.
+
+// binaryValue — значение клиента, не являющееся строкой UTF-8, или значение с флагами
+// (encoding/json кодирует []byte в base64).
+type binaryValue struct {
+	Bytes []byte `json:"$bytes"`
+	Flags uint32 `json:"$flags,omitempty"`
+}
+
+// ToJSON переводит значение клиента и его флаги в значение кэша.
+func ToJSON(b []byte, flags uint32) json.RawMessage {
+	var encoded []byte
+	if flags == 0 && utf8.Valid(b) {
+		encoded, _ = json.Marshal(string(b))
+	} else {
+		encoded, _ = json.Marshal(binaryValue{Bytes: b, Flags: flags})
+	}
+	return encoded
+}
+
+// FromJSON переводит значение кэша в значение для клиента и его флаги.
+func FromJSON(raw json.RawMessage) ([]byte, uint32) {
+	if len(raw) == 0 {
+		return raw, 0
+	}
+	switch raw[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return []byte(s), 0
+		}
+	case '{':
+		if v, ok := decodeBinary(raw); ok {
+			return v.Bytes, v.Flags
+		}
+	}
+	return raw, 0
+}
+
+// decodeBinary разбирает объект binaryValue; объекты с другими полями — обычный JSON.
+func decodeBinary(raw json.RawMessage) (*binaryValue, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var v binaryValue
+	if err := dec.Decode(&v); err != nil || v.Bytes == nil || dec.More() {
+		return nil, false
+	}
+	return &v, true
+}